@@ -26,6 +26,7 @@ class Actor(BaseContract):
 
 
 class Quota(BaseContract):
+    # -1 when the user has no token quota
     tokens_remaining: int = 0
     calls_today: int = 0
     tier: str = "free"
@@ -142,6 +143,8 @@ AIResult = EditProposal | InlineTransformResult | InlineAssistResult | RAGAnswer
 class AgentInlineEditResponse(BaseContract):
     text: str = ""
     result: AIResult | None = None
+    usage: dict[str, int] = Field(default_factory=dict)
+    model_name: str | None = None
 
 
 class ConsentDecisionRequest(BaseContract):
@@ -170,6 +173,7 @@ class ToolCallEvent(StreamEventBase):
     type: Literal["tool.call"] = "tool.call"
     tool_call_id: str
     tool: str
+    args: dict[str, Any] = Field(default_factory=dict)
 
 
 class ToolResultEvent(StreamEventBase):
@@ -196,3 +200,5 @@ class RunFailedEvent(StreamEventBase):
     code: str
     message: str
     retryable: bool = False
+    usage: dict[str, Any] | None = None
+    model_name: str | None = None
@@ -59,6 +59,12 @@ def tool_call_id_from_args(args: Any) -> str:
     return str(args.get("_tool_call_id", ""))
 
 
+def tool_call_args(args: Any) -> dict[str, Any]:
+    if not isinstance(args, dict):
+        return {}
+    return {key: value for key, value in args.items() if not key.startswith("_")}
+
+
 def queue_event(state: "RunState", event: Any) -> None:
     state.queue.put_nowait(now_payload(event))
 
@@ -73,6 +79,7 @@ def queue_failed_event(
     code: str,
     message: str,
     retryable: bool = False,
+    usage: TokenUsageInfo | None = None,
 ) -> None:
     queue_event(
         state,
@@ -81,6 +88,8 @@ def queue_failed_event(
             code=code,
             message=message,
             retryable=retryable,
+            usage=usage_payload(usage) if usage is not None else None,
+            model_name=MODEL_NAME if usage is not None else None,
         ),
     )
 
@@ -171,7 +180,14 @@ class StreamCallbacks(AgentCallbacks):
     def on_tool_call_start(self, name: str, args: Any) -> None:
         tool_call_id = tool_call_id_from_args(args)
         self._tool_call_by_name[name] = tool_call_id
-        self._emit(ToolCallEvent(run_id=self.request.run_id, tool_call_id=tool_call_id, tool=name))
+        self._emit(
+            ToolCallEvent(
+                run_id=self.request.run_id,
+                tool_call_id=tool_call_id,
+                tool=name,
+                args=tool_call_args(args),
+            )
+        )
 
     def on_tool_call_end(self, name: str, result: str) -> None:
         tool_call_id = self._tool_call_by_name.get(name, "")
@@ -298,6 +314,7 @@ async def run_agent_task(request: AgentRunRequest, state: RunState) -> None:
             code="INTERNAL",
             message=str(exc),
             retryable=False,
+            usage=callbacks.state.last_usage,
         )
     finally:
         close_queue(state)
@@ -307,6 +324,7 @@ async def run_inline_edit_task(
     request: AgentInlineEditRequest,
 ) -> AgentInlineEditResponse:
     client = AsyncOpenAI()
+    usage: list[TokenUsageInfo] = []
     try:
         text = await run_inline_edit(
             action=request.action,
@@ -329,6 +347,7 @@ async def run_inline_edit_task(
             },
             timeout_ms=request.policy.timeout_ms,
             max_tokens=request.policy.max_tokens,
+            on_usage=usage.append,
         )
     except TimeoutError as exc:
         raise HTTPException(
@@ -346,7 +365,12 @@ async def run_inline_edit_task(
     elif hasattr(text, "answer"):
         result_text = str(text.answer)
 
-    return AgentInlineEditResponse(text=result_text, result=text)
+    return AgentInlineEditResponse(
+        text=result_text,
+        result=text,
+        usage=usage_payload(usage[-1] if usage else None),
+        model_name=MODEL_NAME,
+    )
 
 
 async def run_inline_edit_streaming_task(
@@ -396,6 +420,7 @@ async def run_inline_edit_streaming_task(
             code="INTERNAL",
             message=str(exc),
             retryable=False,
+            usage=callbacks.state.last_usage,
         )
     finally:
         close_queue(state)
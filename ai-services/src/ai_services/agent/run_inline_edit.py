@@ -3,7 +3,7 @@ from __future__ import annotations
 import asyncio
 import json
 import logging
-from typing import Any
+from typing import Any, Callable
 
 from openai import AsyncOpenAI
 from openai.types.chat import ChatCompletionMessageParam
@@ -17,6 +17,7 @@ from .api_contracts import (
     RAGAnswer,
 )
 from .command_registry import resolve_command
+from .contracts import TokenUsageInfo
 from .run import MODEL_NAME
 from .runtime_context import reset_run_context, set_run_context
 from .system import build_system_prompt
@@ -36,6 +37,18 @@ _ACTION_HINTS: dict[str, str] = {
 }
 
 
+def _token_usage(usage: Any) -> TokenUsageInfo:
+    # The context window is not known here; only the token counts are reported
+    return TokenUsageInfo(
+        input_tokens=usage.prompt_tokens,
+        output_tokens=usage.completion_tokens,
+        total_tokens=usage.total_tokens,
+        context_window=0,
+        threshold=0,
+        percentage=0,
+    )
+
+
 def _build_inline_messages(
     *,
     action: str,
@@ -151,6 +164,7 @@ async def run_inline_edit(
     timeout_ms: int = 10_000,
     max_tokens: int = 20_000,
     callbacks: Any | None = None,
+    on_usage: Callable[[TokenUsageInfo], None] | None = None,
 ) -> AIResult:
     actor_context = actor or {}
     resource_context_data = resource_context or {}
@@ -190,23 +204,7 @@ async def run_inline_edit(
             async for chunk in response_stream:
                 if not chunk.choices:
                     if hasattr(chunk, "usage") and chunk.usage:
-                        from .contracts import TokenUsageInfo
-
-                        # We don't have context_window and other info here easily,
-                        # but we can report what we have.
-                        # Note: TokenUsageInfo expects more fields.
-                        # Let's see if we can adapt or if we should just pass raw.
-                        usage = chunk.usage
-                        callbacks.on_token_usage(
-                            TokenUsageInfo(
-                                input_tokens=usage.prompt_tokens,
-                                output_tokens=usage.completion_tokens,
-                                total_tokens=usage.total_tokens,
-                                context_window=0,  # Unknown here
-                                threshold=0,
-                                percentage=0,
-                            )
-                        )
+                        callbacks.on_token_usage(_token_usage(chunk.usage))
                     continue
                 delta = chunk.choices[0].delta.content
                 if delta:
@@ -238,6 +236,9 @@ async def run_inline_edit(
         ):
             reset_run_context(run_token, actor_token, resource_token)
 
+    if on_usage is not None and getattr(response, "usage", None):
+        on_usage(_token_usage(response.usage))
+
     if not response.choices:
         logger.warning("Inline edit returned no choices")
         return _build_inline_result(
@@ -117,6 +117,9 @@ async def _notes_write(args: dict[str, Any]) -> str:
         input_payload["patch"] = args.get("patch")
     if "idempotency_key" in args:
         input_payload["idempotency_key"] = args.get("idempotency_key")
+    if args.get("propose"):
+        input_payload["propose"] = True
+        input_payload["summary"] = str(args.get("summary", ""))
 
     return await _execute_backend_tool("notes.write", input_payload)
 
@@ -128,7 +131,7 @@ notes_read = ToolSpec(
         "Use when the note_id is already known (from rag.search results, "
         "user-provided link, or active runtime context). "
         "For discovery/search across notes, use `rag.search` instead. "
-        "Returns: {note_id, version, content, metadata}."
+        "Returns: {note_id, version, content, blocks, metadata}."
     ),
     input_schema={
         "type": "object",
@@ -165,8 +168,31 @@ notes_write = ToolSpec(
             "content": {"type": "string", "description": "Content for replace/append"},
             "patch": {
                 "type": "array",
-                "items": {"type": "object"},
-                "description": "Patch payload for patch operation",
+                "items": {
+                    "type": "object",
+                    "properties": {
+                        "op": {"type": "string", "enum": ["insert", "replace", "delete"]},
+                        "position": {
+                            "type": "string",
+                            "enum": ["before", "after", "start", "end"],
+                            "description": "Where insert places content; start/end need no anchor",
+                        },
+                        "anchor": {
+                            "type": "object",
+                            "properties": {
+                                "block_id": {"type": "string", "description": "Block id from notes.read blocks"},
+                                "text": {"type": "string", "description": "Text inside the target block"},
+                                "before": {"type": "string", "description": "Text directly before `text`"},
+                                "after": {"type": "string", "description": "Text directly after `text`"},
+                            },
+                            "additionalProperties": False,
+                        },
+                        "content": {"type": "string", "description": "Markdown for insert/replace"},
+                    },
+                    "required": ["op"],
+                    "additionalProperties": False,
+                },
+                "description": "Block-level edits for patch operation, anchored against the version read",
             },
             "expected_version": {
                 "type": "integer",
@@ -177,6 +203,14 @@ notes_write = ToolSpec(
                 "type": "string",
                 "description": "Optional key for deduplication",
             },
+            "propose": {
+                "type": "boolean",
+                "description": "Store the edit as a proposal for the user to accept or reject instead of writing it",
+            },
+            "summary": {
+                "type": "string",
+                "description": "Short summary shown with a proposal",
+            },
         },
         "required": ["note_id", "operation", "expected_version"],
         "additionalProperties": False,
@@ -75,6 +75,8 @@ async def retrieve_rag_chunks(
 	embedding: list[float],
 	top_k: int = 5,
 	min_score: float = 0.2,
+	query: str = "",
+	mode: str = "hybrid",
 ) -> list[dict[str, Any]]:
 	run_id = get_run_id() or ""
 	tool_call_id = get_tool_call_id() or ""
@@ -93,8 +95,14 @@ async def retrieve_rag_chunks(
 			"embedding": embedding,
 			"top_k": top_k,
 			"min_score": min_score,
+			"mode": mode,
 		},
 	}
+	# Hybrid mode fuses full-text and vector ranking of the query text,
+	# matching what the user sees in note search. min_score then only drops
+	# notes found by vector similarity alone.
+	if mode == "hybrid":
+		payload["input"]["query"] = query
 
 	headers = {"Content-Type": "application/json"}
 	token = _backend_token()
@@ -146,6 +154,10 @@ async def _rag_search(args: dict[str, Any]) -> str:
 	if not isinstance(min_score, (int, float)):
 		min_score = 0.2
 
+	mode = args.get("mode", "hybrid")
+	if mode not in ("hybrid", "vector"):
+		return "Error INVALID_INPUT: mode must be hybrid or vector"
+
 	actor = get_actor()
 	user_id = str(actor.get("user_id") or "").strip()
 	if not user_id:
@@ -158,6 +170,8 @@ async def _rag_search(args: dict[str, Any]) -> str:
 			embedding=embedding,
 			top_k=top_k,
 			min_score=float(min_score),
+			query=query,
+			mode=mode,
 		)
 	except Exception as exc:  # noqa: BLE001
 		return f"Error RAG_SEARCH_FAILED: {exc}"
@@ -165,6 +179,7 @@ async def _rag_search(args: dict[str, Any]) -> str:
 	return json.dumps(
 		{
 			"query": query,
+			"mode": mode,
 			"top_k": top_k,
 			"min_score": float(min_score),
 			"total": len(chunks),
@@ -210,7 +225,18 @@ rag_search = ToolSpec(
 				"type": "number",
 				"minimum": 0,
 				"maximum": 1,
-				"description": "Minimum cosine similarity threshold",
+				"description": (
+					"Minimum cosine similarity threshold. In hybrid mode it only "
+					"filters notes that full-text search did not match"
+				),
+			},
+			"mode": {
+				"type": "string",
+				"enum": ["hybrid", "vector"],
+				"description": (
+					"hybrid (default) combines keyword and semantic ranking; "
+					"vector uses embedding similarity only"
+				),
 			},
 		},
 		"required": ["query"],
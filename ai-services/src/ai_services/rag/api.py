@@ -1,6 +1,6 @@
 from __future__ import annotations
 
-from fastapi import APIRouter
+from fastapi import APIRouter, HTTPException
 from pydantic import BaseModel, Field
 
 from ai_services.rag.chunking import chunk_note_event, embed_chunks_for_note
@@ -18,6 +18,8 @@ class ChunkNoteRequest(BaseModel):
     content_type: str = "text"
     updated_at: str | None = None
     event: str = Field(default="note.save")
+    # sha256 hex of chunk texts the backend already has embeddings for
+    known_chunk_hashes: list[str] = Field(default_factory=list)
 
 
 @router.get("/health")
@@ -39,7 +41,12 @@ def embed_note_chunks(req: ChunkNoteRequest) -> dict[str, object]:
     """Chunk note and return embeddings for each chunk.
 
     This endpoint is used by the Go backend to persist chunk vectors into Postgres.
+    Embedding failures return 502 so the backend's chunk job retries instead of
+    treating the note as having no chunks.
     """
 
-    result = embed_chunks_for_note(req.model_dump())
+    try:
+        result = embed_chunks_for_note(req.model_dump())
+    except RuntimeError as exc:
+        raise HTTPException(status_code=502, detail=str(exc)) from exc
     return result
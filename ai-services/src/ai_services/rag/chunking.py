@@ -1,5 +1,6 @@
 from __future__ import annotations
 
+import hashlib
 import json
 import os
 import re
@@ -444,15 +445,23 @@ def _normalize_embedding_vector(value: Any) -> list[float]:
     return vector
 
 
+def chunk_content_hash(text: str) -> str:
+    """Must match utils.ChunkContentHash in the Go backend."""
+    return hashlib.sha256(text.encode("utf-8")).hexdigest()
+
+
 def embed_chunks_for_note(note: dict[str, Any]) -> dict[str, Any]:
     """Prepare, chunk and embed a note using external embedding service.
 
+    Chunks whose content hash is in note["known_chunk_hashes"] are returned
+    with an empty embedding; the backend already stores their vectors.
+
     Returns a dict with note_id, user_id and a list of chunks:
     {
         "note_id": str,
         "user_id": str,
         "chunks": [
-            {"chunk_index": int, "text": str, "embedding": list[float]},
+            {"chunk_index": int, "text": str, "content_hash": str, "embedding": list[float]},
             ...
         ],
     }
@@ -497,47 +506,57 @@ def embed_chunks_for_note(note: dict[str, Any]) -> dict[str, Any]:
             "chunks": [],
         }
 
-    embed_url = _get_embed_url()
-    print(f"[EMBED][DEBUG] embed_url={embed_url}")
-
-    try:
-        resp = httpx.post(embed_url, json={"texts": chunks}, timeout=60.0)
-        resp.raise_for_status()
-    except Exception as exc:  # noqa: BLE001
-        print(f"[EMBED][ERROR] Failed to call embed service: {exc}")
-        print("[EMBED][DONE]")
-        print("=" * 80)
-        return {
-            "note_id": note_id,
-            "user_id": user_id,
-            "chunks": [],
-        }
-
-    data = resp.json()
-    embeddings = data.get("embeddings") or []
-    print(f"[EMBED][DEBUG] Received {len(embeddings)} embeddings from service")
-    
-    if not isinstance(embeddings, list):
-        print("[EMBED][ERROR] Invalid embeddings format from service")
-        print("[EMBED][DONE]")
-        print("=" * 80)
-        return {
-            "note_id": note_id,
-            "user_id": user_id,
-            "chunks": [],
-        }
-
-    if len(embeddings) != len(chunks):
-        print(
-            f"[EMBED][WARN] embeddings count ({len(embeddings)}) != chunks count ({len(chunks)}). "
-            "Using min length for zipping.",
-        )
+    known_hashes = set(note.get("known_chunk_hashes") or [])
+    hashes = [chunk_content_hash(text) for text in chunks]
+    to_embed: list[str] = []
+    embed_at: dict[str, int] = {}
+    for text, digest in zip(chunks, hashes):
+        if digest in known_hashes or digest in embed_at:
+            continue
+        embed_at[digest] = len(to_embed)
+        to_embed.append(text)
+    print(f"[EMBED][DEBUG] unchanged_chunks={len(chunks) - len(to_embed)} to_embed={len(to_embed)}")
+
+    embeddings: list[Any] = []
+    if to_embed:
+        embed_url = _get_embed_url()
+        print(f"[EMBED][DEBUG] embed_url={embed_url}")
+
+        try:
+            resp = httpx.post(embed_url, json={"texts": to_embed}, timeout=60.0)
+            resp.raise_for_status()
+        except Exception as exc:  # noqa: BLE001
+            print(f"[EMBED][ERROR] Failed to call embed service: {exc}")
+            print("[EMBED][DONE]")
+            print("=" * 80)
+            raise RuntimeError(f"embed service failed: {exc}") from exc
+
+        data = resp.json()
+        embeddings = data.get("embeddings") or []
+        print(f"[EMBED][DEBUG] Received {len(embeddings)} embeddings from service")
+
+        if not isinstance(embeddings, list) or len(embeddings) != len(to_embed):
+            print("[EMBED][ERROR] Invalid embeddings format from service")
+            print("[EMBED][DONE]")
+            print("=" * 80)
+            raise RuntimeError("embed service returned invalid embeddings")
 
     result_chunks: list[dict[str, Any]] = []
     invalid_embeddings = 0
     expected_dim = 0
-    for idx, (text, emb) in enumerate(zip(chunks, embeddings)):
-        vector = _normalize_embedding_vector(emb)
+    for idx, (text, digest) in enumerate(zip(chunks, hashes)):
+        if digest not in embed_at:
+            result_chunks.append(
+                {
+                    "chunk_index": idx,
+                    "text": text,
+                    "content_hash": digest,
+                    "embedding": [],
+                }
+            )
+            continue
+
+        vector = _normalize_embedding_vector(embeddings[embed_at[digest]])
         if not vector:
             invalid_embeddings += 1
             continue
@@ -552,6 +571,7 @@ def embed_chunks_for_note(note: dict[str, Any]) -> dict[str, Any]:
             {
                 "chunk_index": idx,
                 "text": text,
+                "content_hash": digest,
                 "embedding": vector,
             }
         )
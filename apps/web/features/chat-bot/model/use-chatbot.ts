@@ -193,7 +193,6 @@ export function useChatbot({ droppedNotePayload }: UseChatbotParams) {
     setIsStreaming(true);
 
     const payload: ReqCreateAIRun = {
-      workspace_id: "default-workspace",
       session_id: sessionIdRef.current,
       note_id: selectedNoteId || undefined,
       folder_id: selectedFolderId || undefined,
@@ -52,7 +52,6 @@ export default function RichTextEditor(props: RichTextEditorProps) {
         props.onAIAction ??
         (async (action, selectedText, customPrompt, context) =>
           requestInlineEdit({
-            workspaceId: "default-workspace",
             noteId: props.noteId,
             action: action as Parameters<typeof requestInlineEdit>[0]["action"],
             selectedText,
@@ -84,7 +84,7 @@ export type AIActionResult =
     };
 
 export type RequestInlineEditOptions = {
-  workspaceId: string;
+  workspaceId?: string;
   noteId?: string;
   action: InlineEditAction;
   selectedText: string;
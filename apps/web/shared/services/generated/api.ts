@@ -228,7 +228,8 @@ export type ReqCreateAIRunMessage = {
 };
 
 export interface ReqCreateAIRun {
-  workspace_id: string;
+  /** Defaults to the active workspace (X-Workspace-ID, or the personal workspace) and must match it when sent */
+  workspace_id?: string;
   session_id: string;
   note_id?: string;
   folder_id?: string;
@@ -252,7 +253,8 @@ export interface ResProvideAIRunConsent {
 export type ReqInlineEditContextBlocksItem = { [key: string]: unknown };
 
 export interface ReqInlineEdit {
-  workspace_id: string;
+  /** Defaults to the active workspace (X-Workspace-ID, or the personal workspace) and must match it when sent */
+  workspace_id?: string;
   note_id?: string;
   action: string;
   selected_text: string;
@@ -9,9 +9,12 @@ import (
 
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/domain"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/embeddings"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/mailer"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/pubsub"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/vectorstore"
@@ -39,11 +42,6 @@ func New(ctx context.Context) (*App, func(), error) {
 		return nil, nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
-	// Seed initial data
-	if err := db.SeedData(); err != nil {
-		log.Printf("Warning: failed to seed data: %v", err)
-	}
-
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	accountRepo := repository.NewAccountRepository(db)
@@ -53,43 +51,89 @@ func New(ctx context.Context) (*App, func(), error) {
 	eventRepo := repository.NewEventRepository(db)
 	commentRepo := repository.NewCommentRepository(db)
 	noteChunkRepo := repository.NewNoteChunkRepository(db)
+	noteRevisionRepo := repository.NewNoteRevisionRepository(db)
+	trashRepo := repository.NewTrashRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	chunkJobRepo := repository.NewChunkJobRepository(db)
+	collabDocRepo := repository.NewCollabDocRepository(db)
+	editProposalRepo := repository.NewAIEditProposalRepository(db)
+	aiUsageRepo := repository.NewAIUsageRepository(db)
+	aiAuditRepo := repository.NewAIAuditRepository(db)
+	noteShareRepo := repository.NewNoteShareRepository(db)
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	noteShareLinkRepo := repository.NewNoteShareLinkRepository(db)
+	authSessionRepo := repository.NewAuthSessionRepository(db)
+	userTokenRepo := repository.NewUserTokenRepository(db)
 
-	var (
-		searchService service.SearchService
-		searchHandler *handlers.SearchHandler
-	)
+	// In-process embeddings (optional - the default "remote" provider leaves chunking to the ai-service)
+	embeddingProvider, err := embeddings.NewProvider(cfg.Embeddings, cfg.Cohere)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize embeddings provider: %w", err)
+	}
+	if embeddingProvider != nil && embeddingProvider.GetDimension() != models.NoteChunkEmbeddingDimension {
+		return nil, nil, fmt.Errorf("embeddings provider %q has dimension %d, note_chunks expects %d",
+			cfg.Embeddings.Provider, embeddingProvider.GetDimension(), models.NoteChunkEmbeddingDimension)
+	}
 
-	// Initialize search components (optional - only if Pinecone and Cohere are configured)
-	if cfg.Pinecone.APIKey != "" && cfg.Cohere.APIKey != "" {
-		embeddingProvider, err := embeddings.NewCohereProvider(cfg.Cohere)
-		if err != nil {
-			log.Printf("Warning: failed to initialize Cohere embeddings: %v", err)
-		} else {
-			vectorStore, err := vectorstore.NewPineconeStore(ctx, cfg.Pinecone)
-			if err != nil {
-				log.Printf("Warning: failed to initialize Pinecone: %v", err)
-			} else {
-				searchService = service.NewSearchService(embeddingProvider, vectorStore, noteRepo)
-				searchHandler = handlers.NewSearchHandler(searchService)
-				log.Printf("🔍 Semantic search: ✅ Enabled (Pinecone + Cohere)")
-			}
-		}
+	// Note-level vector store (optional)
+	semanticService, err := newSemanticSearchService(ctx, cfg, db, noteRepo, workspaceRepo, embeddingProvider)
+	if err != nil {
+		log.Printf("Warning: vector store disabled: %v", err)
+	}
+
+	// Hybrid search: Postgres full-text fused with note chunk vectors
+	searchService := service.NewHybridSearchService(repository.NewNoteSearchRepository(db), noteChunkRepo, noteRepo, embeddingProvider, semanticService, cfg.Search)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	if embeddingProvider != nil {
+		log.Printf("🔍 Search: ✅ Hybrid (full-text + pgvector, %s embeddings in-process)", cfg.Embeddings.Provider)
 	} else {
-		log.Printf("🔍 Semantic search: ⚠️ Disabled (missing Pinecone or Cohere API keys)")
+		log.Printf("🔍 Search: ✅ Full-text (vector ranking only for pre-embedded queries from the ai-service)")
 	}
 
 	// Initialize services
 	userService := service.NewUserService(userRepo, cfg)
-	chunkingService := service.NewChunkingService(cfg.AI, noteChunkRepo)
-	noteService := service.NewNoteService(noteRepo, cfg, searchService, chunkingService)
+	chunkingService := service.NewChunkingService(cfg.AI, noteChunkRepo, chunkJobRepo, noteRepo, embeddingProvider, cfg.Embeddings, cfg.ChunkQueue)
+	noteService := service.NewNoteService(noteRepo, cfg, searchService, chunkingService, noteRevisionRepo, tagRepo)
+	noteRevisionService := service.NewNoteRevisionService(noteRevisionRepo, noteRepo, chunkingService)
 	folderService := service.NewFolderService(folderRepo, noteRepo, cfg)
 	templateService := service.NewTemplateService(templateRepo)
+	trashService := service.NewTrashService(trashRepo, folderRepo, cfg.Trash)
+	tagService := service.NewTagService(tagRepo)
 	eventService := service.NewEventService(eventRepo)
-	authService := service.NewAuthService(userRepo, accountRepo, cfg)
-	commentService := service.NewCommentService(commentRepo, noteRepo, userRepo)
+	// Revoked sessions are cached in Redis when it is reachable, otherwise every request checks the database
+	pingCtx, cancelPing := context.WithTimeout(ctx, 2*time.Second)
+	revocations, err := service.NewRedisRevocationList(pingCtx, cfg.Redis)
+	cancelPing()
+	if err != nil {
+		log.Printf("Warning: session revocation list disabled, falling back to the database: %v", err)
+		revocations = nil
+	} else {
+		log.Printf("🔐 Session revocation list: ✅ Redis")
+	}
+	accountMailer, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+	authService := service.NewAuthService(userRepo, accountRepo, authSessionRepo, userTokenRepo, revocations, accountMailer, cfg)
+	workspaceService := service.NewWorkspaceService(workspaceRepo, userRepo)
+	workspaceAPI := handlers.NewWorkspaceAPI(workspaceService)
+	noteShareService := service.NewNoteShareService(noteShareRepo, noteRepo, folderRepo, userRepo, workspaceRepo)
+	noteShareAPI := handlers.NewNoteShareAPI(noteShareService)
+	commentService := service.NewCommentService(commentRepo, noteRepo, userRepo, noteShareService)
+	noteShareLinkService := service.NewNoteShareLinkService(noteShareLinkRepo, noteRepo, commentService)
+	noteShareLinkAPI := handlers.NewNoteShareLinkAPI(noteShareLinkService)
+	editProposalService := service.NewAIEditProposalService(editProposalRepo, noteService)
 	aiRunRepository := repository.NewAIRunRepository(db)
-	aiRunAPI := handlers.NewAIRunAPI(cfg, noteService, folderService, aiRunRepository)
-	aiInternalAPI := handlers.NewAIInternalAPI(noteService, folderService, noteChunkRepo, cfg)
+	aiUsageService := service.NewAIUsageService(aiUsageRepo, &cfg.AI)
+	aiRunAPI := handlers.NewAIRunAPI(cfg, noteService, folderService, aiRunRepository, editProposalService, aiUsageService)
+	aiInternalAPI := handlers.NewAIInternalAPI(noteService, folderService, noteChunkRepo, searchService, editProposalService, noteShareService, workspaceService, cfg)
+	editProposalAPI := handlers.NewAIEditProposalAPI(noteService, noteShareService, editProposalService)
+	aiAuditService := service.NewAIAuditService(aiAuditRepo, aiRunRepository)
+	aiAuditAPI := handlers.NewAIAuditAPI(cfg, aiAuditService)
+	noteRevisionAPI := handlers.NewNoteRevisionAPI(noteService, noteRevisionService)
+	trashAPI := handlers.NewTrashAPI(trashService)
+	tagAPI := handlers.NewTagAPI(tagService, noteService)
+	chunkJobAPI := handlers.NewChunkJobAPI(chunkingService, noteService)
 
 	mediaService, err := service.NewMediaService(ctx, cfg.CDN)
 	if err != nil {
@@ -97,9 +141,49 @@ func New(ctx context.Context) (*App, func(), error) {
 	}
 
 	// Initialize collaboration (websocket) components
+	collabBroker, err := pubsub.NewBroker(ctx, cfg.Collab, cfg.Redis)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize collaboration pubsub: %w", err)
+	}
 	clientRepo := domain.NewInMemoryClientRepository()
-	collabService := service.NewCollaborationService(userRepo, noteRepo, clientRepo, userService, noteService)
-	wsHandler := handlers.NewWebSocketHandler(collabService)
+	collabService := service.NewCollaborationService(userRepo, noteRepo, clientRepo, userService, noteService, collabBroker, cfg.Collab)
+	wsHandler := handlers.NewWebSocketHandler(collabService, authService, noteService, noteShareService, cfg)
+	log.Printf("👥 Collaboration rooms: ✅ %s pubsub (presence TTL %s)", cfg.Collab.PubSub, collabService.PresenceTTL())
+
+	// Keep this instance's /ws clients present in their rooms
+	go func() {
+		ticker := time.NewTicker(collabService.PresenceTTL() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				collabService.RefreshPresence(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Merge the collab server's stored Yjs updates and refresh note snapshots from them
+	collabDocService := service.NewCollabDocService(collabDocRepo, noteRepo, noteRevisionRepo, chunkingService, cfg.Collab)
+	log.Printf("🧱 Collab compaction: ✅ Enabled (every %ds, from %d updates)", cfg.Collab.CompactionIntervalSeconds, cfg.Collab.CompactionMinUpdates)
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.Collab.CompactionIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				compacted, err := collabDocService.CompactDue(ctx)
+				if err != nil {
+					log.Printf("Warning: collab compaction failed: %v", err)
+				} else if compacted > 0 {
+					log.Printf("🧱 Collab compaction: compacted %d documents", compacted)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
 	gcalService := service.NewGoogleCalendarService(db.DB, accountRepo, cfg.Google)
 	googleCalendarAPI := handlers.NewGoogleCalendarAPI(gcalService, authService)
@@ -129,8 +213,92 @@ func New(ctx context.Context) (*App, func(), error) {
 		log.Printf("📅 Google Calendar integration: ⚠️  Disabled (missing GOOGLE_CLIENT_ID or GOOGLE_CLIENT_SECRET)")
 	}
 
+	// Start background trash purge goroutine
+	log.Printf("🗑️  Trash purge: ✅ Enabled (retention %d days, every %d minutes)", cfg.Trash.RetentionDays, cfg.Trash.PurgeIntervalMinutes)
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.Trash.PurgeIntervalMinutes) * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				purged, err := trashService.PurgeExpired(context.Background())
+				if err != nil {
+					log.Printf("Warning: trash purge failed: %v", err)
+				} else if purged > 0 {
+					log.Printf("🗑️  Trash purge: removed %d expired items", purged)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Start background chunking worker; keeps claiming while batches come back full
+	log.Printf("🧩 Chunk queue: ✅ Enabled (debounce %ds, every %ds, max %d attempts)", cfg.ChunkQueue.DebounceSeconds, cfg.ChunkQueue.PollIntervalSeconds, cfg.ChunkQueue.MaxAttempts)
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.ChunkQueue.PollIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for ctx.Err() == nil {
+					claimed, err := chunkingService.ProcessDueJobs(ctx)
+					if err != nil {
+						log.Printf("Warning: chunk queue poll failed: %v", err)
+						break
+					}
+					if claimed < cfg.ChunkQueue.BatchSize {
+						break
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Expire AI tool calls nobody consented to in time
+	log.Printf("⏳ AI consent sweeper: ✅ Enabled (timeout %ds, every %ds)", cfg.AI.ConsentTimeoutSeconds, cfg.AI.ConsentSweepIntervalSeconds)
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.AI.ConsentSweepIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				expired, err := aiRunAPI.ExpireStaleConsents(ctx)
+				if err != nil {
+					log.Printf("Warning: consent sweep failed: %v", err)
+				} else if expired > 0 {
+					log.Printf("⏳ AI consent sweeper: expired %d tool calls", expired)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Return AI edit proposals whose accept never finished to pending
+	log.Printf("✏️  Edit proposal sweeper: ✅ Enabled (every minute)")
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				released, err := editProposalService.ReleaseStaleClaims(ctx)
+				if err != nil {
+					log.Printf("Warning: edit proposal sweep failed: %v", err)
+				} else if released > 0 {
+					log.Printf("✏️  Edit proposal sweeper: released %d stale claims", released)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	// Initialize handlers
-	router := handlers.SetupRouter(cfg, authService, userService, noteService, folderService, templateService, *eventService, mediaService, commentService, aiRunAPI, aiInternalAPI, wsHandler, searchHandler, googleCalendarAPI, googleLoginAPI)
+	router := handlers.SetupRouter(cfg, authService, userService, noteService, folderService, templateService, *eventService, mediaService, commentService, noteShareService, workspaceService, aiRunAPI, aiInternalAPI, wsHandler, searchHandler, googleCalendarAPI, googleLoginAPI, noteRevisionAPI, trashAPI, tagAPI, chunkJobAPI, editProposalAPI, aiAuditAPI, noteShareAPI, workspaceAPI, noteShareLinkAPI)
 
 	app := &App{
 		router: router,
@@ -139,6 +307,9 @@ func New(ctx context.Context) (*App, func(), error) {
 	}
 
 	cleanup := func() {
+		if err := collabBroker.Close(); err != nil {
+			log.Printf("Error closing collaboration pubsub: %v", err)
+		}
 		if err := db.Close(); err != nil {
 			log.Printf("Error closing database: %v", err)
 		}
@@ -147,6 +318,38 @@ func New(ctx context.Context) (*App, func(), error) {
 	return app, cleanup, nil
 }
 
+// newSemanticSearchService builds the note-level vector store selected by
+// vector_store.provider. It returns nil when no store is configured.
+func newSemanticSearchService(ctx context.Context, cfg *config.Config, db *database.DB, noteRepo repository.NoteRepository, workspaceRepo repository.WorkspaceRepository, embeddingProvider embeddings.EmbeddingProvider) (service.SearchService, error) {
+	provider := cfg.VectorStore.Provider
+	if provider == "" && cfg.Pinecone.APIKey != "" && cfg.Cohere.APIKey != "" {
+		provider = "pinecone"
+	}
+
+	switch provider {
+	case "pinecone":
+		cohereProvider, err := embeddings.NewCohereProvider(cfg.Cohere)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Cohere embeddings: %w", err)
+		}
+		vectorStore, err := vectorstore.NewPineconeStore(ctx, cfg.Pinecone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Pinecone: %w", err)
+		}
+		log.Printf("🧭 Vector store: ✅ Pinecone")
+		return service.NewSearchService(cohereProvider, vectorStore, noteRepo, workspaceRepo), nil
+	case "pgvector":
+		vectorStore, err := vectorstore.NewPgVectorStore(db, embeddingProvider, cfg.VectorStore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize pgvector store: %w", err)
+		}
+		log.Printf("🧭 Vector store: ✅ pgvector (%s embeddings)", cfg.Embeddings.Provider)
+		return service.NewSearchService(embeddingProvider, vectorStore, noteRepo, workspaceRepo), nil
+	default:
+		return nil, nil
+	}
+}
+
 // Run starts the application
 func (a *App) Run(ctx context.Context) error {
 	port := os.Getenv("PORT")
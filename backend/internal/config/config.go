@@ -10,16 +10,23 @@ import (
 
 // Config struct chính
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server" validate:"required"`
-	Database DatabaseConfig `mapstructure:"database" validate:"required"`
-	JWT      JWTConfig      `mapstructure:"jwt" validate:"required"`
-	Redis    RedisConfig    `mapstructure:"redis" validate:"required"`
-	AI       AIConfig       `mapstructure:"ai" validate:"required"`
-	Pinecone PineconeConfig `mapstructure:"pinecone"`
-	Cohere   CohereConfig   `mapstructure:"cohere"`
-	CDN      CDNConfig      `mapstructure:"cdn" validate:"required"`
-	Collab   CollabConfig   `mapstructure:"collab" validate:"required"`
-	Google   GoogleConfig   `mapstructure:"google"`
+	Server      ServerConfig      `mapstructure:"server" validate:"required"`
+	Database    DatabaseConfig    `mapstructure:"database" validate:"required"`
+	JWT         JWTConfig         `mapstructure:"jwt" validate:"required"`
+	Redis       RedisConfig       `mapstructure:"redis" validate:"required"`
+	AI          AIConfig          `mapstructure:"ai" validate:"required"`
+	Pinecone    PineconeConfig    `mapstructure:"pinecone"`
+	Cohere      CohereConfig      `mapstructure:"cohere"`
+	CDN         CDNConfig         `mapstructure:"cdn" validate:"required"`
+	Collab      CollabConfig      `mapstructure:"collab" validate:"required"`
+	Google      GoogleConfig      `mapstructure:"google"`
+	Trash       TrashConfig       `mapstructure:"trash"`
+	Search      SearchConfig      `mapstructure:"search"`
+	Embeddings  EmbeddingsConfig  `mapstructure:"embeddings"`
+	VectorStore VectorStoreConfig `mapstructure:"vector_store"`
+	ChunkQueue  ChunkQueueConfig  `mapstructure:"chunk_queue"`
+	Admin       AdminConfig       `mapstructure:"admin"`
+	Mail        MailConfig        `mapstructure:"mail"`
 }
 
 // Nested structs - chỉ cần tag cho field, prefix tự động
@@ -74,13 +81,82 @@ type CDNConfig struct {
 type GoogleConfig struct {
 	ClientID         string `mapstructure:"client_id"`
 	ClientSecret     string `mapstructure:"client_secret"`
-	RedirectURI      string `mapstructure:"redirect_uri"` // For Calendar
+	RedirectURI      string `mapstructure:"redirect_uri"`       // For Calendar
 	LoginRedirectURI string `mapstructure:"login_redirect_uri"` // For Auth Login
 }
 
 type CollabConfig struct {
 	TokenSecret     string `mapstructure:"token_secret" validate:"required,min=8"`
 	TokenTTLMinutes int    `mapstructure:"token_ttl_minutes" validate:"required,min=5,max=1440"`
+	// PubSub fans /ws room traffic out between backend instances: "memory"
+	// for a single instance, "redis" to share rooms and presence across replicas
+	PubSub             string `mapstructure:"pubsub" validate:"omitempty,oneof=memory redis"`
+	PresenceTTLSeconds int    `mapstructure:"presence_ttl_seconds" validate:"omitempty,min=10,max=3600"`
+	// Compaction merges the collab server's stored Yjs updates per note and
+	// refreshes the note's content snapshot from the merged document
+	CompactionIntervalSeconds int `mapstructure:"compaction_interval_seconds" validate:"min=10,max=86400"`
+	CompactionMinUpdates      int `mapstructure:"compaction_min_updates" validate:"min=2"`
+	CompactionBatchSize       int `mapstructure:"compaction_batch_size" validate:"min=1,max=1000"`
+}
+
+type TrashConfig struct {
+	RetentionDays        int `mapstructure:"retention_days" validate:"min=1,max=3650"`
+	PurgeIntervalMinutes int `mapstructure:"purge_interval_minutes" validate:"min=1,max=10080"`
+}
+
+// SearchConfig tunes hybrid (full-text + vector) note search
+type SearchConfig struct {
+	RRFK           int     `mapstructure:"rrf_k" validate:"min=1,max=1000"`          // reciprocal rank fusion constant
+	CandidatePool  int     `mapstructure:"candidate_pool" validate:"min=1,max=1000"` // results fetched from each ranker
+	MinVectorScore float64 `mapstructure:"min_vector_score" validate:"min=0,max=1"`  // cosine similarity cut-off for chunks
+}
+
+// EmbeddingsConfig selects where note chunks are embedded. "remote" keeps
+// chunking and embedding in the ai-service; "cohere" and "hashing" run in-process.
+// Every provider must produce vectors the size of the note_chunks column.
+type EmbeddingsConfig struct {
+	Provider     string `mapstructure:"provider" validate:"oneof=remote cohere hashing"`
+	ChunkSize    int    `mapstructure:"chunk_size" validate:"min=50,max=8000"`   // in tokens
+	ChunkOverlap int    `mapstructure:"chunk_overlap" validate:"min=0,max=4000"` // in tokens
+}
+
+// VectorStoreConfig selects the note-level semantic index. Empty disables it,
+// unless Pinecone and Cohere keys are both set (the historical default).
+type VectorStoreConfig struct {
+	Provider  string `mapstructure:"provider" validate:"omitempty,oneof=pinecone pgvector"`
+	Namespace string `mapstructure:"namespace"` // pgvector namespace for Upsert/Search without a user
+}
+
+// ChunkQueueConfig tunes the background chunking/embedding job queue
+type ChunkQueueConfig struct {
+	DebounceSeconds     int `mapstructure:"debounce_seconds" validate:"min=0,max=3600"`      // quiet period after a save before chunking
+	PollIntervalSeconds int `mapstructure:"poll_interval_seconds" validate:"min=1,max=3600"` // how often the worker looks for due jobs
+	BatchSize           int `mapstructure:"batch_size" validate:"min=1,max=500"`             // jobs claimed per poll
+	MaxAttempts         int `mapstructure:"max_attempts" validate:"min=1,max=100"`           // attempts before a job is dead-lettered
+	BackoffBaseSeconds  int `mapstructure:"backoff_base_seconds" validate:"min=1,max=86400"` // first retry delay, doubled per attempt
+	BackoffMaxSeconds   int `mapstructure:"backoff_max_seconds" validate:"min=1,max=604800"` // retry delay cap
+	LeaseSeconds        int `mapstructure:"lease_seconds" validate:"min=10,max=86400"`       // running jobs older than this are reclaimed
+}
+
+// AdminConfig lists the users allowed to use the admin API
+type AdminConfig struct {
+	Emails []string `mapstructure:"emails" validate:"dive,email"` // matched case-insensitively
+}
+
+// MailConfig selects how account emails are delivered. "log" prints them and
+// "file" writes them to FileDir, both for development and tests. As those
+// emails carry verification and reset tokens, release mode refuses them unless
+// AllowLocalInRelease is set.
+type MailConfig struct {
+	Provider            string `mapstructure:"provider" validate:"oneof=log file smtp"`
+	AllowLocalInRelease bool   `mapstructure:"allow_local_in_release"`
+	From                string `mapstructure:"from" validate:"required"`
+	FrontendURL         string `mapstructure:"frontend_url" validate:"required,url"` // links in emails point here
+	FileDir             string `mapstructure:"file_dir"`
+	SMTPHost            string `mapstructure:"smtp_host" validate:"required_if=Provider smtp"`
+	SMTPPort            string `mapstructure:"smtp_port" validate:"required_if=Provider smtp"`
+	SMTPUsername        string `mapstructure:"smtp_username"`
+	SMTPPassword        string `mapstructure:"smtp_password"`
 }
 
 type AIConfig struct {
@@ -88,6 +164,20 @@ type AIConfig struct {
 	ServiceURL       string `mapstructure:"service_url" validate:"required,url"`
 	ServiceToken     string `mapstructure:"service_token" validate:"required,min=8"`
 	RequestTimeoutMs int    `mapstructure:"request_timeout_ms" validate:"required,min=1000,max=180000"`
+
+	ConsentTimeoutSeconds       int `mapstructure:"consent_timeout_seconds" validate:"min=10,max=86400"`      // how long a tool call waits for consent
+	ConsentSweepIntervalSeconds int `mapstructure:"consent_sweep_interval_seconds" validate:"min=1,max=3600"` // how often stale consents are expired
+
+	RunMaxTokens        int `mapstructure:"run_max_tokens" validate:"omitempty,min=256,max=32768"`         // max_tokens for agent runs
+	InlineEditMaxTokens int `mapstructure:"inline_edit_max_tokens" validate:"omitempty,min=128,max=32768"` // max_tokens for inline edit runs
+
+	// Per-user quotas, 0 means unlimited. Rows in ai_usage_limits override them.
+	DailyTokenQuota         int64   `mapstructure:"daily_token_quota" validate:"min=0"`
+	MonthlyTokenQuota       int64   `mapstructure:"monthly_token_quota" validate:"min=0"`
+	DailyRunQuota           int64   `mapstructure:"daily_run_quota" validate:"min=0"`
+	MonthlyBudgetUSD        float64 `mapstructure:"monthly_budget_usd" validate:"min=0"`
+	PromptTokenPriceUSD     float64 `mapstructure:"prompt_token_price_usd" validate:"min=0"`     // per million prompt tokens
+	CompletionTokenPriceUSD float64 `mapstructure:"completion_token_price_usd" validate:"min=0"` // per million completion tokens
 }
 
 // Validate method - cải thiện để không panic nếu API key rỗng
@@ -124,6 +214,10 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if err := c.Mail.validateForMode(c.Server.Mode); err != nil {
+		return err
+	}
+
 	// Business logic validation - không bắt buộc API keys ở development
 	if c.Pinecone.APIKey == "" {
 		log.Println("⚠️  Pinecone API key is empty. Vector search features will be disabled.")
@@ -135,3 +229,12 @@ func (c *Config) Validate() error {
 	}
 	return nil
 }
+
+// validateForMode refuses the log and file providers in release mode, where
+// they would leave account tokens in logs or on disk
+func (m MailConfig) validateForMode(mode string) error {
+	if mode == "release" && m.Provider != "smtp" && !m.AllowLocalInRelease {
+		return fmt.Errorf("mail provider %q exposes account tokens; use smtp in release mode or set mail.allow_local_in_release", m.Provider)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestMailConfigRefusesLocalProvidersInRelease(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		mode    string
+		mail    MailConfig
+		wantErr bool
+	}{
+		{"log in debug", "debug", MailConfig{Provider: "log"}, false},
+		{"log in release", "release", MailConfig{Provider: "log"}, true},
+		{"file in release", "release", MailConfig{Provider: "file"}, true},
+		{"file in release with opt-in", "release", MailConfig{Provider: "file", AllowLocalInRelease: true}, false},
+		{"smtp in release", "release", MailConfig{Provider: "smtp"}, false},
+	} {
+		if err := tc.mail.validateForMode(tc.mode); (err != nil) != tc.wantErr {
+			t.Fatalf("%s: expected error=%v, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
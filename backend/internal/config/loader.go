@@ -103,12 +103,68 @@ func setDefaults(v *viper.Viper) {
 	// Collab defaults
 	v.SetDefault("collab.token_secret", "your-collab-token-secret")
 	v.SetDefault("collab.token_ttl_minutes", 60)
+	v.SetDefault("collab.pubsub", "memory")
+	v.SetDefault("collab.presence_ttl_seconds", 60)
+	v.SetDefault("collab.compaction_interval_seconds", 300)
+	v.SetDefault("collab.compaction_min_updates", 50)
+	v.SetDefault("collab.compaction_batch_size", 20)
 
 	// AI defaults
 	v.SetDefault("ai.enabled", true)
 	v.SetDefault("ai.service_url", "http://localhost:8090")
 	v.SetDefault("ai.service_token", "dev-ai-service-token")
 	v.SetDefault("ai.request_timeout_ms", 30000)
+	v.SetDefault("ai.consent_timeout_seconds", 300)
+	v.SetDefault("ai.consent_sweep_interval_seconds", 30)
+	v.SetDefault("ai.run_max_tokens", 4096)
+	v.SetDefault("ai.inline_edit_max_tokens", 20000)
+	v.SetDefault("ai.daily_token_quota", 0)
+	v.SetDefault("ai.monthly_token_quota", 0)
+	v.SetDefault("ai.daily_run_quota", 0)
+	v.SetDefault("ai.monthly_budget_usd", 0)
+	v.SetDefault("ai.prompt_token_price_usd", 0.15)
+	v.SetDefault("ai.completion_token_price_usd", 0.6)
+
+	// Trash defaults
+	v.SetDefault("trash.retention_days", 30)
+	v.SetDefault("trash.purge_interval_minutes", 60)
+
+	// Search defaults
+	v.SetDefault("search.rrf_k", 60)
+	v.SetDefault("search.candidate_pool", 50)
+	v.SetDefault("search.min_vector_score", 0.2)
+
+	// Embeddings defaults
+	v.SetDefault("embeddings.provider", "remote")
+	v.SetDefault("embeddings.chunk_size", 1000)
+	v.SetDefault("embeddings.chunk_overlap", 200)
+
+	// Vector store defaults
+	v.SetDefault("vector_store.provider", "")
+	v.SetDefault("vector_store.namespace", "default")
+
+	// Chunk queue defaults
+	v.SetDefault("chunk_queue.debounce_seconds", 5)
+	v.SetDefault("chunk_queue.poll_interval_seconds", 2)
+	v.SetDefault("chunk_queue.batch_size", 10)
+	v.SetDefault("chunk_queue.max_attempts", 8)
+	v.SetDefault("chunk_queue.backoff_base_seconds", 10)
+	v.SetDefault("chunk_queue.backoff_max_seconds", 3600)
+	v.SetDefault("chunk_queue.lease_seconds", 300)
+
+	// Admin defaults (nobody)
+	v.SetDefault("admin.emails", []string{})
+
+	// Mail defaults (printed to the log)
+	v.SetDefault("mail.provider", "log")
+	v.SetDefault("mail.allow_local_in_release", false)
+	v.SetDefault("mail.from", "no-reply@localhost")
+	v.SetDefault("mail.frontend_url", "http://localhost:3000")
+	v.SetDefault("mail.file_dir", "./tmp/mail")
+	v.SetDefault("mail.smtp_host", "")
+	v.SetDefault("mail.smtp_port", "587")
+	v.SetDefault("mail.smtp_username", "")
+	v.SetDefault("mail.smtp_password", "")
 
 	// Google OAuth defaults
 	v.SetDefault("google.client_id", "")
@@ -88,6 +88,8 @@ func New(ctx context.Context, cfg config.DatabaseConfig) (*DB, error) {
 	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Account{},
+		&models.AuthSession{},
+		&models.UserToken{},
 		&models.Folder{},
 		&models.Note{},
 		&models.Tag{},
@@ -101,6 +103,16 @@ func New(ctx context.Context, cfg config.DatabaseConfig) (*DB, error) {
 		&models.AIRunEvent{},
 		&models.AIConversation{},
 		&models.AIConversationMessage{},
+		&models.NoteRevision{},
+		&models.VectorDocument{},
+		&models.ChunkJob{},
+		&models.AIEditProposal{},
+		&models.AIUsageLimit{},
+		&models.NoteShare{},
+		&models.NoteShareLink{},
+		&models.NoteShareLinkView{},
+		&models.Workspace{},
+		&models.WorkspaceMember{},
 	); err != nil {
 		return nil, fmt.Errorf("failed to auto migrate: %w", err)
 	}
@@ -109,6 +121,34 @@ func New(ctx context.Context, cfg config.DatabaseConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to ensure note_chunks vector indexes: %w", err)
 	}
 
+	if err := ensureNoteSearchVector(db); err != nil {
+		return nil, fmt.Errorf("failed to ensure notes search vector: %w", err)
+	}
+
+	if err := ensureVectorDocumentIndexes(db); err != nil {
+		return nil, fmt.Errorf("failed to ensure vector_documents indexes: %w", err)
+	}
+
+	if err := ensureConversationMessageSearchVector(db); err != nil {
+		return nil, fmt.Errorf("failed to ensure ai_conversation_messages search vector: %w", err)
+	}
+
+	if err := ensureAIRunEventSeq(db); err != nil {
+		return nil, fmt.Errorf("failed to ensure ai_run_events seq: %w", err)
+	}
+
+	if err := ensureNoteShareIndexes(db); err != nil {
+		return nil, fmt.Errorf("failed to ensure note_shares indexes: %w", err)
+	}
+
+	if err := ensurePersonalWorkspaces(db); err != nil {
+		return nil, fmt.Errorf("failed to ensure personal workspaces: %w", err)
+	}
+
+	if err := ensureTagIndexes(db); err != nil {
+		return nil, fmt.Errorf("failed to ensure tags indexes: %w", err)
+	}
+
 	return &DB{db}, nil
 }
 
@@ -131,6 +171,165 @@ func ensureNoteChunkVectorIndexes(db *gorm.DB) error {
 	return nil
 }
 
+func ensureVectorDocumentIndexes(db *gorm.DB) error {
+	queries := []string{
+		`CREATE INDEX IF NOT EXISTS idx_vector_documents_embedding_hnsw ON vector_documents USING hnsw (embedding vector_cosine_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_vector_documents_metadata ON vector_documents USING gin (metadata jsonb_path_ops)`,
+	}
+
+	for _, query := range queries {
+		if err := db.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureNoteSearchVector adds the generated tsvector column used by lexical
+// search (title weighted above content, HTML tags and entities stripped).
+// It is kept out of the GORM model so saves never write to it.
+func ensureNoteSearchVector(db *gorm.DB) error {
+	queries := []string{
+		`ALTER TABLE notes ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('simple', regexp_replace(coalesce(content, ''), '<[^>]+>|&[#a-zA-Z0-9]+;', ' ', 'g')), 'B')
+			) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_notes_search_vector ON notes USING gin (search_vector)`,
+	}
+
+	for _, query := range queries {
+		if err := db.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ensureConversationMessageSearchVector(db *gorm.DB) error {
+	queries := []string{
+		`ALTER TABLE ai_conversation_messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (to_tsvector('simple', coalesce(content, ''))) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_ai_conversation_messages_search_vector ON ai_conversation_messages USING gin (search_vector)`,
+	}
+
+	for _, query := range queries {
+		if err := db.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureAIRunEventSeq numbers run events in insertion order. Deltas are stored
+// one row per frame, so many share a created_at and only seq orders them.
+func ensureAIRunEventSeq(db *gorm.DB) error {
+	queries := []string{
+		`ALTER TABLE ai_run_events ADD COLUMN IF NOT EXISTS seq bigserial`,
+		`CREATE INDEX IF NOT EXISTS idx_ai_run_events_run_seq ON ai_run_events (run_id, seq)`,
+	}
+
+	for _, query := range queries {
+		if err := db.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureNoteShareIndexes keeps a single live share per note or folder and email
+func ensureNoteShareIndexes(db *gorm.DB) error {
+	queries := []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_note_shares_note_email ON note_shares (note_id, grantee_email)
+			WHERE note_id IS NOT NULL AND deleted_at IS NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_note_shares_folder_email ON note_shares (folder_id, grantee_email)
+			WHERE folder_id IS NOT NULL AND deleted_at IS NULL`,
+	}
+
+	for _, query := range queries {
+		if err := db.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensurePersonalWorkspaces gives every user a personal workspace and moves
+// their notes, folders, templates and tags that predate workspaces into it
+func ensurePersonalWorkspaces(db *gorm.DB) error {
+	queries := []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_workspaces_personal_owner ON workspaces (owner_id)
+			WHERE personal AND deleted_at IS NULL`,
+		`INSERT INTO workspaces (name, owner_id, personal, created_at, updated_at)
+			SELECT 'Personal', u.id, true, NOW(), NOW() FROM users u
+			WHERE NOT EXISTS (
+				SELECT 1 FROM workspaces w WHERE w.owner_id = u.id AND w.personal AND w.deleted_at IS NULL
+			)`,
+		`INSERT INTO workspace_members (workspace_id, user_id, role, created_at, updated_at)
+			SELECT w.id, w.owner_id, 'owner', NOW(), NOW() FROM workspaces w
+			WHERE w.personal AND w.deleted_at IS NULL
+			ON CONFLICT (workspace_id, user_id) DO NOTHING`,
+	}
+	for _, table := range []string{"notes", "folders", "templates", "tags"} {
+		queries = append(queries, `UPDATE `+table+` t SET workspace_id = w.id FROM workspaces w
+			WHERE t.workspace_id IS NULL AND w.owner_id = t.user_id AND w.personal AND w.deleted_at IS NULL`)
+	}
+
+	for _, query := range queries {
+		if err := db.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureTagIndexes makes tag names unique per workspace. Tags used to be
+// unique per user, so members of a shared workspace may hold the same name:
+// their notes move to the oldest of those tags and the others are dropped.
+// Tags outside any workspace stay unique per user.
+func ensureTagIndexes(db *gorm.DB) error {
+	queries := []string{
+		`DROP INDEX IF EXISTS idx_tags_user_name`,
+		`DO $$
+DECLARE
+	dupe RECORD;
+BEGIN
+	FOR dupe IN
+		SELECT id, keep_id FROM (
+			SELECT id, FIRST_VALUE(id) OVER (PARTITION BY workspace_id, name ORDER BY created_at, id) AS keep_id
+			FROM tags
+			WHERE workspace_id IS NOT NULL
+		) ranked
+		WHERE id <> keep_id
+	LOOP
+		INSERT INTO note_tags (note_id, tag_id)
+			SELECT note_id, dupe.keep_id FROM note_tags WHERE tag_id = dupe.id
+			ON CONFLICT DO NOTHING;
+		DELETE FROM note_tags WHERE tag_id = dupe.id;
+		DELETE FROM tags WHERE id = dupe.id;
+	END LOOP;
+END $$;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_workspace_name ON tags (workspace_id, name)
+			WHERE workspace_id IS NOT NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_personal_user_name ON tags (user_id, name)
+			WHERE workspace_id IS NULL`,
+	}
+
+	for _, query := range queries {
+		if err := db.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func migrateUserSchema(db *gorm.DB) error {
 	queries := []string{
 		`DO $$
@@ -153,6 +352,65 @@ BEGIN
 				ELSE NULL
 			END;
 	END IF;
+END $$;`,
+		// Tags used to be global with a unique name. They are per-user now: each
+		// tag goes to the owner of the first note using it, and every other
+		// owner gets a copy of their own. Tags no note uses cannot be
+		// attributed to anyone and are dropped.
+		`DO $$
+DECLARE
+	pair RECORD;
+	copy_id uuid;
+BEGIN
+	IF EXISTS (
+		SELECT 1
+		FROM information_schema.tables
+		WHERE table_schema = 'public'
+			AND table_name = 'tags'
+	) AND NOT EXISTS (
+		SELECT 1
+		FROM information_schema.columns
+		WHERE table_schema = 'public'
+			AND table_name = 'tags'
+			AND column_name = 'user_id'
+	) THEN
+		DROP INDEX IF EXISTS idx_tags_name;
+		ALTER TABLE tags ADD COLUMN user_id uuid;
+
+		IF EXISTS (
+			SELECT 1
+			FROM information_schema.tables
+			WHERE table_schema = 'public'
+				AND table_name = 'note_tags'
+		) THEN
+			UPDATE tags t SET user_id = owners.user_id
+			FROM (
+				SELECT DISTINCT ON (nt.tag_id) nt.tag_id, n.user_id
+				FROM note_tags nt
+				JOIN notes n ON n.id = nt.note_id
+				ORDER BY nt.tag_id, n.created_at, n.id
+			) owners
+			WHERE t.id = owners.tag_id;
+
+			FOR pair IN
+				SELECT DISTINCT nt.tag_id, n.user_id
+				FROM note_tags nt
+				JOIN notes n ON n.id = nt.note_id
+				JOIN tags t ON t.id = nt.tag_id
+				WHERE n.user_id <> t.user_id
+			LOOP
+				copy_id := gen_random_uuid();
+				INSERT INTO tags (id, created_at, updated_at, name, color, user_id)
+					SELECT copy_id, created_at, updated_at, name, color, pair.user_id
+					FROM tags WHERE id = pair.tag_id;
+				UPDATE note_tags SET tag_id = copy_id
+					WHERE tag_id = pair.tag_id
+						AND note_id IN (SELECT id FROM notes WHERE user_id = pair.user_id);
+			END LOOP;
+		END IF;
+
+		DELETE FROM tags WHERE user_id IS NULL;
+	END IF;
 END $$;`,
 	}
 
@@ -215,30 +473,3 @@ func (db *DB) Close() error {
 
 	return nil
 }
-
-// SeedData seeds initial data into the database
-func (db *DB) SeedData() error {
-	// Create default tags
-	tags := []models.Tag{
-		{Name: "Important", Color: "#EF4444"},
-		{Name: "Work", Color: "#3B82F6"},
-		{Name: "Personal", Color: "#10B981"},
-		{Name: "Ideas", Color: "#F59E0B"},
-		{Name: "Draft", Color: "#6B7280"},
-	}
-
-	for _, tag := range tags {
-		var existingTag models.Tag
-		if err := db.Where("name = ?", tag.Name).First(&existingTag).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				if err := db.Create(&tag).Error; err != nil {
-					return fmt.Errorf("failed to create tag %s: %w", tag.Name, err)
-				}
-			} else {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type AIEditProposalStatus string
+
+const (
+	AIEditProposalStatusPending  AIEditProposalStatus = "pending"
+	AIEditProposalStatusApplying AIEditProposalStatus = "applying" // claimed by an accept that is writing the note
+	AIEditProposalStatusAccepted AIEditProposalStatus = "accepted"
+	AIEditProposalStatusRejected AIEditProposalStatus = "rejected"
+)
+
+// AIEditProposal is a note edit suggested by the AI that waits for the user to
+// accept or reject it. Original is the note content at ExpectedVersion and
+// Proposed the full content the AI wants instead.
+type AIEditProposal struct {
+	BaseModel
+	NoteID          string               `gorm:"type:uuid;index;not null" json:"note_id"`
+	UserID          string               `gorm:"type:varchar(64);index;not null" json:"user_id"`
+	RunID           string               `gorm:"type:varchar(64);index" json:"run_id"`
+	ToolCallID      string               `gorm:"type:varchar(128)" json:"tool_call_id,omitempty"`
+	ExpectedVersion int                  `gorm:"not null" json:"expected_version"`
+	Original        string               `gorm:"type:text;not null" json:"original"`
+	Proposed        string               `gorm:"type:text;not null" json:"proposed"`
+	Summary         string               `gorm:"type:text" json:"summary"`
+	Status          AIEditProposalStatus `gorm:"type:varchar(32);index;not null" json:"status"`
+	AppliedVersion  *int                 `json:"applied_version,omitempty"`
+	Rebased         bool                 `gorm:"not null;default:false" json:"rebased"`
+	ResolvedAt      *time.Time           `json:"resolved_at,omitempty"`
+}
+
+// TableName returns the table name for AIEditProposal
+func (AIEditProposal) TableName() string {
+	return "ai_edit_proposals"
+}
@@ -13,6 +13,14 @@ const (
 	AIRunStatusAwaitingConsent AIRunStatus = "awaiting_consent"
 	AIRunStatusCompleted       AIRunStatus = "completed"
 	AIRunStatusFailed          AIRunStatus = "failed"
+	AIRunStatusExpired         AIRunStatus = "expired"
+)
+
+type AIRunMode string
+
+const (
+	AIRunModeChat       AIRunMode = "chat"
+	AIRunModeInlineEdit AIRunMode = "inline_edit"
 )
 
 type AIToolCallStatus string
@@ -36,6 +44,14 @@ type AIRun struct {
 	ResumeToken    string         `gorm:"type:varchar(64);index" json:"resume_token"`
 	LastEventID    string         `gorm:"type:varchar(64)" json:"last_event_id"`
 	Metadata       datatypes.JSON `gorm:"type:jsonb" json:"metadata"`
+
+	// Usage reported by the ai-service when the run completes
+	Mode             AIRunMode `gorm:"type:varchar(32);index;not null;default:'chat'" json:"mode"`
+	Model            string    `gorm:"type:varchar(128)" json:"model"`
+	PromptTokens     int64     `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int64     `gorm:"not null;default:0" json:"completion_tokens"`
+	TotalTokens      int64     `gorm:"not null;default:0" json:"total_tokens"`
+	CostUSD          float64   `gorm:"type:numeric(12,6);not null;default:0" json:"cost_usd"`
 }
 
 type AIToolCall struct {
@@ -57,6 +73,9 @@ type AIRunEvent struct {
 	EventID   string         `gorm:"type:varchar(64);index;not null" json:"event_id"`
 	EventType string         `gorm:"type:varchar(64);index;not null" json:"event_type"`
 	Payload   datatypes.JSON `gorm:"type:jsonb;not null" json:"payload"`
+
+	// Insertion order, assigned by the database (see ensureAIRunEventSeq)
+	Seq int64 `gorm:"->;-:migration" json:"-"`
 }
 
 type AIConversation struct {
@@ -65,6 +84,10 @@ type AIConversation struct {
 	Title         string    `gorm:"type:varchar(160);not null" json:"title"`
 	LastMessageAt time.Time `gorm:"index;not null" json:"last_message_at"`
 	IsDeleted     bool      `gorm:"index;not null;default:false" json:"is_deleted"`
+
+	// Set on branches forked from another conversation
+	ParentID            *string `gorm:"type:uuid;index" json:"parent_id"`
+	ForkedFromMessageID *string `gorm:"type:uuid" json:"forked_from_message_id"`
 }
 
 type AIConversationMessage struct {
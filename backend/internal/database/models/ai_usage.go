@@ -0,0 +1,18 @@
+package models
+
+// AIUsageLimit overrides the configured AI quotas for one user. A nil field
+// falls back to the config default and 0 means unlimited.
+type AIUsageLimit struct {
+	BaseModel
+	UserID           string   `gorm:"type:varchar(64);uniqueIndex;not null" json:"user_id"`
+	Tier             string   `gorm:"type:varchar(32);not null;default:'free'" json:"tier"`
+	DailyTokens      *int64   `json:"daily_tokens"`
+	MonthlyTokens    *int64   `json:"monthly_tokens"`
+	DailyRuns        *int64   `json:"daily_runs"`
+	MonthlyBudgetUSD *float64 `gorm:"type:numeric(12,6)" json:"monthly_budget_usd"`
+}
+
+// TableName returns the table name for AIUsageLimit
+func (AIUsageLimit) TableName() string {
+	return "ai_usage_limits"
+}
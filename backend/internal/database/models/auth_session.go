@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Reasons a session was revoked
+const (
+	SessionRevokedLogout          = "logout"
+	SessionRevokedByUser          = "revoked"
+	SessionRevokedTokenReuse      = "refresh_token_reused"
+	SessionRevokedPasswordChanged = "password_changed"
+	SessionRevokedPasswordReset   = "password_reset"
+)
+
+// AuthSession is one signed-in device. Its access and refresh tokens carry
+// the session ID, and only the refresh token whose ID matches RefreshTokenID
+// may be exchanged; presenting an older one revokes the session.
+type AuthSession struct {
+	BaseModel
+	UserID         string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	RefreshTokenID string     `gorm:"type:varchar(64);not null" json:"-"`
+	Device         string     `gorm:"type:varchar(100)" json:"device"`
+	UserAgent      string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IPAddress      string     `gorm:"type:varchar(45)" json:"ip_address"`
+	LastUsedAt     time.Time  `json:"last_used_at"`
+	ExpiresAt      time.Time  `gorm:"index" json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason  string     `gorm:"type:varchar(30)" json:"revoked_reason,omitempty"`
+
+	// Computed for responses
+	Current bool `gorm:"-" json:"current"`
+}
+
+// TableName returns the table name for AuthSession
+func (AuthSession) TableName() string {
+	return "auth_sessions"
+}
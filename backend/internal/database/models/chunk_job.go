@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ChunkJobStatus is the state of a note's chunking job
+type ChunkJobStatus string
+
+const (
+	ChunkJobStatusPending   ChunkJobStatus = "pending"
+	ChunkJobStatusRunning   ChunkJobStatus = "running"
+	ChunkJobStatusSucceeded ChunkJobStatus = "succeeded"
+	ChunkJobStatusDead      ChunkJobStatus = "dead"
+)
+
+// ChunkJob queues (re)chunking and embedding of a note. There is one row per
+// note: saving again pushes RunAfter back and bumps Generation, so rapid saves
+// collapse into a single run and a run that raced a newer save is not marked done.
+type ChunkJob struct {
+	BaseModel
+
+	NoteID      string         `gorm:"type:uuid;not null;uniqueIndex" json:"note_id"`
+	UserID      string         `gorm:"type:uuid;not null;index" json:"user_id"`
+	Event       string         `gorm:"type:varchar(64)" json:"event"`
+	Status      ChunkJobStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Generation  int            `gorm:"not null;default:1" json:"generation"`
+	Attempts    int            `gorm:"not null;default:0" json:"attempts"`
+	RunAfter    time.Time      `gorm:"not null;index" json:"run_after"`
+	LockedAt    *time.Time     `json:"locked_at,omitempty"`
+	LastError   string         `gorm:"type:text" json:"last_error,omitempty"`
+	ChunkCount  int            `gorm:"not null;default:0" json:"chunk_count"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+}
+
+func (ChunkJob) TableName() string {
+	return "chunk_jobs"
+}
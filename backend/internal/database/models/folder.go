@@ -8,8 +8,12 @@ type Folder struct {
 	SortOrder int    `gorm:"column:order_index;not null;default:1;index" json:"order"`
 
 	// Foreign Keys
-	UserID   string  `gorm:"type:uuid;not null" json:"user_id"`
-	ParentID *string `gorm:"type:uuid;index" json:"parent_id,omitempty"`
+	UserID      string  `gorm:"type:uuid;not null" json:"user_id"`
+	WorkspaceID *string `gorm:"type:uuid;index" json:"workspace_id,omitempty"`
+	ParentID    *string `gorm:"type:uuid;index" json:"parent_id,omitempty"`
+
+	// TrashedWith points at the ancestor folder whose deletion moved this folder to the trash
+	TrashedWith *string `gorm:"type:uuid;index" json:"trashed_with,omitempty"`
 
 	// Relationships
 	User     User     `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
@@ -15,8 +15,12 @@ type Note struct {
 	PublicEditToken   string     `gorm:"type:varchar(64)" json:"public_edit_token,omitempty"`
 
 	// Foreign Keys
-	UserID   string  `gorm:"type:uuid;not null" json:"user_id"`
-	FolderID *string `gorm:"type:uuid;index" json:"folder_id,omitempty"`
+	UserID      string  `gorm:"type:uuid;not null" json:"user_id"`
+	WorkspaceID *string `gorm:"type:uuid;index" json:"workspace_id,omitempty"`
+	FolderID    *string `gorm:"type:uuid;index" json:"folder_id,omitempty"`
+
+	// TrashedWith points at the folder whose deletion moved this note to the trash
+	TrashedWith *string `gorm:"type:uuid;index" json:"trashed_with,omitempty"`
 
 	// Relationships
 	User   User    `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
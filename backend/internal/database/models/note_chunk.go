@@ -4,6 +4,9 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+// NoteChunkEmbeddingDimension is the size of the note_chunks vector column
+const NoteChunkEmbeddingDimension = 1024
+
 // NoteChunk stores a single chunk of a note along with its embedding vector.
 type NoteChunk struct {
 	BaseModel
@@ -12,6 +15,7 @@ type NoteChunk struct {
 	UserID         string          `gorm:"type:uuid;not null;index" json:"user_id"`
 	ChunkIndex     int             `gorm:"not null" json:"chunk_index"`
 	Text           string          `gorm:"type:text" json:"text"`
+	ContentHash    string          `gorm:"type:varchar(64);index" json:"content_hash"`
 	TextEmbeddings pgvector.Vector `gorm:"column:text_embeddings;type:vector(1024)" json:"-"`
 }
 
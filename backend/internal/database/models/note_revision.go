@@ -0,0 +1,34 @@
+package models
+
+// NoteRevisionSource describes who produced a note revision
+type NoteRevisionSource string
+
+const (
+	NoteRevisionSourceUser       NoteRevisionSource = "user"
+	NoteRevisionSourceAI         NoteRevisionSource = "ai"
+	NoteRevisionSourcePublicEdit NoteRevisionSource = "public_edit"
+	NoteRevisionSourceSystem     NoteRevisionSource = "system"
+)
+
+// NoteRevision is an immutable copy of a note's content taken on every save
+type NoteRevision struct {
+	BaseModel
+	NoteID        string             `gorm:"type:uuid;not null;index" json:"note_id"`
+	Version       int                `gorm:"not null;default:0" json:"version"`
+	Title         string             `gorm:"type:varchar(200)" json:"title"`
+	Content       string             `gorm:"type:text" json:"content"`
+	TiptapContent string             `gorm:"type:text" json:"tiptap_content,omitempty"`
+	Source        NoteRevisionSource `gorm:"type:varchar(32);not null;index" json:"source"`
+
+	// Attribution (depends on Source)
+	AuthorID             *string `gorm:"type:uuid;index" json:"author_id,omitempty"`
+	RunID                string  `gorm:"type:varchar(64);index" json:"run_id,omitempty"`
+	ToolCallID           string  `gorm:"type:varchar(128)" json:"tool_call_id,omitempty"`
+	EditTokenFingerprint string  `gorm:"type:varchar(16)" json:"edit_token_fingerprint,omitempty"`
+	RestoredFromID       *string `gorm:"type:uuid" json:"restored_from_id,omitempty"`
+}
+
+// TableName returns the table name for NoteRevision
+func (NoteRevision) TableName() string {
+	return "note_revisions"
+}
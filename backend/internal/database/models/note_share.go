@@ -0,0 +1,58 @@
+package models
+
+// NoteRole is what a user may do with a note. Shares grant viewer, commenter
+// or editor; owner is only ever derived from the note itself.
+type NoteRole string
+
+const (
+	NoteRoleViewer    NoteRole = "viewer"
+	NoteRoleCommenter NoteRole = "commenter"
+	NoteRoleEditor    NoteRole = "editor"
+	NoteRoleOwner     NoteRole = "owner"
+)
+
+func (r NoteRole) rank() int {
+	switch r {
+	case NoteRoleViewer:
+		return 1
+	case NoteRoleCommenter:
+		return 2
+	case NoteRoleEditor:
+		return 3
+	case NoteRoleOwner:
+		return 4
+	}
+	return 0
+}
+
+// Includes reports whether r grants at least what other grants
+func (r NoteRole) Includes(other NoteRole) bool {
+	return other.rank() > 0 && r.rank() >= other.rank()
+}
+
+// CanView reports whether the role may read the note
+func (r NoteRole) CanView() bool { return r.Includes(NoteRoleViewer) }
+
+// CanComment reports whether the role may comment on the note
+func (r NoteRole) CanComment() bool { return r.Includes(NoteRoleCommenter) }
+
+// CanEdit reports whether the role may change the note's content
+func (r NoteRole) CanEdit() bool { return r.Includes(NoteRoleEditor) }
+
+// NoteShare grants a user access to a note, or to every note in a folder and
+// its subfolders. Exactly one of NoteID and FolderID is set. Shares with an
+// email that has no account yet keep GranteeUserID empty until it signs up.
+type NoteShare struct {
+	BaseModel
+	NoteID        *string  `gorm:"type:uuid;index" json:"note_id,omitempty"`
+	FolderID      *string  `gorm:"type:uuid;index" json:"folder_id,omitempty"`
+	OwnerID       string   `gorm:"type:uuid;index;not null" json:"owner_id"`
+	GranteeUserID *string  `gorm:"type:uuid;index" json:"grantee_user_id,omitempty"`
+	GranteeEmail  string   `gorm:"type:varchar(255);index;not null" json:"grantee_email"`
+	Role          NoteRole `gorm:"type:varchar(20);not null" json:"role"`
+}
+
+// TableName returns the table name for NoteShare
+func (NoteShare) TableName() string {
+	return "note_shares"
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// ShareLinkStatus tells whether a share link still opens its note
+type ShareLinkStatus string
+
+const (
+	ShareLinkStatusActive  ShareLinkStatus = "active"
+	ShareLinkStatusExpired ShareLinkStatus = "expired"
+	ShareLinkStatusRevoked ShareLinkStatus = "revoked"
+)
+
+// NoteShareLink is a read-only public link to a note. A note can have many
+// links so one recipient's access can be rotated or revoked on its own.
+type NoteShareLink struct {
+	BaseModel
+	NoteID        string     `gorm:"type:uuid;index;not null" json:"note_id"`
+	OwnerID       string     `gorm:"type:uuid;index;not null" json:"owner_id"`
+	Slug          string     `gorm:"type:varchar(32);uniqueIndex;not null" json:"slug"`
+	Label         string     `gorm:"type:varchar(100)" json:"label"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	PasswordHash  string     `gorm:"type:varchar(100)" json:"-"`
+	AllowComments bool       `gorm:"default:false" json:"allow_comments"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	ViewCount     int64      `gorm:"not null;default:0" json:"view_count"`
+	LastViewedAt  *time.Time `json:"last_viewed_at,omitempty"`
+
+	// Computed for responses
+	HasPassword bool            `gorm:"-" json:"has_password"`
+	Status      ShareLinkStatus `gorm:"-" json:"status"`
+}
+
+// TableName returns the table name for NoteShareLink
+func (NoteShareLink) TableName() string {
+	return "note_share_links"
+}
+
+// StatusAt reports whether the link is active, expired or revoked at a time
+func (l *NoteShareLink) StatusAt(now time.Time) ShareLinkStatus {
+	switch {
+	case l.RevokedAt != nil:
+		return ShareLinkStatusRevoked
+	case l.ExpiresAt != nil && !now.Before(*l.ExpiresAt):
+		return ShareLinkStatusExpired
+	}
+	return ShareLinkStatusActive
+}
+
+// NoteShareLinkView records one view of a share link. VisitorHash is a
+// salted hash of the viewer's address and user agent, never the raw values.
+type NoteShareLinkView struct {
+	BaseModel
+	LinkID      string `gorm:"type:uuid;index;not null" json:"link_id"`
+	VisitorHash string `gorm:"type:varchar(64);not null" json:"-"`
+	Referrer    string `gorm:"type:varchar(255)" json:"referrer,omitempty"`
+}
+
+// TableName returns the table name for NoteShareLinkView
+func (NoteShareLinkView) TableName() string {
+	return "note_share_link_views"
+}
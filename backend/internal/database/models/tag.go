@@ -1,10 +1,13 @@
 package models
 
-// Tag represents a tag in the system
+// Tag represents a tag of a workspace. Names are unique per workspace, see
+// ensureTagIndexes.
 type Tag struct {
 	BaseModel
-	Name      string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"name"`
-	Color     string    `gorm:"type:varchar(7);default:'#3B82F6'" json:"color"`
+	Name        string  `gorm:"type:varchar(50);not null" json:"name"`
+	Color       string  `gorm:"type:varchar(7);default:'#3B82F6'" json:"color"`
+	UserID      string  `gorm:"type:uuid;not null;index" json:"user_id"`
+	WorkspaceID *string `gorm:"type:uuid;index" json:"workspace_id,omitempty"`
 
 	// Relationships
 	Notes []Note `gorm:"many2many:note_tags;constraint:OnDelete:CASCADE" json:"notes,omitempty"`
@@ -10,7 +10,8 @@ type Template struct {
 	Color   string `gorm:"type:varchar(20)" json:"color"`
 
 	// Foreign Keys
-	UserID string `gorm:"type:uuid;not null" json:"user_id"`
+	UserID      string  `gorm:"type:uuid;not null" json:"user_id"`
+	WorkspaceID *string `gorm:"type:uuid;index" json:"workspace_id,omitempty"`
 
 	// Relationships
 	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// UserTokenPurpose is what an emailed account token may be used for
+type UserTokenPurpose string
+
+const (
+	UserTokenEmailVerification UserTokenPurpose = "email_verification"
+	UserTokenPasswordReset     UserTokenPurpose = "password_reset"
+)
+
+// UserToken records an emailed, signed account token so it can be used only
+// once. TokenID matches the token's jti claim; the token itself is never
+// stored.
+type UserToken struct {
+	BaseModel
+	UserID    string           `gorm:"type:uuid;not null;index" json:"user_id"`
+	Purpose   UserTokenPurpose `gorm:"type:varchar(30);not null" json:"purpose"`
+	TokenID   string           `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time        `json:"expires_at"`
+	UsedAt    *time.Time       `json:"used_at,omitempty"`
+}
+
+// TableName returns the table name for UserToken
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
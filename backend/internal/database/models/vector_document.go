@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/datatypes"
+)
+
+// VectorDocument is a record of the pgvector-backed vector store. Documents
+// are keyed by namespace (one per user, plus a default one) and ID.
+type VectorDocument struct {
+	Namespace string          `gorm:"type:varchar(128);primaryKey" json:"namespace"`
+	ID        string          `gorm:"type:varchar(255);primaryKey" json:"id"`
+	Text      string          `gorm:"type:text" json:"text"`
+	Embedding pgvector.Vector `gorm:"type:vector(1024)" json:"-"`
+	Metadata  datatypes.JSON  `gorm:"type:jsonb" json:"metadata"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+func (VectorDocument) TableName() string {
+	return "vector_documents"
+}
@@ -0,0 +1,78 @@
+package models
+
+// WorkspaceRole is what a member may do in a workspace
+type WorkspaceRole string
+
+const (
+	WorkspaceRoleViewer WorkspaceRole = "viewer"
+	WorkspaceRoleMember WorkspaceRole = "member"
+	WorkspaceRoleAdmin  WorkspaceRole = "admin"
+	WorkspaceRoleOwner  WorkspaceRole = "owner"
+)
+
+// Valid reports whether r is a known workspace role
+func (r WorkspaceRole) Valid() bool {
+	switch r {
+	case WorkspaceRoleViewer, WorkspaceRoleMember, WorkspaceRoleAdmin, WorkspaceRoleOwner:
+		return true
+	}
+	return false
+}
+
+// CanManage reports whether the role may rename the workspace and manage its
+// members
+func (r WorkspaceRole) CanManage() bool {
+	return r == WorkspaceRoleAdmin || r == WorkspaceRoleOwner
+}
+
+// CanWrite reports whether the role may create notes, folders, templates and
+// tags in the workspace
+func (r WorkspaceRole) CanWrite() bool {
+	return r == WorkspaceRoleMember || r.CanManage()
+}
+
+// NoteRole is the access the workspace role gives to every note in the
+// workspace. Owning the workspace does not make someone the owner of a
+// teammate's note.
+func (r WorkspaceRole) NoteRole() NoteRole {
+	switch {
+	case r.CanWrite():
+		return NoteRoleEditor
+	case r == WorkspaceRoleViewer:
+		return NoteRoleViewer
+	}
+	return ""
+}
+
+// Workspace is the tenant notes, folders, templates and tags belong to.
+// Every user has a personal workspace; team workspaces have members.
+type Workspace struct {
+	BaseModel
+	Name     string `gorm:"type:varchar(100);not null" json:"name"`
+	OwnerID  string `gorm:"type:uuid;index;not null" json:"owner_id"`
+	Personal bool   `gorm:"default:false" json:"personal"`
+
+	// Role is the current user's role, filled in when listing workspaces
+	Role WorkspaceRole `gorm:"-" json:"role,omitempty"`
+}
+
+// TableName returns the table name for Workspace
+func (Workspace) TableName() string {
+	return "workspaces"
+}
+
+// WorkspaceMember gives a user a role in a workspace
+type WorkspaceMember struct {
+	BaseModel
+	WorkspaceID string        `gorm:"type:uuid;not null;uniqueIndex:idx_workspace_members_workspace_user" json:"workspace_id"`
+	UserID      string        `gorm:"type:uuid;not null;index;uniqueIndex:idx_workspace_members_workspace_user" json:"user_id"`
+	Role        WorkspaceRole `gorm:"type:varchar(20);not null" json:"role"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+// TableName returns the table name for WorkspaceMember
+func (WorkspaceMember) TableName() string {
+	return "workspace_members"
+}
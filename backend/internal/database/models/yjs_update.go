@@ -0,0 +1,20 @@
+package models
+
+// Yjs update row versions written by y-postgresql
+const (
+	YjsUpdateVersionUpdate      = "v1"
+	YjsUpdateVersionStateVector = "v1_sv"
+)
+
+// YjsUpdate is a row of the collab server's y-postgresql table. The table is
+// created and owned by the collab server, so it is not auto-migrated here.
+type YjsUpdate struct {
+	ID      int64  `gorm:"primaryKey" json:"id"`
+	Docname string `gorm:"column:docname" json:"docname"`
+	Value   []byte `gorm:"column:value" json:"-"`
+	Version string `gorm:"column:version" json:"version"`
+}
+
+func (YjsUpdate) TableName() string {
+	return "yjs_updates"
+}
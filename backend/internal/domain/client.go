@@ -1,15 +1,60 @@
 package domain
 
 import (
+	"sync"
+
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
 	"github.com/gorilla/websocket"
 )
 
 // Client represents a WebSocket client connection
 type Client struct {
-	Conn *websocket.Conn
-	User *models.User
+	// ID identifies this connection across backend instances
+	ID     string
+	Conn   *websocket.Conn
+	User   *models.User
 	NoteID string
+	Role   CollabRole
+
+	writeMu sync.Mutex
+}
+
+// Send writes a text message to the client. Room fan-out and request
+// handling write from different goroutines, so writes are serialized.
+func (c *Client) Send(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// CollabRole is what a connection may do in a note. The values match the
+// role claim of collab tokens.
+type CollabRole string
+
+const (
+	CollabRoleOwner      CollabRole = "owner"
+	CollabRoleEditor     CollabRole = "editor"
+	CollabRolePublicEdit CollabRole = "public_edit"
+	CollabRoleViewer     CollabRole = "viewer"
+)
+
+// CanEdit reports whether the role may change the note's content
+func (r CollabRole) CanEdit() bool {
+	return r == CollabRoleOwner || r == CollabRoleEditor || r == CollabRolePublicEdit
+}
+
+// CollabRoleFor maps a user's role on a note to what their connection may
+// do. Commenters comment over the REST API, so in the editor they only view.
+func CollabRoleFor(role models.NoteRole) CollabRole {
+	switch {
+	case role == models.NoteRoleOwner:
+		return CollabRoleOwner
+	case role.CanEdit():
+		return CollabRoleEditor
+	case role.CanView():
+		return CollabRoleViewer
+	}
+	return ""
 }
 
 // ClientRepository defines the interface for client management
@@ -19,6 +64,7 @@ type ClientRepository interface {
 	Get(conn *websocket.Conn) (*Client, error)
 	GetAll() []*Client
 	GetByUserID(userID string) (*Client, error)
+	GetByNoteID(noteID string) []*Client
 }
 
 // CollaborationService defines the interface for collaboration features
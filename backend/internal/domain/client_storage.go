@@ -70,3 +70,17 @@ func (r *InMemoryClientRepository) GetByUserID(userID string) (*Client, error) {
 	}
 	return nil, fmt.Errorf("client not found for user: %s", userID)
 }
+
+// GetByNoteID returns the clients connected to a note
+func (r *InMemoryClientRepository) GetByNoteID(noteID string) []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clients := make([]*Client, 0)
+	for _, client := range r.clients {
+		if client.NoteID == noteID {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
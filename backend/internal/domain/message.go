@@ -20,6 +20,7 @@ const (
 	MessageTypeUserUpdated MessageType = "user_updated"
 	MessageTypePing      MessageType = "ping"
 	MessageTypePong      MessageType = "pong"
+	MessageTypeError     MessageType = "error"
 )
 
 // Message represents a WebSocket message envelope
@@ -53,6 +54,12 @@ type InitPayload struct {
 	Version int    `json:"version"`
 }
 
+// ErrorPayload represents the payload for error message
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 // UserLeftPayload represents the payload for user left message
 type UserLeftPayload struct {
 	ID string `json:"id"`
@@ -0,0 +1,106 @@
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// HashingDefaultDimension matches the note_chunks vector column
+	HashingDefaultDimension = 1024
+
+	hashingWordWeight    = 1.0
+	hashingTrigramWeight = 0.5
+)
+
+// hashingProvider implements EmbeddingProvider with feature hashing.
+// Words and character trigrams are hashed into a fixed number of signed
+// buckets and the result is L2-normalised, so cosine similarity reflects
+// shared vocabulary. It needs no model files or network access and always
+// returns the same vector for the same text, which suits air-gapped installs
+// and tests. It does not capture meaning beyond word overlap.
+type hashingProvider struct {
+	dimension int
+}
+
+// NewHashingProvider creates a deterministic hashing embedding provider
+func NewHashingProvider(dimension int) EmbeddingProvider {
+	if dimension <= 0 {
+		dimension = HashingDefaultDimension
+	}
+	return &hashingProvider{dimension: dimension}
+}
+
+// Embed generates embeddings for multiple texts (for indexing documents)
+func (p *hashingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, p.embed(text))
+	}
+	return vectors, nil
+}
+
+// EmbedQuery generates embedding for a single query text (for searching)
+func (p *hashingProvider) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.embed(query), nil
+}
+
+// GetDimension returns the dimension of the embedding vectors
+func (p *hashingProvider) GetDimension() int {
+	return p.dimension
+}
+
+func (p *hashingProvider) embed(text string) []float32 {
+	acc := make([]float64, p.dimension)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		p.add(acc, "w:"+word, hashingWordWeight)
+
+		// Trigrams let related word forms ("index", "indexing") overlap
+		runes := []rune("^" + word + "$")
+		for i := 0; i+3 <= len(runes); i++ {
+			p.add(acc, "t:"+string(runes[i:i+3]), hashingTrigramWeight)
+		}
+	}
+
+	var norm float64
+	for _, value := range acc {
+		norm += value * value
+	}
+	norm = math.Sqrt(norm)
+
+	vector := make([]float32, p.dimension)
+	if norm == 0 {
+		return vector
+	}
+	for i, value := range acc {
+		vector[i] = float32(value / norm)
+	}
+	return vector
+}
+
+// add hashes a feature into a bucket; a second hash bit picks the sign so
+// collisions cancel out on average instead of piling up
+func (p *hashingProvider) add(acc []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	bucket := int(sum % uint64(p.dimension))
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	acc[bucket] += weight
+}
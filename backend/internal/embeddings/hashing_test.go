@@ -0,0 +1,48 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestHashingProviderIsDeterministicAndNormalised(t *testing.T) {
+	provider := NewHashingProvider(256)
+
+	first, err := provider.EmbedQuery(context.Background(), "Quarterly planning notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := provider.Embed(context.Background(), []string{"quarterly PLANNING notes"})
+
+	if len(first) != 256 {
+		t.Fatalf("expected 256 dimensions, got %d", len(first))
+	}
+	if math.Abs(cosine(first, first)-1) > 1e-5 {
+		t.Fatalf("expected unit vector, got norm² %f", cosine(first, first))
+	}
+	if math.Abs(cosine(first, second[0])-1) > 1e-5 {
+		t.Fatal("expected case-insensitive identical embeddings")
+	}
+}
+
+func TestHashingProviderRanksSharedVocabularyHigher(t *testing.T) {
+	provider := NewHashingProvider(HashingDefaultDimension)
+	vectors, _ := provider.Embed(context.Background(), []string{
+		"postgres full text search",
+		"searching postgres text",
+		"grocery list: apples and bread",
+	})
+
+	if cosine(vectors[0], vectors[1]) <= cosine(vectors[0], vectors[2]) {
+		t.Fatal("expected overlapping texts to be more similar than unrelated ones")
+	}
+}
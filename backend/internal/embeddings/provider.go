@@ -0,0 +1,29 @@
+package embeddings
+
+import (
+	"fmt"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+)
+
+// Embedding provider names accepted in embeddings.provider
+const (
+	ProviderRemote  = "remote"
+	ProviderCohere  = "cohere"
+	ProviderHashing = "hashing"
+)
+
+// NewProvider creates the in-process provider selected in config. It returns
+// nil for the remote provider, where the ai-service embeds chunks itself.
+func NewProvider(cfg config.EmbeddingsConfig, cohereCfg config.CohereConfig) (EmbeddingProvider, error) {
+	switch cfg.Provider {
+	case "", ProviderRemote:
+		return nil, nil
+	case ProviderCohere:
+		return NewCohereProvider(cohereCfg)
+	case ProviderHashing:
+		return NewHashingProvider(HashingDefaultDimension), nil
+	default:
+		return nil, fmt.Errorf("unknown embeddings provider %q", cfg.Provider)
+	}
+}
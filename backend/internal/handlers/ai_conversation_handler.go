@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type forkConversationRequest struct {
+	MessageID string `json:"message_id" binding:"required"`
+	Title     string `json:"title"`
+}
+
+type saveMessageAsNoteRequest struct {
+	FolderID *string `json:"folder_id"`
+	Title    string  `json:"title"`
+}
+
+type aiConversationSearchHitResponse struct {
+	Conversation aiConversationResponse `json:"conversation"`
+	MessageID    string                 `json:"message_id"`
+	Role         string                 `json:"role"`
+	Snippet      string                 `json:"snippet"`
+	Rank         float64                `json:"rank"`
+}
+
+// aiCitation is a note source an assistant answer relied on, as stored under
+// "citations" in message metadata
+type aiCitation struct {
+	NoteID     string  `json:"note_id"`
+	Title      string  `json:"title,omitempty"`
+	ChunkIndex int     `json:"chunk_index"`
+	Snippet    string  `json:"snippet"`
+	Score      float64 `json:"score"`
+}
+
+type aiConversationExportMessage struct {
+	aiConversationMessageResponse
+	Citations []aiCitation `json:"citations"`
+}
+
+// encodeConversationCursor encodes the position after a conversation
+func encodeConversationCursor(conversation dbmodels.AIConversation) string {
+	raw := conversation.LastMessageAt.UTC().Format(time.RFC3339Nano) + "|" + conversation.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeConversationCursor(cursor string) (*repository.AIConversationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, errors.New("malformed cursor")
+	}
+	lastMessageAt, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.AIConversationCursor{LastMessageAt: lastMessageAt, ID: id}, nil
+}
+
+// completionMetadata keeps the sources of a run.completed result as the
+// citations of the assistant message
+func completionMetadata(payload map[string]interface{}) map[string]interface{} {
+	result, _ := payload["result"].(map[string]interface{})
+	sources, _ := result["sources"].([]interface{})
+	if len(sources) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"citations": sources}
+}
+
+func messageCitations(message dbmodels.AIConversationMessage) []aiCitation {
+	citations := []aiCitation{}
+	if len(message.Metadata) == 0 {
+		return citations
+	}
+	var metadata struct {
+		Citations []aiCitation `json:"citations"`
+	}
+	if err := json.Unmarshal(message.Metadata, &metadata); err != nil || metadata.Citations == nil {
+		return citations
+	}
+	return metadata.Citations
+}
+
+// Get /api/v1/ai/conversations/search?q=&limit=
+// Full-text search over the messages of the user's conversations
+func (api *AIRunAPI) SearchConversations(c *gin.Context) {
+	if api.aiRuns == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "ai run service unavailable"})
+		return
+	}
+
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	user := userVal.(*dbmodels.User)
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	hits, err := api.aiRuns.SearchConversations(c.Request.Context(), user.ID, query, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search conversations"})
+		return
+	}
+
+	items := make([]aiConversationSearchHitResponse, 0, len(hits))
+	for _, hit := range hits {
+		items = append(items, aiConversationSearchHitResponse{
+			Conversation: conversationResponse(hit.Conversation),
+			MessageID:    hit.MessageID,
+			Role:         hit.Role,
+			Snippet:      hit.Snippet,
+			Rank:         hit.Rank,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// Post /api/v1/ai/conversations/:conversation_id/fork
+// Start a new branch holding the conversation up to and including message_id
+func (api *AIRunAPI) ForkConversation(c *gin.Context) {
+	if api.aiRuns == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "ai run service unavailable"})
+		return
+	}
+
+	var req forkConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	user := userVal.(*dbmodels.User)
+
+	conversation, messages, ok := api.loadConversation(c, user.ID)
+	if !ok {
+		return
+	}
+
+	cut := -1
+	for i, message := range messages {
+		if message.ID == req.MessageID {
+			cut = i
+			break
+		}
+	}
+	if cut < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = conversation.Title + " (branch)"
+	}
+	branch := &dbmodels.AIConversation{
+		UserID:              user.ID,
+		Title:               conversationTitleFromPrompt(title),
+		LastMessageAt:       messages[cut].CreatedAt,
+		ParentID:            &conversation.ID,
+		ForkedFromMessageID: &messages[cut].ID,
+	}
+	if err := api.aiRuns.ForkConversation(c.Request.Context(), branch, messages[:cut+1]); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fork conversation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, conversationResponse(*branch))
+}
+
+// Get /api/v1/ai/conversations/:conversation_id/export?format=markdown|json
+// Download a conversation with the citations of its answers
+func (api *AIRunAPI) ExportConversation(c *gin.Context) {
+	if api.aiRuns == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "ai run service unavailable"})
+		return
+	}
+
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	user := userVal.(*dbmodels.User)
+
+	format := c.DefaultQuery("format", "markdown")
+	if format != "markdown" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be markdown or json"})
+		return
+	}
+
+	conversation, messages, ok := api.loadConversation(c, user.ID)
+	if !ok {
+		return
+	}
+
+	if format == "json" {
+		items := make([]aiConversationExportMessage, 0, len(messages))
+		for _, message := range messages {
+			items = append(items, aiConversationExportMessage{
+				aiConversationMessageResponse: conversationMessageResponse(message),
+				Citations:                     messageCitations(message),
+			})
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="conversation-%s.json"`, conversation.ID))
+		c.JSON(http.StatusOK, gin.H{
+			"conversation": conversationResponse(*conversation),
+			"messages":     items,
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="conversation-%s.md"`, conversation.ID))
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(conversationMarkdown(conversation, messages)))
+}
+
+// Post /api/v1/ai/conversations/:conversation_id/messages/:message_id/note
+// Create a note from an answer, linking back to the notes it cited
+func (api *AIRunAPI) SaveMessageAsNote(c *gin.Context) {
+	if api.aiRuns == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "ai run service unavailable"})
+		return
+	}
+
+	var req saveMessageAsNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	user := userVal.(*dbmodels.User)
+
+	conversation, messages, ok := api.loadConversation(c, user.ID)
+	if !ok {
+		return
+	}
+
+	var message *dbmodels.AIConversationMessage
+	for i := range messages {
+		if messages[i].ID == c.Param("message_id") {
+			message = &messages[i]
+			break
+		}
+	}
+	if message == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+		return
+	}
+	if message.Role != "assistant" || strings.TrimSpace(message.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only assistant answers can be saved as notes"})
+		return
+	}
+	if !requireWorkspaceWrite(c) {
+		return
+	}
+
+	var folderID *string
+	if req.FolderID != nil && strings.TrimSpace(*req.FolderID) != "" {
+		trimmed := strings.TrimSpace(*req.FolderID)
+		if api.folderService != nil {
+			folder, err := api.folderService.GetFolderByID(c.Request.Context(), trimmed)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "folder not found"})
+				return
+			}
+			if folder.UserID != user.ID && !canWriteInWorkspace(c, folder.WorkspaceID) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+				return
+			}
+		}
+		folderID = &trimmed
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = answerNoteTitle(message.Content, conversation.Title)
+	}
+
+	// Only cite notes the user can still open, with their current titles
+	var citations []aiCitation
+	seen := make(map[string]bool)
+	for _, citation := range messageCitations(*message) {
+		if citation.NoteID == "" || seen[citation.NoteID] {
+			continue
+		}
+		seen[citation.NoteID] = true
+		note, err := api.noteService.GetNoteByID(c.Request.Context(), citation.NoteID)
+		if err != nil || note == nil || (note.UserID != user.ID && !inActiveWorkspace(c, note.WorkspaceID)) {
+			continue
+		}
+		citation.Title = note.Title
+		citations = append(citations, citation)
+	}
+
+	note, err := api.noteService.CreateNote(c.Request.Context(), service.CreateNoteRequest{
+		Title:       title,
+		Content:     answerNoteHTML(message.Content, citations),
+		ContentType: "html",
+		FolderID:    folderID,
+		UserID:      user.ID,
+		WorkspaceID: activeWorkspaceID(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create note"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+func (api *AIRunAPI) loadConversation(c *gin.Context, userID string) (*dbmodels.AIConversation, []dbmodels.AIConversationMessage, bool) {
+	conversation, err := api.aiRuns.GetConversation(c.Request.Context(), c.Param("conversation_id"), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "conversation not found"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get conversation"})
+		return nil, nil, false
+	}
+
+	messages, err := api.aiRuns.ListConversationMessages(c.Request.Context(), conversation.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list messages"})
+		return nil, nil, false
+	}
+	return conversation, messages, true
+}
+
+func citedNoteLink(noteID string) string {
+	return "/note/" + url.PathEscape(noteID)
+}
+
+// answerNoteTitle uses the first line of an answer, without Markdown heading
+// or emphasis marks, falling back to the conversation title
+func answerNoteTitle(content, fallback string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "#*_> ")
+		if line != "" {
+			return conversationTitleFromPrompt(line)
+		}
+	}
+	return conversationTitleFromPrompt(fallback)
+}
+
+// answerNoteHTML renders an answer as note HTML followed by a list of links
+// to the notes it cited
+func answerNoteHTML(content string, citations []aiCitation) string {
+	var b strings.Builder
+	b.WriteString(markdownToHTML(content))
+	if len(citations) == 0 {
+		return b.String()
+	}
+
+	b.WriteString("<h2>Sources</h2><ol>")
+	for _, citation := range citations {
+		label := citation.Title
+		if label == "" {
+			label = citation.NoteID
+		}
+		fmt.Fprintf(&b, `<li><a href="%s">%s</a>`, html.EscapeString(citedNoteLink(citation.NoteID)), html.EscapeString(label))
+		if snippet := strings.TrimSpace(citation.Snippet); snippet != "" {
+			fmt.Fprintf(&b, " — %s", html.EscapeString(strings.Join(strings.Fields(snippet), " ")))
+		}
+		b.WriteString("</li>")
+	}
+	b.WriteString("</ol>")
+	return b.String()
+}
+
+// conversationMarkdown renders a conversation as Markdown, listing the
+// citations of each answer below it
+func conversationMarkdown(conversation *dbmodels.AIConversation, messages []dbmodels.AIConversationMessage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", conversation.Title)
+	fmt.Fprintf(&b, "_Started %s_\n", conversation.CreatedAt.UTC().Format(time.RFC3339))
+
+	for _, message := range messages {
+		role := message.Role
+		if role != "" {
+			role = strings.ToUpper(role[:1]) + role[1:]
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", role, strings.TrimSpace(message.Content))
+
+		citations := messageCitations(message)
+		if len(citations) == 0 {
+			continue
+		}
+		b.WriteString("\n**Sources**\n\n")
+		for i, citation := range citations {
+			label := citation.Title
+			if label == "" {
+				label = citation.NoteID
+			}
+			fmt.Fprintf(&b, "%d. [%s](%s)", i+1, label, citedNoteLink(citation.NoteID))
+			if snippet := strings.TrimSpace(citation.Snippet); snippet != "" {
+				fmt.Fprintf(&b, " — %s", strings.Join(strings.Fields(snippet), " "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
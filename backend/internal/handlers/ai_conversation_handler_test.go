@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/stretchr/testify/require"
+)
+
+func seedConversation(t *testing.T, repo *aiRunHandlerTestRepo, id string, lastMessageAt time.Time, messages ...models.AIConversationMessage) {
+	t.Helper()
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.conversations[id] = &models.AIConversation{
+		BaseModel:     models.BaseModel{ID: id, CreatedAt: lastMessageAt},
+		UserID:        "user-1",
+		Title:         "Meeting notes",
+		LastMessageAt: lastMessageAt,
+	}
+	for _, message := range messages {
+		message.ConversationID = id
+		repo.messages[id] = append(repo.messages[id], message)
+	}
+}
+
+func TestAIConversationListPaginatesWithCursor(t *testing.T) {
+	t.Parallel()
+
+	router, repo := newAIRunHandlerTestRouter(t, func(w http.ResponseWriter, r *http.Request) {}, &models.Note{UserID: "user-1"})
+	now := time.Now().UTC()
+	seedConversation(t, repo, "conv-a", now.Add(-3*time.Minute))
+	seedConversation(t, repo, "conv-b", now.Add(-2*time.Minute))
+	seedConversation(t, repo, "conv-c", now.Add(-time.Minute))
+
+	var page struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+		NextCursor string `json:"next_cursor"`
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/ai/conversations?limit=2", nil))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &page))
+	require.Len(t, page.Items, 2)
+	require.Equal(t, "conv-c", page.Items[0].ID)
+	require.NotEmpty(t, page.NextCursor)
+
+	cursor := page.NextCursor
+	page.NextCursor = ""
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/ai/conversations?limit=2&cursor="+cursor, nil))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &page))
+	require.Len(t, page.Items, 1)
+	require.Equal(t, "conv-a", page.Items[0].ID)
+	require.Empty(t, page.NextCursor)
+}
+
+func TestAIConversationForkCopiesMessagesUpToTheChosenOne(t *testing.T) {
+	t.Parallel()
+
+	router, repo := newAIRunHandlerTestRouter(t, func(w http.ResponseWriter, r *http.Request) {}, &models.Note{UserID: "user-1"})
+	now := time.Now().UTC()
+	seedConversation(t, repo, "conv-1", now,
+		models.AIConversationMessage{BaseModel: models.BaseModel{ID: "m1", CreatedAt: now}, Role: "user", Content: "Summarize my meeting notes"},
+		models.AIConversationMessage{BaseModel: models.BaseModel{ID: "m2", CreatedAt: now}, Role: "assistant", Content: "Here is the summary"},
+		models.AIConversationMessage{BaseModel: models.BaseModel{ID: "m3", CreatedAt: now}, Role: "user", Content: "Shorter please"},
+	)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/ai/conversations/conv-1/fork", bytes.NewBufferString(`{"message_id":"m2"}`))
+	request.Header.Set("content-type", "application/json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	require.Equal(t, http.StatusCreated, response.Code, response.Body.String())
+	var branch aiConversationResponse
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &branch))
+	require.Equal(t, "Meeting notes (branch)", branch.Title)
+	require.NotNil(t, branch.ParentID)
+	require.Equal(t, "conv-1", *branch.ParentID)
+
+	messages, err := repo.ListConversationMessages(request.Context(), branch.ID)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	require.Equal(t, "Here is the summary", messages[1].Content)
+
+	missing := httptest.NewRequest(http.MethodPost, "/api/v1/ai/conversations/conv-1/fork", bytes.NewBufferString(`{"message_id":"nope"}`))
+	missing.Header.Set("content-type", "application/json")
+	missingResponse := httptest.NewRecorder()
+	router.ServeHTTP(missingResponse, missing)
+	require.Equal(t, http.StatusNotFound, missingResponse.Code)
+}
+
+func TestAIConversationExportIncludesCitations(t *testing.T) {
+	t.Parallel()
+
+	router, repo := newAIRunHandlerTestRouter(t, func(w http.ResponseWriter, r *http.Request) {}, &models.Note{UserID: "user-1"})
+	now := time.Now().UTC()
+	seedConversation(t, repo, "conv-1", now,
+		models.AIConversationMessage{BaseModel: models.BaseModel{ID: "m1", CreatedAt: now}, Role: "user", Content: "What did we decide?"},
+		models.AIConversationMessage{
+			BaseModel: models.BaseModel{ID: "m2", CreatedAt: now},
+			Role:      "assistant",
+			Content:   "Ship on Friday.",
+			Metadata:  []byte(`{"citations":[{"note_id":"note-9","chunk_index":0,"snippet":"ship friday","score":0.8}]}`),
+		},
+	)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/ai/conversations/conv-1/export", nil))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	body := response.Body.String()
+	require.Contains(t, body, "# Meeting notes")
+	require.Contains(t, body, "## Assistant\n\nShip on Friday.")
+	require.Contains(t, body, "1. [note-9](/note/note-9) — ship friday")
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/ai/conversations/conv-1/export?format=json", nil))
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	require.Contains(t, response.Body.String(), `"citations":[{"note_id":"note-9"`)
+}
+
+func TestAIConversationSaveMessageAsNoteLinksCitations(t *testing.T) {
+	t.Parallel()
+
+	router, repo := newAIRunHandlerTestRouter(t, func(w http.ResponseWriter, r *http.Request) {}, &models.Note{UserID: "user-1", Title: "Sprint plan"})
+	now := time.Now().UTC()
+	seedConversation(t, repo, "conv-1", now,
+		models.AIConversationMessage{BaseModel: models.BaseModel{ID: "m1", CreatedAt: now}, Role: "user", Content: "What did we decide?"},
+		models.AIConversationMessage{
+			BaseModel: models.BaseModel{ID: "m2", CreatedAt: now},
+			Role:      "assistant",
+			Content:   "## Release\n\nShip on **Friday**.",
+			Metadata:  []byte(`{"citations":[{"note_id":"note-9","chunk_index":0,"snippet":"ship friday","score":0.8},{"note_id":"note-9","chunk_index":1,"snippet":"again","score":0.5}]}`),
+		},
+	)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/ai/conversations/conv-1/messages/m2/note", bytes.NewBufferString(`{}`))
+	request.Header.Set("content-type", "application/json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	require.Equal(t, http.StatusCreated, response.Code, response.Body.String())
+	var note models.Note
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &note))
+	require.Equal(t, "Release", note.Title)
+	require.Equal(t, "html", note.ContentType)
+	require.Contains(t, note.Content, "<strong>Friday</strong>")
+	require.Contains(t, note.Content, `<h2>Sources</h2><ol><li><a href="/note/note-9">Sprint plan</a> — ship friday</li></ol>`)
+
+	question := httptest.NewRequest(http.MethodPost, "/api/v1/ai/conversations/conv-1/messages/m1/note", bytes.NewBufferString(`{}`))
+	question.Header.Set("content-type", "application/json")
+	questionResponse := httptest.NewRecorder()
+	router.ServeHTTP(questionResponse, question)
+	require.Equal(t, http.StatusBadRequest, questionResponse.Code)
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,6 +19,10 @@ type AIInternalAPI struct {
 	noteService   service.NoteService
 	folderService service.FolderService
 	noteChunkRepo repository.NoteChunkRepository
+	searchService service.HybridSearchService
+	proposals     service.AIEditProposalService
+	shareService  service.NoteShareService
+	workspaces    service.WorkspaceService
 	config        *config.Config
 }
 
@@ -24,12 +32,20 @@ func NewAIInternalAPI(
 	noteService service.NoteService,
 	folderService service.FolderService,
 	noteChunkRepo repository.NoteChunkRepository,
+	searchService service.HybridSearchService,
+	proposals service.AIEditProposalService,
+	shareService service.NoteShareService,
+	workspaces service.WorkspaceService,
 	cfg *config.Config,
 ) *AIInternalAPI {
 	return &AIInternalAPI{
 		noteService:   noteService,
 		folderService: folderService,
 		noteChunkRepo: noteChunkRepo,
+		searchService: searchService,
+		proposals:     proposals,
+		shareService:  shareService,
+		workspaces:    workspaces,
 		config:        cfg,
 	}
 }
@@ -40,6 +56,20 @@ type aiActor struct {
 	WorkspaceID string `json:"workspace_id"`
 }
 
+// actorWorkspaceID returns the run's workspace when the actor is a member of
+// it, so list tools cover every member's notes and folders. Otherwise they
+// only list the actor's own.
+func (api *AIInternalAPI) actorWorkspaceID(c *gin.Context, actor aiActor) *string {
+	if api.workspaces == nil || actor.WorkspaceID == "" {
+		return nil
+	}
+	role, err := api.workspaces.MemberRole(c.Request.Context(), actor.WorkspaceID, actor.UserID)
+	if err != nil || role == "" {
+		return nil
+	}
+	return &actor.WorkspaceID
+}
+
 type aiToolExecuteRequest struct {
 	RunID      string                 `json:"run_id" binding:"required"`
 	ToolCallID string                 `json:"tool_call_id" binding:"required"`
@@ -116,16 +146,10 @@ func (api *AIInternalAPI) ExecuteTool(c *gin.Context) {
 	}
 }
 
+// executeRAGSearch runs rag.search. mode=vector (default) returns the closest
+// chunks for input.embedding; mode=hybrid ranks notes for input.query the same
+// way /api/v1/notes/search does, using input.embedding for the vector side when given.
 func (api *AIInternalAPI) executeRAGSearch(c *gin.Context, req aiToolExecuteRequest) {
-	if api.noteChunkRepo == nil {
-		c.JSON(http.StatusServiceUnavailable, aiToolResponse{
-			OK:         false,
-			ToolCallID: req.ToolCallID,
-			Error:      &aiToolError{Code: "INTERNAL", Message: "chunk repository unavailable", Retryable: true},
-		})
-		return
-	}
-
 	userID := req.Actor.UserID
 	if userID == "" {
 		c.JSON(http.StatusBadRequest, aiToolResponse{
@@ -136,28 +160,34 @@ func (api *AIInternalAPI) executeRAGSearch(c *gin.Context, req aiToolExecuteRequ
 		return
 	}
 
-	rawEmbedding, ok := req.Input["embedding"].([]interface{})
-	if !ok || len(rawEmbedding) == 0 {
+	mode, _ := req.Input["mode"].(string)
+	if mode == "" {
+		mode = "vector"
+	}
+	if mode != "vector" && mode != "hybrid" {
 		c.JSON(http.StatusBadRequest, aiToolResponse{
 			OK:         false,
 			ToolCallID: req.ToolCallID,
-			Error:      &aiToolError{Code: "INVALID_INPUT", Message: "embedding is required", Retryable: false},
+			Error:      &aiToolError{Code: "INVALID_INPUT", Message: "mode must be vector or hybrid", Retryable: false},
 		})
 		return
 	}
 
-	embedding := make([]float64, 0, len(rawEmbedding))
-	for _, item := range rawEmbedding {
-		value, ok := item.(float64)
-		if !ok {
-			c.JSON(http.StatusBadRequest, aiToolResponse{
-				OK:         false,
-				ToolCallID: req.ToolCallID,
-				Error:      &aiToolError{Code: "INVALID_INPUT", Message: "embedding values must be numeric", Retryable: false},
-			})
-			return
+	var embedding []float64
+	if rawEmbedding, ok := req.Input["embedding"].([]interface{}); ok {
+		embedding = make([]float64, 0, len(rawEmbedding))
+		for _, item := range rawEmbedding {
+			value, ok := item.(float64)
+			if !ok {
+				c.JSON(http.StatusBadRequest, aiToolResponse{
+					OK:         false,
+					ToolCallID: req.ToolCallID,
+					Error:      &aiToolError{Code: "INVALID_INPUT", Message: "embedding values must be numeric", Retryable: false},
+				})
+				return
+			}
+			embedding = append(embedding, value)
 		}
-		embedding = append(embedding, value)
 	}
 
 	topK := 5
@@ -174,7 +204,36 @@ func (api *AIInternalAPI) executeRAGSearch(c *gin.Context, req aiToolExecuteRequ
 		}
 	}
 
-	results, err := api.noteChunkRepo.SearchSimilarByUser(c.Request.Context(), userID, embedding, topK, minScore)
+	if mode == "hybrid" {
+		api.executeHybridRAGSearch(c, req, embedding, topK, minScore)
+		return
+	}
+
+	if api.noteChunkRepo == nil {
+		c.JSON(http.StatusServiceUnavailable, aiToolResponse{
+			OK:         false,
+			ToolCallID: req.ToolCallID,
+			Error:      &aiToolError{Code: "INTERNAL", Message: "chunk repository unavailable", Retryable: true},
+		})
+		return
+	}
+
+	if len(embedding) == 0 {
+		c.JSON(http.StatusBadRequest, aiToolResponse{
+			OK:         false,
+			ToolCallID: req.ToolCallID,
+			Error:      &aiToolError{Code: "INVALID_INPUT", Message: "embedding is required", Retryable: false},
+		})
+		return
+	}
+
+	results, err := api.noteChunkRepo.SearchSimilarFiltered(c.Request.Context(), repository.NoteChunkSearchParams{
+		UserID:         userID,
+		WorkspaceID:    api.actorWorkspaceID(c, req.Actor),
+		QueryEmbedding: embedding,
+		TopK:           topK,
+		MinScore:       minScore,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, aiToolResponse{
 			OK:         false,
@@ -198,12 +257,89 @@ func (api *AIInternalAPI) executeRAGSearch(c *gin.Context, req aiToolExecuteRequ
 		OK:         true,
 		ToolCallID: req.ToolCallID,
 		Output: gin.H{
+			"mode":   mode,
 			"chunks": outputChunks,
 			"total":  len(outputChunks),
 		},
 	})
 }
 
+// executeHybridRAGSearch returns one entry per note, shaped like vector chunks
+// so existing callers can read either mode. minScore applies to the vector
+// similarity of notes without a full-text match.
+func (api *AIInternalAPI) executeHybridRAGSearch(c *gin.Context, req aiToolExecuteRequest, embedding []float64, topK int, minScore float64) {
+	if api.searchService == nil {
+		c.JSON(http.StatusServiceUnavailable, aiToolResponse{
+			OK:         false,
+			ToolCallID: req.ToolCallID,
+			Error:      &aiToolError{Code: "INTERNAL", Message: "search service unavailable", Retryable: true},
+		})
+		return
+	}
+
+	query, _ := req.Input["query"].(string)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		c.JSON(http.StatusBadRequest, aiToolResponse{
+			OK:         false,
+			ToolCallID: req.ToolCallID,
+			Error:      &aiToolError{Code: "INVALID_INPUT", Message: "query is required for hybrid mode", Retryable: false},
+		})
+		return
+	}
+
+	searchReq := service.HybridSearchRequest{
+		NoteSearchRequest: service.NoteSearchRequest{
+			UserID:      req.Actor.UserID,
+			WorkspaceID: api.actorWorkspaceID(c, req.Actor),
+			Query:       query,
+			Limit:       topK,
+		},
+		QueryEmbedding: embedding,
+		MinVectorScore: minScore,
+	}
+	if folderID, ok := req.Input["folder_id"].(string); ok && folderID != "" {
+		searchReq.FolderID = &folderID
+	}
+
+	results, total, err := api.searchService.HybridSearch(c.Request.Context(), searchReq)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, aiToolResponse{
+			OK:         false,
+			ToolCallID: req.ToolCallID,
+			Error:      &aiToolError{Code: "INTERNAL", Message: err.Error(), Retryable: false},
+		})
+		return
+	}
+
+	outputChunks := make([]gin.H, 0, len(results))
+	for _, item := range results {
+		text := item.ChunkText
+		if text == "" {
+			text = item.Snippet
+		}
+		outputChunks = append(outputChunks, gin.H{
+			"note_id":       item.Note.ID,
+			"title":         item.Note.Title,
+			"chunk_index":   item.ChunkIndex,
+			"text":          text,
+			"score":         item.Score,
+			"lexical_score": item.LexicalScore,
+			"vector_score":  item.VectorScore,
+		})
+	}
+
+	c.JSON(http.StatusOK, aiToolResponse{
+		OK:         true,
+		ToolCallID: req.ToolCallID,
+		Output: gin.H{
+			"mode":   "hybrid",
+			"chunks": outputChunks,
+			"total":  total,
+		},
+	})
+}
+
 func (api *AIInternalAPI) executeNotesRead(c *gin.Context, req aiToolExecuteRequest) {
 	noteID, _ := req.Input["note_id"].(string)
 	if noteID == "" {
@@ -225,7 +361,7 @@ func (api *AIInternalAPI) executeNotesRead(c *gin.Context, req aiToolExecuteRequ
 		return
 	}
 
-	if note.UserID != req.Actor.UserID {
+	if !api.noteRole(c, note, req.Actor.UserID).CanView() {
 		c.JSON(http.StatusForbidden, aiToolResponse{
 			OK:         false,
 			ToolCallID: req.ToolCallID,
@@ -240,6 +376,7 @@ func (api *AIInternalAPI) executeNotesRead(c *gin.Context, req aiToolExecuteRequ
 		Output: gin.H{
 			"note_id":    note.ID,
 			"content":    note.Content,
+			"blocks":     utils.SplitNoteBlocks(noteHTML(note)),
 			"version":    note.Version,
 			"updated_at": note.UpdatedAt,
 		},
@@ -291,7 +428,7 @@ func (api *AIInternalAPI) executeNotesWrite(c *gin.Context, req aiToolExecuteReq
 		return
 	}
 
-	if note.UserID != req.Actor.UserID {
+	if !api.noteRole(c, note, req.Actor.UserID).CanEdit() {
 		c.JSON(http.StatusForbidden, aiToolResponse{
 			OK:         false,
 			ToolCallID: req.ToolCallID,
@@ -308,12 +445,40 @@ func (api *AIInternalAPI) executeNotesWrite(c *gin.Context, req aiToolExecuteReq
 	case "append":
 		newContent = note.Content + htmlContent
 	case "patch":
-		c.JSON(http.StatusBadRequest, aiToolResponse{
-			OK:         false,
-			ToolCallID: req.ToolCallID,
-			Error:      &aiToolError{Code: "INVALID_INPUT", Message: "patch operation is not supported in MVP", Retryable: false},
-		})
-		return
+		// Anchors refer to the content the agent read, so a stale version
+		// must fail before the patch is resolved against newer content
+		if note.Version != expectedVersion {
+			c.JSON(http.StatusConflict, aiToolResponse{
+				OK:         false,
+				ToolCallID: req.ToolCallID,
+				Error:      &aiToolError{Code: "VERSION_CONFLICT", Message: "version conflict", Retryable: true},
+			})
+			return
+		}
+
+		ops, err := parseNotePatchOps(req.Input["patch"])
+		if err != nil {
+			c.JSON(http.StatusBadRequest, aiToolResponse{
+				OK:         false,
+				ToolCallID: req.ToolCallID,
+				Error:      &aiToolError{Code: "INVALID_INPUT", Message: err.Error(), Retryable: false},
+			})
+			return
+		}
+
+		newContent, err = utils.ApplyNotePatch(noteHTML(note), ops)
+		if err != nil {
+			code := "INVALID_INPUT"
+			if errors.Is(err, utils.ErrNotePatchAnchorNotFound) || errors.Is(err, utils.ErrNotePatchAnchorAmbiguous) {
+				code = "ANCHOR_NOT_RESOLVED"
+			}
+			c.JSON(http.StatusUnprocessableEntity, aiToolResponse{
+				OK:         false,
+				ToolCallID: req.ToolCallID,
+				Error:      &aiToolError{Code: code, Message: err.Error(), Retryable: false},
+			})
+			return
+		}
 	default:
 		c.JSON(http.StatusBadRequest, aiToolResponse{
 			OK:         false,
@@ -323,7 +488,19 @@ func (api *AIInternalAPI) executeNotesWrite(c *gin.Context, req aiToolExecuteReq
 		return
 	}
 
-	updated, err := api.noteService.UpdateNoteContentWithVersion(c.Request.Context(), noteID, newContent, expectedVersion)
+	// propose=true stores the edit for the user to review instead of writing it
+	if propose, _ := req.Input["propose"].(bool); propose {
+		api.proposeNotesWrite(c, req, note, newContent, expectedVersion)
+		return
+	}
+
+	ctx := service.WithRevisionActor(c.Request.Context(), service.RevisionActor{
+		Source:     dbmodels.NoteRevisionSourceAI,
+		AuthorID:   req.Actor.UserID,
+		RunID:      req.RunID,
+		ToolCallID: req.ToolCallID,
+	})
+	updated, err := api.noteService.UpdateNoteContentWithVersion(ctx, noteID, newContent, expectedVersion)
 	if err != nil {
 		if errors.Is(err, service.ErrVersionConflict) {
 			c.JSON(http.StatusConflict, aiToolResponse{
@@ -350,16 +527,167 @@ func (api *AIInternalAPI) executeNotesWrite(c *gin.Context, req aiToolExecuteReq
 		return
 	}
 
+	lines := utils.DiffLines(utils.SplitContentLines(noteHTML(note)), utils.SplitContentLines(updated.Content))
+	changes := make([]utils.DiffLine, 0)
+	added, removed := 0, 0
+	for _, line := range lines {
+		switch line.Op {
+		case utils.DiffOpInsert:
+			added++
+		case utils.DiffOpDelete:
+			removed++
+		default:
+			continue
+		}
+		changes = append(changes, line)
+	}
+
 	c.JSON(http.StatusOK, aiToolResponse{
 		OK:         true,
 		ToolCallID: req.ToolCallID,
 		Output: gin.H{
 			"note_id":     updated.ID,
 			"new_version": updated.Version,
+			"diff": gin.H{
+				"lines":   changes,
+				"added":   added,
+				"removed": removed,
+			},
+		},
+	})
+}
+
+func (api *AIInternalAPI) proposeNotesWrite(c *gin.Context, req aiToolExecuteRequest, note *dbmodels.Note, newContent string, expectedVersion int) {
+	if api.proposals == nil {
+		c.JSON(http.StatusServiceUnavailable, aiToolResponse{
+			OK:         false,
+			ToolCallID: req.ToolCallID,
+			Error:      &aiToolError{Code: "INTERNAL", Message: "edit proposals unavailable", Retryable: true},
+		})
+		return
+	}
+	if note.Version != expectedVersion {
+		c.JSON(http.StatusConflict, aiToolResponse{
+			OK:         false,
+			ToolCallID: req.ToolCallID,
+			Error:      &aiToolError{Code: "VERSION_CONFLICT", Message: "version conflict", Retryable: true},
+		})
+		return
+	}
+
+	summary, _ := req.Input["summary"].(string)
+	proposal, err := api.proposals.CreateProposal(c.Request.Context(), service.CreateEditProposalRequest{
+		NoteID:          note.ID,
+		UserID:          req.Actor.UserID,
+		RunID:           req.RunID,
+		ToolCallID:      req.ToolCallID,
+		ExpectedVersion: expectedVersion,
+		Original:        note.Content,
+		Proposed:        newContent,
+		Summary:         summary,
+	})
+	if err != nil {
+		status, code := http.StatusInternalServerError, "INTERNAL"
+		if errors.Is(err, service.ErrValidationFailed) {
+			status, code = http.StatusBadRequest, "INVALID_INPUT"
+		}
+		c.JSON(status, aiToolResponse{
+			OK:         false,
+			ToolCallID: req.ToolCallID,
+			Error:      &aiToolError{Code: code, Message: err.Error(), Retryable: false},
+		})
+		return
+	}
+
+	diff, err := api.proposals.DiffProposal(c.Request.Context(), proposal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, aiToolResponse{
+			OK:         false,
+			ToolCallID: req.ToolCallID,
+			Error:      &aiToolError{Code: "INTERNAL", Message: err.Error(), Retryable: false},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, aiToolResponse{
+		OK:         true,
+		ToolCallID: req.ToolCallID,
+		Output: gin.H{
+			"note_id":     note.ID,
+			"proposal_id": proposal.ID,
+			"status":      proposal.Status,
+			"diff": gin.H{
+				"hunks":   diff.Hunks,
+				"added":   diff.Added,
+				"removed": diff.Removed,
+			},
 		},
 	})
 }
 
+// parseNotePatchOps decodes input.patch. Op content is markdown, like content
+// for replace and append, and is converted to HTML here.
+func parseNotePatchOps(raw interface{}) ([]utils.NotePatchOp, error) {
+	if raw == nil {
+		return nil, errors.New("patch is required for patch operation")
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, errors.New("patch must be an array of operations")
+	}
+	var ops []utils.NotePatchOp
+	if err := json.Unmarshal(encoded, &ops); err != nil {
+		return nil, errors.New("patch must be an array of operations")
+	}
+	for i := range ops {
+		ops[i].Content = markdownToHTML(ops[i].Content)
+	}
+	return ops, nil
+}
+
+// noteHTML is the note's HTML, rendered from its Tiptap JSON when only that
+// has been saved
+func noteHTML(note *dbmodels.Note) string {
+	if strings.TrimSpace(note.Content) != "" || note.TiptapContent == "" {
+		return note.Content
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(note.TiptapContent), &doc); err != nil {
+		return note.Content
+	}
+	return utils.TiptapJSONToHTML(doc)
+}
+
+// noteRole resolves the actor's role on a note the same way the REST API
+// does, so tools never reach past what the user could open themselves
+func (api *AIInternalAPI) noteRole(c *gin.Context, note *dbmodels.Note, userID string) dbmodels.NoteRole {
+	if userID != "" && note.UserID == userID {
+		return dbmodels.NoteRoleOwner
+	}
+	if api.shareService == nil {
+		return ""
+	}
+	role, err := api.shareService.NoteRole(c.Request.Context(), note, userID)
+	if err != nil {
+		return ""
+	}
+	return role
+}
+
+func (api *AIInternalAPI) folderRole(c *gin.Context, folder *dbmodels.Folder, userID string) dbmodels.NoteRole {
+	if userID != "" && folder.UserID == userID {
+		return dbmodels.NoteRoleOwner
+	}
+	if api.shareService == nil {
+		return ""
+	}
+	role, err := api.shareService.FolderRole(c.Request.Context(), folder, userID)
+	if err != nil {
+		return ""
+	}
+	return role
+}
+
 func (api *AIInternalAPI) isAuthorized(c *gin.Context) bool {
 	if api.config == nil {
 		return false
@@ -398,9 +726,10 @@ func (api *AIInternalAPI) executeNotesList(c *gin.Context, req aiToolExecuteRequ
 	}
 
 	params := repository.NoteListParams{
-		Limit:    200,
-		FolderID: folderIDPtr,
-		Query:    queryPtr,
+		Limit:       200,
+		FolderID:    folderIDPtr,
+		Query:       queryPtr,
+		WorkspaceID: api.actorWorkspaceID(c, req.Actor),
 	}
 
 	notes, _, err := api.noteService.GetNotesByUserID(c.Request.Context(), userID, params)
@@ -444,7 +773,8 @@ func (api *AIInternalAPI) executeFoldersList(c *gin.Context, req aiToolExecuteRe
 	}
 
 	params := repository.FolderListParams{
-		Limit: 200,
+		Limit:       200,
+		WorkspaceID: api.actorWorkspaceID(c, req.Actor),
 	}
 
 	folders, _, err := api.folderService.GetFoldersByUserID(c.Request.Context(), userID, params)
@@ -496,7 +826,7 @@ func (api *AIInternalAPI) executeFoldersRead(c *gin.Context, req aiToolExecuteRe
 		return
 	}
 
-	if folder.UserID != req.Actor.UserID {
+	if !api.folderRole(c, folder, req.Actor.UserID).CanView() {
 		c.JSON(http.StatusForbidden, aiToolResponse{
 			OK:         false,
 			ToolCallID: req.ToolCallID,
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// defaultConsentTimeout applies when ai.consent_timeout_seconds is unset
+const defaultConsentTimeout = 5 * time.Minute
+
+// consentTimeout is how long a tool call waits for the user's decision
+func (api *AIRunAPI) consentTimeout() time.Duration {
+	if api.config != nil && api.config.AI.ConsentTimeoutSeconds > 0 {
+		return time.Duration(api.config.AI.ConsentTimeoutSeconds) * time.Second
+	}
+	return defaultConsentTimeout
+}
+
+type pendingConsentResponse struct {
+	RunID      string         `json:"run_id"`
+	ToolCallID string         `json:"tool_call_id"`
+	Tool       string         `json:"tool"`
+	Summary    string         `json:"summary"`
+	Args       datatypes.JSON `json:"args,omitempty"`
+	ExpiresAt  *time.Time     `json:"expires_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Get /api/v1/ai/consents/pending
+// List the tool calls waiting for the user's consent across all of their runs
+func (api *AIRunAPI) ListPendingConsents(c *gin.Context) {
+	if api.aiRuns == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "ai run service unavailable"})
+		return
+	}
+
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	user := userVal.(*dbmodels.User)
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	calls, err := api.aiRuns.ListPendingToolCalls(c.Request.Context(), user.ID, time.Now().UTC(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list pending consents"})
+		return
+	}
+
+	items := make([]pendingConsentResponse, 0, len(calls))
+	for _, call := range calls {
+		items = append(items, pendingConsentResponse{
+			RunID:      call.RunID,
+			ToolCallID: call.ToolCallID,
+			Tool:       call.Tool,
+			Summary:    call.Summary,
+			Args:       call.Args,
+			ExpiresAt:  call.ExpiresAt,
+			CreatedAt:  call.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"consents": items})
+}
+
+// ExpireStaleConsents expires tool calls whose consent window has passed. Runs
+// still waiting on one of them are marked expired, listeners get a
+// run.consent_expired event and the ai-service is told the call was denied.
+func (api *AIRunAPI) ExpireStaleConsents(ctx context.Context) (int, error) {
+	if api.aiRuns == nil {
+		return 0, nil
+	}
+
+	expired, err := api.aiRuns.ExpirePendingToolCalls(ctx, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, call := range expired {
+		if run, err := api.aiRuns.GetRun(ctx, call.RunID); err == nil && run.Status == dbmodels.AIRunStatusAwaitingConsent {
+			_ = api.aiRuns.UpdateRunStatus(ctx, call.RunID, dbmodels.AIRunStatusExpired)
+		}
+		api.publishConsentExpired(ctx, call)
+		api.notifyConsentExpired(ctx, call)
+	}
+	return len(expired), nil
+}
+
+// publishConsentExpired stores a run.consent_expired event and pushes it to
+// any client attached to the run on this server
+func (api *AIRunAPI) publishConsentExpired(ctx context.Context, call dbmodels.AIToolCall) {
+	eventID := uuid.NewString()
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":         "run.consent_expired",
+		"run_id":       call.RunID,
+		"event_id":     eventID,
+		"tool_call_id": call.ToolCallID,
+		"tool":         call.Tool,
+	})
+	if err != nil {
+		return
+	}
+
+	if err := api.aiRuns.AppendEvent(ctx, &dbmodels.AIRunEvent{
+		RunID:     call.RunID,
+		EventID:   eventID,
+		EventType: "run.consent_expired",
+		Payload:   datatypes.JSON(payload),
+	}); err != nil {
+		eventID = ""
+	}
+
+	var raw bytes.Buffer
+	if eventID != "" {
+		fmt.Fprintf(&raw, "id: %s\n", eventID)
+	}
+	fmt.Fprintf(&raw, "event: run.consent_expired\ndata: %s\n\n", payload)
+	api.streams.publish(call.RunID, aiRunFrame{ID: eventID, Raw: raw.Bytes()})
+}
+
+// notifyConsentExpired denies the call on the ai-service so a run still
+// waiting there stops waiting. The run may already be gone, so errors are only logged.
+func (api *AIRunAPI) notifyConsentExpired(ctx context.Context, call dbmodels.AIToolCall) {
+	if api.config == nil || api.config.AI.ServiceURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"run_id":       call.RunID,
+		"tool_call_id": call.ToolCallID,
+		"approved":     false,
+	})
+	if err != nil {
+		return
+	}
+
+	aiURL := strings.TrimRight(api.config.AI.ServiceURL, "/") + "/internal/v1/agent/runs/" + call.RunID + "/consent"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPatch, aiURL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", api.config.AI.ServiceToken))
+
+	resp, err := api.httpClient.Do(httpReq)
+	if err != nil {
+		log.Printf("Warning: failed to notify ai-service of expired consent %s/%s: %v", call.RunID, call.ToolCallID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
+		log.Printf("Warning: ai-service rejected expired consent %s/%s: status %d", call.RunID, call.ToolCallID, resp.StatusCode)
+	}
+}
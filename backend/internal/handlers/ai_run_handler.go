@@ -3,11 +3,15 @@ package handlers
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,16 +31,24 @@ type AIRunAPI struct {
 	noteService      service.NoteService
 	folderService    service.FolderService
 	aiRuns           repository.AIRunRepository
+	proposals        service.AIEditProposalService
+	usage            service.AIUsageService
 	httpClient       *http.Client
 	streamHTTPClient *http.Client
+	streams          *aiRunStreamHub
 }
 
+// aiRunMaxDuration bounds how long a run keeps streaming after its client is gone
+const aiRunMaxDuration = 15 * time.Minute
+
 type aiConversationResponse struct {
-	ID            string    `json:"id"`
-	Title         string    `json:"title"`
-	LastMessageAt time.Time `json:"last_message_at"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID                  string    `json:"id"`
+	Title               string    `json:"title"`
+	ParentID            *string   `json:"parent_id,omitempty"`
+	ForkedFromMessageID *string   `json:"forked_from_message_id,omitempty"`
+	LastMessageAt       time.Time `json:"last_message_at"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
 }
 
 type aiConversationMessageResponse struct {
@@ -58,15 +70,18 @@ type aiRunHistory struct {
 
 var _ interfaces.AIRunAPIHandler = (*AIRunAPI)(nil)
 
-func NewAIRunAPI(cfg *config.Config, noteService service.NoteService, folderService service.FolderService, aiRuns repository.AIRunRepository) *AIRunAPI {
+func NewAIRunAPI(cfg *config.Config, noteService service.NoteService, folderService service.FolderService, aiRuns repository.AIRunRepository, proposals service.AIEditProposalService, usage service.AIUsageService) *AIRunAPI {
 	timeout := time.Duration(cfg.AI.RequestTimeoutMs) * time.Millisecond
 	return &AIRunAPI{
 		config:           cfg,
 		noteService:      noteService,
 		folderService:    folderService,
 		aiRuns:           aiRuns,
+		proposals:        proposals,
+		usage:            usage,
 		httpClient:       &http.Client{Timeout: timeout},
 		streamHTTPClient: &http.Client{},
+		streams:          newAIRunStreamHub(),
 	}
 }
 
@@ -85,11 +100,13 @@ func conversationTitleFromPrompt(prompt string) string {
 
 func conversationResponse(conversation dbmodels.AIConversation) aiConversationResponse {
 	return aiConversationResponse{
-		ID:            conversation.ID,
-		Title:         conversation.Title,
-		LastMessageAt: conversation.LastMessageAt,
-		CreatedAt:     conversation.CreatedAt,
-		UpdatedAt:     conversation.UpdatedAt,
+		ID:                  conversation.ID,
+		Title:               conversation.Title,
+		ParentID:            conversation.ParentID,
+		ForkedFromMessageID: conversation.ForkedFromMessageID,
+		LastMessageAt:       conversation.LastMessageAt,
+		CreatedAt:           conversation.CreatedAt,
+		UpdatedAt:           conversation.UpdatedAt,
 	}
 }
 
@@ -134,7 +151,7 @@ func (api *AIRunAPI) ensureConversation(
 }
 
 func (api *AIRunAPI) appendConversationMessage(
-	c *gin.Context,
+	ctx context.Context,
 	conversationID string,
 	runID string,
 	role string,
@@ -154,7 +171,7 @@ func (api *AIRunAPI) appendConversationMessage(
 		rawMetadata = datatypes.JSON(encoded)
 	}
 
-	return api.aiRuns.AppendConversationMessage(c.Request.Context(), &dbmodels.AIConversationMessage{
+	return api.aiRuns.AppendConversationMessage(ctx, &dbmodels.AIConversationMessage{
 		ConversationID: conversationID,
 		RunID:          runID,
 		Role:           role,
@@ -163,6 +180,26 @@ func (api *AIRunAPI) appendConversationMessage(
 	})
 }
 
+// resolveRunWorkspace picks the workspace a run acts in: the one resolved by
+// authMiddleware. A workspace_id sent by the client must name that same
+// workspace; without an active workspace it is used as is.
+func resolveRunWorkspace(c *gin.Context, requested string) (string, bool) {
+	requested = strings.TrimSpace(requested)
+	active := activeWorkspaceID(c)
+	if active == nil {
+		if requested == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+			return "", false
+		}
+		return requested, true
+	}
+	if requested != "" && requested != *active {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id does not match the active workspace"})
+		return "", false
+	}
+	return *active, true
+}
+
 func (api *AIRunAPI) persistRun(
 	c *gin.Context,
 	user *dbmodels.User,
@@ -171,6 +208,7 @@ func (api *AIRunAPI) persistRun(
 	noteID string,
 	sessionID string,
 	conversationID string,
+	mode dbmodels.AIRunMode,
 ) error {
 	if api.aiRuns == nil {
 		return nil
@@ -187,11 +225,12 @@ func (api *AIRunAPI) persistRun(
 		SessionID:      sessionID,
 		ConversationID: convID,
 		Status:         dbmodels.AIRunStatusRunning,
+		Mode:           mode,
 	})
 }
 
 type createRunRequest struct {
-	WorkspaceID        string `json:"workspace_id" binding:"required"`
+	WorkspaceID        string `json:"workspace_id"`
 	SessionID          string `json:"session_id" binding:"required"`
 	NoteID             string `json:"note_id"`
 	FolderID           string `json:"folder_id"`
@@ -217,7 +256,7 @@ type consentRequest struct {
 }
 
 type inlineEditRequest struct {
-	WorkspaceID     string                   `json:"workspace_id" binding:"required"`
+	WorkspaceID     string                   `json:"workspace_id"`
 	ExpectedVersion int                      `json:"expected_version"`
 	NoteID          string                   `json:"note_id"`
 	Action          string                   `json:"action" binding:"required"`
@@ -242,6 +281,12 @@ func (api *AIRunAPI) CreateRun(c *gin.Context) {
 	}
 	user := userVal.(*dbmodels.User)
 
+	workspaceID, ok := resolveRunWorkspace(c, req.WorkspaceID)
+	if !ok {
+		return
+	}
+	req.WorkspaceID = workspaceID
+
 	trimmedNoteID := strings.TrimSpace(req.NoteID)
 	trimmedFolderID := strings.TrimSpace(req.FolderID)
 
@@ -284,7 +329,7 @@ func (api *AIRunAPI) CreateRun(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
 			return
 		}
-		if note.UserID != user.ID {
+		if note.UserID != user.ID && !inActiveWorkspace(c, note.WorkspaceID) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
@@ -299,12 +344,17 @@ func (api *AIRunAPI) CreateRun(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "folder not found"})
 			return
 		}
-		if folder.UserID != user.ID {
+		if folder.UserID != user.ID && !inActiveWorkspace(c, folder.WorkspaceID) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
 	}
 
+	quota, ok := api.checkQuota(c, user.ID)
+	if !ok {
+		return
+	}
+
 	displayUserMessage := strings.TrimSpace(req.DisplayUserMessage)
 	if displayUserMessage == "" {
 		displayUserMessage = req.Message.Content
@@ -333,11 +383,11 @@ func (api *AIRunAPI) CreateRun(c *gin.Context) {
 	}
 
 	runID := uuid.NewString()
-	if err := api.persistRun(c, user, runID, req.WorkspaceID, req.NoteID, req.SessionID, conversationID); err != nil {
+	if err := api.persistRun(c, user, runID, req.WorkspaceID, req.NoteID, req.SessionID, conversationID, dbmodels.AIRunModeChat); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to persist ai run"})
 		return
 	}
-	if err := api.appendConversationMessage(c, conversationID, runID, "user", displayUserMessage, map[string]interface{}{
+	if err := api.appendConversationMessage(c.Request.Context(), conversationID, runID, "user", displayUserMessage, map[string]interface{}{
 		"workspace_id": req.WorkspaceID,
 		"note_id":      resourceNoteID,
 		"session_id":   req.SessionID,
@@ -351,7 +401,7 @@ func (api *AIRunAPI) CreateRun(c *gin.Context) {
 		"trace_id": c.GetString("trace_id"),
 		"actor": map[string]string{
 			"user_id":      user.ID,
-			"tenant_id":    "",
+			"tenant_id":    req.WorkspaceID,
 			"workspace_id": req.WorkspaceID,
 		},
 		"conversation": []map[string]string{
@@ -369,13 +419,9 @@ func (api *AIRunAPI) CreateRun(c *gin.Context) {
 			"max_tool_calls": 5,
 			"timeout_ms":     30000,
 			"redact_pii":     true,
-			"max_tokens":     4096,
-		},
-		"quota": map[string]interface{}{
-			"tokens_remaining": 0,
-			"calls_today":      0,
-			"tier":             "free",
+			"max_tokens":     api.maxTokens(api.config.AI.RunMaxTokens, 4096, 256, quota),
 		},
+		"quota": quotaPayload(quota),
 	}
 
 	body, err := json.Marshal(payload)
@@ -385,29 +431,7 @@ func (api *AIRunAPI) CreateRun(c *gin.Context) {
 	}
 
 	aiURL := strings.TrimRight(api.config.AI.ServiceURL, "/") + "/internal/v1/agent/runs"
-	httpReq, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, aiURL, bytes.NewReader(body))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create ai request"})
-		return
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "text/event-stream")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", api.config.AI.ServiceToken))
-
-	resp, err := api.streamHTTPClient.Do(httpReq)
-	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to reach ai service"})
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		raw, _ := io.ReadAll(resp.Body)
-		c.JSON(http.StatusBadGateway, gin.H{"error": string(raw)})
-		return
-	}
-
-	api.proxySSE(c, resp.Body, &aiRunHistory{
+	api.startRunStream(c, runID, aiURL, body, &aiRunHistory{
 		RunID:          runID,
 		ConversationID: conversationID,
 		Title:          conversationTitle,
@@ -428,7 +452,23 @@ func (api *AIRunAPI) ListConversations(c *gin.Context) {
 	}
 	user := userVal.(*dbmodels.User)
 
-	conversations, err := api.aiRuns.ListConversations(c.Request.Context(), user.ID, 50)
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 && v <= 100 {
+			limit = v
+		}
+	}
+	var before *repository.AIConversationCursor
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeConversationCursor(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		before = cursor
+	}
+
+	conversations, err := api.aiRuns.ListConversations(c.Request.Context(), user.ID, before, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list conversations"})
 		return
@@ -438,7 +478,11 @@ func (api *AIRunAPI) ListConversations(c *gin.Context) {
 	for _, conversation := range conversations {
 		items = append(items, conversationResponse(conversation))
 	}
-	c.JSON(http.StatusOK, gin.H{"items": items})
+	response := gin.H{"items": items}
+	if len(conversations) == limit {
+		response["next_cursor"] = encodeConversationCursor(conversations[len(conversations)-1])
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (api *AIRunAPI) CreateConversation(c *gin.Context) {
@@ -601,23 +645,36 @@ func (api *AIRunAPI) InlineEdit(c *gin.Context) {
 	}
 	user := userVal.(*dbmodels.User)
 
+	workspaceID, ok := resolveRunWorkspace(c, req.WorkspaceID)
+	if !ok {
+		return
+	}
+	req.WorkspaceID = workspaceID
+
 	trimmedNoteID := strings.TrimSpace(req.NoteID)
 	noteVersion := 0
+	var note *dbmodels.Note
 	if trimmedNoteID != "" {
-		note, err := api.noteService.GetNoteByID(c.Request.Context(), trimmedNoteID)
+		var err error
+		note, err = api.noteService.GetNoteByID(c.Request.Context(), trimmedNoteID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
 			return
 		}
-		if note.UserID != user.ID {
+		if note.UserID != user.ID && !inActiveWorkspace(c, note.WorkspaceID) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
 		noteVersion = note.Version
 	}
 
+	quota, ok := api.checkQuota(c, user.ID)
+	if !ok {
+		return
+	}
+
 	runID := uuid.NewString()
-	if err := api.persistRun(c, user, runID, req.WorkspaceID, req.NoteID, "", ""); err != nil {
+	if err := api.persistRun(c, user, runID, req.WorkspaceID, req.NoteID, "", "", dbmodels.AIRunModeInlineEdit); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to persist ai run"})
 		return
 	}
@@ -630,7 +687,7 @@ func (api *AIRunAPI) InlineEdit(c *gin.Context) {
 		"trace_id": c.GetString("trace_id"),
 		"actor": map[string]string{
 			"user_id":      user.ID,
-			"tenant_id":    "",
+			"tenant_id":    req.WorkspaceID,
 			"workspace_id": req.WorkspaceID,
 		},
 		"action":         req.Action,
@@ -645,7 +702,7 @@ func (api *AIRunAPI) InlineEdit(c *gin.Context) {
 		},
 		"policy": map[string]interface{}{
 			"timeout_ms": api.config.AI.RequestTimeoutMs,
-			"max_tokens": 1024,
+			"max_tokens": api.maxTokens(0, 1024, 128, quota),
 		},
 	}
 
@@ -677,10 +734,65 @@ func (api *AIRunAPI) InlineEdit(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, gin.H{"error": string(raw)})
 		return
 	}
+	api.recordResponseUsage(c.Request.Context(), runID, raw)
 
+	if note != nil {
+		raw = api.attachEditProposal(c.Request.Context(), raw, note, user.ID, runID, req.SelectedText)
+	}
 	c.Data(http.StatusOK, "application/json", raw)
 }
 
+// attachEditProposal stores an edit_proposal result as a reviewable proposal
+// and adds its proposal_id to the response. The selection is located in the
+// note HTML; when it is not there exactly once the response is left as is.
+func (api *AIRunAPI) attachEditProposal(ctx context.Context, raw []byte, note *dbmodels.Note, userID, runID, selectedText string) []byte {
+	if api.proposals == nil {
+		return raw
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return raw
+	}
+	result, _ := response["result"].(map[string]interface{})
+	if resultType, _ := result["type"].(string); resultType != "edit_proposal" {
+		return raw
+	}
+
+	original, _ := result["original"].(string)
+	if original == "" {
+		original = selectedText
+	}
+	proposed, _ := result["proposed"].(string)
+	summary, _ := result["summary"].(string)
+
+	selection := html.EscapeString(original)
+	if selection == "" || strings.Count(note.Content, selection) != 1 {
+		return raw
+	}
+
+	proposal, err := api.proposals.CreateProposal(ctx, service.CreateEditProposalRequest{
+		NoteID:          note.ID,
+		UserID:          userID,
+		RunID:           runID,
+		ExpectedVersion: note.Version,
+		Original:        note.Content,
+		Proposed:        strings.Replace(note.Content, selection, html.EscapeString(proposed), 1),
+		Summary:         summary,
+	})
+	if err != nil {
+		log.Printf("inline edit proposal for note %s not stored: %v", note.ID, err)
+		return raw
+	}
+
+	response["proposal_id"] = proposal.ID
+	updated, err := json.Marshal(response)
+	if err != nil {
+		return raw
+	}
+	return updated
+}
+
 func (api *AIRunAPI) InlineEditRun(c *gin.Context) {
 	var req inlineEditRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -695,6 +807,12 @@ func (api *AIRunAPI) InlineEditRun(c *gin.Context) {
 	}
 	user := userVal.(*dbmodels.User)
 
+	workspaceID, ok := resolveRunWorkspace(c, req.WorkspaceID)
+	if !ok {
+		return
+	}
+	req.WorkspaceID = workspaceID
+
 	trimmedNoteID := strings.TrimSpace(req.NoteID)
 	noteVersion := 0
 	if trimmedNoteID != "" {
@@ -703,15 +821,20 @@ func (api *AIRunAPI) InlineEditRun(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
 			return
 		}
-		if note.UserID != user.ID {
+		if note.UserID != user.ID && !inActiveWorkspace(c, note.WorkspaceID) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
 		noteVersion = note.Version
 	}
 
+	quota, ok := api.checkQuota(c, user.ID)
+	if !ok {
+		return
+	}
+
 	runID := uuid.NewString()
-	if err := api.persistRun(c, user, runID, req.WorkspaceID, req.NoteID, "", ""); err != nil {
+	if err := api.persistRun(c, user, runID, req.WorkspaceID, req.NoteID, "", "", dbmodels.AIRunModeInlineEdit); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to persist ai run"})
 		return
 	}
@@ -724,7 +847,7 @@ func (api *AIRunAPI) InlineEditRun(c *gin.Context) {
 		"trace_id": c.GetString("trace_id"),
 		"actor": map[string]string{
 			"user_id":      user.ID,
-			"tenant_id":    "",
+			"tenant_id":    req.WorkspaceID,
 			"workspace_id": req.WorkspaceID,
 		},
 		"action":         req.Action,
@@ -739,7 +862,7 @@ func (api *AIRunAPI) InlineEditRun(c *gin.Context) {
 		},
 		"policy": map[string]interface{}{
 			"timeout_ms": api.config.AI.RequestTimeoutMs,
-			"max_tokens": 20_000,
+			"max_tokens": api.maxTokens(api.config.AI.InlineEditMaxTokens, 20_000, 128, quota),
 		},
 	}
 
@@ -750,29 +873,7 @@ func (api *AIRunAPI) InlineEditRun(c *gin.Context) {
 	}
 
 	aiURL := strings.TrimRight(api.config.AI.ServiceURL, "/") + "/internal/v1/agent/inline-edit/runs"
-	httpReq, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, aiURL, bytes.NewReader(body))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create ai request"})
-		return
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "text/event-stream")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", api.config.AI.ServiceToken))
-
-	resp, err := api.streamHTTPClient.Do(httpReq)
-	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to reach ai service"})
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		raw, _ := io.ReadAll(resp.Body)
-		c.JSON(http.StatusBadGateway, gin.H{"error": string(raw)})
-		return
-	}
-
-	api.proxySSE(c, resp.Body, nil)
+	api.startRunStream(c, runID, aiURL, body, nil)
 }
 
 func (api *AIRunAPI) ProvideConsent(c *gin.Context) {
@@ -812,7 +913,8 @@ func (api *AIRunAPI) ProvideConsent(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
-		if _, err := api.aiRuns.GetToolCall(c.Request.Context(), runID, req.ToolCallID); err != nil {
+		call, err := api.aiRuns.GetToolCall(c.Request.Context(), runID, req.ToolCallID)
+		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				c.JSON(http.StatusNotFound, gin.H{"error": "tool call not found"})
 				return
@@ -820,6 +922,11 @@ func (api *AIRunAPI) ProvideConsent(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tool call"})
 			return
 		}
+		// The sweeper may not have caught up with it yet
+		if call.Status == dbmodels.AIToolCallStatusExpired || (call.ExpiresAt != nil && call.ExpiresAt.Before(time.Now())) {
+			c.JSON(http.StatusConflict, gin.H{"error": "tool call consent expired"})
+			return
+		}
 		if err := api.aiRuns.ApproveToolCall(c.Request.Context(), runID, req.ToolCallID, user.ID, req.Approved); err != nil {
 			if errors.Is(err, repository.ErrAIToolCallNotPending) {
 				c.JSON(http.StatusConflict, gin.H{"error": "tool call is not pending"})
@@ -866,7 +973,54 @@ func (api *AIRunAPI) ProvideConsent(c *gin.Context) {
 	c.Data(http.StatusOK, "application/json", raw)
 }
 
-func (api *AIRunAPI) proxySSE(c *gin.Context, reader io.Reader, history *aiRunHistory) {
+// startRunStream starts the run on the ai-service and streams it to the client.
+// The upstream request is detached from the client's request, so the run keeps
+// going (and being persisted) if the client disconnects; it can reattach
+// through StreamRunEvents.
+func (api *AIRunAPI) startRunStream(c *gin.Context, runID, aiURL string, body []byte, history *aiRunHistory) {
+	runCtx, cancelRun := context.WithTimeout(context.WithoutCancel(c.Request.Context()), aiRunMaxDuration)
+
+	httpReq, err := http.NewRequestWithContext(runCtx, http.MethodPost, aiURL, bytes.NewReader(body))
+	if err != nil {
+		cancelRun()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create ai request"})
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", api.config.AI.ServiceToken))
+
+	resp, err := api.streamHTTPClient.Do(httpReq)
+	if err != nil {
+		cancelRun()
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to reach ai service"})
+		return
+	}
+
+	if resp.StatusCode >= 400 {
+		raw, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancelRun()
+		c.JSON(http.StatusBadGateway, gin.H{"error": string(raw)})
+		return
+	}
+
+	stream := api.streams.open(runID)
+	frames, unsubscribe, _ := stream.subscribe()
+	go func() {
+		defer cancelRun()
+		defer resp.Body.Close()
+		defer api.streams.close(runID, stream)
+		api.pumpSSE(runCtx, resp.Body, stream, history)
+	}()
+
+	api.proxySSE(c, frames, unsubscribe)
+}
+
+// proxySSE writes frames to the client until the stream ends or the client leaves
+func (api *AIRunAPI) proxySSE(c *gin.Context, frames <-chan aiRunFrame, unsubscribe func()) {
+	defer unsubscribe()
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
@@ -879,6 +1033,25 @@ func (api *AIRunAPI) proxySSE(c *gin.Context, reader io.Reader, history *aiRunHi
 		return
 	}
 
+	for {
+		select {
+		case frame, ok := <-frames:
+			if !ok {
+				return
+			}
+			if _, err := c.Writer.Write(frame.Raw); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// pumpSSE reads the ai-service stream frame by frame, persists each frame and
+// then publishes it, tagged with its event ID so clients can resume after it.
+func (api *AIRunAPI) pumpSSE(ctx context.Context, reader io.Reader, stream *aiRunStream, history *aiRunHistory) {
 	var assistantContent strings.Builder
 	if history != nil && history.ConversationID != "" {
 		payload, _ := json.Marshal(map[string]interface{}{
@@ -887,61 +1060,194 @@ func (api *AIRunAPI) proxySSE(c *gin.Context, reader io.Reader, history *aiRunHi
 			"title":           history.Title,
 			"created":         history.Created,
 		})
-		_, _ = fmt.Fprintf(c.Writer, "event: conversation.created\ndata: %s\n\n", payload)
-		flusher.Flush()
+		reader = io.MultiReader(strings.NewReader(fmt.Sprintf("event: conversation.created\ndata: %s\n\n", payload)), reader)
 	}
 
 	bReader := bufio.NewReader(reader)
 	var eventType string
 	var dataLines [][]byte
+	var lines [][]byte
+	emit := func() {
+		if len(lines) == 0 {
+			return
+		}
+		eventID := api.persistSSEFrame(ctx, eventType, dataLines, history, &assistantContent)
+		var raw bytes.Buffer
+		if eventID != "" {
+			fmt.Fprintf(&raw, "id: %s\n", eventID)
+		}
+		for _, line := range lines {
+			raw.Write(line)
+		}
+		stream.publish(aiRunFrame{ID: eventID, Raw: raw.Bytes()})
+		eventType = ""
+		dataLines = nil
+		lines = nil
+	}
+
 	for {
 		line, err := bReader.ReadBytes('\n')
 		if len(line) > 0 {
 			trimmed := bytes.TrimSpace(line)
 			switch {
+			case bytes.HasPrefix(trimmed, []byte("id:")):
+				// Frames are re-tagged with the persisted event ID
 			case bytes.HasPrefix(trimmed, []byte("event:")):
 				eventType = strings.TrimSpace(string(bytes.TrimPrefix(trimmed, []byte("event:"))))
+				lines = append(lines, line)
 			case bytes.HasPrefix(trimmed, []byte("data:")):
 				dataLines = append(dataLines, bytes.TrimSpace(bytes.TrimPrefix(trimmed, []byte("data:"))))
+				lines = append(lines, line)
 			case len(trimmed) == 0:
-				api.persistSSEFrame(c, eventType, dataLines, history, &assistantContent)
-				eventType = ""
-				dataLines = nil
+				lines = append(lines, line)
+				emit()
+			default:
+				lines = append(lines, line)
 			}
-			if _, writeErr := c.Writer.Write(line); writeErr != nil {
+		}
+		if err != nil {
+			emit()
+			return
+		}
+	}
+}
+
+// StreamRunEvents replays a run's persisted events after Last-Event-ID, then
+// follows the live stream while the run is still being proxied by this server.
+func (api *AIRunAPI) StreamRunEvents(c *gin.Context) {
+	runID := c.Param("run_id")
+	if runID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "run_id is required"})
+		return
+	}
+
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	user, ok := userVal.(*dbmodels.User)
+	if !ok || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if api.aiRuns == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "ai run service unavailable"})
+		return
+	}
+
+	run, err := api.aiRuns.GetRun(c.Request.Context(), runID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load run"})
+		return
+	}
+	if run.UserID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	// Last-Event-ID wins; a resume token on its own replays the whole run
+	lastEventID := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if lastEventID == "" {
+		lastEventID = strings.TrimSpace(c.Query("last_event_id"))
+	}
+	if token := strings.TrimSpace(c.Query("resume_token")); token != "" && token != run.ResumeToken {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid resume token"})
+		return
+	}
+
+	// Subscribe before reading stored events so nothing published in between is missed
+	live, unsubscribe, attached := api.streams.subscribe(runID)
+	if attached {
+		defer unsubscribe()
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "streaming unsupported"})
+		return
+	}
+
+	sent := make(map[string]struct{})
+	if lastEventID != "" {
+		sent[lastEventID] = struct{}{}
+	}
+	after := lastEventID
+	for {
+		events, err := api.aiRuns.ListEventsAfter(c.Request.Context(), runID, after, aiRunReplayPageSize)
+		if err != nil {
+			return
+		}
+		for _, event := range events {
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.EventID, event.EventType, event.Payload); err != nil {
 				return
 			}
-			flusher.Flush()
+			sent[event.EventID] = struct{}{}
+			after = event.EventID
 		}
-		if err != nil {
-			if errors.Is(err, io.EOF) {
+		flusher.Flush()
+		if len(events) < aiRunReplayPageSize {
+			break
+		}
+	}
+
+	if !attached {
+		return
+	}
+	for {
+		select {
+		case frame, ok := <-live:
+			if !ok {
 				return
 			}
+			if _, seen := sent[frame.ID]; seen && frame.ID != "" {
+				continue
+			}
+			if _, err := c.Writer.Write(frame.Raw); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-c.Request.Context().Done():
 			return
 		}
 	}
 }
 
+// aiRunReplayPageSize is the ListEventsAfter page size used when replaying
+const aiRunReplayPageSize = 200
+
+// persistSSEFrame stores a frame as a run event and returns its event ID, or
+// "" when the frame carries no run payload.
 func (api *AIRunAPI) persistSSEFrame(
-	c *gin.Context,
+	ctx context.Context,
 	eventType string,
 	dataLines [][]byte,
 	history *aiRunHistory,
 	assistantContent *strings.Builder,
-) {
+) string {
 	if api.aiRuns == nil || len(dataLines) == 0 {
-		return
+		return ""
 	}
 
 	data := bytes.Join(dataLines, nil)
 	var payload map[string]interface{}
 	if err := json.Unmarshal(data, &payload); err != nil {
-		return
+		return ""
 	}
 
 	runID, _ := payload["run_id"].(string)
 	if runID == "" {
-		return
+		return ""
 	}
 	if eventType == "" {
 		eventType, _ = payload["type"].(string)
@@ -951,47 +1257,58 @@ func (api *AIRunAPI) persistSSEFrame(
 		eventID = uuid.NewString()
 	}
 
+	// Deltas are stored too so a resumed stream can rebuild the answer. A frame
+	// that failed to persist is still streamed, just without a resumable ID.
+	persistedID := eventID
+	if err := api.aiRuns.AppendEvent(ctx, &dbmodels.AIRunEvent{
+		RunID:     runID,
+		EventID:   eventID,
+		EventType: eventType,
+		Payload:   datatypes.JSON(data),
+	}); err != nil {
+		persistedID = ""
+	}
+
 	if eventType == "assistant.delta" || strings.HasSuffix(eventType, ".delta") {
 		if history != nil && assistantContent != nil {
 			if delta, ok := payload["content"].(string); ok {
 				assistantContent.WriteString(delta)
 			}
 		}
-		return
+		return persistedID
 	}
 
-	_ = api.aiRuns.AppendEvent(c.Request.Context(), &dbmodels.AIRunEvent{
-		RunID:     runID,
-		EventID:   eventID,
-		EventType: eventType,
-		Payload:   datatypes.JSON(data),
-	})
-
 	switch eventType {
 	case "run.started":
 		resumeToken, _ := payload["resume_token"].(string)
-		_ = api.aiRuns.SetRunResume(c.Request.Context(), runID, resumeToken, eventID)
+		_ = api.aiRuns.SetRunResume(ctx, runID, resumeToken, eventID)
 	case "run.awaiting_consent":
-		_ = api.aiRuns.UpdateRunStatus(c.Request.Context(), runID, dbmodels.AIRunStatusAwaitingConsent)
-		api.persistPendingConsent(c, runID, payload)
+		_ = api.aiRuns.UpdateRunStatus(ctx, runID, dbmodels.AIRunStatusAwaitingConsent)
+		api.persistPendingConsent(ctx, runID, payload)
 	case "run.completed":
-		_ = api.aiRuns.UpdateRunStatus(c.Request.Context(), runID, dbmodels.AIRunStatusCompleted)
+		_ = api.aiRuns.UpdateRunStatus(ctx, runID, dbmodels.AIRunStatusCompleted)
+		api.recordRunUsage(ctx, runID, payload)
 		if history != nil && assistantContent != nil {
-			_ = api.appendConversationMessage(c, history.ConversationID, runID, "assistant", assistantContent.String(), nil)
+			_ = api.appendConversationMessage(ctx, history.ConversationID, runID, "assistant", assistantContent.String(), completionMetadata(payload))
 		}
 	case "run.failed":
-		_ = api.aiRuns.UpdateRunStatus(c.Request.Context(), runID, dbmodels.AIRunStatusFailed)
+		_ = api.aiRuns.UpdateRunStatus(ctx, runID, dbmodels.AIRunStatusFailed)
+		// Tokens spent before the failure count toward the quota too
+		if _, ok := payload["usage"]; ok {
+			api.recordRunUsage(ctx, runID, payload)
+		}
 		if history != nil && assistantContent != nil {
-			_ = api.appendConversationMessage(c, history.ConversationID, runID, "assistant", assistantContent.String(), map[string]interface{}{
+			_ = api.appendConversationMessage(ctx, history.ConversationID, runID, "assistant", assistantContent.String(), map[string]interface{}{
 				"status": "failed",
 			})
 		}
 	default:
-		_ = api.aiRuns.SetRunResume(c.Request.Context(), runID, "", eventID)
+		_ = api.aiRuns.SetRunResume(ctx, runID, "", eventID)
 	}
+	return persistedID
 }
 
-func (api *AIRunAPI) persistPendingConsent(c *gin.Context, runID string, payload map[string]interface{}) {
+func (api *AIRunAPI) persistPendingConsent(ctx context.Context, runID string, payload map[string]interface{}) {
 	consent, ok := payload["consent"].(map[string]interface{})
 	if !ok {
 		return
@@ -1003,8 +1320,8 @@ func (api *AIRunAPI) persistPendingConsent(c *gin.Context, runID string, payload
 		return
 	}
 	args, _ := json.Marshal(consent)
-	expiresAt := time.Now().UTC().Add(5 * time.Minute)
-	_ = api.aiRuns.UpsertPendingToolCall(c.Request.Context(), &dbmodels.AIToolCall{
+	expiresAt := time.Now().UTC().Add(api.consentTimeout())
+	_ = api.aiRuns.UpsertPendingToolCall(ctx, &dbmodels.AIToolCall{
 		RunID:      runID,
 		ToolCallID: toolCallID,
 		Tool:       tool,
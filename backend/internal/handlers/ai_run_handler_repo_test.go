@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -77,7 +80,9 @@ func (r *aiRunHandlerTestRepo) SetRunResume(_ context.Context, runID, resumeToke
 	if !ok {
 		return gorm.ErrRecordNotFound
 	}
-	run.ResumeToken = resumeToken
+	if resumeToken != "" {
+		run.ResumeToken = resumeToken
+	}
 	run.LastEventID = lastEventID
 	return nil
 }
@@ -182,15 +187,34 @@ func (r *aiRunHandlerTestRepo) GetToolCall(_ context.Context, runID string, tool
 	return &toolCallCopy, nil
 }
 
-func (r *aiRunHandlerTestRepo) ExpirePendingToolCalls(_ context.Context, before time.Time) error {
+func (r *aiRunHandlerTestRepo) ExpirePendingToolCalls(_ context.Context, now time.Time) ([]models.AIToolCall, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	var expired []models.AIToolCall
 	for _, toolCall := range r.toolCalls {
-		if toolCall.Status == models.AIToolCallStatusPending && toolCall.CreatedAt.Before(before) {
+		if toolCall.Status == models.AIToolCallStatusPending && toolCall.ExpiresAt != nil && toolCall.ExpiresAt.Before(now) {
 			toolCall.Status = models.AIToolCallStatusExpired
+			expired = append(expired, *toolCall)
 		}
 	}
-	return nil
+	return expired, nil
+}
+
+func (r *aiRunHandlerTestRepo) ListPendingToolCalls(_ context.Context, userID string, now time.Time, _ int) ([]models.AIToolCall, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []models.AIToolCall
+	for _, toolCall := range r.toolCalls {
+		run, ok := r.runs[toolCall.RunID]
+		if !ok || run.UserID != userID || toolCall.Status != models.AIToolCallStatusPending {
+			continue
+		}
+		if toolCall.ExpiresAt != nil && !toolCall.ExpiresAt.After(now) {
+			continue
+		}
+		pending = append(pending, *toolCall)
+	}
+	return pending, nil
 }
 
 func (r *aiRunHandlerTestRepo) CreateConversation(_ context.Context, conversation *models.AIConversation) error {
@@ -205,7 +229,7 @@ func (r *aiRunHandlerTestRepo) CreateConversation(_ context.Context, conversatio
 	return nil
 }
 
-func (r *aiRunHandlerTestRepo) ListConversations(_ context.Context, userID string, _ int) ([]models.AIConversation, error) {
+func (r *aiRunHandlerTestRepo) ListConversations(_ context.Context, userID string, before *repository.AIConversationCursor, limit int) ([]models.AIConversation, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	conversations := make([]models.AIConversation, 0, len(r.conversations))
@@ -214,9 +238,63 @@ func (r *aiRunHandlerTestRepo) ListConversations(_ context.Context, userID strin
 			conversations = append(conversations, *conversation)
 		}
 	}
+	sort.Slice(conversations, func(i, j int) bool {
+		if !conversations[i].LastMessageAt.Equal(conversations[j].LastMessageAt) {
+			return conversations[i].LastMessageAt.After(conversations[j].LastMessageAt)
+		}
+		return conversations[i].ID > conversations[j].ID
+	})
+	if before != nil {
+		page := conversations[:0]
+		for _, conversation := range conversations {
+			if conversation.LastMessageAt.Before(before.LastMessageAt) ||
+				(conversation.LastMessageAt.Equal(before.LastMessageAt) && conversation.ID < before.ID) {
+				page = append(page, conversation)
+			}
+		}
+		conversations = page
+	}
+	if limit > 0 && len(conversations) > limit {
+		conversations = conversations[:limit]
+	}
 	return conversations, nil
 }
 
+func (r *aiRunHandlerTestRepo) SearchConversations(_ context.Context, userID, query string, _ int) ([]repository.AIConversationSearchHit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hits := []repository.AIConversationSearchHit{}
+	for conversationID, messages := range r.messages {
+		conversation, ok := r.conversations[conversationID]
+		if !ok || conversation.UserID != userID || conversation.IsDeleted {
+			continue
+		}
+		for _, message := range messages {
+			if strings.Contains(strings.ToLower(message.Content), strings.ToLower(query)) {
+				hits = append(hits, repository.AIConversationSearchHit{Conversation: *conversation, MessageID: message.ID, Role: message.Role, Snippet: message.Content})
+				break
+			}
+		}
+	}
+	return hits, nil
+}
+
+func (r *aiRunHandlerTestRepo) ForkConversation(_ context.Context, conversation *models.AIConversation, messages []models.AIConversationMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if conversation.ID == "" {
+		conversation.ID = fmt.Sprintf("conversation-%d", len(r.conversations)+1)
+	}
+	stored := *conversation
+	r.conversations[stored.ID] = &stored
+	for _, message := range messages {
+		message.ID = ""
+		message.ConversationID = stored.ID
+		r.messages[stored.ID] = append(r.messages[stored.ID], message)
+	}
+	return nil
+}
+
 func (r *aiRunHandlerTestRepo) GetConversation(_ context.Context, conversationID, userID string) (*models.AIConversation, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -303,6 +381,8 @@ func newAIRunHandlerTestRouter(t *testing.T, python http.HandlerFunc, note *mode
 		aiRunHandlerTestNoteService{note: note},
 		nil,
 		repo,
+		nil,
+		nil,
 	)
 
 	router := gin.New()
@@ -313,12 +393,18 @@ func newAIRunHandlerTestRouter(t *testing.T, python http.HandlerFunc, note *mode
 	group := router.Group("/api/v1/ai")
 	group.POST("/runs", api.CreateRun)
 	group.GET("/conversations", api.ListConversations)
+	group.GET("/conversations/search", api.SearchConversations)
+	group.POST("/conversations/:conversation_id/fork", api.ForkConversation)
+	group.GET("/conversations/:conversation_id/export", api.ExportConversation)
+	group.POST("/conversations/:conversation_id/messages/:message_id/note", api.SaveMessageAsNote)
 	group.POST("/conversations", api.CreateConversation)
 	group.GET("/conversations/:conversation_id", api.GetConversation)
 	group.PATCH("/conversations/:conversation_id", api.UpdateConversation)
 	group.DELETE("/conversations/:conversation_id", api.DeleteConversation)
 	group.POST("/inline-edit/runs", api.InlineEditRun)
 	group.POST("/runs/:run_id/consent", api.ProvideConsent)
+	group.GET("/consents/pending", api.ListPendingConsents)
+	group.GET("/runs/:run_id/events", api.StreamRunEvents)
 
 	return router, repo
 }
@@ -12,8 +12,14 @@ type aiRunHandlerTestNoteService struct {
 	note *models.Note
 }
 
-func (s aiRunHandlerTestNoteService) CreateNote(context.Context, service.CreateNoteRequest) (*models.Note, error) {
-	return nil, nil
+func (s aiRunHandlerTestNoteService) CreateNote(_ context.Context, req service.CreateNoteRequest) (*models.Note, error) {
+	return &models.Note{
+		Title:       req.Title,
+		Content:     req.Content,
+		ContentType: req.ContentType,
+		FolderID:    req.FolderID,
+		UserID:      req.UserID,
+	}, nil
 }
 
 func (s aiRunHandlerTestNoteService) GetNoteByID(context.Context, string) (*models.Note, error) {
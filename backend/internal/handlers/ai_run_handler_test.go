@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 )
 
@@ -53,6 +58,120 @@ func TestAIRunInlineEditForwardsAgentContract(t *testing.T) {
 	}, received["resource_context"])
 }
 
+type aiRunHandlerTestUsage struct {
+	service.AIUsageService
+	recorded map[string]service.AIRunUsage
+}
+
+func (f *aiRunHandlerTestUsage) CheckQuota(ctx context.Context, userID string) (*service.AIQuotaStatus, error) {
+	return nil, nil
+}
+
+func (f *aiRunHandlerTestUsage) RecordRunUsage(ctx context.Context, runID string, usage service.AIRunUsage) error {
+	f.recorded[runID] = usage
+	return nil
+}
+
+func TestAIRunInlineEditRecordsUsage(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	python := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/internal/v1/agent/inline-edit", r.URL.Path)
+		w.Header().Set("content-type", "application/json")
+		_, _ = w.Write([]byte(`{"text":"Hello","result":null,"usage":{"prompt_tokens":120,"completion_tokens":30,"total_tokens":150},"model_name":"gpt-test"}`))
+	}))
+	t.Cleanup(python.Close)
+
+	usage := &aiRunHandlerTestUsage{recorded: map[string]service.AIRunUsage{}}
+	api := NewAIRunAPI(
+		&config.Config{AI: config.AIConfig{ServiceURL: python.URL, ServiceToken: "test-service-token"}},
+		aiRunHandlerTestNoteService{},
+		nil,
+		newAIRunHandlerTestRepo(),
+		nil,
+		usage,
+	)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &models.User{BaseModel: models.BaseModel{ID: "user-1"}})
+		c.Next()
+	})
+	router.POST("/api/v1/ai/inline-edit", api.InlineEdit)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/ai/inline-edit", bytes.NewBufferString(`{"action":"fix","selected_text":"helo","workspace_id":"ws-1"}`))
+	request.Header.Set("content-type", "application/json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	require.Len(t, usage.recorded, 1)
+	for _, recorded := range usage.recorded {
+		require.Equal(t, service.AIRunUsage{Model: "gpt-test", PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150}, recorded)
+	}
+}
+
+func TestAIRunFailedRecordsUsage(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		failed string
+		want   *service.AIRunUsage
+	}{
+		{"with usage", `"usage":{"prompt_tokens":80,"completion_tokens":5,"total_tokens":85},"model_name":"gpt-test",`, &service.AIRunUsage{Model: "gpt-test", PromptTokens: 80, CompletionTokens: 5, TotalTokens: 85}},
+		{"without usage", ``, nil},
+	} {
+		gin.SetMode(gin.TestMode)
+		python := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			runID := payload["run_id"].(string)
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprintf(w, "event: run.started\ndata: {\"run_id\":%q}\n\n", runID)
+			_, _ = fmt.Fprintf(w, "event: run.failed\ndata: {\"run_id\":%q,%s\"code\":\"INTERNAL\",\"message\":\"boom\"}\n\n", runID, tc.failed)
+		}))
+		t.Cleanup(python.Close)
+
+		usage := &aiRunHandlerTestUsage{recorded: map[string]service.AIRunUsage{}}
+		api := NewAIRunAPI(
+			&config.Config{AI: config.AIConfig{ServiceURL: python.URL, ServiceToken: "test-service-token"}},
+			aiRunHandlerTestNoteService{},
+			nil,
+			newAIRunHandlerTestRepo(),
+			nil,
+			usage,
+		)
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", &models.User{BaseModel: models.BaseModel{ID: "user-1"}})
+			c.Next()
+		})
+		router.POST("/api/v1/ai/runs", api.CreateRun)
+
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/ai/runs", bytes.NewBufferString(`{
+			"workspace_id":"workspace-1",
+			"session_id":"session-1",
+			"message":{"role":"user","content":"hi"}
+		}`))
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		require.Equal(t, http.StatusOK, response.Code, tc.name)
+		require.Contains(t, response.Body.String(), "event: run.failed", tc.name)
+		if tc.want == nil {
+			require.Empty(t, usage.recorded, tc.name)
+			continue
+		}
+		require.Len(t, usage.recorded, 1, tc.name)
+		for _, recorded := range usage.recorded {
+			require.Equal(t, *tc.want, recorded, tc.name)
+		}
+	}
+}
+
 func TestAIRunConsentRequiresRunOwner(t *testing.T) {
 	t.Parallel()
 
@@ -116,6 +235,53 @@ func TestAIRunConsentPersistsApprovedAuditTrail(t *testing.T) {
 	require.Equal(t, "user-1", toolCall.ApprovedBy)
 	require.NotNil(t, toolCall.ApprovedAt)
 }
+func TestAIRunPendingConsentsSkipExpiredCalls(t *testing.T) {
+	t.Parallel()
+
+	router, repo := newAIRunHandlerTestRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("python service should not be called for expired consent")
+	}, &models.Note{UserID: "user-1"})
+	repo.mustCreateRun(t, &models.AIRun{
+		BaseModel: models.BaseModel{ID: "run-pending"},
+		UserID:    "user-1",
+		Status:    models.AIRunStatusAwaitingConsent,
+	})
+	future := time.Now().Add(time.Minute)
+	past := time.Now().Add(-time.Minute)
+	repo.mustCreateToolCall(t, &models.AIToolCall{
+		BaseModel:  models.BaseModel{ID: "tool-live"},
+		RunID:      "run-pending",
+		ToolCallID: "call-live",
+		Tool:       "notes.write",
+		Status:     models.AIToolCallStatusPending,
+		ExpiresAt:  &future,
+	})
+	repo.mustCreateToolCall(t, &models.AIToolCall{
+		BaseModel:  models.BaseModel{ID: "tool-stale"},
+		RunID:      "run-pending",
+		ToolCallID: "call-stale",
+		Tool:       "notes.write",
+		Status:     models.AIToolCallStatusPending,
+		ExpiresAt:  &past,
+	})
+
+	list := httptest.NewRequest(http.MethodGet, "/api/v1/ai/consents/pending", nil)
+	listResponse := httptest.NewRecorder()
+	router.ServeHTTP(listResponse, list)
+
+	require.Equal(t, http.StatusOK, listResponse.Code, listResponse.Body.String())
+	require.Contains(t, listResponse.Body.String(), `"tool_call_id":"call-live"`)
+	require.NotContains(t, listResponse.Body.String(), "call-stale")
+
+	consent := httptest.NewRequest(http.MethodPost, "/api/v1/ai/runs/run-pending/consent", bytes.NewBufferString(`{"tool_call_id":"tool-stale","approved":true}`))
+	consent.Header.Set("content-type", "application/json")
+	consentResponse := httptest.NewRecorder()
+	router.ServeHTTP(consentResponse, consent)
+
+	require.Equal(t, http.StatusConflict, consentResponse.Code, consentResponse.Body.String())
+	require.Equal(t, models.AIToolCallStatusPending, repo.mustGetToolCall(t, "tool-stale").Status)
+}
+
 func TestAIRunCreatePersistsConversationMessages(t *testing.T) {
 	note := &models.Note{
 		BaseModel: models.BaseModel{ID: "note-1"},
@@ -162,3 +328,80 @@ func TestAIRunCreatePersistsConversationMessages(t *testing.T) {
 	require.Equal(t, "assistant", messages[1].Role)
 	require.Equal(t, "Hello back", messages[1].Content)
 }
+
+func TestAIRunEventsReplayAfterLastEventID(t *testing.T) {
+	router, repo := newAIRunHandlerTestRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		runID := payload["run_id"].(string)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprintf(w, "event: run.started\ndata: {\"run_id\":%q,\"resume_token\":\"resume-1\"}\n\n", runID)
+		_, _ = fmt.Fprintf(w, "event: assistant.delta\ndata: {\"run_id\":%q,\"content\":\"Hello \"}\n\n", runID)
+		_, _ = fmt.Fprintf(w, "event: assistant.delta\ndata: {\"run_id\":%q,\"content\":\"again\"}\n\n", runID)
+		_, _ = fmt.Fprintf(w, "event: run.completed\ndata: {\"run_id\":%q}\n\n", runID)
+	}, &models.Note{UserID: "user-1"})
+
+	create := httptest.NewRequest(http.MethodPost, "/api/v1/ai/runs", bytes.NewBufferString(`{
+		"workspace_id":"workspace-1",
+		"session_id":"session-1",
+		"message":{"role":"user","content":"hi"}
+	}`))
+	create.Header.Set("Content-Type", "application/json")
+	createResponse := httptest.NewRecorder()
+	router.ServeHTTP(createResponse, create)
+	require.Equal(t, http.StatusOK, createResponse.Code)
+
+	repo.mu.Lock()
+	var runID, startedID string
+	for id, events := range repo.events {
+		runID = id
+		for _, event := range events {
+			if event.EventType == "run.started" {
+				startedID = event.EventID
+			}
+		}
+	}
+	repo.mu.Unlock()
+	require.NotEmpty(t, startedID)
+	require.Contains(t, createResponse.Body.String(), "id: "+startedID+"\n")
+
+	replay := httptest.NewRequest(http.MethodGet, "/api/v1/ai/runs/"+runID+"/events", nil)
+	replay.Header.Set("Last-Event-ID", startedID)
+	replayResponse := httptest.NewRecorder()
+	router.ServeHTTP(replayResponse, replay)
+
+	require.Equal(t, http.StatusOK, replayResponse.Code)
+	body := replayResponse.Body.String()
+	require.NotContains(t, body, "run.started")
+	require.Contains(t, body, `"content":"Hello "`)
+	require.Contains(t, body, `"content":"again"`)
+	require.Contains(t, body, "event: run.completed")
+
+	badToken := httptest.NewRequest(http.MethodGet, "/api/v1/ai/runs/"+runID+"/events?resume_token=wrong", nil)
+	badTokenResponse := httptest.NewRecorder()
+	router.ServeHTTP(badTokenResponse, badToken)
+	require.Equal(t, http.StatusForbidden, badTokenResponse.Code)
+}
+
+func TestResolveRunWorkspaceUsesActiveWorkspace(t *testing.T) {
+	t.Parallel()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("workspace", &models.Workspace{BaseModel: models.BaseModel{ID: "ws-team"}, Role: models.WorkspaceRoleMember})
+
+	workspaceID, ok := resolveRunWorkspace(c, "")
+	require.True(t, ok)
+	require.Equal(t, "ws-team", workspaceID)
+
+	workspaceID, ok = resolveRunWorkspace(c, "ws-team")
+	require.True(t, ok)
+	require.Equal(t, "ws-team", workspaceID)
+
+	mismatch := httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(mismatch)
+	c.Set("workspace", &models.Workspace{BaseModel: models.BaseModel{ID: "ws-team"}, Role: models.WorkspaceRoleMember})
+	_, ok = resolveRunWorkspace(c, "ws-other")
+	require.False(t, ok)
+	require.Equal(t, http.StatusBadRequest, mismatch.Code)
+}
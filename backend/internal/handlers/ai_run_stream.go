@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"sync"
+)
+
+// aiRunStreamBuffer is how many frames a subscriber may fall behind before it
+// is dropped; the client then reconnects with Last-Event-ID and replays.
+const aiRunStreamBuffer = 256
+
+// aiRunFrame is one SSE frame as written to clients. ID is empty for frames
+// that were passed through without being persisted.
+type aiRunFrame struct {
+	ID  string
+	Raw []byte
+}
+
+// aiRunStream fans the frames of one in-flight run out to its subscribers
+type aiRunStream struct {
+	mu          sync.Mutex
+	subscribers map[chan aiRunFrame]struct{}
+	closed      bool
+}
+
+// aiRunStreamHub tracks the runs this server is currently proxying, so a
+// reconnecting client can attach to a run after replaying its stored events.
+type aiRunStreamHub struct {
+	mu      sync.Mutex
+	streams map[string]*aiRunStream
+}
+
+func newAIRunStreamHub() *aiRunStreamHub {
+	return &aiRunStreamHub{streams: make(map[string]*aiRunStream)}
+}
+
+// open registers a live run
+func (h *aiRunStreamHub) open(runID string) *aiRunStream {
+	stream := &aiRunStream{subscribers: make(map[chan aiRunFrame]struct{})}
+	h.mu.Lock()
+	h.streams[runID] = stream
+	h.mu.Unlock()
+	return stream
+}
+
+// close ends a run's stream, closing every subscriber channel
+func (h *aiRunStreamHub) close(runID string, stream *aiRunStream) {
+	h.mu.Lock()
+	if h.streams[runID] == stream {
+		delete(h.streams, runID)
+	}
+	h.mu.Unlock()
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	stream.closed = true
+	for ch := range stream.subscribers {
+		close(ch)
+	}
+	stream.subscribers = nil
+}
+
+// subscribe attaches to a live run. It returns false when the run is not
+// being streamed by this server.
+func (h *aiRunStreamHub) subscribe(runID string) (<-chan aiRunFrame, func(), bool) {
+	h.mu.Lock()
+	stream, ok := h.streams[runID]
+	h.mu.Unlock()
+	if !ok {
+		return nil, nil, false
+	}
+	return stream.subscribe()
+}
+
+// publish delivers a frame to a live run's subscribers, if this server is
+// streaming it
+func (h *aiRunStreamHub) publish(runID string, frame aiRunFrame) {
+	h.mu.Lock()
+	stream, ok := h.streams[runID]
+	h.mu.Unlock()
+	if ok {
+		stream.publish(frame)
+	}
+}
+
+func (s *aiRunStream) subscribe() (<-chan aiRunFrame, func(), bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, nil, false
+	}
+
+	ch := make(chan aiRunFrame, aiRunStreamBuffer)
+	s.subscribers[ch] = struct{}{}
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, true
+}
+
+// publish delivers a frame to every subscriber, dropping those that fell behind
+func (s *aiRunStream) publish(frame aiRunFrame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- frame:
+		default:
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
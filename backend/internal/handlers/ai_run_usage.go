@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// checkQuota stops a run before it is proxied once the user is over quota.
+// It writes the error response itself and reports whether the run may go on.
+func (api *AIRunAPI) checkQuota(c *gin.Context, userID string) (*service.AIQuotaStatus, bool) {
+	if api.usage == nil {
+		return nil, true
+	}
+
+	status, err := api.usage.CheckQuota(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrAIQuotaExceeded) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":    err.Error(),
+				"exceeded": status.Exceeded,
+				"quota":    status,
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check ai usage quota"})
+		return nil, false
+	}
+	return status, true
+}
+
+// maxTokens picks the max_tokens policy of a run: the configured value (or
+// fallback), capped by the tokens left in the user's quota but never below
+// what the ai-service accepts
+func (api *AIRunAPI) maxTokens(configured, fallback, floor int, quota *service.AIQuotaStatus) int {
+	limit := configured
+	if limit <= 0 {
+		limit = fallback
+	}
+	if quota != nil && quota.TokensRemaining >= 0 && quota.TokensRemaining < int64(limit) {
+		limit = max(int(quota.TokensRemaining), floor)
+	}
+	return limit
+}
+
+// quotaPayload describes the user's quota to the ai-service
+func quotaPayload(quota *service.AIQuotaStatus) map[string]interface{} {
+	if quota == nil {
+		return map[string]interface{}{
+			"tokens_remaining": -1,
+			"calls_today":      0,
+			"tier":             "free",
+		}
+	}
+	return map[string]interface{}{
+		"tokens_remaining": quota.TokensRemaining,
+		"calls_today":      quota.Today.Runs,
+		"tier":             quota.Quota.Tier,
+	}
+}
+
+// recordRunUsage stores the usage carried by a run.completed event
+func (api *AIRunAPI) recordRunUsage(ctx context.Context, runID string, payload map[string]interface{}) {
+	if api.usage == nil {
+		return
+	}
+
+	usage, _ := payload["usage"].(map[string]interface{})
+	model, _ := payload["model_name"].(string)
+	tokens := func(key string) int64 {
+		value, _ := usage[key].(float64)
+		return int64(value)
+	}
+	if err := api.usage.RecordRunUsage(ctx, runID, service.AIRunUsage{
+		Model:            model,
+		PromptTokens:     tokens("prompt_tokens"),
+		CompletionTokens: tokens("completion_tokens"),
+		TotalTokens:      tokens("total_tokens"),
+	}); err != nil {
+		log.Printf("Warning: failed to record usage of ai run %s: %v", runID, err)
+	}
+}
+
+// recordResponseUsage stores the usage carried by a non-streaming ai-service
+// response, which has the same usage and model_name fields as run.completed
+func (api *AIRunAPI) recordResponseUsage(ctx context.Context, runID string, raw []byte) {
+	if api.usage == nil {
+		return
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(raw, &response); err != nil {
+		log.Printf("Warning: failed to read usage of ai run %s: %v", runID, err)
+		return
+	}
+	api.recordRunUsage(ctx, runID, response)
+}
+
+// Get /api/v1/ai/usage?period=day|month
+// Report the user's AI usage broken down by mode and tool, with their quota
+func (api *AIRunAPI) GetUsage(c *gin.Context) {
+	if api.usage == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "ai usage service unavailable"})
+		return
+	}
+
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	user := userVal.(*dbmodels.User)
+
+	report, err := api.usage.GetUsage(c.Request.Context(), user.ID, c.Query("period"))
+	if err != nil {
+		if errors.Is(err, service.ErrValidationFailed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day or month"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load ai usage"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -109,3 +109,14 @@ func (api *AIAPI) ProvideAiRunConsent(c *gin.Context) {
 
 	api.aiRun.ProvideConsent(c)
 }
+
+// Get /api/v1/ai/runs/:run_id/events
+// Replay and follow the event stream of an AI run
+func (api *AIAPI) StreamAiRunEvents(c *gin.Context) {
+	if api.aiRun == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "ai run service unavailable"})
+		return
+	}
+
+	api.aiRun.StreamRunEvents(c)
+}
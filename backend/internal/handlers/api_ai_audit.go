@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// aiAuditExportBatch is how many runs an export loads at a time
+const aiAuditExportBatch = 200
+
+// AIAuditAPI lets admins inspect what AI runs did, down to each tool call
+type AIAuditAPI struct {
+	config       *config.Config
+	auditService service.AIAuditService
+}
+
+var _ interfaces.AIAuditAPIHandler = (*AIAuditAPI)(nil)
+
+// NewAIAuditAPI creates a new AI audit API
+func NewAIAuditAPI(cfg *config.Config, auditService service.AIAuditService) *AIAuditAPI {
+	return &AIAuditAPI{
+		config:       cfg,
+		auditService: auditService,
+	}
+}
+
+// Get /api/v1/admin/ai/runs?user_id=&note_id=&status=&from=&to=&limit=&offset=
+// List AI runs with their tool calls, newest first
+func (api *AIAuditAPI) ListRuns(c *gin.Context) {
+	if !api.requireAdmin(c) {
+		return
+	}
+	filter, ok := parseAIRunAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	runs, total, err := api.auditService.ListRuns(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs, "total": total})
+}
+
+// Get /api/v1/admin/ai/runs/:run_id
+// Get a single run with its tool calls and the note versions it wrote
+func (api *AIAuditAPI) GetRun(c *gin.Context) {
+	if !api.requireAdmin(c) {
+		return
+	}
+
+	run, err := api.auditService.GetRun(c.Request.Context(), c.Param("run_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrAIRunNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// Get /api/v1/admin/ai/runs/export?format=jsonl|csv (same filters as ListRuns)
+// Export every matching run. JSONL has one run per line, CSV one tool call per row.
+func (api *AIAuditAPI) ExportRuns(c *gin.Context) {
+	if !api.requireAdmin(c) {
+		return
+	}
+	filter, ok := parseAIRunAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "jsonl")
+	var write func([]service.AIRunAudit) error
+	switch format {
+	case "jsonl":
+		c.Header("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(c.Writer)
+		write = func(runs []service.AIRunAudit) error {
+			for _, run := range runs {
+				if err := encoder.Encode(run); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case "csv":
+		c.Header("Content-Type", "text/csv")
+		writer := csv.NewWriter(c.Writer)
+		header := false
+		write = func(runs []service.AIRunAudit) error {
+			if !header {
+				header = true
+				if err := writer.Write(aiAuditCSVHeader); err != nil {
+					return err
+				}
+			}
+			for _, run := range runs {
+				if err := writer.WriteAll(aiAuditCSVRows(run)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be jsonl or csv"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="ai-runs-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format))
+	c.Status(http.StatusOK)
+
+	filter.Limit = aiAuditExportBatch
+	for {
+		runs, _, err := api.auditService.ListRuns(c.Request.Context(), filter)
+		if err != nil || write(runs) != nil {
+			// Headers are gone, all we can do is cut the file short
+			return
+		}
+		c.Writer.Flush()
+		if len(runs) < filter.Limit {
+			return
+		}
+		// Page by position rather than offset so runs created during the
+		// export do not shift later batches
+		last := runs[len(runs)-1]
+		filter.Before = &repository.AIRunAuditCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		filter.Offset = 0
+	}
+}
+
+// requireAdmin only lets the users listed in admin.emails through, once they
+// have verified that address
+func (api *AIAuditAPI) requireAdmin(c *gin.Context) bool {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return false
+	}
+	u := userVal.(*dbmodels.User)
+	if !u.EmailVerified {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return false
+	}
+
+	for _, email := range api.config.Admin.Emails {
+		if strings.EqualFold(strings.TrimSpace(email), u.Email) {
+			return true
+		}
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	return false
+}
+
+func parseAIRunAuditFilter(c *gin.Context) (repository.AIRunAuditFilter, bool) {
+	filter := repository.AIRunAuditFilter{
+		UserID: c.Query("user_id"),
+		NoteID: c.Query("note_id"),
+		Status: dbmodels.AIRunStatus(c.Query("status")),
+	}
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		filter.Limit = v
+	}
+	if v, err := strconv.Atoi(c.Query("offset")); err == nil && v > 0 {
+		filter.Offset = v
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		raw := c.Query(bound.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := parseAuditTime(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": bound.name + " must be RFC 3339 or YYYY-MM-DD"})
+			return filter, false
+		}
+		*bound.target = &parsed
+	}
+	return filter, true
+}
+
+func parseAuditTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
+
+var aiAuditCSVHeader = []string{
+	"run_id", "user_id", "workspace_id", "note_id", "mode", "status", "run_created_at",
+	"total_tokens", "cost_usd", "tool_call_id", "tool", "args", "called_at", "ok",
+	"consent_status", "approved_by", "approved_at", "tool_note_id", "note_version",
+}
+
+// aiAuditCSVRows flattens a run into one row per tool call, or a single row
+// when it made none
+func aiAuditCSVRows(run service.AIRunAudit) [][]string {
+	base := []string{
+		run.RunID,
+		run.UserID,
+		run.WorkspaceID,
+		run.NoteID,
+		string(run.Mode),
+		string(run.Status),
+		run.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.FormatInt(run.TotalTokens, 10),
+		strconv.FormatFloat(run.CostUSD, 'f', 6, 64),
+	}
+	if len(run.ToolCalls) == 0 {
+		return [][]string{append(base, make([]string, len(aiAuditCSVHeader)-len(base))...)}
+	}
+
+	rows := make([][]string, 0, len(run.ToolCalls))
+	for _, call := range run.ToolCalls {
+		row := append([]string{}, base...)
+		row = append(row,
+			call.ToolCallID,
+			call.Tool,
+			string(call.Args),
+			formatAuditTime(call.CalledAt),
+			formatAuditBool(call.OK),
+			string(call.ConsentStatus),
+			call.ApprovedBy,
+			formatAuditTime(call.ApprovedAt),
+			call.NoteID,
+		)
+		if call.NoteVersion != nil {
+			row = append(row, strconv.Itoa(*call.NoteVersion))
+		} else {
+			row = append(row, "")
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func formatAuditTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatAuditBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+)
+
+type aiAuditHandlerTestService struct {
+	service.AIAuditService
+	runs    []service.AIRunAudit
+	filters []repository.AIRunAuditFilter
+}
+
+// ListRuns pages through runs the way the repository does, newest first
+func (s *aiAuditHandlerTestService) ListRuns(_ context.Context, filter repository.AIRunAuditFilter) ([]service.AIRunAudit, int64, error) {
+	s.filters = append(s.filters, filter)
+	start := filter.Offset
+	if filter.Before != nil {
+		for start = 0; start < len(s.runs); start++ {
+			if s.runs[start].ID == filter.Before.ID {
+				start++
+				break
+			}
+		}
+	}
+	end := min(start+filter.Limit, len(s.runs))
+	return s.runs[start:end], int64(len(s.runs)), nil
+}
+
+func newAIAuditHandlerTestRouter(audit service.AIAuditService, user *models.User) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Admin.Emails = []string{"admin@example.com"}
+	api := NewAIAuditAPI(cfg, audit)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", user)
+		c.Next()
+	})
+	router.GET("/api/v1/admin/ai/runs", api.ListRuns)
+	router.GET("/api/v1/admin/ai/runs/export", api.ExportRuns)
+	return router
+}
+
+func TestAIAuditRequiresVerifiedAdminEmail(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		user *models.User
+		want int
+	}{
+		{"unverified admin address", &models.User{Email: "admin@example.com"}, http.StatusForbidden},
+		{"verified other address", &models.User{Email: "someone@example.com", EmailVerified: true}, http.StatusForbidden},
+		{"verified admin address", &models.User{Email: "ADMIN@example.com", EmailVerified: true}, http.StatusOK},
+	} {
+		router := newAIAuditHandlerTestRouter(&aiAuditHandlerTestService{}, tc.user)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/admin/ai/runs", nil))
+		require.Equal(t, tc.want, response.Code, tc.name)
+	}
+}
+
+func TestAIAuditExportPagesByCursor(t *testing.T) {
+	t.Parallel()
+
+	audit := &aiAuditHandlerTestService{}
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < aiAuditExportBatch+1; i++ {
+		run := models.AIRun{RunID: "run", UserID: "user-1"}
+		run.ID = strings.Repeat("x", i+1)
+		run.CreatedAt = createdAt.Add(-time.Duration(i) * time.Second)
+		audit.runs = append(audit.runs, service.AIRunAudit{AIRun: run})
+	}
+	router := newAIAuditHandlerTestRouter(audit, &models.User{Email: "admin@example.com", EmailVerified: true})
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/admin/ai/runs/export", nil))
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Len(t, strings.Split(strings.TrimSpace(response.Body.String()), "\n"), aiAuditExportBatch+1)
+	require.Len(t, audit.filters, 2)
+	require.Nil(t, audit.filters[0].Before)
+	last := audit.runs[aiAuditExportBatch-1]
+	require.Equal(t, &repository.AIRunAuditCursor{CreatedAt: last.CreatedAt, ID: last.ID}, audit.filters[1].Before)
+	require.Zero(t, audit.filters[1].Offset)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AIEditProposalAPI lets anyone who may edit a note review, accept and reject
+// the AI edit proposals made for it
+type AIEditProposalAPI struct {
+	noteService     service.NoteService
+	shareService    service.NoteShareService
+	proposalService service.AIEditProposalService
+}
+
+var _ interfaces.AIEditProposalAPIHandler = (*AIEditProposalAPI)(nil)
+
+// NewAIEditProposalAPI creates a new AI edit proposal API
+func NewAIEditProposalAPI(noteService service.NoteService, shareService service.NoteShareService, proposalService service.AIEditProposalService) *AIEditProposalAPI {
+	return &AIEditProposalAPI{
+		noteService:     noteService,
+		shareService:    shareService,
+		proposalService: proposalService,
+	}
+}
+
+// Get /api/v1/notes/:note_id/edit-proposals?status=pending
+// List the edit proposals of a note (editors only)
+func (api *AIEditProposalAPI) ListProposals(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	noteID := c.Param("note_id")
+	if !api.canEditNote(c, noteID, u.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
+		return
+	}
+
+	status := dbmodels.AIEditProposalStatus(c.Query("status"))
+	switch status {
+	case "", dbmodels.AIEditProposalStatusPending, dbmodels.AIEditProposalStatusApplying, dbmodels.AIEditProposalStatusAccepted, dbmodels.AIEditProposalStatusRejected:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, applying, accepted or rejected"})
+		return
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	proposals, err := api.proposalService.ListProposals(c.Request.Context(), noteID, status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"proposals": proposals})
+}
+
+// Get /api/v1/ai/edit-proposals/:proposal_id
+// Get a single proposal including original and proposed content
+func (api *AIEditProposalAPI) GetProposal(c *gin.Context) {
+	proposal, ok := api.editableProposal(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, proposal)
+}
+
+// Get /api/v1/ai/edit-proposals/:proposal_id/diff
+// Render a proposal as a line diff with acceptable hunks
+func (api *AIEditProposalAPI) DiffProposal(c *gin.Context) {
+	proposal, ok := api.editableProposal(c)
+	if !ok {
+		return
+	}
+
+	diff, err := api.proposalService.DiffProposal(c.Request.Context(), proposal.ID)
+	if err != nil {
+		writeEditProposalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// Post /api/v1/ai/edit-proposals/:proposal_id/accept
+// Apply all hunks of a proposal, or only those listed in "hunks"
+func (api *AIEditProposalAPI) AcceptProposal(c *gin.Context) {
+	proposal, ok := api.editableProposal(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Hunks []int `json:"hunks"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+			return
+		}
+	}
+
+	ctx := service.WithRevisionActor(c.Request.Context(), service.RevisionActor{
+		Source:   dbmodels.NoteRevisionSourceAI,
+		AuthorID: proposal.UserID,
+		RunID:    proposal.RunID,
+	})
+	result, err := api.proposalService.AcceptProposal(ctx, proposal.ID, body.Hunks)
+	if err != nil {
+		writeEditProposalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Post /api/v1/ai/edit-proposals/:proposal_id/reject
+// Reject a proposal, leaving the note untouched
+func (api *AIEditProposalAPI) RejectProposal(c *gin.Context) {
+	proposal, ok := api.editableProposal(c)
+	if !ok {
+		return
+	}
+
+	rejected, err := api.proposalService.RejectProposal(c.Request.Context(), proposal.ID)
+	if err != nil {
+		writeEditProposalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rejected)
+}
+
+// editableProposal loads the proposal in the path when the user may edit its
+// note. Others get a 404, as if it did not exist.
+func (api *AIEditProposalAPI) editableProposal(c *gin.Context) (*dbmodels.AIEditProposal, bool) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+	u := userVal.(*dbmodels.User)
+
+	proposal, err := api.proposalService.GetProposal(c.Request.Context(), c.Param("proposal_id"))
+	if err != nil {
+		writeEditProposalError(c, err)
+		return nil, false
+	}
+	if !api.canEditNote(c, proposal.NoteID, u.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": service.ErrEditProposalNotFound.Error()})
+		return nil, false
+	}
+	return proposal, true
+}
+
+// canEditNote reports whether the user holds at least the editor role on the
+// note, as owner, workspace member or through a share
+func (api *AIEditProposalAPI) canEditNote(c *gin.Context, noteID, userID string) bool {
+	note, err := api.noteService.GetNoteByID(c.Request.Context(), noteID)
+	if err != nil {
+		return false
+	}
+	if note.UserID == userID {
+		return true
+	}
+	if api.shareService == nil {
+		return false
+	}
+	role, err := api.shareService.NoteRole(c.Request.Context(), note, userID)
+	if err != nil {
+		log.Printf("Warning: failed to resolve role on note %s: %v", note.ID, err)
+		return false
+	}
+	return role.CanEdit()
+}
+
+func writeEditProposalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrEditProposalNotFound), errors.Is(err, service.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrValidationFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEditProposalResolved), errors.Is(err, service.ErrEditProposalConflict), errors.Is(err, service.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
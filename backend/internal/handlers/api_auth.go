@@ -80,7 +80,7 @@ func (api *AuthAPI) Login(c *gin.Context) {
 		return
 	}
 
-	tokens, err := api.authService.Login(c.Request.Context(), req)
+	tokens, err := api.authService.Login(clientContext(c), req)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -133,7 +133,7 @@ func (api *AuthAPI) Register(c *gin.Context) {
 		return
 	}
 
-	tokens, err := api.authService.Register(c.Request.Context(), req)
+	tokens, err := api.authService.Register(clientContext(c), req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -169,7 +169,7 @@ func (api *AuthAPI) RefreshToken(c *gin.Context) {
 	}
 
 	// 2. Gọi Service để verify và tạo cặp token mới
-	tokens, err := api.authService.RefreshToken(c.Request.Context(), refreshToken)
+	tokens, err := api.authService.RefreshToken(clientContext(c), refreshToken)
 	if err != nil {
 		// QUAN TRỌNG: Nếu token hết hạn hoặc không hợp lệ, xóa luôn Cookie cũ
 		// để trình duyệt sạch sẽ và trả về 401.
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+var _ interfaces.AuthAccountAPIHandler = (*AuthAPI)(nil)
+
+type accountTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// Post /api/v1/auth/verify-email
+// Verify an email address with the token from a verification link
+func (api *AuthAPI) VerifyEmail(c *gin.Context) {
+	var req accountTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	if err := api.authService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// Post /api/v1/auth/verify-email/resend
+// Email the current user a new verification link
+func (api *AuthAPI) ResendVerificationEmail(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	if err := api.authService.SendVerificationEmail(c.Request.Context(), u.ID); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// Post /api/v1/auth/forgot-password
+// Email a password reset link. The response is the same whether or not the
+// address has an account.
+func (api *AuthAPI) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	if err := api.authService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the address has an account, a reset link is on its way"})
+}
+
+// Post /api/v1/auth/reset-password
+// Set a new password with the token from a reset link, signing out everywhere
+func (api *AuthAPI) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	if err := api.authService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	clearAuthCookies(c, api.config)
+	c.JSON(http.StatusOK, gin.H{"message": "password reset, please sign in again"})
+}
+
+// Post /api/v1/auth/change-password
+// Change the current user's password, signing out every other device
+func (api *AuthAPI) ChangePassword(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	err := api.authService.ChangePassword(c.Request.Context(), u.ID, api.currentSessionID(c), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
+}
+
+func writeAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAccountTokenInvalid),
+		errors.Is(err, service.ErrPasswordNotSet):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCurrentPasswordInvalid):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrValidationFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password must be 8-72 characters"})
+	case errors.Is(err, service.ErrNotImplemented):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "email delivery is not configured"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
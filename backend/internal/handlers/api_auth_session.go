@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+var _ interfaces.AuthSessionAPIHandler = (*AuthAPI)(nil)
+
+// Get /api/v1/auth/sessions
+// List the current user's signed-in devices, marking this one as current
+func (api *AuthAPI) ListSessions(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	sessions, err := api.authService.ListSessions(c.Request.Context(), u.ID, api.currentSessionID(c))
+	if err != nil {
+		writeSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// Delete /api/v1/auth/sessions/:session_id
+// Sign one device out. Revoking the current session also clears its cookies.
+func (api *AuthAPI) RevokeSession(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	sessionID := c.Param("session_id")
+	if err := api.authService.RevokeSession(c.Request.Context(), u.ID, sessionID); err != nil {
+		writeSessionError(c, err)
+		return
+	}
+	if sessionID == api.currentSessionID(c) {
+		clearAuthCookies(c, api.config)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Delete /api/v1/auth/sessions?keep_current=true
+// Sign out everywhere, optionally keeping this device signed in
+func (api *AuthAPI) RevokeAllSessions(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	keepCurrent := c.Query("keep_current") == "true"
+	except := ""
+	if keepCurrent {
+		except = api.currentSessionID(c)
+	}
+
+	revoked, err := api.authService.RevokeAllSessions(c.Request.Context(), u.ID, except)
+	if err != nil {
+		writeSessionError(c, err)
+		return
+	}
+	if !keepCurrent {
+		clearAuthCookies(c, api.config)
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+// currentSessionID returns the session of the token the request was made with
+func (api *AuthAPI) currentSessionID(c *gin.Context) string {
+	return api.authService.SessionIDFromToken(extractTokenFromRequest(c))
+}
+
+// clientContext attaches the caller's address and user agent so sessions
+// created or refreshed by the request can show where they are used
+func clientContext(c *gin.Context) context.Context {
+	return service.WithClientInfo(c.Request.Context(), service.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+}
+
+func writeSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
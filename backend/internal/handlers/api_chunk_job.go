@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ChunkJobAPI reports chunking health and re-drives chunking jobs
+type ChunkJobAPI struct {
+	chunkingService service.ChunkingService
+	noteService     service.NoteService
+}
+
+var _ interfaces.ChunkJobAPIHandler = (*ChunkJobAPI)(nil)
+
+// NewChunkJobAPI creates a new chunk job API
+func NewChunkJobAPI(chunkingService service.ChunkingService, noteService service.NoteService) *ChunkJobAPI {
+	return &ChunkJobAPI{
+		chunkingService: chunkingService,
+		noteService:     noteService,
+	}
+}
+
+// Get /api/v1/notes/:note_id/chunks/status
+// Report whether a note's chunks are fresh, stale, missing, queued or dead
+func (api *ChunkJobAPI) GetNoteChunkStatus(c *gin.Context) {
+	noteID := c.Param("note_id")
+	if !api.ownsNote(c, noteID) {
+		return
+	}
+
+	status, err := api.chunkingService.GetNoteChunkStatus(c.Request.Context(), noteID)
+	if err != nil {
+		writeChunkJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Post /api/v1/notes/:note_id/chunks/reindex
+// Queue a note for chunking right away
+func (api *ChunkJobAPI) ReindexNote(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	queued, err := api.chunkingService.Requeue(c.Request.Context(), u.ID, []string{c.Param("note_id")})
+	if err != nil {
+		writeChunkJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"queued": queued})
+}
+
+// Get /api/v1/chunks/status
+// List the current user's notes whose chunks are stale, missing, queued or dead
+func (api *ChunkJobAPI) ListChunkStatus(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	notes, err := api.chunkingService.ListUnhealthyNotes(c.Request.Context(), u.ID, limit)
+	if err != nil {
+		writeChunkJobError(c, err)
+		return
+	}
+
+	counts := map[string]int{
+		service.ChunkStateStale:   0,
+		service.ChunkStateMissing: 0,
+		service.ChunkStateQueued:  0,
+		service.ChunkStateDead:    0,
+	}
+	for _, note := range notes {
+		counts[note.State]++
+	}
+	c.JSON(http.StatusOK, gin.H{"notes": notes, "counts": counts, "total": len(notes)})
+}
+
+// Post /api/v1/chunks/reindex
+// Re-drive the given notes, or every stale, missing or dead note when none are given
+func (api *ChunkJobAPI) ReindexNotes(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var body struct {
+		NoteIDs []string `json:"note_ids"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+			return
+		}
+	}
+
+	queued, err := api.chunkingService.Requeue(c.Request.Context(), u.ID, body.NoteIDs)
+	if err != nil {
+		writeChunkJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"queued": queued})
+}
+
+// Get /api/v1/chunks/jobs
+// List the current user's chunking jobs, optionally filtered by status
+func (api *ChunkJobAPI) ListChunkJobs(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var status *dbmodels.ChunkJobStatus
+	if raw := c.Query("status"); raw != "" {
+		s := dbmodels.ChunkJobStatus(raw)
+		switch s {
+		case dbmodels.ChunkJobStatusPending, dbmodels.ChunkJobStatusRunning, dbmodels.ChunkJobStatusSucceeded, dbmodels.ChunkJobStatusDead:
+			status = &s
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, running, succeeded, dead"})
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	jobs, err := api.chunkingService.ListJobs(c.Request.Context(), u.ID, status, limit)
+	if err != nil {
+		writeChunkJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs, "total": len(jobs)})
+}
+
+func (api *ChunkJobAPI) ownsNote(c *gin.Context, noteID string) bool {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return false
+	}
+	u := userVal.(*dbmodels.User)
+
+	note, err := api.noteService.GetNoteByID(c.Request.Context(), noteID)
+	if err != nil || note.UserID != u.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
+		return false
+	}
+	return true
+}
+
+func writeChunkJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotImplemented):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "chunking is not configured"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -10,11 +10,15 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
 	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/domain"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/dto"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
@@ -25,9 +29,10 @@ import (
 )
 
 type CollabAPI struct {
-	noteService service.NoteService
-	authService service.AuthService
-	config      *config.Config
+	noteService  service.NoteService
+	authService  service.AuthService
+	shareService service.NoteShareService
+	config       *config.Config
 }
 
 var _ interfaces.CollabAPIHandler = (*CollabAPI)(nil)
@@ -39,11 +44,12 @@ type collabClaims struct {
 	jwt.RegisteredClaims
 }
 
-func NewCollabAPI(noteService service.NoteService, authService service.AuthService, cfg *config.Config) *CollabAPI {
+func NewCollabAPI(noteService service.NoteService, authService service.AuthService, shareService service.NoteShareService, cfg *config.Config) *CollabAPI {
 	return &CollabAPI{
-		noteService: noteService,
-		authService: authService,
-		config:      cfg,
+		noteService:  noteService,
+		authService:  authService,
+		shareService: shareService,
+		config:       cfg,
 	}
 }
 
@@ -86,23 +92,49 @@ func (api *CollabAPI) CreateCollabToken(c *gin.Context) {
 }
 
 func (api *CollabAPI) resolveEditorIdentity(c *gin.Context, note *dbmodels.Note, editToken string) (string, string) {
-	// Try owner auth (bearer or cookie)
+	// Try owner or shared user auth (bearer or cookie)
+	var sharedRole domain.CollabRole
+	var sharedUserID string
 	token := extractTokenFromRequest(c)
 	if token != "" {
 		user, err := api.authService.ValidateToken(c.Request.Context(), token)
-		if err == nil && note.UserID == user.ID {
-			return "owner", user.ID
+		if err == nil {
+			if note.UserID == user.ID {
+				return string(domain.CollabRoleOwner), user.ID
+			}
+			sharedRole, sharedUserID = sharedCollabRole(c.Request.Context(), api.shareService, note, user.ID), user.ID
+			if sharedRole.CanEdit() {
+				return string(sharedRole), user.ID
+			}
 		}
 	}
 
 	// Public edit token
 	if editToken != "" && note.PublicEditEnabled && note.PublicEditToken == editToken {
-		return "public_edit", "anon-" + uuid.NewString()
+		return string(domain.CollabRolePublicEdit), "anon-" + uuid.NewString()
+	}
+
+	// Viewers and commenters join read-only
+	if sharedRole != "" {
+		return string(sharedRole), sharedUserID
 	}
 
 	return "", ""
 }
 
+// sharedCollabRole is the collab role a note's shares give a user, if any
+func sharedCollabRole(ctx context.Context, shareService service.NoteShareService, note *dbmodels.Note, userID string) domain.CollabRole {
+	if shareService == nil {
+		return ""
+	}
+	role, err := shareService.NoteRole(ctx, note, userID)
+	if err != nil {
+		log.Printf("Warning: failed to resolve role on note %s: %v", note.ID, err)
+		return ""
+	}
+	return domain.CollabRoleFor(role)
+}
+
 func (api *CollabAPI) signCollabToken(noteID, userID, role string) (string, error) {
 	now := time.Now()
 	claims := collabClaims{
@@ -118,6 +150,21 @@ func (api *CollabAPI) signCollabToken(noteID, userID, role string) (string, erro
 	return token.SignedString([]byte(api.config.Collab.TokenSecret))
 }
 
+// parseCollabToken verifies a token issued by signCollabToken
+func parseCollabToken(secret, tokenString string) (*collabClaims, error) {
+	claims := &collabClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.NoteID == "" || claims.UserID == "" {
+		return nil, fmt.Errorf("collab token is missing note or user")
+	}
+	return claims, nil
+}
+
 func toResCollabTokenNote(note *dbmodels.Note) dto.ResCollabTokenNote {
 	tags := make([]string, 0, len(note.Tags))
 	for _, tag := range note.Tags {
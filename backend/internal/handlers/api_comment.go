@@ -10,6 +10,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
@@ -58,6 +59,10 @@ func (api *CommentAPI) CreateComment(c *gin.Context) {
 		ParentID: req.ParentId,
 	})
 	if err != nil {
+		if errors.Is(err, service.ErrCommentForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -98,10 +103,17 @@ func (api *CommentAPI) DeleteComment(c *gin.Context) {
 func (api *CommentAPI) ListComments(c *gin.Context) {
 	noteID := c.Param("note_id")
 
-	comments, err := api.commentService.ListCommentsByNoteID(c.Request.Context(), noteID)
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	comments, err := api.commentService.ListCommentsByNoteID(c.Request.Context(), noteID, u.ID)
 	if err != nil {
-		if err.Error() == "note not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if err.Error() == "note not found" || errors.Is(err, service.ErrNoteForbidden) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
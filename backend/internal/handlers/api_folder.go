@@ -90,7 +90,7 @@ func (api *FolderAPI) AddNoteToFolder(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	if folder.UserID != u.ID {
+	if folder.UserID != u.ID && !canWriteInWorkspace(c, folder.WorkspaceID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "folder not found"})
 		return
 	}
@@ -142,6 +142,9 @@ func (api *FolderAPI) CreateFolder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
 		return
 	}
+	if !requireWorkspaceWrite(c) {
+		return
+	}
 
 	// Prepare parent ID
 	var parentID *string
@@ -150,11 +153,12 @@ func (api *FolderAPI) CreateFolder(c *gin.Context) {
 	}
 
 	folder, err := api.folderService.CreateFolder(c.Request.Context(), service.CreateFolderRequest{
-		Name:     body.Name,
-		IsPublic: body.IsPublic,
-		UserID:   u.ID,
-		ParentID: parentID,
-		Order:    body.Order,
+		Name:        body.Name,
+		IsPublic:    body.IsPublic,
+		UserID:      u.ID,
+		ParentID:    parentID,
+		Order:       body.Order,
+		WorkspaceID: activeWorkspaceID(c),
 	})
 	if err != nil {
 		if errors.Is(err, service.ErrFolderNotFound) {
@@ -191,7 +195,7 @@ func (api *FolderAPI) DeleteFolder(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	if folder.UserID != u.ID {
+	if folder.UserID != u.ID && !canWriteInWorkspace(c, folder.WorkspaceID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "folder not found"})
 		return
 	}
@@ -231,8 +235,8 @@ func (api *FolderAPI) GetFolder(c *gin.Context) {
 		return
 	}
 
-	// Check ownership or public access
-	if folder.UserID != u.ID && !folder.IsPublic {
+	// Check ownership, workspace membership or public access
+	if folder.UserID != u.ID && !folder.IsPublic && !inActiveWorkspace(c, folder.WorkspaceID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "folder not found"})
 		return
 	}
@@ -276,8 +280,9 @@ func (api *FolderAPI) ListFolders(c *gin.Context) {
 
 	// Prepare params
 	params := repository.FolderListParams{
-		Page:  page,
-		Limit: limit,
+		Page:        page,
+		Limit:       limit,
+		WorkspaceID: activeWorkspaceID(c),
 	}
 	// Check if parent_id query param exists (even if empty)
 	if parentID, exists := c.GetQuery("parent_id"); exists {
@@ -327,7 +332,7 @@ func (api *FolderAPI) UpdateFolder(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	if existingFolder.UserID != u.ID {
+	if existingFolder.UserID != u.ID && !canWriteInWorkspace(c, existingFolder.WorkspaceID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "folder not found"})
 		return
 	}
@@ -370,6 +375,7 @@ type FolderResponse struct {
 	ID              string      `json:"id"`
 	Name            string      `json:"name"`
 	ParentID        string      `json:"parent_id"`
+	WorkspaceID     string      `json:"workspace_id,omitempty"`
 	IsPublic        bool        `json:"is_public"`
 	Order           int         `json:"order"`
 	Notes           []FolderRef `json:"notes"`
@@ -399,11 +405,16 @@ func toFolderResponse(folder *dbmodels.Folder) FolderResponse {
 	if folder.ParentID != nil {
 		parentID = *folder.ParentID
 	}
+	workspaceID := ""
+	if folder.WorkspaceID != nil {
+		workspaceID = *folder.WorkspaceID
+	}
 
 	return FolderResponse{
 		ID:              folder.ID,
 		Name:            folder.Name,
 		ParentID:        parentID,
+		WorkspaceID:     workspaceID,
 		IsPublic:        folder.IsPublic,
 		Order:           folder.SortOrder,
 		Notes:           noteRefs,
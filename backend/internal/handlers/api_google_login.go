@@ -162,7 +162,7 @@ func (api *GoogleLoginAPI) OAuthCallback(c *gin.Context) {
 
 	// Perform login/registration
 	authTokens, err := api.authService.GoogleLogin(
-		c.Request.Context(),
+		clientContext(c),
 		userInfo.ID,
 		userInfo.Email,
 		userInfo.VerifiedEmail,
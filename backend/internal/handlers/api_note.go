@@ -10,6 +10,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -25,8 +26,9 @@ import (
 )
 
 type NoteAPI struct {
-	noteService service.NoteService
-	authService service.AuthService
+	noteService  service.NoteService
+	authService  service.AuthService
+	shareService service.NoteShareService
 }
 
 var _ interfaces.NoteAPIHandler = (*NoteAPI)(nil)
@@ -58,6 +60,9 @@ func (api *NoteAPI) CreateNote(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
 		return
 	}
+	if !requireWorkspaceWrite(c) {
+		return
+	}
 
 	created, err := api.noteService.CreateNote(c.Request.Context(), service.CreateNoteRequest{
 		Title:       body.Title,
@@ -68,6 +73,7 @@ func (api *NoteAPI) CreateNote(c *gin.Context) {
 		FolderID:    body.FolderID,
 		IsPublic:    body.IsPublic,
 		UserID:      u.ID,
+		WorkspaceID: activeWorkspaceID(c),
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -99,18 +105,21 @@ func (api *NoteAPI) GetNote(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	// ownership check
+	// access check: owners and anyone the note is shared with
 	userVal, _ := c.Get("user")
 	u := userVal.(*dbmodels.User)
-	if note.UserID != u.ID {
+	if !api.noteRole(c.Request.Context(), note, u.ID).CanView() {
 		c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
 		return
 	}
+	if note.UserID != u.ID {
+		note.PublicEditToken = ""
+	}
 	c.JSON(http.StatusOK, note)
 }
 
 // Get /api/v1/notes/list
-// List user's notes
+// List user's notes (tag_ids and tag_match=any|all filter by tag)
 func (api *NoteAPI) ListNotes(c *gin.Context) {
 	// Optional filters
 	limitStr := c.Query("limit")
@@ -136,13 +145,14 @@ func (api *NoteAPI) ListNotes(c *gin.Context) {
 		page = (offset / limit) + 1
 	}
 
-	// list only current user's notes
+	// list the current workspace's notes, or only the user's own without one
 	userVal, _ := c.Get("user")
 	u := userVal.(*dbmodels.User)
-	params := repository.NoteListParams{Page: page, Limit: limit, Query: &query}
+	params := repository.NoteListParams{Page: page, Limit: limit, Query: &query, WorkspaceID: activeWorkspaceID(c)}
 	if folderID != "" {
 		params.FolderID = &folderID
 	}
+	params.TagIDs, params.TagMatch = parseTagFilter(c)
 	notes, total, err := api.noteService.GetNotesByUserID(c.Request.Context(), u.ID, params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -217,7 +227,7 @@ func (api *NoteAPI) UpdateNote(c *gin.Context) {
 		log.Printf("DEBUG UpdateNote - NoteID: %s, no folder_id field", idStr)
 	}
 
-	// Ensure ownership before update
+	// Ensure edit access before update
 	existing, err := api.noteService.GetNoteByID(c.Request.Context(), idStr)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -225,12 +235,26 @@ func (api *NoteAPI) UpdateNote(c *gin.Context) {
 	}
 	userVal, _ := c.Get("user")
 	u := userVal.(*dbmodels.User)
-	if existing.UserID != u.ID {
+	role := api.noteRole(c.Request.Context(), existing, u.ID)
+	if !role.CanView() {
 		c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
 		return
 	}
+	if !role.CanEdit() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	// Moving and publishing stay with the owner
+	if role != dbmodels.NoteRoleOwner && (hasFolderID || body.IsPublic != nil) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the owner can move or publish a note"})
+		return
+	}
 
-	updated, err := api.noteService.UpdateNote(c.Request.Context(), idStr, service.UpdateNoteRequest{
+	ctx := service.WithRevisionActor(c.Request.Context(), service.RevisionActor{
+		Source:   dbmodels.NoteRevisionSourceUser,
+		AuthorID: u.ID,
+	})
+	updated, err := api.noteService.UpdateNote(ctx, idStr, service.UpdateNoteRequest{
 		Title:          body.Title,
 		Content:        body.Content,
 		ContentType:    body.ContentType,
@@ -441,13 +465,14 @@ func (api *NoteAPI) SaveNoteSnapshot(c *gin.Context) {
 		return
 	}
 
-	if !api.canEditNote(c, note) {
+	actor, ok := api.resolveNoteEditor(c, note)
+	if !ok {
 		fmt.Print("[SaveSnapshot] - Can not edit note!")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	updated, err := api.noteService.SaveNoteSnapshot(c.Request.Context(), idStr, body.Content)
+	updated, err := api.noteService.SaveNoteSnapshot(service.WithRevisionActor(c.Request.Context(), actor), idStr, body.Content)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -501,13 +526,14 @@ func (api *NoteAPI) SaveNoteTiptapSnapshot(c *gin.Context) {
 		return
 	}
 
-	if !api.canEditNote(c, note) {
+	actor, ok := api.resolveNoteEditor(c, note)
+	if !ok {
 		fmt.Print("[SaveTiptapSnapshot] - Can not edit note!")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	updated, err := api.noteService.SaveNoteTiptapSnapshot(c.Request.Context(), idStr, storedTiptapContent)
+	updated, err := api.noteService.SaveNoteTiptapSnapshot(service.WithRevisionActor(c.Request.Context(), actor), idStr, storedTiptapContent)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -519,29 +545,56 @@ func (api *NoteAPI) SaveNoteTiptapSnapshot(c *gin.Context) {
 	})
 }
 
-func (api *NoteAPI) canEditNote(c *gin.Context, note *dbmodels.Note) bool {
-	// Owner via middleware
+// resolveNoteEditor checks edit access and returns who is editing, so saves
+// can be attributed in the note's revision history.
+func (api *NoteAPI) resolveNoteEditor(c *gin.Context, note *dbmodels.Note) (service.RevisionActor, bool) {
+	// Owner or editor via middleware
 	if userVal, ok := c.Get("user"); ok {
 		u := userVal.(*dbmodels.User)
-		return note.UserID == u.ID
+		if !api.canEditNote(c.Request.Context(), note, u.ID) {
+			return service.RevisionActor{}, false
+		}
+		return service.RevisionActor{Source: dbmodels.NoteRevisionSourceUser, AuthorID: u.ID}, true
 	}
 
-	// Owner via bearer token (public route)
+	// Owner or editor via bearer token (public route)
 	token := extractTokenFromRequest(c)
 	if token != "" {
 		user, err := api.authService.ValidateToken(c.Request.Context(), token)
-		if err == nil && note.UserID == user.ID {
-			return true
+		if err == nil && api.canEditNote(c.Request.Context(), note, user.ID) {
+			return service.RevisionActor{Source: dbmodels.NoteRevisionSourceUser, AuthorID: user.ID}, true
 		}
 	}
 
 	// Public edit token
 	editToken := getEditToken(c)
 	if editToken != "" && note.PublicEditEnabled && note.PublicEditToken == editToken {
-		return true
+		return service.RevisionActor{Source: dbmodels.NoteRevisionSourcePublicEdit, EditToken: editToken}, true
+	}
+
+	return service.RevisionActor{}, false
+}
+
+// noteRole resolves what the user may do with the note. Without sharing
+// wired in, only the owner has access.
+func (api *NoteAPI) noteRole(ctx context.Context, note *dbmodels.Note, userID string) dbmodels.NoteRole {
+	if note.UserID == userID {
+		return dbmodels.NoteRoleOwner
+	}
+	if api.shareService == nil {
+		return ""
 	}
+	role, err := api.shareService.NoteRole(ctx, note, userID)
+	if err != nil {
+		log.Printf("Warning: failed to resolve role on note %s: %v", note.ID, err)
+		return ""
+	}
+	return role
+}
 
-	return false
+// canEditNote reports whether the user owns the note or was shared it as editor
+func (api *NoteAPI) canEditNote(ctx context.Context, note *dbmodels.Note, userID string) bool {
+	return api.noteRole(ctx, note, userID).CanEdit()
 }
 
 func getEditToken(c *gin.Context) string {
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// NoteRevisionAPI exposes the revision history of a note to its owner and the
+// members of its workspace
+type NoteRevisionAPI struct {
+	noteService     service.NoteService
+	revisionService service.NoteRevisionService
+}
+
+var _ interfaces.NoteRevisionAPIHandler = (*NoteRevisionAPI)(nil)
+
+// NewNoteRevisionAPI creates a new note revision API
+func NewNoteRevisionAPI(noteService service.NoteService, revisionService service.NoteRevisionService) *NoteRevisionAPI {
+	return &NoteRevisionAPI{
+		noteService:     noteService,
+		revisionService: revisionService,
+	}
+}
+
+type noteRevisionSummaryResponse struct {
+	ID                   string                      `json:"id"`
+	NoteID               string                      `json:"note_id"`
+	Version              int                         `json:"version"`
+	Title                string                      `json:"title"`
+	Source               dbmodels.NoteRevisionSource `json:"source"`
+	AuthorID             *string                     `json:"author_id,omitempty"`
+	RunID                string                      `json:"run_id,omitempty"`
+	ToolCallID           string                      `json:"tool_call_id,omitempty"`
+	EditTokenFingerprint string                      `json:"edit_token_fingerprint,omitempty"`
+	RestoredFromID       *string                     `json:"restored_from_id,omitempty"`
+	CreatedAt            time.Time                   `json:"created_at"`
+}
+
+func noteRevisionSummary(revision *dbmodels.NoteRevision) noteRevisionSummaryResponse {
+	return noteRevisionSummaryResponse{
+		ID:                   revision.ID,
+		NoteID:               revision.NoteID,
+		Version:              revision.Version,
+		Title:                revision.Title,
+		Source:               revision.Source,
+		AuthorID:             revision.AuthorID,
+		RunID:                revision.RunID,
+		ToolCallID:           revision.ToolCallID,
+		EditTokenFingerprint: revision.EditTokenFingerprint,
+		RestoredFromID:       revision.RestoredFromID,
+		CreatedAt:            revision.CreatedAt,
+	}
+}
+
+// Get /api/v1/notes/:note_id/revisions
+// List revisions of a note
+func (api *NoteRevisionAPI) ListRevisions(c *gin.Context) {
+	noteID := c.Param("note_id")
+	if _, _, ok := api.revisionNote(c, noteID, false); !ok {
+		return
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
+			limit = v
+		}
+	}
+
+	revisions, err := api.revisionService.ListRevisions(c.Request.Context(), noteID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]noteRevisionSummaryResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		items = append(items, noteRevisionSummary(revision))
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": items})
+}
+
+// Get /api/v1/notes/:note_id/revisions/:revision_id
+// Get a single revision including its content
+func (api *NoteRevisionAPI) GetRevision(c *gin.Context) {
+	noteID := c.Param("note_id")
+	if _, _, ok := api.revisionNote(c, noteID, false); !ok {
+		return
+	}
+
+	revision, err := api.revisionService.GetRevision(c.Request.Context(), noteID, c.Param("revision_id"))
+	if err != nil {
+		writeNoteRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
+// Get /api/v1/notes/:note_id/revisions/:revision_id/diff?against={revision_id}
+// Diff a revision against another revision, or against the current note
+func (api *NoteRevisionAPI) DiffRevision(c *gin.Context) {
+	noteID := c.Param("note_id")
+	if _, _, ok := api.revisionNote(c, noteID, false); !ok {
+		return
+	}
+
+	diff, err := api.revisionService.DiffRevision(c.Request.Context(), noteID, c.Param("revision_id"), c.Query("against"))
+	if err != nil {
+		writeNoteRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// Post /api/v1/notes/:note_id/revisions/:revision_id/restore
+// Restore a revision as a new version of the note
+func (api *NoteRevisionAPI) RestoreRevision(c *gin.Context) {
+	noteID := c.Param("note_id")
+	_, u, ok := api.revisionNote(c, noteID, true)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		ExpectedVersion *int `json:"expected_version"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+			return
+		}
+	}
+
+	ctx := service.WithRevisionActor(c.Request.Context(), service.RevisionActor{
+		Source:   dbmodels.NoteRevisionSourceUser,
+		AuthorID: u.ID,
+	})
+	restored, err := api.revisionService.RestoreRevision(ctx, noteID, c.Param("revision_id"), body.ExpectedVersion)
+	if err != nil {
+		writeNoteRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, restored)
+}
+
+// revisionNote loads a note whose history the user may see: their own, or one
+// of the active workspace. Restoring also needs write access to the workspace.
+func (api *NoteRevisionAPI) revisionNote(c *gin.Context, noteID string, write bool) (*dbmodels.Note, *dbmodels.User, bool) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, nil, false
+	}
+	u := userVal.(*dbmodels.User)
+
+	note, err := api.noteService.GetNoteByID(c.Request.Context(), noteID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
+		return nil, nil, false
+	}
+	if note.UserID != u.ID {
+		allowed := inActiveWorkspace(c, note.WorkspaceID)
+		if write {
+			allowed = canWriteInWorkspace(c, note.WorkspaceID)
+		}
+		if !allowed {
+			c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
+			return nil, nil, false
+		}
+	}
+	return note, u, true
+}
+
+func writeNoteRevisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNoteRevisionNotFound), errors.Is(err, service.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// NoteShareAPI lets owners share notes and folders with other users as
+// viewer, commenter or editor
+type NoteShareAPI struct {
+	shareService service.NoteShareService
+}
+
+var _ interfaces.NoteShareAPIHandler = (*NoteShareAPI)(nil)
+
+// NewNoteShareAPI creates a new note share API
+func NewNoteShareAPI(shareService service.NoteShareService) *NoteShareAPI {
+	return &NoteShareAPI{shareService: shareService}
+}
+
+type updateShareRequest struct {
+	Role dbmodels.NoteRole `json:"role" binding:"required"`
+}
+
+// Get /api/v1/notes/:note_id/shares
+// List who a note is shared with (owner only)
+func (api *NoteShareAPI) ListNoteShares(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	shares, err := api.shareService.ListNoteShares(c.Request.Context(), u.ID, c.Param("note_id"))
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// Post /api/v1/notes/:note_id/shares
+// Share a note with a user by user_id or email (owner only)
+func (api *NoteShareAPI) ShareNote(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req service.ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	share, err := api.shareService.ShareNote(c.Request.Context(), u.ID, c.Param("note_id"), req)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, share)
+}
+
+// Get /api/v1/folders/:id/shares
+// List who a folder is shared with (owner only)
+func (api *NoteShareAPI) ListFolderShares(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	shares, err := api.shareService.ListFolderShares(c.Request.Context(), u.ID, c.Param("id"))
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// Post /api/v1/folders/:id/shares
+// Share a folder, and every note under it, with a user (owner only)
+func (api *NoteShareAPI) ShareFolder(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req service.ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	share, err := api.shareService.ShareFolder(c.Request.Context(), u.ID, c.Param("id"), req)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, share)
+}
+
+// Patch /api/v1/shares/:share_id
+// Change the role a share grants (owner only)
+func (api *NoteShareAPI) UpdateShare(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req updateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	share, err := api.shareService.UpdateShare(c.Request.Context(), u.ID, c.Param("share_id"), req.Role)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, share)
+}
+
+// Delete /api/v1/shares/:share_id
+// Revoke a share (owner only)
+func (api *NoteShareAPI) RevokeShare(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	if err := api.shareService.RevokeShare(c.Request.Context(), u.ID, c.Param("share_id")); err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Get /api/v1/shares/incoming
+// List the notes and folders others shared with the current user
+func (api *NoteShareAPI) ListIncomingShares(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	shares, err := api.shareService.ListSharedWithUser(c.Request.Context(), u.ID)
+	if err != nil {
+		writeShareError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+func writeShareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNoteNotFound),
+		errors.Is(err, service.ErrFolderNotFound),
+		errors.Is(err, service.ErrNoteShareNotFound),
+		errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrValidationFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be viewer, commenter or editor, and the grantee a valid user or email other than the owner"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
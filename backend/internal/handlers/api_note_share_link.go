@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// shareLinkPasswordHeader carries the password of a protected share link
+const shareLinkPasswordHeader = "X-Share-Password"
+
+// NoteShareLinkAPI manages read-only public links to notes and serves the
+// notes behind them
+type NoteShareLinkAPI struct {
+	linkService service.NoteShareLinkService
+}
+
+var _ interfaces.NoteShareLinkAPIHandler = (*NoteShareLinkAPI)(nil)
+
+// NewNoteShareLinkAPI creates a new share link API
+func NewNoteShareLinkAPI(linkService service.NoteShareLinkService) *NoteShareLinkAPI {
+	return &NoteShareLinkAPI{linkService: linkService}
+}
+
+type linkCommentRequest struct {
+	Content  string  `json:"content" binding:"required"`
+	ParentID *string `json:"parent_id"`
+}
+
+// publicLinkNote is the part of a note a share link exposes
+type publicLinkNote struct {
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	Content       string    `json:"content"`
+	TiptapContent string    `json:"tiptap_content"`
+	ContentType   string    `json:"content_type"`
+	Thumbnail     string    `json:"thumbnail"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Get /api/v1/notes/:note_id/links
+// List a note's share links (owner only)
+func (api *NoteShareLinkAPI) ListLinks(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	links, err := api.linkService.ListLinks(c.Request.Context(), u.ID, c.Param("note_id"))
+	if err != nil {
+		writeShareLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// Post /api/v1/notes/:note_id/links
+// Create a share link with optional expiry, password and comments (owner only)
+func (api *NoteShareLinkAPI) CreateLink(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req service.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	link, err := api.linkService.CreateLink(c.Request.Context(), u.ID, c.Param("note_id"), req)
+	if err != nil {
+		writeShareLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+// Patch /api/v1/share-links/:link_id
+// Change a link's label, expiry, password or comment setting (owner only)
+func (api *NoteShareLinkAPI) UpdateLink(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req service.UpdateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	link, err := api.linkService.UpdateLink(c.Request.Context(), u.ID, c.Param("link_id"), req)
+	if err != nil {
+		writeShareLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// Delete /api/v1/share-links/:link_id
+// Revoke a link without touching the note's other links (owner only)
+func (api *NoteShareLinkAPI) RevokeLink(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	if err := api.linkService.RevokeLink(c.Request.Context(), u.ID, c.Param("link_id")); err != nil {
+		writeShareLinkError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Get /api/v1/share-links/:link_id/views?days=30
+// View counts and unique visitors per day for a link (owner only)
+func (api *NoteShareLinkAPI) GetLinkAnalytics(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	days, _ := strconv.Atoi(c.Query("days"))
+	analytics, err := api.linkService.LinkAnalytics(c.Request.Context(), u.ID, c.Param("link_id"), days)
+	if err != nil {
+		writeShareLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, analytics)
+}
+
+// Get /api/v1/public/links/:slug
+// Open a note through a share link. Protected links need the password in
+// the X-Share-Password header. Every successful open counts as a view; the
+// note is still served when the view cannot be recorded.
+func (api *NoteShareLinkAPI) GetLinkedNote(c *gin.Context) {
+	link, note, err := api.linkService.OpenLink(c.Request.Context(), c.Param("slug"), c.GetHeader(shareLinkPasswordHeader))
+	if err != nil {
+		writeShareLinkError(c, err)
+		return
+	}
+
+	visitor := c.ClientIP() + "|" + c.Request.UserAgent()
+	if err := api.linkService.RecordView(c.Request.Context(), link, visitor, c.Request.Referer()); err != nil {
+		log.Printf("Warning: failed to record view of share link %s: %v", link.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"note": publicLinkNote{
+			ID:            note.ID,
+			Title:         note.Title,
+			Content:       note.Content,
+			TiptapContent: note.TiptapContent,
+			ContentType:   note.ContentType,
+			Thumbnail:     note.Thumbnail,
+			UpdatedAt:     note.UpdatedAt,
+		},
+		"link": gin.H{
+			"slug":           link.Slug,
+			"label":          link.Label,
+			"expires_at":     link.ExpiresAt,
+			"allow_comments": link.AllowComments,
+		},
+	})
+}
+
+// Get /api/v1/public/links/:slug/comments
+// List the comments on a linked note when the link allows comments
+func (api *NoteShareLinkAPI) ListLinkComments(c *gin.Context) {
+	comments, err := api.linkService.ListLinkComments(c.Request.Context(), c.Param("slug"), c.GetHeader(shareLinkPasswordHeader))
+	if err != nil {
+		writeShareLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"comments": comments})
+}
+
+// Post /api/v1/links/:slug/comments
+// Comment on a linked note as the signed-in user when the link allows it
+func (api *NoteShareLinkAPI) AddLinkComment(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req linkCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	comment, err := api.linkService.AddLinkComment(c.Request.Context(), c.Param("slug"), c.GetHeader(shareLinkPasswordHeader), service.CreateCommentRequest{
+		UserID:   u.ID,
+		Content:  req.Content,
+		ParentID: req.ParentID,
+	})
+	if err != nil {
+		writeShareLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, comment)
+}
+
+func writeShareLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNoteNotFound),
+		errors.Is(err, service.ErrShareLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrShareLinkUnavailable):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrShareLinkPasswordRequired),
+		errors.Is(err, service.ErrShareLinkPasswordInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrShareLinkCommentsDisabled),
+		errors.Is(err, service.ErrCommentForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrValidationFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "label must be at most 100 characters, expires_at in the future and password 4-72 characters"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+)
+
+type shareLinkHandlerTestService struct {
+	service.NoteShareLinkService
+}
+
+func (shareLinkHandlerTestService) OpenLink(_ context.Context, slug, _ string) (*models.NoteShareLink, *models.Note, error) {
+	link := &models.NoteShareLink{Slug: slug}
+	note := &models.Note{Title: "Shared"}
+	note.ID = "note-1"
+	return link, note, nil
+}
+
+// RecordView fails as if the analytics table were unavailable
+func (shareLinkHandlerTestService) RecordView(context.Context, *models.NoteShareLink, string, string) error {
+	return service.ErrInternalServerError
+}
+
+func TestGetLinkedNoteServesNoteWhenViewIsNotRecorded(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/public/links/:slug", NewNoteShareLinkAPI(shareLinkHandlerTestService{}).GetLinkedNote)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/public/links/abc", nil))
+
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Contains(t, response.Body.String(), `"title":"Shared"`)
+}
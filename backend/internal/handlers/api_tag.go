@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TagAPI handles per-user tags and tagging notes
+type TagAPI struct {
+	tagService  service.TagService
+	noteService service.NoteService
+}
+
+var _ interfaces.TagAPIHandler = (*TagAPI)(nil)
+
+// NewTagAPI creates a new tag API
+func NewTagAPI(tagService service.TagService, noteService service.NoteService) *TagAPI {
+	return &TagAPI{
+		tagService:  tagService,
+		noteService: noteService,
+	}
+}
+
+// Get /api/v1/tags
+// List the tags of the current workspace, or the user's own, with note counts
+func (api *TagAPI) ListTags(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var tags []*repository.TagWithCount
+	var err error
+	if workspaceID := activeWorkspaceID(c); workspaceID != nil {
+		tags, err = api.tagService.ListWorkspaceTags(c.Request.Context(), *workspaceID)
+	} else {
+		tags, err = api.tagService.ListTags(c.Request.Context(), u.ID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags, "total": len(tags)})
+}
+
+// Post /api/v1/tags
+// Create a tag
+func (api *TagAPI) CreateTag(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var body struct {
+		Name  string `json:"name" binding:"required"`
+		Color string `json:"color"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+	if !requireWorkspaceWrite(c) {
+		return
+	}
+
+	tag, err := api.tagService.CreateTag(c.Request.Context(), service.CreateTagRequest{
+		Name:        body.Name,
+		Color:       body.Color,
+		UserID:      u.ID,
+		WorkspaceID: activeWorkspaceID(c),
+	})
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, tag)
+}
+
+// Put /api/v1/tags/:tag_id
+// Rename or recolor a tag
+func (api *TagAPI) UpdateTag(c *gin.Context) {
+	tagID := c.Param("tag_id")
+	if !api.ownsTag(c, tagID) {
+		return
+	}
+
+	var body struct {
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	tag, err := api.tagService.UpdateTag(c.Request.Context(), tagID, service.UpdateTagRequest{
+		Name:  body.Name,
+		Color: body.Color,
+	})
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tag)
+}
+
+// Delete /api/v1/tags/:tag_id
+// Delete a tag and detach it from all notes
+func (api *TagAPI) DeleteTag(c *gin.Context) {
+	tagID := c.Param("tag_id")
+	if !api.ownsTag(c, tagID) {
+		return
+	}
+
+	if err := api.tagService.DeleteTag(c.Request.Context(), tagID); err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Post /api/v1/notes/:note_id/tags/:tag_id
+// Attach a tag to a note
+func (api *TagAPI) AttachTag(c *gin.Context) {
+	noteID := c.Param("note_id")
+	if !api.ownsNote(c, noteID) {
+		return
+	}
+
+	if err := api.noteService.AddTagToNote(c.Request.Context(), noteID, c.Param("tag_id")); err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Delete /api/v1/notes/:note_id/tags/:tag_id
+// Detach a tag from a note
+func (api *TagAPI) DetachTag(c *gin.Context) {
+	noteID := c.Param("note_id")
+	if !api.ownsNote(c, noteID) {
+		return
+	}
+
+	if err := api.noteService.RemoveTagFromNote(c.Request.Context(), noteID, c.Param("tag_id")); err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (api *TagAPI) ownsTag(c *gin.Context, tagID string) bool {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return false
+	}
+	u := userVal.(*dbmodels.User)
+
+	tag, err := api.tagService.GetTagByID(c.Request.Context(), tagID)
+	if err != nil || (tag.UserID != u.ID && !canWriteInWorkspace(c, tag.WorkspaceID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return false
+	}
+	return true
+}
+
+func (api *TagAPI) ownsNote(c *gin.Context, noteID string) bool {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return false
+	}
+	u := userVal.(*dbmodels.User)
+
+	note, err := api.noteService.GetNoteByID(c.Request.Context(), noteID)
+	if err != nil || (note.UserID != u.ID && !canWriteInWorkspace(c, note.WorkspaceID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "note not found"})
+		return false
+	}
+	return true
+}
+
+func writeTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTagNotFound), errors.Is(err, service.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTagAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrValidationFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// parseTagFilter reads tag_ids (comma separated or repeated) and tag_match=any|all
+func parseTagFilter(c *gin.Context) ([]string, repository.TagMatchMode) {
+	seen := make(map[string]struct{})
+	tagIDs := make([]string, 0)
+	for _, raw := range c.QueryArray("tag_ids") {
+		for _, id := range strings.Split(raw, ",") {
+			id = strings.TrimSpace(id)
+			if id == "" {
+				continue
+			}
+			if _, dup := seen[id]; dup {
+				continue
+			}
+			seen[id] = struct{}{}
+			tagIDs = append(tagIDs, id)
+		}
+	}
+
+	match := repository.TagMatchAny
+	if strings.EqualFold(c.Query("tag_match"), string(repository.TagMatchAll)) {
+		match = repository.TagMatchAll
+	}
+	return tagIDs, match
+}
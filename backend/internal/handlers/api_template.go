@@ -50,14 +50,18 @@ func (api *TemplateAPI) CreateTemplate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
 		return
 	}
+	if !requireWorkspaceWrite(c) {
+		return
+	}
 
 	created, err := api.templateService.CreateTemplate(c.Request.Context(), service.CreateTemplateRequest{
-		Name:    body.Name,
-		Icon:    body.Icon,
-		Content: body.Content,
-		Tags:    body.Tags,
-		Color:   body.Color,
-		UserID:  u.ID,
+		Name:        body.Name,
+		Icon:        body.Icon,
+		Content:     body.Content,
+		Tags:        body.Tags,
+		Color:       body.Color,
+		UserID:      u.ID,
+		WorkspaceID: activeWorkspaceID(c),
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -96,7 +100,13 @@ func (api *TemplateAPI) ListTemplates(c *gin.Context) {
 	}
 	u := userVal.(*dbmodels.User)
 
-	templates, err := api.templateService.ListTemplatesByUserID(c.Request.Context(), u.ID)
+	var templates []*dbmodels.Template
+	var err error
+	if workspaceID := activeWorkspaceID(c); workspaceID != nil {
+		templates, err = api.templateService.ListTemplatesByWorkspaceID(c.Request.Context(), *workspaceID)
+	} else {
+		templates, err = api.templateService.ListTemplatesByUserID(c.Request.Context(), u.ID)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -137,10 +147,10 @@ func (api *TemplateAPI) GetTemplate(c *gin.Context) {
 		return
 	}
 
-	// Ownership check
+	// Ownership or workspace membership check
 	userVal, _ := c.Get("user")
 	u := userVal.(*dbmodels.User)
-	if template.UserID != u.ID {
+	if template.UserID != u.ID && !inActiveWorkspace(c, template.WorkspaceID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
 		return
 	}
@@ -180,7 +190,7 @@ func (api *TemplateAPI) UpdateTemplate(c *gin.Context) {
 
 	userVal, _ := c.Get("user")
 	u := userVal.(*dbmodels.User)
-	if template.UserID != u.ID {
+	if template.UserID != u.ID && !canWriteInWorkspace(c, template.WorkspaceID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
 		return
 	}
@@ -245,7 +255,7 @@ func (api *TemplateAPI) DeleteTemplate(c *gin.Context) {
 
 	userVal, _ := c.Get("user")
 	u := userVal.(*dbmodels.User)
-	if template.UserID != u.ID {
+	if template.UserID != u.ID && !canWriteInWorkspace(c, template.WorkspaceID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
 		return
 	}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TrashAPI exposes deleted notes and folders to their owner, or in a
+// workspace, to its members
+type TrashAPI struct {
+	trashService service.TrashService
+}
+
+var _ interfaces.TrashAPIHandler = (*TrashAPI)(nil)
+
+// NewTrashAPI creates a new trash API
+func NewTrashAPI(trashService service.TrashService) *TrashAPI {
+	return &TrashAPI{trashService: trashService}
+}
+
+// Get /api/v1/trash
+// List the current user's trashed notes and folders, or those of the active
+// workspace
+func (api *TrashAPI) ListTrash(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	listing, err := api.trashService.ListTrash(c.Request.Context(), u.ID, activeWorkspaceID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, listing)
+}
+
+// Post /api/v1/trash/notes/:note_id/restore
+// Restore a trashed note
+func (api *TrashAPI) RestoreNote(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+	if !requireWorkspaceWrite(c) {
+		return
+	}
+
+	note, err := api.trashService.RestoreNote(c.Request.Context(), u.ID, activeWorkspaceID(c), c.Param("note_id"))
+	if err != nil {
+		writeTrashError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, note)
+}
+
+// Post /api/v1/trash/folders/:folder_id/restore
+// Restore a trashed folder with its subfolders and notes
+func (api *TrashAPI) RestoreFolder(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+	if !requireWorkspaceWrite(c) {
+		return
+	}
+
+	folder, err := api.trashService.RestoreFolder(c.Request.Context(), u.ID, activeWorkspaceID(c), c.Param("folder_id"))
+	if err != nil {
+		writeTrashError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toFolderResponse(folder))
+}
+
+// Delete /api/v1/trash/notes/:note_id
+// Permanently delete a trashed note
+func (api *TrashAPI) PurgeNote(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+	if !requireWorkspaceWrite(c) {
+		return
+	}
+
+	if err := api.trashService.PurgeNote(c.Request.Context(), u.ID, activeWorkspaceID(c), c.Param("note_id")); err != nil {
+		writeTrashError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Delete /api/v1/trash/folders/:folder_id
+// Permanently delete a trashed folder and everything deleted with it
+func (api *TrashAPI) PurgeFolder(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+	if !requireWorkspaceWrite(c) {
+		return
+	}
+
+	if err := api.trashService.PurgeFolder(c.Request.Context(), u.ID, activeWorkspaceID(c), c.Param("folder_id")); err != nil {
+		writeTrashError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeTrashError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrTrashItemNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
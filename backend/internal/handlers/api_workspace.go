@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	dbmodels "github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// workspaceHeader selects the workspace a request acts in. Without it the
+// user's personal workspace is used.
+const workspaceHeader = "X-Workspace-ID"
+
+// WorkspaceAPI manages workspaces and their members
+type WorkspaceAPI struct {
+	workspaceService service.WorkspaceService
+}
+
+var _ interfaces.WorkspaceAPIHandler = (*WorkspaceAPI)(nil)
+
+// NewWorkspaceAPI creates a new workspace API
+func NewWorkspaceAPI(workspaceService service.WorkspaceService) *WorkspaceAPI {
+	return &WorkspaceAPI{workspaceService: workspaceService}
+}
+
+type workspaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type updateWorkspaceMemberRequest struct {
+	Role dbmodels.WorkspaceRole `json:"role" binding:"required"`
+}
+
+// Get /api/v1/workspaces
+// List the workspaces the current user belongs to, with their role
+func (api *WorkspaceAPI) ListWorkspaces(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	workspaces, err := api.workspaceService.ListWorkspaces(c.Request.Context(), u.ID)
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"workspaces": workspaces})
+}
+
+// Post /api/v1/workspaces
+// Create a team workspace owned by the current user
+func (api *WorkspaceAPI) CreateWorkspace(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req workspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	workspace, err := api.workspaceService.CreateWorkspace(c.Request.Context(), u.ID, req.Name)
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, workspace)
+}
+
+// Patch /api/v1/workspaces/:workspace_id
+// Rename a workspace (admins and the owner)
+func (api *WorkspaceAPI) RenameWorkspace(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req workspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	workspace, err := api.workspaceService.RenameWorkspace(c.Request.Context(), u.ID, c.Param("workspace_id"), req.Name)
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, workspace)
+}
+
+// Delete /api/v1/workspaces/:workspace_id
+// Delete an empty team workspace (owner only)
+func (api *WorkspaceAPI) DeleteWorkspace(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	if err := api.workspaceService.DeleteWorkspace(c.Request.Context(), u.ID, c.Param("workspace_id")); err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Get /api/v1/workspaces/:workspace_id/members
+// List a workspace's members (any member)
+func (api *WorkspaceAPI) ListMembers(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	members, err := api.workspaceService.ListMembers(c.Request.Context(), u.ID, c.Param("workspace_id"))
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// Post /api/v1/workspaces/:workspace_id/members
+// Add a user to a workspace by user_id or email (admins and the owner)
+func (api *WorkspaceAPI) AddMember(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req service.AddWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	member, err := api.workspaceService.AddMember(c.Request.Context(), u.ID, c.Param("workspace_id"), req)
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, member)
+}
+
+// Patch /api/v1/workspaces/:workspace_id/members/:user_id
+// Change a member's role (admins and the owner)
+func (api *WorkspaceAPI) UpdateMember(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	var req updateWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request, " + err.Error()})
+		return
+	}
+
+	member, err := api.workspaceService.UpdateMemberRole(c.Request.Context(), u.ID, c.Param("workspace_id"), c.Param("user_id"), req.Role)
+	if err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+// Delete /api/v1/workspaces/:workspace_id/members/:user_id
+// Remove a member (admins and the owner), or leave the workspace
+func (api *WorkspaceAPI) RemoveMember(c *gin.Context) {
+	userVal, ok := c.Get("user")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u := userVal.(*dbmodels.User)
+
+	if err := api.workspaceService.RemoveMember(c.Request.Context(), u.ID, c.Param("workspace_id"), c.Param("user_id")); err != nil {
+		writeWorkspaceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writeWorkspaceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWorkspaceNotFound),
+		errors.Is(err, service.ErrWorkspaceMemberNotFound),
+		errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWorkspaceForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWorkspaceNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrValidationFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1-100 characters, role viewer, member or admin, and the owner's membership cannot change"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// activeWorkspace returns the workspace authMiddleware resolved for the
+// request and the user's role in it, or nil when there is none
+func activeWorkspace(c *gin.Context) (*dbmodels.Workspace, dbmodels.WorkspaceRole) {
+	workspaceVal, ok := c.Get("workspace")
+	if !ok {
+		return nil, ""
+	}
+	workspace, _ := workspaceVal.(*dbmodels.Workspace)
+	if workspace == nil {
+		return nil, ""
+	}
+	return workspace, workspace.Role
+}
+
+// activeWorkspaceID returns the ID of the active workspace, or nil
+func activeWorkspaceID(c *gin.Context) *string {
+	workspace, _ := activeWorkspace(c)
+	if workspace == nil {
+		return nil
+	}
+	return &workspace.ID
+}
+
+// inActiveWorkspace reports whether something belongs to the active workspace
+func inActiveWorkspace(c *gin.Context, workspaceID *string) bool {
+	active := activeWorkspaceID(c)
+	return active != nil && workspaceID != nil && *active == *workspaceID
+}
+
+// canWriteInWorkspace reports whether the user may change something that
+// belongs to the active workspace
+func canWriteInWorkspace(c *gin.Context, workspaceID *string) bool {
+	_, role := activeWorkspace(c)
+	return inActiveWorkspace(c, workspaceID) && role.CanWrite()
+}
+
+// requireWorkspaceWrite rejects creating content in a workspace where the
+// user is only a viewer
+func requireWorkspaceWrite(c *gin.Context) bool {
+	workspace, role := activeWorkspace(c)
+	if workspace != nil && !role.CanWrite() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you can only view this workspace"})
+		return false
+	}
+	return true
+}
@@ -11,5 +11,6 @@ type AIAPIHandler interface {
 	InlineEditAiRun(c *gin.Context)
 	ListAiConversations(c *gin.Context)
 	ProvideAiRunConsent(c *gin.Context)
+	StreamAiRunEvents(c *gin.Context)
 	UpdateAiConversation(c *gin.Context)
 }
@@ -11,7 +11,14 @@ type AIRunAPIHandler interface {
 	InlineEdit(c *gin.Context)
 	InlineEditRun(c *gin.Context)
 	ProvideConsent(c *gin.Context)
+	ListPendingConsents(c *gin.Context)
+	GetUsage(c *gin.Context)
+	StreamRunEvents(c *gin.Context)
 	ListConversations(c *gin.Context)
+	SearchConversations(c *gin.Context)
+	ForkConversation(c *gin.Context)
+	ExportConversation(c *gin.Context)
+	SaveMessageAsNote(c *gin.Context)
 	CreateConversation(c *gin.Context)
 	GetConversation(c *gin.Context)
 	UpdateConversation(c *gin.Context)
@@ -32,6 +39,98 @@ type SearchHandler interface {
 	ReindexAllNotes(c *gin.Context)
 }
 
+type NoteRevisionAPIHandler interface {
+	ListRevisions(c *gin.Context)
+	GetRevision(c *gin.Context)
+	DiffRevision(c *gin.Context)
+	RestoreRevision(c *gin.Context)
+}
+
+type AIEditProposalAPIHandler interface {
+	ListProposals(c *gin.Context)
+	GetProposal(c *gin.Context)
+	DiffProposal(c *gin.Context)
+	AcceptProposal(c *gin.Context)
+	RejectProposal(c *gin.Context)
+}
+
+type AIAuditAPIHandler interface {
+	ListRuns(c *gin.Context)
+	GetRun(c *gin.Context)
+	ExportRuns(c *gin.Context)
+}
+
+type NoteShareAPIHandler interface {
+	ListNoteShares(c *gin.Context)
+	ShareNote(c *gin.Context)
+	ListFolderShares(c *gin.Context)
+	ShareFolder(c *gin.Context)
+	UpdateShare(c *gin.Context)
+	RevokeShare(c *gin.Context)
+	ListIncomingShares(c *gin.Context)
+}
+
+type WorkspaceAPIHandler interface {
+	ListWorkspaces(c *gin.Context)
+	CreateWorkspace(c *gin.Context)
+	RenameWorkspace(c *gin.Context)
+	DeleteWorkspace(c *gin.Context)
+	ListMembers(c *gin.Context)
+	AddMember(c *gin.Context)
+	UpdateMember(c *gin.Context)
+	RemoveMember(c *gin.Context)
+}
+
+type AuthSessionAPIHandler interface {
+	ListSessions(c *gin.Context)
+	RevokeSession(c *gin.Context)
+	RevokeAllSessions(c *gin.Context)
+}
+
+type AuthAccountAPIHandler interface {
+	VerifyEmail(c *gin.Context)
+	ResendVerificationEmail(c *gin.Context)
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+	ChangePassword(c *gin.Context)
+}
+
+type NoteShareLinkAPIHandler interface {
+	ListLinks(c *gin.Context)
+	CreateLink(c *gin.Context)
+	UpdateLink(c *gin.Context)
+	RevokeLink(c *gin.Context)
+	GetLinkAnalytics(c *gin.Context)
+	GetLinkedNote(c *gin.Context)
+	ListLinkComments(c *gin.Context)
+	AddLinkComment(c *gin.Context)
+}
+
+type TrashAPIHandler interface {
+	ListTrash(c *gin.Context)
+	RestoreNote(c *gin.Context)
+	RestoreFolder(c *gin.Context)
+	PurgeNote(c *gin.Context)
+	PurgeFolder(c *gin.Context)
+}
+
+type TagAPIHandler interface {
+	ListTags(c *gin.Context)
+	CreateTag(c *gin.Context)
+	UpdateTag(c *gin.Context)
+	DeleteTag(c *gin.Context)
+	AttachTag(c *gin.Context)
+	DetachTag(c *gin.Context)
+}
+
+type ChunkJobAPIHandler interface {
+	GetNoteChunkStatus(c *gin.Context)
+	ReindexNote(c *gin.Context)
+	ListChunkStatus(c *gin.Context)
+	ReindexNotes(c *gin.Context)
+	ListChunkJobs(c *gin.Context)
+}
+
 type GoogleCalendarAPIHandler interface {
 	InitiateOAuth(c *gin.Context)
 	OAuthCallback(c *gin.Context)
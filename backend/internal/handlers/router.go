@@ -2,6 +2,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -17,12 +18,15 @@ import (
 // Public paths that don't require authentication (exact match)
 var publicExactPaths = []string{
 	"/health",
-	"/ws",
+	"/ws", // authenticates its own handshake (collab token or access cookie)
 	"/api/v1/auth/login",
 	"/api/v1/auth/register",
 	"/api/v1/auth/logout",
 	"/api/v1/auth/check",
 	"/api/v1/auth/refresh-token",
+	"/api/v1/auth/verify-email",
+	"/api/v1/auth/forgot-password",
+	"/api/v1/auth/reset-password",
 	"/api/v1/auth/google/calendar/callback",
 	"/api/v1/auth/google/login",
 	"/api/v1/auth/google/login/callback",
@@ -32,6 +36,7 @@ var publicExactPaths = []string{
 var publicPrefixPaths = []string{
 	"/api/v1/public/notes",
 	"/api/v1/public/collab",
+	"/api/v1/public/links",
 	"/internal/v1/ai",
 }
 
@@ -46,12 +51,23 @@ func SetupRouter(
 	eventService service.EventService,
 	mediaService service.MediaService,
 	commentService service.CommentService,
+	noteShareService service.NoteShareService,
+	workspaceService service.WorkspaceService,
 	aiRunAPI interfaces.AIRunAPIHandler,
 	aiInternalAPI interfaces.AIInternalAPIHandler,
 	wsHandler interfaces.WebSocketHandler,
 	searchHandler interfaces.SearchHandler,
 	googleCalendarAPI interfaces.GoogleCalendarAPIHandler,
 	googleLoginAPI interfaces.GoogleLoginAPIHandler,
+	noteRevisionAPI interfaces.NoteRevisionAPIHandler,
+	trashAPI interfaces.TrashAPIHandler,
+	tagAPI interfaces.TagAPIHandler,
+	chunkJobAPI interfaces.ChunkJobAPIHandler,
+	editProposalAPI interfaces.AIEditProposalAPIHandler,
+	aiAuditAPI interfaces.AIAuditAPIHandler,
+	noteShareAPI interfaces.NoteShareAPIHandler,
+	workspaceAPI interfaces.WorkspaceAPIHandler,
+	noteShareLinkAPI interfaces.NoteShareLinkAPIHandler,
 ) *gin.Engine {
 	gin.SetMode(cfg.Server.Mode)
 	router := gin.Default()
@@ -60,7 +76,7 @@ func SetupRouter(
 	router.Use(corsMiddleware())
 	router.Use(rateLimitMiddleware())
 	router.Use(loggingMiddleware())
-	router.Use(authMiddleware(authService))
+	router.Use(authMiddleware(authService, workspaceService))
 
 	// Health check
 	router.GET("/health", healthHandler)
@@ -76,6 +92,116 @@ func SetupRouter(
 		router.POST("/api/v1/notes/reindex", searchHandler.ReindexAllNotes)
 	}
 
+	// Note revision history
+	if noteRevisionAPI != nil {
+		router.GET("/api/v1/notes/:note_id/revisions", noteRevisionAPI.ListRevisions)
+		router.GET("/api/v1/notes/:note_id/revisions/:revision_id", noteRevisionAPI.GetRevision)
+		router.GET("/api/v1/notes/:note_id/revisions/:revision_id/diff", noteRevisionAPI.DiffRevision)
+		router.POST("/api/v1/notes/:note_id/revisions/:revision_id/restore", noteRevisionAPI.RestoreRevision)
+	}
+
+	// Sharing notes and folders with other users
+	if noteShareAPI != nil {
+		router.GET("/api/v1/notes/:note_id/shares", noteShareAPI.ListNoteShares)
+		router.POST("/api/v1/notes/:note_id/shares", noteShareAPI.ShareNote)
+		router.GET("/api/v1/folders/:id/shares", noteShareAPI.ListFolderShares)
+		router.POST("/api/v1/folders/:id/shares", noteShareAPI.ShareFolder)
+		router.GET("/api/v1/shares/incoming", noteShareAPI.ListIncomingShares)
+		router.PATCH("/api/v1/shares/:share_id", noteShareAPI.UpdateShare)
+		router.DELETE("/api/v1/shares/:share_id", noteShareAPI.RevokeShare)
+	}
+
+	// Signed-in sessions
+	authAPI := NewAuthAPI(authService, cfg)
+	router.GET("/api/v1/auth/sessions", authAPI.ListSessions)
+	router.DELETE("/api/v1/auth/sessions", authAPI.RevokeAllSessions)
+	router.DELETE("/api/v1/auth/sessions/:session_id", authAPI.RevokeSession)
+
+	// Email verification and passwords
+	router.POST("/api/v1/auth/verify-email", authAPI.VerifyEmail)
+	router.POST("/api/v1/auth/verify-email/resend", authAPI.ResendVerificationEmail)
+	router.POST("/api/v1/auth/forgot-password", authAPI.ForgotPassword)
+	router.POST("/api/v1/auth/reset-password", authAPI.ResetPassword)
+	router.POST("/api/v1/auth/change-password", authAPI.ChangePassword)
+
+	// Read-only public share links
+	if noteShareLinkAPI != nil {
+		router.GET("/api/v1/notes/:note_id/links", noteShareLinkAPI.ListLinks)
+		router.POST("/api/v1/notes/:note_id/links", noteShareLinkAPI.CreateLink)
+		router.PATCH("/api/v1/share-links/:link_id", noteShareLinkAPI.UpdateLink)
+		router.DELETE("/api/v1/share-links/:link_id", noteShareLinkAPI.RevokeLink)
+		router.GET("/api/v1/share-links/:link_id/views", noteShareLinkAPI.GetLinkAnalytics)
+		router.GET("/api/v1/public/links/:slug", noteShareLinkAPI.GetLinkedNote)
+		router.GET("/api/v1/public/links/:slug/comments", noteShareLinkAPI.ListLinkComments)
+		router.POST("/api/v1/links/:slug/comments", noteShareLinkAPI.AddLinkComment)
+	}
+
+	// Workspaces and their members
+	if workspaceAPI != nil {
+		router.GET("/api/v1/workspaces", workspaceAPI.ListWorkspaces)
+		router.POST("/api/v1/workspaces", workspaceAPI.CreateWorkspace)
+		router.PATCH("/api/v1/workspaces/:workspace_id", workspaceAPI.RenameWorkspace)
+		router.DELETE("/api/v1/workspaces/:workspace_id", workspaceAPI.DeleteWorkspace)
+		router.GET("/api/v1/workspaces/:workspace_id/members", workspaceAPI.ListMembers)
+		router.POST("/api/v1/workspaces/:workspace_id/members", workspaceAPI.AddMember)
+		router.PATCH("/api/v1/workspaces/:workspace_id/members/:user_id", workspaceAPI.UpdateMember)
+		router.DELETE("/api/v1/workspaces/:workspace_id/members/:user_id", workspaceAPI.RemoveMember)
+	}
+
+	// Trash
+	if trashAPI != nil {
+		router.GET("/api/v1/trash", trashAPI.ListTrash)
+		router.POST("/api/v1/trash/notes/:note_id/restore", trashAPI.RestoreNote)
+		router.POST("/api/v1/trash/folders/:folder_id/restore", trashAPI.RestoreFolder)
+		router.DELETE("/api/v1/trash/notes/:note_id", trashAPI.PurgeNote)
+		router.DELETE("/api/v1/trash/folders/:folder_id", trashAPI.PurgeFolder)
+	}
+
+	// Tags
+	if tagAPI != nil {
+		router.GET("/api/v1/tags", tagAPI.ListTags)
+		router.POST("/api/v1/tags", tagAPI.CreateTag)
+		router.PUT("/api/v1/tags/:tag_id", tagAPI.UpdateTag)
+		router.DELETE("/api/v1/tags/:tag_id", tagAPI.DeleteTag)
+		router.POST("/api/v1/notes/:note_id/tags/:tag_id", tagAPI.AttachTag)
+		router.DELETE("/api/v1/notes/:note_id/tags/:tag_id", tagAPI.DetachTag)
+	}
+
+	// Chunking jobs
+	if chunkJobAPI != nil {
+		router.GET("/api/v1/notes/:note_id/chunks/status", chunkJobAPI.GetNoteChunkStatus)
+		router.POST("/api/v1/notes/:note_id/chunks/reindex", chunkJobAPI.ReindexNote)
+		router.GET("/api/v1/chunks/status", chunkJobAPI.ListChunkStatus)
+		router.POST("/api/v1/chunks/reindex", chunkJobAPI.ReindexNotes)
+		router.GET("/api/v1/chunks/jobs", chunkJobAPI.ListChunkJobs)
+	}
+
+	// AI conversation search, branching, export and saving answers as notes, pending tool consents and usage
+	if aiRunAPI != nil {
+		router.GET("/api/v1/ai/conversations/search", aiRunAPI.SearchConversations)
+		router.POST("/api/v1/ai/conversations/:conversation_id/fork", aiRunAPI.ForkConversation)
+		router.GET("/api/v1/ai/conversations/:conversation_id/export", aiRunAPI.ExportConversation)
+		router.POST("/api/v1/ai/conversations/:conversation_id/messages/:message_id/note", aiRunAPI.SaveMessageAsNote)
+		router.GET("/api/v1/ai/consents/pending", aiRunAPI.ListPendingConsents)
+		router.GET("/api/v1/ai/usage", aiRunAPI.GetUsage)
+	}
+
+	// AI edit proposals
+	if editProposalAPI != nil {
+		router.GET("/api/v1/notes/:note_id/edit-proposals", editProposalAPI.ListProposals)
+		router.GET("/api/v1/ai/edit-proposals/:proposal_id", editProposalAPI.GetProposal)
+		router.GET("/api/v1/ai/edit-proposals/:proposal_id/diff", editProposalAPI.DiffProposal)
+		router.POST("/api/v1/ai/edit-proposals/:proposal_id/accept", editProposalAPI.AcceptProposal)
+		router.POST("/api/v1/ai/edit-proposals/:proposal_id/reject", editProposalAPI.RejectProposal)
+	}
+
+	// Admin audit trail of AI runs
+	if aiAuditAPI != nil {
+		router.GET("/api/v1/admin/ai/runs", aiAuditAPI.ListRuns)
+		router.GET("/api/v1/admin/ai/runs/export", aiAuditAPI.ExportRuns)
+		router.GET("/api/v1/admin/ai/runs/:run_id", aiAuditAPI.GetRun)
+	}
+
 	if aiInternalAPI != nil {
 		router.POST("/internal/v1/ai/tools/execute", aiInternalAPI.ExecuteTool)
 	}
@@ -99,15 +225,15 @@ func SetupRouter(
 	// API handlers
 	apiHandlers := ApiHandleFunctions{
 		AIAPI:       *NewAIAPI(aiRunAPI),
-		AuthAPI:     *NewAuthAPI(authService, cfg),
+		AuthAPI:     *authAPI,
 		UserAPI:     UserAPI{userService},
-		NoteAPI:     NoteAPI{noteService: noteService, authService: authService},
+		NoteAPI:     NoteAPI{noteService: noteService, authService: authService, shareService: noteShareService},
 		FolderAPI:   FolderAPI{folderService},
 		TemplateAPI: TemplateAPI{templateService: templateService, authService: authService},
 		EventAPI:    EventAPI{eventService: &eventService, authService: authService},
 		MediaAPI:    *NewMediaAPI(mediaService),
 		CommentAPI:  *NewCommentAPI(commentService),
-		CollabAPI:   *NewCollabAPI(noteService, authService, cfg),
+		CollabAPI:   *NewCollabAPI(noteService, authService, noteShareService, cfg),
 	}
 
 	// Register generated routes
@@ -120,28 +246,42 @@ func healthHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "OK"})
 }
 
-func corsMiddleware() gin.HandlerFunc {
+// allowedOrigins reads the CORS allow-list, shared with the /ws origin check
+func allowedOrigins() []string {
 	// 1. Lấy chuỗi từ Env và tách thành Slice
 	originsEnv := os.Getenv("ALLOWED_ORIGINS")
 	if originsEnv == "" {
 		originsEnv = "http://localhost:3000" // Default cho dev
 	}
-	allowedOrigins := strings.Split(originsEnv, ",")
+
+	origins := make([]string, 0)
+	for _, o := range strings.Split(originsEnv, ",") {
+		// Dùng strings.TrimSpace để tránh lỗi nếu lỡ tay gõ dấu cách sau dấu phẩy trong env
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
+// isAllowedOrigin reports whether origin is on the allow-list
+func isAllowedOrigin(origin string, allowed []string) bool {
+	for _, o := range allowed {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func corsMiddleware() gin.HandlerFunc {
+	origins := allowedOrigins()
 
 	return func(c *gin.Context) {
 		origin := c.GetHeader("Origin")
 
 		// 2. Kiểm tra xem Origin có trong whitelist không
-		isAllowed := false
-		for _, o := range allowedOrigins {
-			// Dùng strings.TrimSpace để tránh lỗi nếu lỡ tay gõ dấu cách sau dấu phẩy trong env
-			if strings.TrimSpace(o) == origin {
-				isAllowed = true
-				break
-			}
-		}
-
-		if isAllowed {
+		if isAllowedOrigin(origin, origins) {
 			c.Header("Access-Control-Allow-Origin", origin)
 		}
 
@@ -149,7 +289,7 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Header("Vary", "Origin")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, Accept, X-Requested-With, X-Edit-Token")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, Accept, X-Requested-With, X-Edit-Token, X-Workspace-ID, X-Share-Password")
 
 		// 3. Xử lý Preflight (Quan trọng!)
 		if c.Request.Method == http.MethodOptions {
@@ -176,7 +316,7 @@ func loggingMiddleware() gin.HandlerFunc {
 	})
 }
 
-func authMiddleware(authService service.AuthService) gin.HandlerFunc {
+func authMiddleware(authService service.AuthService, workspaceService service.WorkspaceService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		path := c.Request.URL.Path
 
@@ -201,6 +341,22 @@ func authMiddleware(authService service.AuthService) gin.HandlerFunc {
 		}
 
 		c.Set("user", user)
+
+		// Resolve the workspace the request acts in, the personal one by default
+		if workspaceService != nil {
+			workspaceID := strings.TrimSpace(c.GetHeader(workspaceHeader))
+			workspace, _, err := workspaceService.ResolveActive(c.Request.Context(), user.ID, workspaceID)
+			if err != nil {
+				if errors.Is(err, service.ErrWorkspaceNotFound) {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "workspace not found or you are not a member"})
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.Set("workspace", workspace)
+		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+)
+
+type authMiddlewareTestAuth struct {
+	service.AuthService
+}
+
+func (authMiddlewareTestAuth) ValidateToken(_ context.Context, token string) (*models.User, error) {
+	user := &models.User{}
+	user.ID = token
+	return user, nil
+}
+
+type authMiddlewareTestWorkspaces struct {
+	service.WorkspaceService
+	members map[string]string
+}
+
+// ResolveActive falls back to the personal workspace and otherwise only
+// resolves workspaces the user is a member of
+func (f authMiddlewareTestWorkspaces) ResolveActive(_ context.Context, userID, workspaceID string) (*models.Workspace, models.WorkspaceRole, error) {
+	if workspaceID == "" {
+		workspaceID = "personal-" + userID
+	} else if f.members[workspaceID] != userID {
+		return nil, "", service.ErrWorkspaceNotFound
+	}
+	workspace := &models.Workspace{Role: models.WorkspaceRoleOwner}
+	workspace.ID = workspaceID
+	return workspace, workspace.Role, nil
+}
+
+func TestAuthMiddlewareResolvesActiveWorkspace(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(authMiddleware(authMiddlewareTestAuth{}, authMiddlewareTestWorkspaces{members: map[string]string{"ws-team": "user-1"}}))
+	router.GET("/api/v1/notes", func(c *gin.Context) {
+		c.String(http.StatusOK, *activeWorkspaceID(c))
+	})
+
+	for _, tc := range []struct {
+		name      string
+		workspace string
+		want      int
+		wantBody  string
+	}{
+		{"personal by default", "", http.StatusOK, "personal-user-1"},
+		{"member workspace", "ws-team", http.StatusOK, "ws-team"},
+		{"foreign workspace", "ws-other", http.StatusForbidden, ""},
+	} {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/notes", nil)
+		request.Header.Set("Authorization", "Bearer user-1")
+		if tc.workspace != "" {
+			request.Header.Set(workspaceHeader, tc.workspace)
+		}
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		require.Equal(t, tc.want, response.Code, tc.name)
+		if tc.wantBody != "" {
+			require.Equal(t, tc.wantBody, response.Body.String(), tc.name)
+		}
+	}
+}
@@ -134,6 +134,12 @@ func getRoutes(handleFunctions ApiHandleFunctions) []Route {
 			"/api/v1/ai/runs/:run_id/consent",
 			handleFunctions.AIAPI.ProvideAiRunConsent,
 		},
+		{
+			"StreamAiRunEvents",
+			http.MethodGet,
+			"/api/v1/ai/runs/:run_id/events",
+			handleFunctions.AIAPI.StreamAiRunEvents,
+		},
 		{
 			"UpdateAiConversation",
 			http.MethodPatch,
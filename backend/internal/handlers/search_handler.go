@@ -24,7 +24,8 @@ func NewSearchHandler(searchService service.SearchService) *SearchHandler {
 	}
 }
 
-// SearchNotes handles GET /api/v1/notes/search?q=<query>&limit=10
+// SearchNotes handles GET /api/v1/notes/search?q=<query>&limit=10&offset=0
+// Optional filters: folder_id, status, tag_ids and tag_match=any|all
 func (h *SearchHandler) SearchNotes(c *gin.Context) {
 	// Get authenticated user
 	userVal, exists := c.Get("user")
@@ -41,25 +42,58 @@ func (h *SearchHandler) SearchNotes(c *gin.Context) {
 		return
 	}
 
-	// Get limit (default 10)
+	// Get limit (default 10, max 100) and offset
 	limit := 10
 	if limitStr := c.Query("limit"); limitStr != "" {
 		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
 			limit = v
 		}
 	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if v, err := strconv.Atoi(offsetStr); err == nil && v >= 0 {
+			offset = v
+		}
+	}
+
+	req := service.NoteSearchRequest{
+		UserID:      u.ID,
+		WorkspaceID: activeWorkspaceID(c),
+		Query:       query,
+		Limit:       limit,
+		Offset:      offset,
+	}
+	if folderID := c.Query("folder_id"); folderID != "" {
+		req.FolderID = &folderID
+	}
+	if statusStr := c.Query("status"); statusStr != "" {
+		status := dbmodels.NoteStatus(statusStr)
+		switch status {
+		case dbmodels.NoteStatusDraft, dbmodels.NoteStatusPublished, dbmodels.NoteStatusArchived:
+			req.Status = &status
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+	}
+	req.TagIDs, req.TagMatch = parseTagFilter(c)
 
-	// Perform semantic search
-	notes, total, err := h.searchService.SearchNotes(c.Request.Context(), query, u.ID, limit)
+	results, total, err := h.searchService.Search(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"notes": notes,
-		"total": total,
-		"query": query,
+		"results": results,
+		"notes":   service.SearchNoteList(results),
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+		"query":   query,
 	})
 }
 
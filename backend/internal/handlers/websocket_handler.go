@@ -3,86 +3,100 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/domain"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/handlers/interfaces"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 // WebSocketHandler handles WebSocket connections
 type WebSocketHandler struct {
 	collaborationService *service.CollaborationServiceImpl
+	authService          service.AuthService
+	noteService          service.NoteService
+	shareService         service.NoteShareService
+	config               *config.Config
 	upgrader             websocket.Upgrader
 }
 
 var _ interfaces.WebSocketHandler = (*WebSocketHandler)(nil)
 
+// wsIdentity is who a /ws handshake authenticated as, and in which note
+type wsIdentity struct {
+	user   *models.User
+	noteID string
+	role   domain.CollabRole
+}
+
+// wsHandshakeError rejects a handshake before the connection is upgraded
+type wsHandshakeError struct {
+	status  int
+	message string
+}
+
+func (e *wsHandshakeError) Error() string {
+	return e.message
+}
+
 // NewWebSocketHandler creates a new WebSocket handler
-func NewWebSocketHandler(collaborationService *service.CollaborationServiceImpl) *WebSocketHandler {
+func NewWebSocketHandler(collaborationService *service.CollaborationServiceImpl, authService service.AuthService, noteService service.NoteService, shareService service.NoteShareService, cfg *config.Config) *WebSocketHandler {
+	origins := allowedOrigins()
 	return &WebSocketHandler{
 		collaborationService: collaborationService,
+		authService:          authService,
+		noteService:          noteService,
+		shareService:         shareService,
+		config:               cfg,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for development
+				// Browsers always send Origin; other clients are authenticated by token alone
+				origin := r.Header.Get("Origin")
+				return origin == "" || isAllowedOrigin(origin, origins)
 			},
 		},
 	}
 }
 
-// HandleConnections handles WebSocket connections
+// HandleConnections handles WebSocket connections. The handshake carries
+// either a collab token (?token=) issued by POST /api/v1/public/collab/token,
+// or the access token as a bearer header or cookie with ?note_id=.
 func (h *WebSocketHandler) HandleConnections(w http.ResponseWriter, r *http.Request) {
-	ws, err := h.upgrader.Upgrade(w, r, nil)
+	identity, err := h.authenticate(r)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
-		return
-	}
-	defer h.cleanupClient(ws)
-
-	log.Printf("URL: %v", (r.URL))
-	noteID := r.URL.Query().Get("note_id")
-	if noteID == "" {
-		log.Printf("Missing note_id for websocket connection")
-		return
-	}
-	// Get user ID from query parameter or create new user
-	userID := r.URL.Query().Get("user_id")
-	var user *models.User
-
-	if userID != "" {
-		// Try to get existing user
-		// Convert string to uint for userID
-		user, err = h.collaborationService.GetUserUseCase().GetUserByID(context.Background(), userID)
-		if err != nil {
-			log.Printf("User not found, creating new user: %v", err)
-			user, err = h.collaborationService.GetUserUseCase().CreateUser(context.Background(), service.CreateUserRequest{
-				Name: "",
-			})
+		var handshakeErr *wsHandshakeError
+		if errors.As(err, &handshakeErr) {
+			http.Error(w, handshakeErr.message, handshakeErr.status)
+			return
 		}
-
-	} else {
-		// Create new user
-		user, err = h.collaborationService.GetUserUseCase().CreateUser(context.Background(), service.CreateUserRequest{
-			Name: "",
-		})
+		log.Printf("WebSocket handshake error: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	log.Printf("User connected: %+v", user)
-
+	ws, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("Failed to create/get user: %v", err)
+		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
+	defer h.cleanupClient(ws)
 
 	// Create client
 	client := &domain.Client{
-		Conn: ws,
-		User: user,
-		NoteID: noteID,
+		ID:     uuid.NewString(),
+		Conn:   ws,
+		User:   identity.user,
+		NoteID: identity.noteID,
+		Role:   identity.role,
 	}
+	defer h.collaborationService.HandleDisconnect(context.Background(), client)
 
 	// Handle the connection
 	if err := h.collaborationService.HandleConnection(context.Background(), client); err != nil {
@@ -110,18 +124,122 @@ func (h *WebSocketHandler) HandleConnections(w http.ResponseWriter, r *http.Requ
 			log.Printf("Failed to handle message: %v", err)
 		}
 	}
+}
+
+// authenticate resolves the user and their role in the requested note
+func (h *WebSocketHandler) authenticate(r *http.Request) (*wsIdentity, error) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	noteID := query.Get("note_id")
+
+	if token := query.Get("token"); token != "" {
+		claims, err := parseCollabToken(h.config.Collab.TokenSecret, token)
+		if err != nil {
+			return nil, &wsHandshakeError{http.StatusUnauthorized, "invalid collab token"}
+		}
+		if noteID != "" && noteID != claims.NoteID {
+			return nil, &wsHandshakeError{http.StatusForbidden, "collab token was issued for another note"}
+		}
+		note, err := h.loadNote(ctx, claims.NoteID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch role := domain.CollabRole(claims.Role); role {
+		case domain.CollabRoleOwner:
+			user, err := h.collaborationService.GetUserUseCase().GetUserByID(ctx, claims.UserID)
+			if err != nil || note.UserID != user.ID {
+				return nil, &wsHandshakeError{http.StatusForbidden, "forbidden"}
+			}
+			return &wsIdentity{user: user, noteID: note.ID, role: role}, nil
+		case domain.CollabRolePublicEdit:
+			// Public editing may have been turned off since the token was issued
+			if !note.PublicEditEnabled {
+				return nil, &wsHandshakeError{http.StatusForbidden, "public editing is disabled"}
+			}
+			guest := &models.User{Name: "Guest"}
+			guest.ID = claims.UserID
+			return &wsIdentity{user: guest, noteID: note.ID, role: role}, nil
+		case domain.CollabRoleEditor, domain.CollabRoleViewer:
+			// The share may have been revoked or downgraded since the token was issued
+			current := sharedCollabRole(ctx, h.shareService, note, claims.UserID)
+			if current == "" {
+				return nil, &wsHandshakeError{http.StatusForbidden, "note is no longer shared with you"}
+			}
+			if role == domain.CollabRoleViewer && current.CanEdit() {
+				current = role
+			}
+			user, err := h.collaborationService.GetUserUseCase().GetUserByID(ctx, claims.UserID)
+			if err != nil {
+				return nil, &wsHandshakeError{http.StatusForbidden, "forbidden"}
+			}
+			return &wsIdentity{user: user, noteID: note.ID, role: current}, nil
+		default:
+			return nil, &wsHandshakeError{http.StatusForbidden, "unsupported collab role"}
+		}
+	}
 
-	// Broadcast user left message
-	userLeftMsg := domain.Message{
-		Type: domain.MessageTypeUserLeft,
-		Payload: h.mustMarshal(domain.UserLeftPayload{
-			ID: client.User.ID,
-		}),
+	token := wsAccessToken(r)
+	if token == "" {
+		return nil, &wsHandshakeError{http.StatusUnauthorized, "authentication required"}
+	}
+	user, err := h.authService.ValidateToken(ctx, token)
+	if err != nil {
+		return nil, &wsHandshakeError{http.StatusUnauthorized, "invalid or expired token"}
 	}
+	if noteID == "" {
+		return nil, &wsHandshakeError{http.StatusBadRequest, "note_id is required"}
+	}
+	note, err := h.loadNote(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &wsIdentity{user: user, noteID: note.ID}
+	editToken := query.Get("edit_token")
+	var shared domain.CollabRole
+	if note.UserID != user.ID {
+		shared = sharedCollabRole(ctx, h.shareService, note, user.ID)
+	}
+	switch {
+	case note.UserID == user.ID:
+		identity.role = domain.CollabRoleOwner
+	case shared.CanEdit():
+		identity.role = shared
+	case editToken != "" && note.PublicEditEnabled && note.PublicEditToken == editToken:
+		identity.role = domain.CollabRolePublicEdit
+	case shared != "":
+		identity.role = shared
+	case note.IsPublic:
+		identity.role = domain.CollabRoleViewer
+	default:
+		return nil, &wsHandshakeError{http.StatusForbidden, "forbidden"}
+	}
+	return identity, nil
+}
+
+func (h *WebSocketHandler) loadNote(ctx context.Context, noteID string) (*models.Note, error) {
+	note, err := h.noteService.GetNoteByID(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, service.ErrNoteNotFound) {
+			return nil, &wsHandshakeError{http.StatusNotFound, "note not found"}
+		}
+		return nil, err
+	}
+	return note, nil
+}
 
-	if err := h.collaborationService.BroadcastMessage(&userLeftMsg, ws); err != nil {
-		log.Printf("Failed to broadcast user left message: %v", err)
+// wsAccessToken reads the access token from the Authorization header or cookie
+func wsAccessToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		if token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")); token != "" {
+			return token
+		}
 	}
+	if cookie, err := r.Cookie("access_token"); err == nil {
+		return cookie.Value
+	}
+	return ""
 }
 
 // cleanupClient removes a client from the repository and closes the connection
@@ -129,9 +247,3 @@ func (h *WebSocketHandler) cleanupClient(ws *websocket.Conn) {
 	h.collaborationService.RemoveClient(ws)
 	ws.Close()
 }
-
-// mustMarshal marshals a value to JSON, panicking on error
-func (h *WebSocketHandler) mustMarshal(v interface{}) json.RawMessage {
-	data, _ := json.Marshal(v)
-	return data
-}
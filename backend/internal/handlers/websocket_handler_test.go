@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/domain"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/service"
+)
+
+// wsTestShareService grants role to every user but the owner
+type wsTestShareService struct {
+	service.NoteShareService
+	role models.NoteRole
+}
+
+func (s *wsTestShareService) NoteRole(_ context.Context, note *models.Note, userID string) (models.NoteRole, error) {
+	if note.UserID == userID {
+		return models.NoteRoleOwner, nil
+	}
+	return s.role, nil
+}
+
+func TestWebSocketHandshakeEnforcesCollabToken(t *testing.T) {
+	cfg := &config.Config{Collab: config.CollabConfig{TokenSecret: "test-collab-secret", TokenTTLMinutes: 5}}
+	note := &models.Note{UserID: "owner-1", PublicEditEnabled: true}
+	note.ID = "note-1"
+
+	collabAPI := NewCollabAPI(nil, nil, nil, cfg)
+	handler := NewWebSocketHandler(nil, nil, aiRunHandlerTestNoteService{note: note}, nil, cfg)
+
+	handshake := func(query string) (*wsIdentity, int) {
+		req := httptest.NewRequest(http.MethodGet, "/ws?"+query, nil)
+		identity, err := handler.authenticate(req)
+		if err == nil {
+			return identity, http.StatusOK
+		}
+		var handshakeErr *wsHandshakeError
+		if !errors.As(err, &handshakeErr) {
+			t.Fatalf("unexpected handshake error: %v", err)
+		}
+		return nil, handshakeErr.status
+	}
+
+	if _, status := handshake("note_id=note-1&user_id=owner-1"); status != http.StatusUnauthorized {
+		t.Fatalf("expected unauthenticated handshake to be rejected, got %d", status)
+	}
+
+	token, err := collabAPI.signCollabToken(note.ID, "anon-1", string(domain.CollabRolePublicEdit))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	identity, status := handshake("token=" + token)
+	if status != http.StatusOK || identity.role != domain.CollabRolePublicEdit || identity.noteID != note.ID {
+		t.Fatalf("expected public_edit identity for note-1, got %+v (%d)", identity, status)
+	}
+	if _, status := handshake("note_id=note-2&token=" + token); status != http.StatusForbidden {
+		t.Fatalf("expected token for another note to be rejected, got %d", status)
+	}
+
+	note.PublicEditEnabled = false
+	if _, status := handshake("token=" + token); status != http.StatusForbidden {
+		t.Fatalf("expected public_edit token to be rejected once public editing is off, got %d", status)
+	}
+
+	forged := &CollabAPI{config: &config.Config{Collab: config.CollabConfig{TokenSecret: "another-secret", TokenTTLMinutes: 5}}}
+	forgedToken, _ := forged.signCollabToken(note.ID, "owner-1", string(domain.CollabRoleOwner))
+	if _, status := handshake("token=" + forgedToken); status != http.StatusUnauthorized {
+		t.Fatalf("expected token signed with another secret to be rejected, got %d", status)
+	}
+}
+
+func TestWebSocketHandshakeRechecksSharedRole(t *testing.T) {
+	cfg := &config.Config{Collab: config.CollabConfig{TokenSecret: "test-collab-secret", TokenTTLMinutes: 5}}
+	note := &models.Note{UserID: "owner-1"}
+	note.ID = "note-1"
+
+	shares := &wsTestShareService{role: models.NoteRoleEditor}
+	collabAPI := NewCollabAPI(nil, nil, shares, cfg)
+	handler := NewWebSocketHandler(nil, nil, aiRunHandlerTestNoteService{note: note}, shares, cfg)
+
+	if role := sharedCollabRole(context.Background(), shares, note, "editor-1"); role != domain.CollabRoleEditor {
+		t.Fatalf("expected an editor share to edit in collab, got %q", role)
+	}
+	shares.role = models.NoteRoleCommenter
+	if role := sharedCollabRole(context.Background(), shares, note, "editor-1"); role != domain.CollabRoleViewer || role.CanEdit() {
+		t.Fatalf("expected a commenter share to join read-only, got %q", role)
+	}
+
+	token, err := collabAPI.signCollabToken(note.ID, "editor-1", string(domain.CollabRoleEditor))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	shares.role = ""
+	_, err = handler.authenticate(httptest.NewRequest(http.MethodGet, "/ws?token="+token, nil))
+	var handshakeErr *wsHandshakeError
+	if !errors.As(err, &handshakeErr) || handshakeErr.status != http.StatusForbidden {
+		t.Fatalf("expected editor token to be rejected once the share is revoked, got %v", err)
+	}
+}
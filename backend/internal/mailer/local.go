@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// logMailer prints emails to the log instead of sending them
+type logMailer struct {
+	from string
+}
+
+// NewLogMailer creates a mailer that logs every email
+func NewLogMailer(from string) Mailer {
+	return &logMailer{from: from}
+}
+
+// Send logs the email
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("📧 Mail from %s to %s: %s\n%s", m.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// fileMailer writes each email to its own file so tests and developers can
+// read the links they contain
+type fileMailer struct {
+	from string
+	dir  string
+	seq  atomic.Int64
+}
+
+// NewFileMailer creates a mailer that writes emails to dir
+func NewFileMailer(from, dir string) (Mailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mail file_dir is required for the file provider")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &fileMailer{from: from, dir: dir}, nil
+}
+
+// Send writes the email as a .eml file named by time and recipient
+func (m *fileMailer) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%s-%03d-%s.eml",
+		time.Now().UTC().Format("20060102T150405.000"),
+		m.seq.Add(1),
+		strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To),
+	)
+	if err := os.WriteFile(filepath.Join(m.dir, name), formatMessage(m.from, msg), 0o644); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
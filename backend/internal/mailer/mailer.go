@@ -0,0 +1,36 @@
+// Package mailer delivers account emails such as address verification and
+// password reset links.
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New creates the mailer selected by cfg.Provider
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Provider {
+	case "", "log":
+		return NewLogMailer(cfg.From), nil
+	case "file":
+		return NewFileMailer(cfg.From, cfg.FileDir)
+	case "smtp":
+		return NewSMTPMailer(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown mail provider %q", cfg.Provider)
+	}
+}
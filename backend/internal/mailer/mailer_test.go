@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+)
+
+func TestFileMailerWritesOneFilePerMessage(t *testing.T) {
+	dir := t.TempDir()
+	m, err := New(config.MailConfig{Provider: "file", From: "no-reply@example.com", FileDir: dir})
+	if err != nil {
+		t.Fatalf("new mailer: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := m.Send(context.Background(), Message{To: "ana@example.com", Subject: "Reset your password", Body: "https://app/reset?token=abc"}); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("expected 2 mail files, got %v (%v)", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read mail: %v", err)
+	}
+	for _, want := range []string{"To: ana@example.com", "Subject: Reset your password", "https://app/reset?token=abc"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("expected mail to contain %q, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestNewRejectsUnknownProvider(t *testing.T) {
+	if _, err := New(config.MailConfig{Provider: "carrier-pigeon"}); err == nil {
+		t.Fatalf("expected an unknown provider to be rejected")
+	}
+	if _, err := New(config.MailConfig{Provider: "file"}); err == nil {
+		t.Fatalf("expected the file provider to require a directory")
+	}
+}
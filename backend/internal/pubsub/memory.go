@@ -0,0 +1,147 @@
+package pubsub
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryBroker implements Broker within a single process
+type memoryBroker struct {
+	mu       sync.Mutex
+	topics   map[string]map[*memorySubscription]struct{}
+	presence map[string]map[string]memoryPresence
+	now      func() time.Time
+}
+
+type memoryPresence struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+type memorySubscription struct {
+	broker *memoryBroker
+	topic  string
+	ch     chan []byte
+	once   sync.Once
+}
+
+// NewMemoryBroker creates an in-process broker. Rooms are only shared by
+// clients connected to the same instance.
+func NewMemoryBroker() Broker {
+	return &memoryBroker{
+		topics:   make(map[string]map[*memorySubscription]struct{}),
+		presence: make(map[string]map[string]memoryPresence),
+		now:      time.Now,
+	}
+}
+
+// Publish delivers data to every subscriber of topic
+func (b *memoryBroker) Publish(ctx context.Context, topic string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.topics[topic] {
+		select {
+		case sub.ch <- data:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe starts receiving messages published to topic
+func (b *memoryBroker) Subscribe(ctx context.Context, topic string) (Subscription, error) {
+	sub := &memorySubscription{
+		broker: b,
+		topic:  topic,
+		ch:     make(chan []byte, subscriptionBuffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[*memorySubscription]struct{})
+	}
+	b.topics[topic][sub] = struct{}{}
+	return sub, nil
+}
+
+// SetPresence records member in topic until ttl elapses
+func (b *memoryBroker) SetPresence(ctx context.Context, topic, member string, data []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.presence[topic] == nil {
+		b.presence[topic] = make(map[string]memoryPresence)
+	}
+	b.presence[topic][member] = memoryPresence{data: data, expiresAt: b.now().Add(ttl)}
+	return nil
+}
+
+// RemovePresence drops member from topic
+func (b *memoryBroker) RemovePresence(ctx context.Context, topic, member string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.presence[topic], member)
+	if len(b.presence[topic]) == 0 {
+		delete(b.presence, topic)
+	}
+	return nil
+}
+
+// ListPresence returns the data of every live member of topic, ordered by member
+func (b *memoryBroker) ListPresence(ctx context.Context, topic string) ([][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	members := make([]string, 0, len(b.presence[topic]))
+	for member, entry := range b.presence[topic] {
+		if !entry.expiresAt.After(now) {
+			delete(b.presence[topic], member)
+			continue
+		}
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	out := make([][]byte, 0, len(members))
+	for _, member := range members {
+		out = append(out, b.presence[topic][member].data)
+	}
+	return out, nil
+}
+
+// Close ends every open subscription
+func (b *memoryBroker) Close() error {
+	b.mu.Lock()
+	subs := make([]*memorySubscription, 0)
+	for _, topicSubs := range b.topics {
+		for sub := range topicSubs {
+			subs = append(subs, sub)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+	return nil
+}
+
+func (s *memorySubscription) Messages() <-chan []byte {
+	return s.ch
+}
+
+func (s *memorySubscription) Close() error {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		defer s.broker.mu.Unlock()
+		delete(s.broker.topics[s.topic], s)
+		if len(s.broker.topics[s.topic]) == 0 {
+			delete(s.broker.topics, s.topic)
+		}
+		close(s.ch)
+	})
+	return nil
+}
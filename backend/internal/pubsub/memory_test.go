@@ -0,0 +1,74 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryBrokerDeliversOnlyToTopicSubscribers(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker()
+	defer broker.Close()
+
+	noteA, err := broker.Subscribe(ctx, "note:a")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	noteB, err := broker.Subscribe(ctx, "note:b")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	if err := broker.Publish(ctx, "note:a", []byte("hello")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	select {
+	case msg := <-noteA.Messages():
+		if string(msg) != "hello" {
+			t.Fatalf("expected %q, got %q", "hello", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected subscriber of note:a to receive the message")
+	}
+	select {
+	case msg := <-noteB.Messages():
+		t.Fatalf("expected note:b to receive nothing, got %q", msg)
+	default:
+	}
+
+	noteA.Close()
+	if _, ok := <-noteA.Messages(); ok {
+		t.Fatal("expected closed subscription channel")
+	}
+}
+
+func TestMemoryBrokerPresenceExpires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	broker := &memoryBroker{
+		topics:   make(map[string]map[*memorySubscription]struct{}),
+		presence: make(map[string]map[string]memoryPresence),
+		now:      func() time.Time { return now },
+	}
+
+	broker.SetPresence(ctx, "note:a", "conn-1", []byte("alice"), time.Minute)
+	broker.SetPresence(ctx, "note:a", "conn-2", []byte("bob"), 10*time.Second)
+	broker.SetPresence(ctx, "note:b", "conn-3", []byte("carol"), time.Minute)
+
+	now = now.Add(30 * time.Second)
+	members, err := broker.ListPresence(ctx, "note:a")
+	if err != nil {
+		t.Fatalf("list presence: %v", err)
+	}
+	if len(members) != 1 || string(members[0]) != "alice" {
+		t.Fatalf("expected only alice present in note:a, got %q", members)
+	}
+
+	broker.RemovePresence(ctx, "note:a", "conn-1")
+	members, _ = broker.ListPresence(ctx, "note:a")
+	if len(members) != 0 {
+		t.Fatalf("expected note:a empty after removal, got %q", members)
+	}
+}
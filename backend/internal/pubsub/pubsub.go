@@ -0,0 +1,51 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+)
+
+// Broker fans messages out to every subscriber of a topic and tracks who is
+// present in it, so several backend instances can share collaboration rooms
+type Broker interface {
+	// Publish delivers data to every subscriber of topic, on any instance
+	Publish(ctx context.Context, topic string, data []byte) error
+	// Subscribe starts receiving messages published to topic
+	Subscribe(ctx context.Context, topic string) (Subscription, error)
+
+	// SetPresence records member in topic until ttl elapses without a refresh
+	SetPresence(ctx context.Context, topic, member string, data []byte, ttl time.Duration) error
+	// RemovePresence drops member from topic
+	RemovePresence(ctx context.Context, topic, member string) error
+	// ListPresence returns the data of every live member of topic
+	ListPresence(ctx context.Context, topic string) ([][]byte, error)
+
+	// Close releases the broker's connections
+	Close() error
+}
+
+// Subscription is a live subscription to one topic
+type Subscription interface {
+	// Messages is closed once the subscription is closed
+	Messages() <-chan []byte
+	Close() error
+}
+
+// subscriptionBuffer is how many messages a subscriber may fall behind
+// before further messages to it are dropped
+const subscriptionBuffer = 256
+
+// NewBroker creates the broker selected by cfg.PubSub
+func NewBroker(ctx context.Context, cfg config.CollabConfig, redisCfg config.RedisConfig) (Broker, error) {
+	switch cfg.PubSub {
+	case "", "memory":
+		return NewMemoryBroker(), nil
+	case "redis":
+		return NewRedisBroker(ctx, redisCfg)
+	default:
+		return nil, fmt.Errorf("unknown collab pubsub backend %q", cfg.PubSub)
+	}
+}
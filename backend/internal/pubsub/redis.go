@@ -0,0 +1,197 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+)
+
+// redisKeyPrefix namespaces every channel and key the broker uses
+const redisKeyPrefix = "collab:"
+
+// redisBroker implements Broker on Redis pub/sub. Presence is kept per topic
+// in a sorted set scored by expiry, with member data in a companion hash.
+type redisBroker struct {
+	client *redis.Client
+}
+
+type redisSubscription struct {
+	pubsub *redis.PubSub
+	ch     chan []byte
+	once   sync.Once
+	done   chan struct{}
+}
+
+// NewRedisBroker connects to Redis and verifies the connection
+func NewRedisBroker(ctx context.Context, cfg config.RedisConfig) (Broker, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return &redisBroker{client: client}, nil
+}
+
+func redisChannel(topic string) string {
+	return redisKeyPrefix + topic
+}
+
+func redisPresenceKeys(topic string) (string, string) {
+	return redisKeyPrefix + "presence:" + topic, redisKeyPrefix + "presence-data:" + topic
+}
+
+// Publish delivers data to every subscriber of topic, on any instance
+func (b *redisBroker) Publish(ctx context.Context, topic string, data []byte) error {
+	if err := b.client.Publish(ctx, redisChannel(topic), data).Err(); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Subscribe starts receiving messages published to topic
+func (b *redisBroker) Subscribe(ctx context.Context, topic string) (Subscription, error) {
+	ps := b.client.Subscribe(ctx, redisChannel(topic))
+	// Wait for the subscription to be confirmed so no later publish is missed
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+
+	sub := &redisSubscription{
+		pubsub: ps,
+		ch:     make(chan []byte, subscriptionBuffer),
+		done:   make(chan struct{}),
+	}
+	go sub.pump()
+	return sub, nil
+}
+
+// SetPresence records member in topic until ttl elapses without a refresh
+func (b *redisBroker) SetPresence(ctx context.Context, topic, member string, data []byte, ttl time.Duration) error {
+	setKey, dataKey := redisPresenceKeys(topic)
+	expiresAt := time.Now().Add(ttl).UnixMilli()
+
+	pipe := b.client.TxPipeline()
+	pipe.ZAdd(ctx, setKey, redis.Z{Score: float64(expiresAt), Member: member})
+	pipe.HSet(ctx, dataKey, member, data)
+	// Keys outlive their members so an abandoned room cleans itself up
+	pipe.Expire(ctx, setKey, 2*ttl)
+	pipe.Expire(ctx, dataKey, 2*ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set presence in %s: %w", topic, err)
+	}
+	return nil
+}
+
+// RemovePresence drops member from topic
+func (b *redisBroker) RemovePresence(ctx context.Context, topic, member string) error {
+	setKey, dataKey := redisPresenceKeys(topic)
+
+	pipe := b.client.TxPipeline()
+	pipe.ZRem(ctx, setKey, member)
+	pipe.HDel(ctx, dataKey, member)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove presence from %s: %w", topic, err)
+	}
+	return nil
+}
+
+// ListPresence returns the data of every live member of topic, ordered by member
+func (b *redisBroker) ListPresence(ctx context.Context, topic string) ([][]byte, error) {
+	setKey, dataKey := redisPresenceKeys(topic)
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	// Members whose instance died without removing them expire here
+	expired, err := b.client.ZRangeByScore(ctx, setKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list presence in %s: %w", topic, err)
+	}
+	if len(expired) > 0 {
+		pipe := b.client.TxPipeline()
+		pipe.ZRemRangeByScore(ctx, setKey, "-inf", now)
+		pipe.HDel(ctx, dataKey, expired...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to expire presence in %s: %w", topic, err)
+		}
+	}
+
+	members, err := b.client.ZRangeByScore(ctx, setKey, &redis.ZRangeBy{Min: "(" + now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list presence in %s: %w", topic, err)
+	}
+	if len(members) == 0 {
+		return [][]byte{}, nil
+	}
+
+	values, err := b.client.HMGet(ctx, dataKey, members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presence in %s: %w", topic, err)
+	}
+
+	// ZRANGEBYSCORE orders by expiry; keep a stable order across instances
+	byMember := make(map[string][]byte, len(members))
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			byMember[members[i]] = []byte(s)
+		}
+	}
+	sort.Strings(members)
+	out := make([][]byte, 0, len(byMember))
+	for _, member := range members {
+		if data, ok := byMember[member]; ok {
+			out = append(out, data)
+		}
+	}
+	return out, nil
+}
+
+// Close closes the Redis client, ending every subscription
+func (b *redisBroker) Close() error {
+	return b.client.Close()
+}
+
+// pump copies Redis messages onto the subscription channel, dropping
+// messages for a subscriber that fell behind
+func (s *redisSubscription) pump() {
+	defer close(s.ch)
+	msgs := s.pubsub.Channel()
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			select {
+			case s.ch <- []byte(msg.Payload):
+			default:
+			}
+		}
+	}
+}
+
+func (s *redisSubscription) Messages() <-chan []byte {
+	return s.ch
+}
+
+func (s *redisSubscription) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		err = s.pubsub.Close()
+	})
+	return err
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// AIRunAuditFilter selects the runs of an audit query. Zero fields match everything.
+type AIRunAuditFilter struct {
+	UserID string
+	NoteID string
+	Status models.AIRunStatus
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+	// Before continues a listing after the last run of the previous page
+	Before *AIRunAuditCursor
+}
+
+// AIRunAuditCursor points at the last run of a page
+type AIRunAuditCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// AIAuditRepository reads AI runs together with their tool activity
+type AIAuditRepository interface {
+	ListRuns(ctx context.Context, filter AIRunAuditFilter) ([]models.AIRun, int64, error)
+	ListToolCalls(ctx context.Context, runIDs []string) ([]models.AIToolCall, error)
+	ListToolEvents(ctx context.Context, runIDs []string) ([]models.AIRunEvent, error)
+	ListRevisions(ctx context.Context, runIDs []string) ([]models.NoteRevision, error)
+}
+
+// aiAuditRepository implements AIAuditRepository
+type aiAuditRepository struct {
+	db *database.DB
+}
+
+// NewAIAuditRepository creates a new AI audit repository
+func NewAIAuditRepository(db *database.DB) AIAuditRepository {
+	return &aiAuditRepository{db: db}
+}
+
+// ListRuns retrieves the matching runs, newest first, and how many match in
+// total. The total ignores the Before cursor.
+func (r *aiAuditRepository) ListRuns(ctx context.Context, filter AIRunAuditFilter) ([]models.AIRun, int64, error) {
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 50
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.AIRun{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.NoteID != "" {
+		query = query.Where("note_id = ?", filter.NoteID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Before != nil {
+		query = query.Where("(created_at, id) < (?, ?)", filter.Before.CreatedAt, filter.Before.ID)
+	}
+	var runs []models.AIRun
+	err := query.Order("created_at DESC").Order("id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&runs).Error
+	return runs, total, err
+}
+
+// ListToolCalls retrieves the consent-tracked tool calls of the given runs
+func (r *aiAuditRepository) ListToolCalls(ctx context.Context, runIDs []string) ([]models.AIToolCall, error) {
+	var calls []models.AIToolCall
+	if len(runIDs) == 0 {
+		return calls, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("run_id IN ?", runIDs).
+		Order("created_at").
+		Find(&calls).Error
+	return calls, err
+}
+
+// ListToolEvents retrieves the tool call and result events of the given runs, in order
+func (r *aiAuditRepository) ListToolEvents(ctx context.Context, runIDs []string) ([]models.AIRunEvent, error) {
+	var events []models.AIRunEvent
+	if len(runIDs) == 0 {
+		return events, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("run_id IN ? AND event_type IN ?", runIDs, []string{"tool.call", "tool.result"}).
+		Order("created_at").
+		Find(&events).Error
+	return events, err
+}
+
+// ListRevisions retrieves the note revisions the given runs produced, without
+// their content
+func (r *aiAuditRepository) ListRevisions(ctx context.Context, runIDs []string) ([]models.NoteRevision, error) {
+	var revisions []models.NoteRevision
+	if len(runIDs) == 0 {
+		return revisions, nil
+	}
+	err := r.db.WithContext(ctx).
+		Select("id", "note_id", "version", "source", "run_id", "tool_call_id", "created_at").
+		Where("run_id IN ?", runIDs).
+		Order("created_at").
+		Find(&revisions).Error
+	return revisions, err
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+var ErrAIEditProposalNotPending = errors.New("ai edit proposal is not pending")
+
+// AIEditProposalRepository defines the interface for AI edit proposal data operations
+type AIEditProposalRepository interface {
+	Create(ctx context.Context, proposal *models.AIEditProposal) error
+	GetByID(ctx context.Context, id string) (*models.AIEditProposal, error)
+	ListByNoteID(ctx context.Context, noteID string, status models.AIEditProposalStatus, limit int) ([]*models.AIEditProposal, error)
+	Claim(ctx context.Context, id string) error
+	Release(ctx context.Context, id string) error
+	ReleaseStale(ctx context.Context, claimedBefore time.Time) (int64, error)
+	Resolve(ctx context.Context, id string, from, status models.AIEditProposalStatus, appliedVersion *int, rebased bool) error
+}
+
+// aiEditProposalRepository implements AIEditProposalRepository
+type aiEditProposalRepository struct {
+	db *database.DB
+}
+
+// NewAIEditProposalRepository creates a new AI edit proposal repository
+func NewAIEditProposalRepository(db *database.DB) AIEditProposalRepository {
+	return &aiEditProposalRepository{db: db}
+}
+
+// Create stores a new proposal
+func (r *aiEditProposalRepository) Create(ctx context.Context, proposal *models.AIEditProposal) error {
+	return r.db.WithContext(ctx).Create(proposal).Error
+}
+
+// GetByID retrieves a proposal by ID
+func (r *aiEditProposalRepository) GetByID(ctx context.Context, id string) (*models.AIEditProposal, error) {
+	var proposal models.AIEditProposal
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&proposal).Error; err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// ListByNoteID retrieves the proposals of a note, newest first. An empty
+// status lists all of them.
+func (r *aiEditProposalRepository) ListByNoteID(ctx context.Context, noteID string, status models.AIEditProposalStatus, limit int) ([]*models.AIEditProposal, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	query := r.db.WithContext(ctx).Where("note_id = ?", noteID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var proposals []*models.AIEditProposal
+	err := query.Order("created_at DESC").Limit(limit).Find(&proposals).Error
+	return proposals, err
+}
+
+// Claim moves a pending proposal to applying, so only one accept writes the
+// note. It fails with ErrAIEditProposalNotPending when another request got
+// there first.
+func (r *aiEditProposalRepository) Claim(ctx context.Context, id string) error {
+	return r.transition(ctx, id, models.AIEditProposalStatusPending, map[string]interface{}{
+		"status": models.AIEditProposalStatusApplying,
+	})
+}
+
+// Release returns a claimed proposal to pending after its note could not be written
+func (r *aiEditProposalRepository) Release(ctx context.Context, id string) error {
+	return r.transition(ctx, id, models.AIEditProposalStatusApplying, map[string]interface{}{
+		"status": models.AIEditProposalStatusPending,
+	})
+}
+
+// ReleaseStale returns proposals claimed before the given time to pending. A
+// claim that old belongs to an accept that crashed or could not resolve it.
+func (r *aiEditProposalRepository) ReleaseStale(ctx context.Context, claimedBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.AIEditProposal{}).
+		Where("status = ? AND updated_at < ?", models.AIEditProposalStatusApplying, claimedBefore).
+		Update("status", models.AIEditProposalStatusPending)
+	return result.RowsAffected, result.Error
+}
+
+// Resolve moves a proposal from the given status to accepted or rejected. It
+// fails with ErrAIEditProposalNotPending when another request moved it first.
+func (r *aiEditProposalRepository) Resolve(ctx context.Context, id string, from, status models.AIEditProposalStatus, appliedVersion *int, rebased bool) error {
+	now := time.Now().UTC()
+	return r.transition(ctx, id, from, map[string]interface{}{
+		"status":          status,
+		"applied_version": appliedVersion,
+		"rebased":         rebased,
+		"resolved_at":     &now,
+	})
+}
+
+func (r *aiEditProposalRepository) transition(ctx context.Context, id string, from models.AIEditProposalStatus, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.AIEditProposal{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAIEditProposalNotPending
+	}
+	return nil
+}
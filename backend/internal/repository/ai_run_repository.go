@@ -9,6 +9,7 @@ import (
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var ErrAIToolCallNotPending = errors.New("ai tool call is not pending")
@@ -23,9 +24,12 @@ type AIRunRepository interface {
 	UpsertPendingToolCall(ctx context.Context, call *models.AIToolCall) error
 	ApproveToolCall(ctx context.Context, runID, toolCallID, userID string, approved bool) error
 	GetToolCall(ctx context.Context, runID, toolCallID string) (*models.AIToolCall, error)
-	ExpirePendingToolCalls(ctx context.Context, now time.Time) error
+	ExpirePendingToolCalls(ctx context.Context, now time.Time) ([]models.AIToolCall, error)
+	ListPendingToolCalls(ctx context.Context, userID string, now time.Time, limit int) ([]models.AIToolCall, error)
 	CreateConversation(ctx context.Context, conversation *models.AIConversation) error
-	ListConversations(ctx context.Context, userID string, limit int) ([]models.AIConversation, error)
+	ListConversations(ctx context.Context, userID string, before *AIConversationCursor, limit int) ([]models.AIConversation, error)
+	SearchConversations(ctx context.Context, userID, query string, limit int) ([]AIConversationSearchHit, error)
+	ForkConversation(ctx context.Context, conversation *models.AIConversation, messages []models.AIConversationMessage) error
 	GetConversation(ctx context.Context, conversationID, userID string) (*models.AIConversation, error)
 	UpdateConversationTitle(ctx context.Context, conversationID, userID, title string) error
 	SoftDeleteConversation(ctx context.Context, conversationID, userID string) error
@@ -33,6 +37,22 @@ type AIRunRepository interface {
 	ListConversationMessages(ctx context.Context, conversationID string) ([]models.AIConversationMessage, error)
 }
 
+// AIConversationCursor points at the last conversation of a page
+type AIConversationCursor struct {
+	LastMessageAt time.Time
+	ID            string
+}
+
+// AIConversationSearchHit is a conversation matching a search with its best
+// matching message
+type AIConversationSearchHit struct {
+	Conversation models.AIConversation
+	MessageID    string
+	Role         string
+	Snippet      string
+	Rank         float64
+}
+
 type aiRunRepository struct {
 	db *gorm.DB
 }
@@ -61,14 +81,18 @@ func (r *aiRunRepository) UpdateRunStatus(ctx context.Context, runID string, sta
 		Error
 }
 
+// SetRunResume records the run's last event; an empty resumeToken keeps the stored one
 func (r *aiRunRepository) SetRunResume(ctx context.Context, runID, resumeToken, lastEventID string) error {
+	updates := map[string]interface{}{
+		"last_event_id": lastEventID,
+	}
+	if resumeToken != "" {
+		updates["resume_token"] = resumeToken
+	}
 	return r.db.WithContext(ctx).
 		Model(&models.AIRun{}).
 		Where("run_id = ?", runID).
-		Updates(map[string]interface{}{
-			"resume_token":  resumeToken,
-			"last_event_id": lastEventID,
-		}).
+		Updates(updates).
 		Error
 }
 
@@ -83,11 +107,10 @@ func (r *aiRunRepository) ListEventsAfter(ctx context.Context, runID, lastEventI
 
 	query := r.db.WithContext(ctx).
 		Where("run_id = ?", runID).
-		Order("created_at ASC").
-		Order("id ASC").
+		Order("seq ASC").
 		Limit(limit)
 	if lastEventID != "" {
-		query = query.Where("event_id <> ? AND created_at > COALESCE((SELECT created_at FROM ai_run_events WHERE run_id = ? AND event_id = ? LIMIT 1), '-infinity')", lastEventID, runID, lastEventID)
+		query = query.Where("seq > COALESCE((SELECT seq FROM ai_run_events WHERE run_id = ? AND event_id = ? LIMIT 1), 0)", runID, lastEventID)
 	}
 
 	var events []models.AIRunEvent
@@ -142,31 +165,160 @@ func (r *aiRunRepository) GetToolCall(ctx context.Context, runID, toolCallID str
 	return &call, nil
 }
 
-func (r *aiRunRepository) ExpirePendingToolCalls(ctx context.Context, now time.Time) error {
-	return r.db.WithContext(ctx).
-		Model(&models.AIToolCall{}).
+// ExpirePendingToolCalls marks pending tool calls past their expiry as expired
+// and returns the calls it changed
+func (r *aiRunRepository) ExpirePendingToolCalls(ctx context.Context, now time.Time) ([]models.AIToolCall, error) {
+	var expired []models.AIToolCall
+	err := r.db.WithContext(ctx).
+		Model(&expired).
+		Clauses(clause.Returning{}).
 		Where("status = ? AND expires_at IS NOT NULL AND expires_at < ?", models.AIToolCallStatusPending, now).
 		Update("status", models.AIToolCallStatusExpired).
 		Error
+	return expired, err
+}
+
+// ListPendingToolCalls lists the tool calls still waiting for userID's consent
+// across all of their runs, newest first
+func (r *aiRunRepository) ListPendingToolCalls(ctx context.Context, userID string, now time.Time, limit int) ([]models.AIToolCall, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	var calls []models.AIToolCall
+	err := r.db.WithContext(ctx).
+		Model(&models.AIToolCall{}).
+		Joins("JOIN ai_runs ON ai_runs.run_id = ai_tool_calls.run_id AND ai_runs.deleted_at IS NULL").
+		Where("ai_runs.user_id = ? AND ai_tool_calls.status = ?", userID, models.AIToolCallStatusPending).
+		Where("ai_tool_calls.expires_at IS NULL OR ai_tool_calls.expires_at > ?", now).
+		Order("ai_tool_calls.created_at DESC").
+		Limit(limit).
+		Find(&calls).Error
+	return calls, err
 }
 
 func (r *aiRunRepository) CreateConversation(ctx context.Context, conversation *models.AIConversation) error {
 	return r.db.WithContext(ctx).Create(conversation).Error
 }
 
-func (r *aiRunRepository) ListConversations(ctx context.Context, userID string, limit int) ([]models.AIConversation, error) {
+// ListConversations lists a user's conversations by last activity, starting
+// after the before cursor when given
+func (r *aiRunRepository) ListConversations(ctx context.Context, userID string, before *AIConversationCursor, limit int) ([]models.AIConversation, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 50
 	}
+	query := r.db.WithContext(ctx).
+		Where("user_id = ? AND is_deleted = ?", userID, false)
+	if before != nil {
+		query = query.Where("(last_message_at, id) < (?, ?)", before.LastMessageAt, before.ID)
+	}
 	var conversations []models.AIConversation
-	return conversations, r.db.WithContext(ctx).
-		Where("user_id = ? AND is_deleted = ?", userID, false).
+	return conversations, query.
 		Order("last_message_at DESC").
-		Order("created_at DESC").
+		Order("id DESC").
 		Limit(limit).
 		Find(&conversations).Error
 }
 
+type conversationSearchRow struct {
+	ConversationID string
+	MessageID      string
+	Role           string
+	Snippet        string
+	Rank           float64
+}
+
+// SearchConversations full-text searches the messages of a user's
+// conversations and returns each matching conversation once, best match first
+func (r *aiRunRepository) SearchConversations(ctx context.Context, userID, query string, limit int) ([]AIConversationSearchHit, error) {
+	tsQuery := BuildPrefixTSQuery(query)
+	if tsQuery == "" {
+		return []AIConversationSearchHit{}, nil
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var rows []conversationSearchRow
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT * FROM (
+			SELECT DISTINCT ON (m.conversation_id)
+				m.conversation_id, m.id AS message_id, m.role,
+				ts_headline('simple', translate(m.content, ?, ''), q, ?) AS snippet,
+				ts_rank_cd(m.search_vector, q, 32) AS rank
+			FROM ai_conversation_messages m
+			JOIN ai_conversations c ON c.id = m.conversation_id
+			CROSS JOIN to_tsquery('simple', ?) AS q
+			WHERE c.user_id = ? AND c.is_deleted = false AND c.deleted_at IS NULL
+				AND m.deleted_at IS NULL AND m.search_vector @@ q
+			ORDER BY m.conversation_id, rank DESC, m.created_at DESC
+		) hits
+		ORDER BY rank DESC
+		LIMIT ?`,
+		highlightStart+highlightStop, noteHeadlineOptions, tsQuery, userID, limit,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []AIConversationSearchHit{}, nil
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ConversationID)
+	}
+	var conversations []models.AIConversation
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&conversations).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.AIConversation, len(conversations))
+	for _, conversation := range conversations {
+		byID[conversation.ID] = conversation
+	}
+
+	hits := make([]AIConversationSearchHit, 0, len(rows))
+	for _, row := range rows {
+		conversation, ok := byID[row.ConversationID]
+		if !ok {
+			continue
+		}
+		hits = append(hits, AIConversationSearchHit{
+			Conversation: conversation,
+			MessageID:    row.MessageID,
+			Role:         row.Role,
+			Snippet:      markHighlights(row.Snippet),
+			Rank:         row.Rank,
+		})
+	}
+	return hits, nil
+}
+
+// ForkConversation creates a branch conversation holding copies of the given
+// messages. The copies keep their timestamps so the branch reads the same.
+func (r *aiRunRepository) ForkConversation(ctx context.Context, conversation *models.AIConversation, messages []models.AIConversationMessage) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(conversation).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		copies := make([]models.AIConversationMessage, 0, len(messages))
+		for _, message := range messages {
+			copies = append(copies, models.AIConversationMessage{
+				BaseModel:      models.BaseModel{CreatedAt: message.CreatedAt, UpdatedAt: message.CreatedAt},
+				ConversationID: conversation.ID,
+				RunID:          message.RunID,
+				Role:           message.Role,
+				Content:        message.Content,
+				Metadata:       message.Metadata,
+			})
+		}
+		return tx.Create(&copies).Error
+	})
+}
+
 func (r *aiRunRepository) GetConversation(ctx context.Context, conversationID, userID string) (*models.AIConversation, error) {
 	var conversation models.AIConversation
 	if err := r.db.WithContext(ctx).
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+)
+
+// sqlRecorder keeps the statements a dry-run database would have executed
+type sqlRecorder struct {
+	logger.Interface
+	statements []string
+}
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
+
+func dryRunDB(t *testing.T) (*database.DB, *sqlRecorder) {
+	t.Helper()
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=invalid"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               recorder,
+	})
+	if err != nil {
+		t.Fatalf("open dry-run database: %v", err)
+	}
+	return &database.DB{DB: db}, recorder
+}
+
+func TestListEventsAfterPagesBySeq(t *testing.T) {
+	db, recorder := dryRunDB(t)
+	repo := NewAIRunRepository(db)
+
+	// Deltas persisted in the same instant share created_at, so the cursor
+	// must not compare timestamps or the rest of that instant is skipped
+	if _, err := repo.ListEventsAfter(context.Background(), "run-1", "evt-200", 200); err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(recorder.statements) != 1 {
+		t.Fatalf("expected one statement, got %v", recorder.statements)
+	}
+	sql := recorder.statements[0]
+	if strings.Contains(sql, "created_at") {
+		t.Fatalf("expected no timestamp cursor, got %s", sql)
+	}
+	for _, want := range []string{
+		"seq > COALESCE((SELECT seq FROM ai_run_events WHERE run_id = 'run-1' AND event_id = 'evt-200' LIMIT 1), 0)",
+		"ORDER BY seq ASC",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in %s", want, sql)
+		}
+	}
+}
+
+func TestSearchConversationsHighlightsWithSentinels(t *testing.T) {
+	db, recorder := dryRunDB(t)
+	repo := NewAIRunRepository(db)
+
+	// Raw scans report that dry runs are unsupported after building the SQL
+	if _, err := repo.SearchConversations(context.Background(), "user-1", "plan", 20); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatalf("search conversations: %v", err)
+	}
+	if len(recorder.statements) != 1 {
+		t.Fatalf("expected one statement, got %v", recorder.statements)
+	}
+	sql := recorder.statements[0]
+	if strings.Contains(sql, "<mark>") {
+		t.Fatalf("expected ts_headline to mark matches with sentinels, got %s", sql)
+	}
+	for _, want := range []string{
+		"ts_headline('simple', translate(m.content, '" + highlightStart + highlightStop + "', ''), q, ",
+		`StartSel="` + highlightStart + `"`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %q in %s", want, sql)
+		}
+	}
+}
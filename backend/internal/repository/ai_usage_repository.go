@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// AIUsageTotals sums the usage of a set of AI runs
+type AIUsageTotals struct {
+	Runs             int64   `json:"runs"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// AIModeUsage is the usage of the runs of one mode
+type AIModeUsage struct {
+	Mode models.AIRunMode `json:"mode"`
+	AIUsageTotals
+}
+
+// AIToolUsage counts the calls made to one tool
+type AIToolUsage struct {
+	Tool  string `json:"tool"`
+	Calls int64  `json:"calls"`
+}
+
+// AIUsageRepository defines the interface for AI usage accounting
+type AIUsageRepository interface {
+	RecordRunUsage(ctx context.Context, runID, model string, promptTokens, completionTokens, totalTokens int64, costUSD float64) error
+	SumUsage(ctx context.Context, userID string, since time.Time) (*AIUsageTotals, error)
+	UsageByMode(ctx context.Context, userID string, since time.Time) ([]AIModeUsage, error)
+	ToolCallsByTool(ctx context.Context, userID string, since time.Time) ([]AIToolUsage, error)
+	GetLimit(ctx context.Context, userID string) (*models.AIUsageLimit, error)
+}
+
+// aiUsageRepository implements AIUsageRepository
+type aiUsageRepository struct {
+	db *database.DB
+}
+
+// NewAIUsageRepository creates a new AI usage repository
+func NewAIUsageRepository(db *database.DB) AIUsageRepository {
+	return &aiUsageRepository{db: db}
+}
+
+// RecordRunUsage stores the token usage and cost of a completed run
+func (r *aiUsageRepository) RecordRunUsage(ctx context.Context, runID, model string, promptTokens, completionTokens, totalTokens int64, costUSD float64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.AIRun{}).
+		Where("run_id = ?", runID).
+		Updates(map[string]interface{}{
+			"model":             model,
+			"prompt_tokens":     promptTokens,
+			"completion_tokens": completionTokens,
+			"total_tokens":      totalTokens,
+			"cost_usd":          costUSD,
+		}).Error
+}
+
+// SumUsage sums the runs a user started since the given time
+func (r *aiUsageRepository) SumUsage(ctx context.Context, userID string, since time.Time) (*AIUsageTotals, error) {
+	var totals AIUsageTotals
+	err := r.db.WithContext(ctx).
+		Model(&models.AIRun{}).
+		Select(usageTotalsColumns).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+// UsageByMode sums the runs a user started since the given time per mode
+func (r *aiUsageRepository) UsageByMode(ctx context.Context, userID string, since time.Time) ([]AIModeUsage, error) {
+	var usage []AIModeUsage
+	err := r.db.WithContext(ctx).
+		Model(&models.AIRun{}).
+		Select("mode, "+usageTotalsColumns).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Group("mode").
+		Order("mode").
+		Scan(&usage).Error
+	return usage, err
+}
+
+// ToolCallsByTool counts the tool calls of a user's runs since the given time
+func (r *aiUsageRepository) ToolCallsByTool(ctx context.Context, userID string, since time.Time) ([]AIToolUsage, error) {
+	var usage []AIToolUsage
+	err := r.db.WithContext(ctx).
+		Model(&models.AIRunEvent{}).
+		Select("ai_run_events.payload->>'tool' AS tool, COUNT(*) AS calls").
+		Joins("JOIN ai_runs ON ai_runs.run_id = ai_run_events.run_id AND ai_runs.deleted_at IS NULL").
+		Where("ai_runs.user_id = ? AND ai_run_events.event_type = ? AND ai_run_events.created_at >= ?", userID, "tool.call", since).
+		Group("tool").
+		Order("calls DESC").
+		Scan(&usage).Error
+	return usage, err
+}
+
+// GetLimit retrieves a user's quota overrides
+func (r *aiUsageRepository) GetLimit(ctx context.Context, userID string) (*models.AIUsageLimit, error) {
+	var limit models.AIUsageLimit
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&limit).Error; err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+const usageTotalsColumns = "COUNT(*) AS runs, " +
+	"COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, " +
+	"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, " +
+	"COALESCE(SUM(total_tokens), 0) AS total_tokens, " +
+	"COALESCE(SUM(cost_usd), 0) AS cost_usd"
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// AuthSessionRepository defines the interface for sign-in session data operations
+type AuthSessionRepository interface {
+	Create(ctx context.Context, session *models.AuthSession) error
+	GetByID(ctx context.Context, id string) (*models.AuthSession, error)
+	ListActiveByUserID(ctx context.Context, userID string, now time.Time) ([]*models.AuthSession, error)
+	Rotate(ctx context.Context, session *models.AuthSession, previousTokenID string) (bool, error)
+	Revoke(ctx context.Context, id, reason string, at time.Time) error
+	RevokeAllByUserID(ctx context.Context, userID, exceptID, reason string, at time.Time) ([]string, error)
+}
+
+// authSessionRepository implements AuthSessionRepository
+type authSessionRepository struct {
+	db *database.DB
+}
+
+// NewAuthSessionRepository creates a new session repository
+func NewAuthSessionRepository(db *database.DB) AuthSessionRepository {
+	return &authSessionRepository{db: db}
+}
+
+// Create stores a new session
+func (r *authSessionRepository) Create(ctx context.Context, session *models.AuthSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// GetByID retrieves a session by ID
+func (r *authSessionRepository) GetByID(ctx context.Context, id string) (*models.AuthSession, error) {
+	var session models.AuthSession
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveByUserID lists a user's unrevoked, unexpired sessions, most
+// recently used first
+func (r *authSessionRepository) ListActiveByUserID(ctx context.Context, userID string, now time.Time) ([]*models.AuthSession, error) {
+	var sessions []*models.AuthSession
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Rotate swaps in the session's new refresh token ID, client details and
+// expiry, but only while previousTokenID is still current. It reports false
+// when another refresh got there first.
+func (r *authSessionRepository) Rotate(ctx context.Context, session *models.AuthSession, previousTokenID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.AuthSession{}).
+		Where("id = ? AND refresh_token_id = ? AND revoked_at IS NULL", session.ID, previousTokenID).
+		Updates(map[string]interface{}{
+			"refresh_token_id": session.RefreshTokenID,
+			"device":           session.Device,
+			"user_agent":       session.UserAgent,
+			"ip_address":       session.IPAddress,
+			"last_used_at":     session.LastUsedAt,
+			"expires_at":       session.ExpiresAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Revoke ends a session. Revoked sessions are kept so reuse of their tokens
+// can still be recognised.
+func (r *authSessionRepository) Revoke(ctx context.Context, id, reason string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.AuthSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"revoked_at":     at,
+			"revoked_reason": reason,
+		}).Error
+}
+
+// RevokeAllByUserID ends every active session of a user except exceptID, if
+// set, and returns the IDs it revoked
+func (r *authSessionRepository) RevokeAllByUserID(ctx context.Context, userID, exceptID, reason string, at time.Time) ([]string, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.AuthSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptID != "" {
+		query = query.Where("id <> ?", exceptID)
+	}
+
+	var ids []string
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&models.AuthSession{}).
+		Where("id IN ? AND revoked_at IS NULL", ids).
+		Updates(map[string]interface{}{
+			"revoked_at":     at,
+			"revoked_reason": reason,
+		}).Error
+	return ids, err
+}
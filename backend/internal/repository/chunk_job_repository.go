@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// ChunkJobRepository defines the interface for the chunking job queue
+type ChunkJobRepository interface {
+	// Enqueue schedules a note for chunking at runAfter, replacing any queued run
+	Enqueue(ctx context.Context, noteID, userID, event string, runAfter time.Time) error
+	// ClaimDue marks up to limit due jobs as running and returns them. Jobs
+	// left running longer than lease (a crashed worker) are claimed again.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.ChunkJob, error)
+	// Complete marks a claimed job succeeded unless the note was saved again meanwhile
+	Complete(ctx context.Context, id string, generation, chunkCount int) error
+	// Fail schedules a retry at retryAt, or dead-letters the job when retryAt is nil
+	Fail(ctx context.Context, id string, generation int, errMsg string, retryAt *time.Time) error
+	GetByNoteID(ctx context.Context, noteID string) (*models.ChunkJob, error)
+	ListByUserID(ctx context.Context, userID string, status *models.ChunkJobStatus, limit int) ([]*models.ChunkJob, error)
+	// GetNoteChunkState reports a note's stored chunks alongside its job
+	GetNoteChunkState(ctx context.Context, noteID string) (*NoteChunkState, error)
+	// ListUnhealthyNoteChunkStates lists a user's notes whose chunks are missing,
+	// older than the note, or whose job is queued or dead
+	ListUnhealthyNoteChunkStates(ctx context.Context, userID string, limit int) ([]*NoteChunkState, error)
+}
+
+// NoteChunkState joins a note with its stored chunks and chunk job
+type NoteChunkState struct {
+	NoteID        string
+	UserID        string
+	Title         string
+	NoteUpdatedAt time.Time
+	ChunkCount    int64
+	LastChunkedAt *time.Time
+	JobStatus     *models.ChunkJobStatus
+	JobAttempts   int
+	JobLastError  string
+	JobRunAfter   *time.Time
+}
+
+// chunkJobRepository implements ChunkJobRepository
+type chunkJobRepository struct {
+	db *database.DB
+}
+
+// NewChunkJobRepository creates a new chunk job repository
+func NewChunkJobRepository(db *database.DB) ChunkJobRepository {
+	return &chunkJobRepository{db: db}
+}
+
+// Enqueue schedules a note for chunking at runAfter, replacing any queued run
+func (r *chunkJobRepository) Enqueue(ctx context.Context, noteID, userID, event string, runAfter time.Time) error {
+	job := &models.ChunkJob{
+		NoteID:     noteID,
+		UserID:     userID,
+		Event:      event,
+		Status:     models.ChunkJobStatusPending,
+		Generation: 1,
+		RunAfter:   runAfter,
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "note_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"event":      event,
+				"status":     models.ChunkJobStatusPending,
+				"generation": gorm.Expr("chunk_jobs.generation + 1"),
+				"attempts":   0,
+				"run_after":  runAfter,
+				"updated_at": time.Now().UTC(),
+			}),
+		}).
+		Create(job).Error
+}
+
+// ClaimDue marks up to limit due jobs as running and returns them
+func (r *chunkJobRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.ChunkJob, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var jobs []*models.ChunkJob
+	err := r.db.WithContext(ctx).Raw(
+		`UPDATE chunk_jobs
+		 SET status = ?, locked_at = ?, attempts = attempts + 1, updated_at = ?
+		 WHERE id IN (
+			SELECT id FROM chunk_jobs
+			WHERE deleted_at IS NULL
+			  AND ((status = ? AND run_after <= ?) OR (status = ? AND locked_at < ?))
+			ORDER BY run_after
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING *`,
+		models.ChunkJobStatusRunning, now, now,
+		models.ChunkJobStatusPending, now,
+		models.ChunkJobStatusRunning, now.Add(-lease),
+		limit,
+	).Scan(&jobs).Error
+	return jobs, err
+}
+
+// Complete marks a claimed job succeeded unless the note was saved again meanwhile
+func (r *chunkJobRepository) Complete(ctx context.Context, id string, generation, chunkCount int) error {
+	now := time.Now().UTC()
+	return r.db.WithContext(ctx).
+		Model(&models.ChunkJob{}).
+		Where("id = ? AND generation = ?", id, generation).
+		Updates(map[string]interface{}{
+			"status":       models.ChunkJobStatusSucceeded,
+			"locked_at":    nil,
+			"last_error":   "",
+			"chunk_count":  chunkCount,
+			"completed_at": now,
+		}).Error
+}
+
+// Fail schedules a retry at retryAt, or dead-letters the job when retryAt is nil.
+// A newer save already re-queued the job, so a stale generation only records the error.
+func (r *chunkJobRepository) Fail(ctx context.Context, id string, generation int, errMsg string, retryAt *time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"locked_at":  nil,
+			"last_error": errMsg,
+		}
+		if retryAt != nil {
+			updates["status"] = models.ChunkJobStatusPending
+			updates["run_after"] = *retryAt
+		} else {
+			updates["status"] = models.ChunkJobStatusDead
+		}
+
+		res := tx.Model(&models.ChunkJob{}).
+			Where("id = ? AND generation = ?", id, generation).
+			Updates(updates)
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+		return tx.Model(&models.ChunkJob{}).
+			Where("id = ?", id).
+			Update("last_error", errMsg).Error
+	})
+}
+
+// GetByNoteID retrieves a note's chunk job
+func (r *chunkJobRepository) GetByNoteID(ctx context.Context, noteID string) (*models.ChunkJob, error) {
+	var job models.ChunkJob
+	err := r.db.WithContext(ctx).Where("note_id = ?", noteID).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListByUserID lists a user's chunk jobs, most recently updated first
+func (r *chunkJobRepository) ListByUserID(ctx context.Context, userID string, status *models.ChunkJobStatus, limit int) ([]*models.ChunkJob, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var jobs []*models.ChunkJob
+	err := query.Order("updated_at DESC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+const noteChunkStateSelect = `n.id AS note_id, n.user_id, n.title, n.updated_at AS note_updated_at,
+	COALESCE(c.chunk_count, 0) AS chunk_count, c.last_chunked_at,
+	j.status AS job_status, COALESCE(j.attempts, 0) AS job_attempts,
+	COALESCE(j.last_error, '') AS job_last_error, j.run_after AS job_run_after`
+
+const noteChunkStateJoins = `LEFT JOIN (
+		SELECT note_id, COUNT(*) AS chunk_count, MAX(updated_at) AS last_chunked_at
+		FROM note_chunks
+		WHERE deleted_at IS NULL
+		GROUP BY note_id
+	) c ON c.note_id = n.id
+	LEFT JOIN chunk_jobs j ON j.note_id = n.id AND j.deleted_at IS NULL`
+
+// GetNoteChunkState reports a note's stored chunks alongside its job
+func (r *chunkJobRepository) GetNoteChunkState(ctx context.Context, noteID string) (*NoteChunkState, error) {
+	var states []*NoteChunkState
+	err := r.db.WithContext(ctx).
+		Table("notes AS n").
+		Select(noteChunkStateSelect).
+		Joins(noteChunkStateJoins).
+		Where("n.id = ? AND n.deleted_at IS NULL", noteID).
+		Limit(1).
+		Scan(&states).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return states[0], nil
+}
+
+// ListUnhealthyNoteChunkStates lists a user's notes whose chunks are missing,
+// older than the note, or whose job is queued or dead
+func (r *chunkJobRepository) ListUnhealthyNoteChunkStates(ctx context.Context, userID string, limit int) ([]*NoteChunkState, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	var states []*NoteChunkState
+	err := r.db.WithContext(ctx).
+		Table("notes AS n").
+		Select(noteChunkStateSelect).
+		Joins(noteChunkStateJoins).
+		Where("n.user_id = ? AND n.deleted_at IS NULL", userID).
+		Where("c.note_id IS NULL OR c.last_chunked_at < n.updated_at OR j.status IN ?",
+			[]models.ChunkJobStatus{models.ChunkJobStatusPending, models.ChunkJobStatusRunning, models.ChunkJobStatusDead}).
+		Order("n.updated_at DESC").
+		Limit(limit).
+		Scan(&states).Error
+	return states, err
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// CollabDocRepository reads and rewrites the Yjs updates the collab server
+// persists per note. The table only exists once the collab server has run,
+// so every method is a no-op without it.
+type CollabDocRepository interface {
+	// ListDocsNeedingCompaction returns documents with at least minUpdates stored updates
+	ListDocsNeedingCompaction(ctx context.Context, minUpdates, limit int) ([]string, error)
+	// ListUpdates returns a document's updates in the order they were stored
+	ListUpdates(ctx context.Context, docname string) ([]*models.YjsUpdate, error)
+	// ReplaceUpdates swaps the listed updates for merged
+	ReplaceUpdates(ctx context.Context, docname string, ids []int64, merged []byte) error
+}
+
+// collabDocRepository implements CollabDocRepository
+type collabDocRepository struct {
+	db *database.DB
+}
+
+// NewCollabDocRepository creates a new collab document repository
+func NewCollabDocRepository(db *database.DB) CollabDocRepository {
+	return &collabDocRepository{db: db}
+}
+
+func (r *collabDocRepository) hasTable() bool {
+	return r.db.Migrator().HasTable(models.YjsUpdate{}.TableName())
+}
+
+func (r *collabDocRepository) ListDocsNeedingCompaction(ctx context.Context, minUpdates, limit int) ([]string, error) {
+	if !r.hasTable() {
+		return nil, nil
+	}
+	var docnames []string
+	err := r.db.WithContext(ctx).
+		Model(&models.YjsUpdate{}).
+		Where("version = ?", models.YjsUpdateVersionUpdate).
+		Group("docname").
+		Having("COUNT(*) >= ?", minUpdates).
+		Order("COUNT(*) DESC").
+		Limit(limit).
+		Pluck("docname", &docnames).Error
+	return docnames, err
+}
+
+func (r *collabDocRepository) ListUpdates(ctx context.Context, docname string) ([]*models.YjsUpdate, error) {
+	if !r.hasTable() {
+		return nil, nil
+	}
+	var updates []*models.YjsUpdate
+	err := r.db.WithContext(ctx).
+		Where("docname = ? AND version = ?", docname, models.YjsUpdateVersionUpdate).
+		Order("id ASC").
+		Find(&updates).Error
+	return updates, err
+}
+
+// ReplaceUpdates deletes exactly the listed rows, so updates the collab
+// server stores meanwhile are kept, and stores merged under the highest of
+// their IDs. The y-postgresql state vector row goes too; it is rebuilt the
+// next time the collab server flushes the document.
+func (r *collabDocRepository) ReplaceUpdates(ctx context.Context, docname string, ids []int64, merged []byte) error {
+	if !r.hasTable() || len(ids) == 0 {
+		return nil
+	}
+	mergedID := ids[0]
+	for _, id := range ids[1:] {
+		if id > mergedID {
+			mergedID = id
+		}
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("docname = ? AND version = ? AND id IN ?", docname, models.YjsUpdateVersionUpdate, ids).
+			Delete(&models.YjsUpdate{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("docname = ? AND version = ?", docname, models.YjsUpdateVersionStateVector).
+			Delete(&models.YjsUpdate{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.YjsUpdate{
+			ID:      mergedID,
+			Docname: docname,
+			Value:   merged,
+			Version: models.YjsUpdateVersionUpdate,
+		}).Error
+	})
+}
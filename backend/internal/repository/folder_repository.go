@@ -76,9 +76,39 @@ func (r *folderRepository) Update(ctx context.Context, folder *models.Folder) er
 	return result.Error
 }
 
-// Delete deletes a folder
+// Delete moves a folder to the trash together with its live subfolders and
+// notes. Descendants are tagged with trashed_with so they can be restored as one.
 func (r *folderRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.Folder{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var subtreeIDs []string
+		if err := tx.Raw(
+			`WITH RECURSIVE subtree AS (
+				SELECT id FROM folders WHERE id = ? AND deleted_at IS NULL
+				UNION ALL
+				SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id WHERE f.deleted_at IS NULL
+			)
+			SELECT id FROM subtree`,
+			id,
+		).Scan(&subtreeIDs).Error; err != nil {
+			return err
+		}
+		if len(subtreeIDs) == 0 {
+			return nil
+		}
+
+		now := tx.NowFunc()
+		if err := tx.Model(&models.Note{}).
+			Where("folder_id IN ?", subtreeIDs).
+			Updates(map[string]interface{}{"deleted_at": now, "trashed_with": id}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Folder{}).
+			Where("id IN ? AND id <> ?", subtreeIDs, id).
+			Updates(map[string]interface{}{"deleted_at": now, "trashed_with": id}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&models.Folder{}).Error
+	})
 }
 
 // List retrieves folders with pagination
@@ -119,7 +149,12 @@ func (r *folderRepository) GetByUserID(ctx context.Context, userID string, param
 	var folders []*models.Folder
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&models.Folder{}).Where("user_id = ?", userID)
+	query := r.db.WithContext(ctx).Model(&models.Folder{})
+	if params.WorkspaceID != nil {
+		query = query.Where("workspace_id = ?", *params.WorkspaceID)
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
 
 	// Apply filters
 	if params.ParentID != nil {
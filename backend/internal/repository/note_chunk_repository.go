@@ -2,20 +2,38 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/utils"
 	"github.com/pgvector/pgvector-go"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // NoteChunkInput represents a chunk to be stored along with its embedding.
+// The embedding may be left empty for chunks whose text is already stored.
 type NoteChunkInput struct {
 	ChunkIndex     int
 	Text           string
 	TextEmbeddings []float64
 }
 
+// NoteChunkSearchParams filters a vector search by the owning note
+type NoteChunkSearchParams struct {
+	UserID         string
+	WorkspaceID    *string
+	QueryEmbedding []float64
+	TopK           int
+	MinScore       float64
+	FolderID       *string
+	Status         *models.NoteStatus
+	TagIDs         []string
+	TagMatch       TagMatchMode
+}
+
 type NoteChunkSearchResult struct {
 	NoteID     string
 	ChunkIndex int
@@ -25,9 +43,15 @@ type NoteChunkSearchResult struct {
 
 // NoteChunkRepository defines operations for persisting note chunks.
 type NoteChunkRepository interface {
-	// ReplaceNoteChunks removes all existing chunks for a note and inserts the new list.
+	// ReplaceNoteChunks makes the note's chunks match the new list, keeping rows whose text is unchanged.
 	ReplaceNoteChunks(ctx context.Context, noteID, userID string, chunks []NoteChunkInput) error
+	// ListChunkHashes returns the content hashes of a note's stored chunks.
+	ListChunkHashes(ctx context.Context, noteID string) (map[string]struct{}, error)
 	SearchSimilarByUser(ctx context.Context, userID string, queryEmbedding []float64, topK int, minScore float64) ([]NoteChunkSearchResult, error)
+	// SearchSimilarFiltered is SearchSimilarByUser restricted by note folder, status and tags.
+	SearchSimilarFiltered(ctx context.Context, params NoteChunkSearchParams) ([]NoteChunkSearchResult, error)
+	// BestLexicalChunks returns, per note, the chunk that best matches a full-text query.
+	BestLexicalChunks(ctx context.Context, noteIDs []string, query string) ([]NoteChunkSearchResult, error)
 }
 
 type noteChunkRepository struct {
@@ -39,34 +63,89 @@ func NewNoteChunkRepository(db *database.DB) NoteChunkRepository {
 	return &noteChunkRepository{db: db}
 }
 
+// ReplaceNoteChunks diffs the new chunk list against the stored one by content
+// hash. Unchanged chunks keep their row (and ID), only moving to their new
+// index; new chunks are inserted and chunks no longer present are deleted.
+// Inputs that match a stored chunk may omit their embedding.
 func (r *noteChunkRepository) ReplaceNoteChunks(ctx context.Context, noteID, userID string, chunks []NoteChunkInput) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Delete existing chunks for this note
-		if err := tx.Unscoped().Where("note_id = ?", noteID).Delete(&models.NoteChunk{}).Error; err != nil {
+		var existing []storedNoteChunk
+		if err := tx.Model(&models.NoteChunk{}).
+			Select("id, chunk_index, content_hash, text").
+			Where("note_id = ?", noteID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Scan(&existing).Error; err != nil {
 			return err
 		}
 
-		if len(chunks) == 0 {
-			return nil
+		plan, err := planNoteChunkSync(existing, chunks)
+		if err != nil {
+			return err
 		}
 
-		items := make([]models.NoteChunk, 0, len(chunks))
-		for _, ch := range chunks {
-			vector := toFloat32Slice(ch.TextEmbeddings)
-			if len(vector) == 0 {
-				continue
+		if len(plan.remove) > 0 {
+			if err := tx.Unscoped().Where("id IN ?", plan.remove).Delete(&models.NoteChunk{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// Kept rows are touched so updated_at records when the note was last chunked
+		now := time.Now().UTC()
+		for _, keep := range plan.keep {
+			if err := tx.Model(&models.NoteChunk{}).
+				Where("id = ?", keep.id).
+				Updates(map[string]interface{}{
+					"chunk_index":  keep.chunkIndex,
+					"content_hash": keep.contentHash,
+					"updated_at":   now,
+				}).Error; err != nil {
+				return err
 			}
+		}
+
+		if len(plan.insert) == 0 && len(plan.copy) == 0 {
+			return nil
+		}
+
+		items := make([]models.NoteChunk, 0, len(plan.insert)+len(plan.copy))
+		for _, ch := range plan.insert {
 			items = append(items, models.NoteChunk{
 				NoteID:         noteID,
 				UserID:         userID,
 				ChunkIndex:     ch.ChunkIndex,
 				Text:           ch.Text,
-				TextEmbeddings: pgvector.NewVector(vector),
+				ContentHash:    utils.ChunkContentHash(ch.Text),
+				TextEmbeddings: pgvector.NewVector(toFloat32Slice(ch.TextEmbeddings)),
 			})
 		}
 
-		if len(items) == 0 {
-			return nil
+		if len(plan.copy) > 0 {
+			sourceIDs := make([]string, 0, len(plan.copy))
+			for _, ch := range plan.copy {
+				sourceIDs = append(sourceIDs, ch.sourceID)
+			}
+			var sources []models.NoteChunk
+			if err := tx.Select("id, text_embeddings").Where("id IN ?", sourceIDs).Find(&sources).Error; err != nil {
+				return err
+			}
+			embeddings := make(map[string]pgvector.Vector, len(sources))
+			for _, source := range sources {
+				embeddings[source.ID] = source.TextEmbeddings
+			}
+			for _, ch := range plan.copy {
+				embedding, ok := embeddings[ch.sourceID]
+				if !ok {
+					return fmt.Errorf("chunk %d copies missing chunk %s", ch.input.ChunkIndex, ch.sourceID)
+				}
+				items = append(items, models.NoteChunk{
+					NoteID:         noteID,
+					UserID:         userID,
+					ChunkIndex:     ch.input.ChunkIndex,
+					Text:           ch.input.Text,
+					ContentHash:    utils.ChunkContentHash(ch.input.Text),
+					TextEmbeddings: embedding,
+				})
+			}
 		}
 
 		if err := tx.Create(&items).Error; err != nil {
@@ -77,6 +156,139 @@ func (r *noteChunkRepository) ReplaceNoteChunks(ctx context.Context, noteID, use
 	})
 }
 
+// ListChunkHashes returns the content hashes of a note's stored chunks
+func (r *noteChunkRepository) ListChunkHashes(ctx context.Context, noteID string) (map[string]struct{}, error) {
+	var existing []storedNoteChunk
+	if err := r.db.WithContext(ctx).
+		Model(&models.NoteChunk{}).
+		Select("id, chunk_index, content_hash, text").
+		Where("note_id = ?", noteID).
+		Scan(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]struct{}, len(existing))
+	for _, chunk := range existing {
+		hashes[chunk.hash()] = struct{}{}
+	}
+	return hashes, nil
+}
+
+type storedNoteChunk struct {
+	ID          string
+	ChunkIndex  int
+	ContentHash string
+	Text        string
+}
+
+// hash falls back to hashing the text for chunks stored before content_hash existed
+func (c storedNoteChunk) hash() string {
+	if c.ContentHash != "" {
+		return c.ContentHash
+	}
+	return utils.ChunkContentHash(c.Text)
+}
+
+type keptNoteChunk struct {
+	id          string
+	chunkIndex  int
+	contentHash string
+}
+
+// copiedNoteChunk is a new chunk whose text is already stored in another row,
+// so it reuses that row's embedding
+type copiedNoteChunk struct {
+	input    NoteChunkInput
+	sourceID string
+}
+
+type noteChunkSyncPlan struct {
+	keep   []keptNoteChunk
+	insert []NoteChunkInput
+	copy   []copiedNoteChunk
+	remove []string
+}
+
+// planNoteChunkSync matches inputs to stored chunks with the same content,
+// preferring one already at the same index when a text repeats. A text that
+// appears more often than it is stored gets new rows for the extra copies;
+// callers skip embedding known texts, so those rows reuse a stored embedding.
+func planNoteChunkSync(existing []storedNoteChunk, inputs []NoteChunkInput) (noteChunkSyncPlan, error) {
+	byHash := make(map[string][]storedNoteChunk, len(existing))
+	sources := make(map[string]string, len(existing))
+	for _, chunk := range existing {
+		h := chunk.hash()
+		byHash[h] = append(byHash[h], chunk)
+		sources[h] = chunk.ID
+	}
+	embedded := make(map[string][]float64, len(inputs))
+	for _, input := range inputs {
+		if len(input.TextEmbeddings) > 0 {
+			embedded[utils.ChunkContentHash(input.Text)] = input.TextEmbeddings
+		}
+	}
+
+	var plan noteChunkSyncPlan
+	pending := make([]NoteChunkInput, 0)
+	pendingHashes := make([]string, 0)
+	for _, input := range inputs {
+		h := utils.ChunkContentHash(input.Text)
+		candidates := byHash[h]
+		match := -1
+		for i, chunk := range candidates {
+			if chunk.ChunkIndex == input.ChunkIndex {
+				match = i
+				break
+			}
+		}
+		if match < 0 {
+			pending = append(pending, input)
+			pendingHashes = append(pendingHashes, h)
+			continue
+		}
+		plan.keep = append(plan.keep, keptNoteChunk{id: candidates[match].ID, chunkIndex: input.ChunkIndex, contentHash: h})
+		byHash[h] = append(candidates[:match:match], candidates[match+1:]...)
+	}
+
+	// Second pass: moved chunks take any remaining stored copy of their text
+	for i, input := range pending {
+		h := pendingHashes[i]
+		if candidates := byHash[h]; len(candidates) > 0 {
+			plan.keep = append(plan.keep, keptNoteChunk{id: candidates[0].ID, chunkIndex: input.ChunkIndex, contentHash: h})
+			byHash[h] = candidates[1:]
+			continue
+		}
+		if len(input.TextEmbeddings) == 0 {
+			if sourceID, ok := sources[h]; ok {
+				plan.copy = append(plan.copy, copiedNoteChunk{input: input, sourceID: sourceID})
+				continue
+			}
+			if embedding, ok := embedded[h]; ok {
+				input.TextEmbeddings = embedding
+			} else {
+				return noteChunkSyncPlan{}, fmt.Errorf("chunk %d is new but has no embedding", input.ChunkIndex)
+			}
+		}
+		plan.insert = append(plan.insert, input)
+	}
+
+	for _, chunk := range existing {
+		if candidates := byHash[chunk.hash()]; containsStoredChunk(candidates, chunk.ID) {
+			plan.remove = append(plan.remove, chunk.ID)
+		}
+	}
+	return plan, nil
+}
+
+func containsStoredChunk(chunks []storedNoteChunk, id string) bool {
+	for _, chunk := range chunks {
+		if chunk.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *noteChunkRepository) SearchSimilarByUser(ctx context.Context, userID string, queryEmbedding []float64, topK int, minScore float64) ([]NoteChunkSearchResult, error) {
 	if topK <= 0 {
 		topK = 5
@@ -109,6 +321,7 @@ func (r *noteChunkRepository) SearchSimilarByUser(ctx context.Context, userID st
 		 FROM note_chunks
 		 WHERE user_id = ?
 		   AND text_embeddings IS NOT NULL
+		   AND EXISTS (SELECT 1 FROM notes n WHERE n.id = note_chunks.note_id AND n.deleted_at IS NULL)
 		   AND (text_embeddings <=> ?::vector) <= ?
 		 ORDER BY text_embeddings <=> ?::vector
 		 LIMIT ?`,
@@ -139,6 +352,78 @@ func (r *noteChunkRepository) SearchSimilarByUser(ctx context.Context, userID st
 	return results, nil
 }
 
+func (r *noteChunkRepository) SearchSimilarFiltered(ctx context.Context, params NoteChunkSearchParams) ([]NoteChunkSearchResult, error) {
+	topK := params.TopK
+	if topK <= 0 {
+		topK = 5
+	}
+	minScore := params.MinScore
+	if minScore < 0 {
+		minScore = 0
+	}
+	if minScore > 1 {
+		minScore = 1
+	}
+
+	queryVector := toFloat32Slice(params.QueryEmbedding)
+	if len(queryVector) == 0 {
+		return []NoteChunkSearchResult{}, nil
+	}
+	vectorParam := pgvector.NewVector(queryVector)
+
+	query := r.db.WithContext(ctx).
+		Table("note_chunks").
+		Joins("JOIN notes ON notes.id = note_chunks.note_id AND notes.deleted_at IS NULL").
+		Where("note_chunks.text_embeddings IS NOT NULL").
+		Where("(note_chunks.text_embeddings <=> ?::vector) <= ?", vectorParam, 1.0-minScore)
+	if params.WorkspaceID != nil {
+		query = query.Where("notes.workspace_id = ?", *params.WorkspaceID)
+	} else {
+		query = query.Where("note_chunks.user_id = ?", params.UserID)
+	}
+	if params.FolderID != nil {
+		query = query.Where("notes.folder_id = ?", *params.FolderID)
+	}
+	if params.Status != nil {
+		query = query.Where("notes.status = ?", *params.Status)
+	}
+	query = applyTagFilter(query, NoteListParams{TagIDs: params.TagIDs, TagMatch: params.TagMatch})
+
+	var rows []NoteChunkSearchResult
+	if err := query.
+		Select("note_chunks.note_id, note_chunks.chunk_index, note_chunks.text, 1 - (note_chunks.text_embeddings <=> ?::vector) AS score", vectorParam).
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "note_chunks.text_embeddings <=> ?::vector", Vars: []interface{}{vectorParam}}}).
+		Limit(topK).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (r *noteChunkRepository) BestLexicalChunks(ctx context.Context, noteIDs []string, query string) ([]NoteChunkSearchResult, error) {
+	tsQuery := BuildPrefixTSQuery(query)
+	if tsQuery == "" || len(noteIDs) == 0 {
+		return []NoteChunkSearchResult{}, nil
+	}
+
+	var rows []NoteChunkSearchResult
+	err := r.db.WithContext(ctx).Raw(
+		`SELECT DISTINCT ON (note_id) note_id, chunk_index, text, ts_rank_cd(to_tsvector('simple', text), q, 32) AS score
+		 FROM note_chunks, to_tsquery('simple', ?) AS q
+		 WHERE note_id IN ?
+		   AND to_tsvector('simple', text) @@ q
+		 ORDER BY note_id, score DESC, chunk_index`,
+		tsQuery,
+		noteIDs,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
 func toFloat32Slice(values []float64) []float32 {
 	result := make([]float32, 0, len(values))
 	for _, value := range values {
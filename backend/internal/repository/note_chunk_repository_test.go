@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/utils"
+)
+
+func TestPlanNoteChunkSyncKeepsUnchangedChunks(t *testing.T) {
+	existing := []storedNoteChunk{
+		{ID: "a", ChunkIndex: 0, ContentHash: utils.ChunkContentHash("intro")},
+		{ID: "b", ChunkIndex: 1, Text: "body"}, // stored before content_hash existed
+		{ID: "c", ChunkIndex: 2, ContentHash: utils.ChunkContentHash("outro")},
+	}
+	inputs := []NoteChunkInput{
+		{ChunkIndex: 0, Text: "intro"},
+		{ChunkIndex: 1, Text: "new section", TextEmbeddings: []float64{1}},
+		{ChunkIndex: 2, Text: "body"},
+	}
+
+	plan, err := planNoteChunkSync(existing, inputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kept := map[string]int{}
+	for _, keep := range plan.keep {
+		kept[keep.id] = keep.chunkIndex
+	}
+	if len(kept) != 2 || kept["a"] != 0 || kept["b"] != 2 {
+		t.Fatalf("expected a kept at 0 and b moved to 2, got %v", kept)
+	}
+	if len(plan.insert) != 1 || plan.insert[0].Text != "new section" {
+		t.Fatalf("expected only the new section inserted, got %+v", plan.insert)
+	}
+	if len(plan.remove) != 1 || plan.remove[0] != "c" {
+		t.Fatalf("expected c removed, got %v", plan.remove)
+	}
+}
+
+func TestPlanNoteChunkSyncRequiresEmbeddingForNewChunks(t *testing.T) {
+	_, err := planNoteChunkSync(nil, []NoteChunkInput{{ChunkIndex: 0, Text: "fresh"}})
+	if err == nil {
+		t.Fatal("expected an error for a new chunk without an embedding")
+	}
+}
+
+func TestPlanNoteChunkSyncCopiesRepeatedText(t *testing.T) {
+	// "same" is stored once but now appears twice; callers only embed texts
+	// that are not stored, so neither input carries an embedding
+	existing := []storedNoteChunk{
+		{ID: "a", ChunkIndex: 0, ContentHash: utils.ChunkContentHash("same")},
+		{ID: "b", ChunkIndex: 1, ContentHash: utils.ChunkContentHash("other")},
+	}
+	inputs := []NoteChunkInput{
+		{ChunkIndex: 0, Text: "same"},
+		{ChunkIndex: 1, Text: "other"},
+		{ChunkIndex: 2, Text: "same"},
+	}
+
+	plan, err := planNoteChunkSync(existing, inputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.keep) != 2 || len(plan.insert) != 0 || len(plan.remove) != 0 {
+		t.Fatalf("expected both stored chunks kept and nothing else, got %+v", plan)
+	}
+	if len(plan.copy) != 1 || plan.copy[0].sourceID != "a" || plan.copy[0].input.ChunkIndex != 2 {
+		t.Fatalf("expected the second copy to reuse a's embedding, got %+v", plan.copy)
+	}
+
+	// Syncing the result again is a no-op
+	existing = append(existing, storedNoteChunk{ID: "c", ChunkIndex: 2, ContentHash: utils.ChunkContentHash("same")})
+	plan, err = planNoteChunkSync(existing, inputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.keep) != 3 || len(plan.insert) != 0 || len(plan.copy) != 0 || len(plan.remove) != 0 {
+		t.Fatalf("expected every chunk kept, got %+v", plan)
+	}
+}
+
+func TestPlanNoteChunkSyncSharesEmbeddingOfNewRepeatedText(t *testing.T) {
+	inputs := []NoteChunkInput{
+		{ChunkIndex: 0, Text: "fresh", TextEmbeddings: []float64{1}},
+		{ChunkIndex: 1, Text: "fresh"},
+	}
+
+	plan, err := planNoteChunkSync(nil, inputs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.insert) != 2 || len(plan.insert[1].TextEmbeddings) != 1 {
+		t.Fatalf("expected both copies inserted with the one embedding, got %+v", plan.insert)
+	}
+}
@@ -17,6 +17,8 @@ type NoteRepository interface {
 	GetByID(ctx context.Context, id string) (*models.Note, error)
 	Update(ctx context.Context, note *models.Note) error
 	UpdateContentWithVersion(ctx context.Context, id string, content string, expectedVersion int) (*models.Note, error)
+	RestoreContentWithVersion(ctx context.Context, id string, title, content, tiptapContent string, expectedVersion int) (*models.Note, error)
+	UpdateCollabSnapshotWithVersion(ctx context.Context, id string, content, tiptapContent string, expectedVersion int) (*models.Note, error)
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context, params NoteListParams) ([]*models.Note, int64, error)
 	GetByUserID(ctx context.Context, userID string, params NoteListParams) ([]*models.Note, int64, error)
@@ -59,27 +61,37 @@ func (r *noteRepository) Update(ctx context.Context, note *models.Note) error {
 }
 
 func (r *noteRepository) UpdateContentWithVersion(ctx context.Context, id string, content string, expectedVersion int) (*models.Note, error) {
-	result := r.db.WithContext(ctx).
-		Model(&models.Note{}).
-		Where("id = ? AND version = ?", id, expectedVersion).
-		Updates(map[string]interface{}{
-			"content": content,
-			"version": gorm.Expr("version + 1"),
-		})
+	return r.updateWithVersion(ctx, id, expectedVersion, map[string]interface{}{
+		"content": content,
+	})
+}
 
-	if result.Error != nil {
-		return nil, result.Error
+// RestoreContentWithVersion overwrites title and both content representations,
+// e.g. when a revision is restored
+func (r *noteRepository) RestoreContentWithVersion(ctx context.Context, id string, title, content, tiptapContent string, expectedVersion int) (*models.Note, error) {
+	return r.updateWithVersion(ctx, id, expectedVersion, map[string]interface{}{
+		"title":          title,
+		"content":        content,
+		"tiptap_content": tiptapContent,
+	})
+}
+
+// UpdateCollabSnapshotWithVersion stores content derived from the note's
+// collab document. Collab updates are kept, since the snapshot describes the
+// session clients are already editing.
+func (r *noteRepository) UpdateCollabSnapshotWithVersion(ctx context.Context, id string, content, tiptapContent string, expectedVersion int) (*models.Note, error) {
+	if err := r.bumpVersion(ctx, id, expectedVersion, map[string]interface{}{
+		"content":        content,
+		"tiptap_content": tiptapContent,
+	}); err != nil {
+		return nil, err
 	}
+	return r.GetByID(ctx, id)
+}
 
-	if result.RowsAffected == 0 {
-		var count int64
-		if err := r.db.WithContext(ctx).Model(&models.Note{}).Where("id = ?", id).Count(&count).Error; err != nil {
-			return nil, err
-		}
-		if count == 0 {
-			return nil, gorm.ErrRecordNotFound
-		}
-		return nil, ErrVersionConflict
+func (r *noteRepository) updateWithVersion(ctx context.Context, id string, expectedVersion int, updates map[string]interface{}) (*models.Note, error) {
+	if err := r.bumpVersion(ctx, id, expectedVersion, updates); err != nil {
+		return nil, err
 	}
 
 	// Clear collab session updates to force client re-hydration on reconnect/re-open
@@ -95,16 +107,36 @@ func (r *noteRepository) UpdateContentWithVersion(ctx context.Context, id string
 	return note, nil
 }
 
-// Delete deletes a note
-func (r *noteRepository) Delete(ctx context.Context, id string) error {
-	err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.Note{}).Error
-	if err == nil {
-		// Clear collab session updates as well
-		if r.db.Migrator().HasTable("yjs_updates") {
-			_ = r.db.WithContext(ctx).Exec("DELETE FROM yjs_updates WHERE docname = ?", id).Error
+// bumpVersion applies updates and increments the version if the note is
+// still at expectedVersion
+func (r *noteRepository) bumpVersion(ctx context.Context, id string, expectedVersion int, updates map[string]interface{}) error {
+	updates["version"] = gorm.Expr("version + 1")
+	result := r.db.WithContext(ctx).
+		Model(&models.Note{}).
+		Where("id = ? AND version = ?", id, expectedVersion).
+		Updates(updates)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.db.WithContext(ctx).Model(&models.Note{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
 		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return ErrVersionConflict
 	}
-	return err
+	return nil
+}
+
+// Delete moves a note to the trash. Collab updates are kept so a restored
+// note resumes its session; they are dropped when the trash is purged.
+func (r *noteRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.Note{}).Error
 }
 
 // List retrieves notes with pagination
@@ -125,6 +157,7 @@ func (r *noteRepository) List(ctx context.Context, params NoteListParams) ([]*mo
 	if params.IsPublic != nil {
 		query = query.Where("is_public = ?", *params.IsPublic)
 	}
+	query = applyTagFilter(query, params)
 
 	// Count total (with all filters applied)
 	if err := query.Count(&total).Error; err != nil {
@@ -141,26 +174,49 @@ func (r *noteRepository) List(ctx context.Context, params NoteListParams) ([]*mo
 	return notes, total, err
 }
 
-// GetByUserID retrieves notes by user ID (excludes top_of_mind notes)
+// applyTagFilter restricts a note query to notes carrying any or all of params.TagIDs
+func applyTagFilter(query *gorm.DB, params NoteListParams) *gorm.DB {
+	if len(params.TagIDs) == 0 {
+		return query
+	}
+
+	if params.TagMatch == TagMatchAll {
+		return query.Where(
+			"notes.id IN (SELECT note_id FROM note_tags WHERE tag_id IN ? GROUP BY note_id HAVING COUNT(DISTINCT tag_id) = ?)",
+			params.TagIDs, len(params.TagIDs),
+		)
+	}
+	return query.Where("notes.id IN (SELECT note_id FROM note_tags WHERE tag_id IN ?)", params.TagIDs)
+}
+
+// GetByUserID retrieves notes by user ID (excludes top_of_mind notes unless filtering by tag)
 func (r *noteRepository) GetByUserID(ctx context.Context, userID string, params NoteListParams) ([]*models.Note, int64, error) {
 	var notes []*models.Note
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&models.Note{}).
-		Where("user_id = ?", userID).
-		Where("top_of_mind IS NULL")
+	query := r.db.WithContext(ctx).Model(&models.Note{})
+	if params.WorkspaceID != nil {
+		query = query.Where("workspace_id = ?", *params.WorkspaceID)
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
 
-	// Filter by folder - if nil, get root notes (folder_id IS NULL)
+	// Filter by folder - if nil, get root notes (folder_id IS NULL).
+	// Tag views span all folders and include top of mind notes.
 	if params.FolderID != nil {
 		query = query.Where("folder_id = ?", *params.FolderID)
-	} else {
+	} else if len(params.TagIDs) == 0 {
 		query = query.Where("folder_id IS NULL")
 	}
+	if len(params.TagIDs) == 0 {
+		query = query.Where("top_of_mind IS NULL")
+	}
 
 	// Apply other filters
 	if params.Status != nil {
 		query = query.Where("status = ?", *params.Status)
 	}
+	query = applyTagFilter(query, params)
 
 	// Count total (with all filters applied)
 	if err := query.Count(&total).Error; err != nil {
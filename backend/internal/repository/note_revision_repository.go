@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// NoteRevisionRepository defines the interface for note revision data operations
+type NoteRevisionRepository interface {
+	Create(ctx context.Context, revision *models.NoteRevision) error
+	GetByID(ctx context.Context, noteID, id string) (*models.NoteRevision, error)
+	GetLatest(ctx context.Context, noteID string) (*models.NoteRevision, error)
+	ListByNoteID(ctx context.Context, noteID string, limit int) ([]*models.NoteRevision, error)
+}
+
+// noteRevisionRepository implements NoteRevisionRepository
+type noteRevisionRepository struct {
+	db *database.DB
+}
+
+// NewNoteRevisionRepository creates a new note revision repository
+func NewNoteRevisionRepository(db *database.DB) NoteRevisionRepository {
+	return &noteRevisionRepository{db: db}
+}
+
+// Create stores a new revision
+func (r *noteRevisionRepository) Create(ctx context.Context, revision *models.NoteRevision) error {
+	return r.db.WithContext(ctx).Create(revision).Error
+}
+
+// GetByID retrieves a revision of a note by ID
+func (r *noteRevisionRepository) GetByID(ctx context.Context, noteID, id string) (*models.NoteRevision, error) {
+	var revision models.NoteRevision
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND note_id = ?", id, noteID).
+		First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// GetLatest retrieves the most recent revision of a note
+func (r *noteRevisionRepository) GetLatest(ctx context.Context, noteID string) (*models.NoteRevision, error) {
+	var revision models.NoteRevision
+	err := r.db.WithContext(ctx).
+		Where("note_id = ?", noteID).
+		Order("created_at DESC").
+		First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// ListByNoteID retrieves the revisions of a note, newest first
+func (r *noteRevisionRepository) ListByNoteID(ctx context.Context, noteID string, limit int) ([]*models.NoteRevision, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	var revisions []*models.NoteRevision
+	err := r.db.WithContext(ctx).
+		Where("note_id = ?", noteID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&revisions).Error
+	return revisions, err
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+const (
+	maxLexicalQueryTerms = 16
+	// ts_headline marks matches with these private-use characters rather than
+	// <mark>, so the text around them can be escaped first (see markHighlights)
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+	// noteHeadlineOptions keeps snippets short
+	noteHeadlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
+	// titleHeadlineOptions keeps the whole title
+	titleHeadlineOptions = `HighlightAll=true, StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+	// headlineText drops highlight characters the text itself contains
+	headlineText = "translate(%s, '" + highlightStart + highlightStop + "', '')"
+	// noteSearchText must match the content expression of notes.search_vector
+	noteSearchText = "regexp_replace(coalesce(notes.content, ''), '<[^>]+>|&[#a-zA-Z0-9]+;', ' ', 'g')"
+)
+
+// NoteSearchParams represents lexical search parameters
+type NoteSearchParams struct {
+	UserID      string
+	WorkspaceID *string
+	Query       string
+	FolderID    *string
+	Status      *models.NoteStatus
+	TagIDs      []string
+	TagMatch    TagMatchMode
+	Limit       int
+	Offset      int
+}
+
+// NoteSearchHit is a note matched by lexical search
+type NoteSearchHit struct {
+	Note           *models.Note
+	Rank           float64
+	TitleHighlight string
+	Snippet        string
+}
+
+// NoteSearchRepository defines full-text search over notes.search_vector
+type NoteSearchRepository interface {
+	SearchLexical(ctx context.Context, params NoteSearchParams) ([]*NoteSearchHit, int64, error)
+}
+
+// noteSearchRepository implements NoteSearchRepository
+type noteSearchRepository struct {
+	db *database.DB
+}
+
+// NewNoteSearchRepository creates a new note search repository
+func NewNoteSearchRepository(db *database.DB) NoteSearchRepository {
+	return &noteSearchRepository{db: db}
+}
+
+type noteSearchRow struct {
+	ID             string
+	Rank           float64
+	TitleHighlight string
+	Snippet        string
+}
+
+// SearchLexical ranks a user's live notes, or those of a workspace, against the
+// query with ts_rank_cd and returns highlighted title and content snippets.
+// Title matches weigh more.
+func (r *noteSearchRepository) SearchLexical(ctx context.Context, params NoteSearchParams) ([]*NoteSearchHit, int64, error) {
+	tsQuery := BuildPrefixTSQuery(params.Query)
+	if tsQuery == "" {
+		return []*NoteSearchHit{}, 0, nil
+	}
+
+	query := r.db.WithContext(ctx).
+		Table("notes").
+		Joins("CROSS JOIN to_tsquery('simple', ?) AS q", tsQuery).
+		Where("notes.deleted_at IS NULL").
+		Where("notes.search_vector @@ q")
+	if params.WorkspaceID != nil {
+		query = query.Where("notes.workspace_id = ?", *params.WorkspaceID)
+	} else {
+		query = query.Where("notes.user_id = ?", params.UserID)
+	}
+
+	if params.FolderID != nil {
+		query = query.Where("notes.folder_id = ?", *params.FolderID)
+	}
+	if params.Status != nil {
+		query = query.Where("notes.status = ?", *params.Status)
+	}
+	query = applyTagFilter(query, NoteListParams{TagIDs: params.TagIDs, TagMatch: params.TagMatch}).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*NoteSearchHit{}, 0, nil
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var rows []noteSearchRow
+	err := query.
+		Select(
+			"notes.id, ts_rank_cd(notes.search_vector, q, 32) AS rank, "+
+				"ts_headline('simple', "+fmt.Sprintf(headlineText, "coalesce(notes.title, '')")+", q, ?) AS title_highlight, "+
+				"ts_headline('simple', "+fmt.Sprintf(headlineText, noteSearchText)+", q, ?) AS snippet",
+			titleHeadlineOptions, noteHeadlineOptions,
+		).
+		Order("rank DESC, notes.updated_at DESC").
+		Limit(limit).
+		Offset(params.Offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+
+	var notes []*models.Note
+	if err := r.db.WithContext(ctx).
+		Preload("Tags").
+		Preload("Folder").
+		Where("id IN ?", ids).
+		Find(&notes).Error; err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[string]*models.Note, len(notes))
+	for _, note := range notes {
+		byID[note.ID] = note
+	}
+
+	hits := make([]*NoteSearchHit, 0, len(rows))
+	for _, row := range rows {
+		note, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		hits = append(hits, &NoteSearchHit{
+			Note:           note,
+			Rank:           row.Rank,
+			TitleHighlight: markHighlights(row.TitleHighlight),
+			Snippet:        markHighlights(strings.TrimSpace(row.Snippet)),
+		})
+	}
+
+	return hits, total, nil
+}
+
+// markHighlights HTML-escapes a ts_headline result and only then turns its
+// highlight characters into <mark> tags, so the only markup in the result
+// is the highlighting
+func markHighlights(headline string) string {
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").
+		Replace(html.EscapeString(headline))
+}
+
+// BuildPrefixTSQuery turns free text into a to_tsquery expression where every
+// word must match and each word also matches longer words starting with it.
+// Punctuation is dropped, so user input can never produce tsquery syntax.
+func BuildPrefixTSQuery(input string) string {
+	words := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	seen := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		if _, dup := seen[word]; dup {
+			continue
+		}
+		seen[word] = struct{}{}
+		terms = append(terms, word+":*")
+		if len(terms) == maxLexicalQueryTerms {
+			break
+		}
+	}
+
+	return strings.Join(terms, " & ")
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBuildPrefixTSQuery(t *testing.T) {
+	manyWords := make([]string, 0, maxLexicalQueryTerms+4)
+	wantTerms := make([]string, 0, maxLexicalQueryTerms)
+	for i := 0; i < maxLexicalQueryTerms+4; i++ {
+		manyWords = append(manyWords, fmt.Sprintf("w%d", i))
+		if i < maxLexicalQueryTerms {
+			wantTerms = append(wantTerms, fmt.Sprintf("w%d:*", i))
+		}
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "empty", input: "", want: ""},
+		{name: "whitespace only", input: " \t\n ", want: ""},
+		{name: "punctuation only", input: "!!! ... &| ''", want: ""},
+		{name: "single word", input: "Meeting", want: "meeting:*"},
+		{name: "every word is a prefix", input: "project plan", want: "project:* & plan:*"},
+		{name: "quotes are dropped", input: `it's "quoted"`, want: "it:* & s:* & quoted:*"},
+		{name: "tsquery operators are dropped", input: "foo:* | bar & !baz", want: "foo:* & bar:* & baz:*"},
+		{name: "grouping and phrase syntax are dropped", input: "(a) <-> b", want: "a:* & b:*"},
+		{name: "repeated words are deduplicated", input: "Go go GO", want: "go:*"},
+		{name: "letters and digits are kept", input: "v2 release 2024", want: "v2:* & release:* & 2024:*"},
+		{name: "non-ascii letters are kept", input: "café naïve", want: "café:* & naïve:*"},
+		{name: "terms are capped", input: strings.Join(manyWords, " "), want: strings.Join(wantTerms, " & ")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := BuildPrefixTSQuery(tc.input); got != tc.want {
+				t.Fatalf("BuildPrefixTSQuery(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMarkHighlightsEscapesText(t *testing.T) {
+	cases := []struct {
+		headline string
+		want     string
+	}{
+		{headline: "plain " + highlightStart + "match" + highlightStop, want: "plain <mark>match</mark>"},
+		{
+			headline: `<img src=x onerror="alert(1)"> ` + highlightStart + "<b>match</b>" + highlightStop + " & more",
+			want:     `&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>&lt;b&gt;match&lt;/b&gt;</mark> &amp; more`,
+		},
+	}
+
+	for _, tc := range cases {
+		if got := markHighlights(tc.headline); got != tc.want {
+			t.Fatalf("markHighlights(%q) = %q, want %q", tc.headline, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// ShareLinkDailyViews is the number of views and distinct visitors of a
+// share link on one day
+type ShareLinkDailyViews struct {
+	Day            time.Time `json:"day"`
+	Views          int64     `json:"views"`
+	UniqueVisitors int64     `json:"unique_visitors"`
+}
+
+// NoteShareLinkRepository defines the interface for share link data operations
+type NoteShareLinkRepository interface {
+	Create(ctx context.Context, link *models.NoteShareLink) error
+	GetByID(ctx context.Context, id string) (*models.NoteShareLink, error)
+	GetBySlug(ctx context.Context, slug string) (*models.NoteShareLink, error)
+	ListByNoteID(ctx context.Context, noteID string) ([]*models.NoteShareLink, error)
+	Update(ctx context.Context, link *models.NoteShareLink) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+	RecordView(ctx context.Context, view *models.NoteShareLinkView) error
+	DailyViews(ctx context.Context, linkID string, since time.Time) ([]ShareLinkDailyViews, error)
+	CountUniqueVisitors(ctx context.Context, linkID string) (int64, error)
+}
+
+// noteShareLinkRepository implements NoteShareLinkRepository
+type noteShareLinkRepository struct {
+	db *database.DB
+}
+
+// NewNoteShareLinkRepository creates a new share link repository
+func NewNoteShareLinkRepository(db *database.DB) NoteShareLinkRepository {
+	return &noteShareLinkRepository{db: db}
+}
+
+// Create stores a new share link
+func (r *noteShareLinkRepository) Create(ctx context.Context, link *models.NoteShareLink) error {
+	return r.db.WithContext(ctx).Create(link).Error
+}
+
+// GetByID retrieves a share link by ID
+func (r *noteShareLinkRepository) GetByID(ctx context.Context, id string) (*models.NoteShareLink, error) {
+	var link models.NoteShareLink
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetBySlug retrieves a share link by its public slug
+func (r *noteShareLinkRepository) GetBySlug(ctx context.Context, slug string) (*models.NoteShareLink, error) {
+	var link models.NoteShareLink
+	if err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListByNoteID lists a note's share links, newest first
+func (r *noteShareLinkRepository) ListByNoteID(ctx context.Context, noteID string) ([]*models.NoteShareLink, error) {
+	var links []*models.NoteShareLink
+	err := r.db.WithContext(ctx).Where("note_id = ?", noteID).Order("created_at DESC").Find(&links).Error
+	return links, err
+}
+
+// Update saves a link's label, expiry, password and comment setting
+func (r *noteShareLinkRepository) Update(ctx context.Context, link *models.NoteShareLink) error {
+	return r.db.WithContext(ctx).
+		Model(&models.NoteShareLink{}).
+		Where("id = ?", link.ID).
+		Updates(map[string]interface{}{
+			"label":          link.Label,
+			"expires_at":     link.ExpiresAt,
+			"password_hash":  link.PasswordHash,
+			"allow_comments": link.AllowComments,
+		}).Error
+}
+
+// Revoke stops a link from opening its note. Revoked links are kept so their
+// views stay visible.
+func (r *noteShareLinkRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.NoteShareLink{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+// RecordView stores a view and bumps the link's counters
+func (r *noteShareLinkRepository) RecordView(ctx context.Context, view *models.NoteShareLinkView) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(view).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.NoteShareLink{}).
+			Where("id = ?", view.LinkID).
+			Updates(map[string]interface{}{
+				"view_count":     gorm.Expr("view_count + 1"),
+				"last_viewed_at": view.CreatedAt,
+			}).Error
+	})
+}
+
+// DailyViews counts a link's views and distinct visitors per day since a time
+func (r *noteShareLinkRepository) DailyViews(ctx context.Context, linkID string, since time.Time) ([]ShareLinkDailyViews, error) {
+	var days []ShareLinkDailyViews
+	err := r.db.WithContext(ctx).
+		Model(&models.NoteShareLinkView{}).
+		Select("date_trunc('day', created_at) AS day, COUNT(*) AS views, COUNT(DISTINCT visitor_hash) AS unique_visitors").
+		Where("link_id = ? AND created_at >= ?", linkID, since).
+		Group("day").
+		Order("day ASC").
+		Scan(&days).Error
+	return days, err
+}
+
+// CountUniqueVisitors counts the distinct visitors of a link over its lifetime
+func (r *noteShareLinkRepository) CountUniqueVisitors(ctx context.Context, linkID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.NoteShareLinkView{}).
+		Where("link_id = ?", linkID).
+		Distinct("visitor_hash").
+		Count(&count).Error
+	return count, err
+}
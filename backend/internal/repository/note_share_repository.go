@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"gorm.io/gorm"
+)
+
+// NoteShareRepository defines the interface for note share data operations
+type NoteShareRepository interface {
+	Create(ctx context.Context, share *models.NoteShare) error
+	GetByID(ctx context.Context, id string) (*models.NoteShare, error)
+	FindByTarget(ctx context.Context, noteID, folderID *string, email string) (*models.NoteShare, error)
+	UpdateRole(ctx context.Context, id string, role models.NoteRole) error
+	Delete(ctx context.Context, id string) error
+	ListByNoteID(ctx context.Context, noteID string) ([]*models.NoteShare, error)
+	ListByFolderID(ctx context.Context, folderID string) ([]*models.NoteShare, error)
+	ListByGrantee(ctx context.Context, userID, email string) ([]*models.NoteShare, error)
+	ListGrants(ctx context.Context, noteID string, folderIDs []string, userID, email string) ([]*models.NoteShare, error)
+	FolderAncestorIDs(ctx context.Context, folderID string) ([]string, error)
+}
+
+// noteShareRepository implements NoteShareRepository
+type noteShareRepository struct {
+	db *database.DB
+}
+
+// NewNoteShareRepository creates a new note share repository
+func NewNoteShareRepository(db *database.DB) NoteShareRepository {
+	return &noteShareRepository{db: db}
+}
+
+// Create stores a new share
+func (r *noteShareRepository) Create(ctx context.Context, share *models.NoteShare) error {
+	share.GranteeEmail = strings.ToLower(strings.TrimSpace(share.GranteeEmail))
+	return r.db.WithContext(ctx).Create(share).Error
+}
+
+// GetByID retrieves a share by ID
+func (r *noteShareRepository) GetByID(ctx context.Context, id string) (*models.NoteShare, error) {
+	var share models.NoteShare
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&share).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// FindByTarget retrieves the share of a note or folder with an email
+func (r *noteShareRepository) FindByTarget(ctx context.Context, noteID, folderID *string, email string) (*models.NoteShare, error) {
+	query := r.db.WithContext(ctx).Where("grantee_email = ?", strings.ToLower(strings.TrimSpace(email)))
+	if noteID != nil {
+		query = query.Where("note_id = ?", *noteID)
+	} else {
+		query = query.Where("folder_id = ?", *folderID)
+	}
+
+	var share models.NoteShare
+	if err := query.First(&share).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// UpdateRole changes the role a share grants
+func (r *noteShareRepository) UpdateRole(ctx context.Context, id string, role models.NoteRole) error {
+	return r.db.WithContext(ctx).Model(&models.NoteShare{}).Where("id = ?", id).Update("role", role).Error
+}
+
+// Delete revokes a share
+func (r *noteShareRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.NoteShare{}).Error
+}
+
+// ListByNoteID lists the shares of a note
+func (r *noteShareRepository) ListByNoteID(ctx context.Context, noteID string) ([]*models.NoteShare, error) {
+	var shares []*models.NoteShare
+	err := r.db.WithContext(ctx).Where("note_id = ?", noteID).Order("created_at ASC").Find(&shares).Error
+	return shares, err
+}
+
+// ListByFolderID lists the shares of a folder
+func (r *noteShareRepository) ListByFolderID(ctx context.Context, folderID string) ([]*models.NoteShare, error) {
+	var shares []*models.NoteShare
+	err := r.db.WithContext(ctx).Where("folder_id = ?", folderID).Order("created_at ASC").Find(&shares).Error
+	return shares, err
+}
+
+// ListByGrantee lists everything shared with a user, newest first
+func (r *noteShareRepository) ListByGrantee(ctx context.Context, userID, email string) ([]*models.NoteShare, error) {
+	var shares []*models.NoteShare
+	err := granteeScope(r.db.WithContext(ctx), userID, email).Order("created_at DESC").Find(&shares).Error
+	return shares, err
+}
+
+// ListGrants lists the shares giving a user access to a note, either directly
+// or through one of the given folders
+func (r *noteShareRepository) ListGrants(ctx context.Context, noteID string, folderIDs []string, userID, email string) ([]*models.NoteShare, error) {
+	query := r.db.WithContext(ctx)
+	switch {
+	case noteID != "" && len(folderIDs) > 0:
+		query = query.Where("(note_id = ? OR folder_id IN ?)", noteID, folderIDs)
+	case noteID != "":
+		query = query.Where("note_id = ?", noteID)
+	case len(folderIDs) > 0:
+		query = query.Where("folder_id IN ?", folderIDs)
+	default:
+		return nil, nil
+	}
+
+	var shares []*models.NoteShare
+	err := granteeScope(query, userID, email).Find(&shares).Error
+	return shares, err
+}
+
+// FolderAncestorIDs returns a folder's ID followed by the IDs of its parents,
+// nearest first
+func (r *noteShareRepository) FolderAncestorIDs(ctx context.Context, folderID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS depth FROM folders WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT f.id, f.parent_id, a.depth + 1 FROM folders f
+			JOIN ancestors a ON f.id = a.parent_id
+			WHERE f.deleted_at IS NULL AND a.depth < 64
+		)
+		SELECT id FROM ancestors ORDER BY depth`, folderID).Scan(&ids).Error
+	return ids, err
+}
+
+// granteeScope matches shares granted to the user, or to their email before
+// they had an account. Callers pass an empty email until it is verified.
+func granteeScope(query *gorm.DB, userID, email string) *gorm.DB {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return query.Where("grantee_user_id = ?", userID)
+	}
+	return query.Where("(grantee_user_id = ? OR (grantee_user_id IS NULL AND grantee_email = ?))", userID, email)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// TagWithCount is a tag together with the number of live notes using it
+type TagWithCount struct {
+	models.Tag
+	NoteCount int64 `json:"note_count"`
+}
+
+// TagRepository defines the interface for tag data operations
+type TagRepository interface {
+	Create(ctx context.Context, tag *models.Tag) error
+	GetByID(ctx context.Context, id string) (*models.Tag, error)
+	GetByName(ctx context.Context, userID string, workspaceID *string, name string) (*models.Tag, error)
+	Update(ctx context.Context, tag *models.Tag) error
+	Delete(ctx context.Context, id string) error
+	ListByUserID(ctx context.Context, userID string) ([]*TagWithCount, error)
+	ListByWorkspaceID(ctx context.Context, workspaceID string) ([]*TagWithCount, error)
+	AttachToNote(ctx context.Context, noteID, tagID string) error
+	DetachFromNote(ctx context.Context, noteID, tagID string) error
+}
+
+// tagRepository implements TagRepository
+type tagRepository struct {
+	db *database.DB
+}
+
+// NewTagRepository creates a new tag repository
+func NewTagRepository(db *database.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+// Create creates a new tag
+func (r *tagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Create(tag).Error
+}
+
+// GetByID retrieves a tag by ID
+func (r *tagRepository) GetByID(ctx context.Context, id string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetByName retrieves a workspace's tag by name (case-insensitive). Without
+// a workspace it looks among the user's tags that belong to none.
+func (r *tagRepository) GetByName(ctx context.Context, userID string, workspaceID *string, name string) (*models.Tag, error) {
+	query := r.db.WithContext(ctx).Where("LOWER(name) = LOWER(?)", name)
+	if workspaceID != nil {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	} else {
+		query = query.Where("user_id = ? AND workspace_id IS NULL", userID)
+	}
+
+	var tag models.Tag
+	if err := query.First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// Update updates a tag's name and color
+func (r *tagRepository) Update(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Tag{}).
+		Where("id = ?", tag.ID).
+		Updates(map[string]interface{}{
+			"name":  tag.Name,
+			"color": tag.Color,
+		}).Error
+}
+
+// Delete permanently deletes a tag and detaches it from every note.
+// Tags are not soft-deleted so their name can be reused right away.
+func (r *tagRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", id).Delete(&models.NoteTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id = ?", id).Delete(&models.Tag{}).Error
+	})
+}
+
+// ListByUserID lists a user's tags ordered by name, with note counts
+func (r *tagRepository) ListByUserID(ctx context.Context, userID string) ([]*TagWithCount, error) {
+	return r.listWithCounts(ctx, "tags.user_id = ?", userID)
+}
+
+// ListByWorkspaceID lists the tags of every member of a workspace ordered by
+// name, with note counts
+func (r *tagRepository) ListByWorkspaceID(ctx context.Context, workspaceID string) ([]*TagWithCount, error) {
+	return r.listWithCounts(ctx, "tags.workspace_id = ?", workspaceID)
+}
+
+func (r *tagRepository) listWithCounts(ctx context.Context, where string, arg string) ([]*TagWithCount, error) {
+	var tags []*TagWithCount
+	err := r.db.WithContext(ctx).
+		Model(&models.Tag{}).
+		Select("tags.*, COUNT(notes.id) AS note_count").
+		Joins("LEFT JOIN note_tags ON note_tags.tag_id = tags.id").
+		Joins("LEFT JOIN notes ON notes.id = note_tags.note_id AND notes.deleted_at IS NULL").
+		Where(where, arg).
+		Group("tags.id").
+		Order("tags.name ASC").
+		Scan(&tags).Error
+	return tags, err
+}
+
+// AttachToNote links a tag to a note; attaching twice is a no-op
+func (r *tagRepository) AttachToNote(ctx context.Context, noteID, tagID string) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.NoteTag{NoteID: noteID, TagID: tagID}).Error
+}
+
+// DetachFromNote unlinks a tag from a note
+func (r *tagRepository) DetachFromNote(ctx context.Context, noteID, tagID string) error {
+	return r.db.WithContext(ctx).
+		Where("note_id = ? AND tag_id = ?", noteID, tagID).
+		Delete(&models.NoteTag{}).Error
+}
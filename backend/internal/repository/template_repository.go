@@ -14,6 +14,7 @@ type TemplateRepository interface {
 	Update(ctx context.Context, template *models.Template) error
 	Delete(ctx context.Context, id string) error
 	ListByUserID(ctx context.Context, userID string) ([]*models.Template, error)
+	ListByWorkspaceID(ctx context.Context, workspaceID string) ([]*models.Template, error)
 }
 
 // templateRepository implements TemplateRepository
@@ -60,3 +61,13 @@ func (r *templateRepository) ListByUserID(ctx context.Context, userID string) ([
 		Find(&templates).Error
 	return templates, err
 }
+
+// ListByWorkspaceID retrieves all templates in a workspace
+func (r *templateRepository) ListByWorkspaceID(ctx context.Context, workspaceID string) ([]*models.Template, error) {
+	var templates []*models.Template
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ?", workspaceID).
+		Order("created_at DESC").
+		Find(&templates).Error
+	return templates, err
+}
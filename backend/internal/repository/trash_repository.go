@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"gorm.io/gorm"
+)
+
+// TrashedFolder is a folder in the trash along with what was deleted with it
+type TrashedFolder struct {
+	models.Folder
+	FolderCount int64 `json:"folder_count"`
+	NoteCount   int64 `json:"note_count"`
+}
+
+// TrashRepository defines the interface for soft-deleted notes and folders
+type TrashRepository interface {
+	ListNotes(ctx context.Context, userID string, workspaceID *string) ([]*models.Note, error)
+	ListFolders(ctx context.Context, userID string, workspaceID *string) ([]*TrashedFolder, error)
+	GetNote(ctx context.Context, id string) (*models.Note, error)
+	GetFolder(ctx context.Context, id string) (*models.Folder, error)
+	RestoreNote(ctx context.Context, id string, folderID *string) error
+	RestoreFolder(ctx context.Context, id string, parentID *string, order int) error
+	PurgeNote(ctx context.Context, id string) error
+	PurgeFolder(ctx context.Context, id string) error
+	ListExpired(ctx context.Context, cutoff time.Time) (noteIDs []string, folderIDs []string, err error)
+}
+
+// trashRepository implements TrashRepository
+type trashRepository struct {
+	db *database.DB
+}
+
+// NewTrashRepository creates a new trash repository
+func NewTrashRepository(db *database.DB) TrashRepository {
+	return &trashRepository{db: db}
+}
+
+// ListNotes lists notes that were deleted on their own (not with a folder),
+// from the whole workspace when one is given
+func (r *trashRepository) ListNotes(ctx context.Context, userID string, workspaceID *string) ([]*models.Note, error) {
+	var notes []*models.Note
+	err := trashScope(r.db.WithContext(ctx).Unscoped(), userID, workspaceID).
+		Where("deleted_at IS NOT NULL AND trashed_with IS NULL").
+		Order("deleted_at DESC").
+		Find(&notes).Error
+	return notes, err
+}
+
+// ListFolders lists folders that were deleted on their own, with the number
+// of subfolders and notes that went to the trash with them
+func (r *trashRepository) ListFolders(ctx context.Context, userID string, workspaceID *string) ([]*TrashedFolder, error) {
+	var folders []*models.Folder
+	err := trashScope(r.db.WithContext(ctx).Unscoped(), userID, workspaceID).
+		Where("deleted_at IS NOT NULL AND trashed_with IS NULL").
+		Order("deleted_at DESC").
+		Find(&folders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*TrashedFolder, 0, len(folders))
+	for _, folder := range folders {
+		item := &TrashedFolder{Folder: *folder}
+		if err := r.db.WithContext(ctx).Unscoped().Model(&models.Folder{}).
+			Where("trashed_with = ?", folder.ID).
+			Count(&item.FolderCount).Error; err != nil {
+			return nil, err
+		}
+		if err := r.db.WithContext(ctx).Unscoped().Model(&models.Note{}).
+			Where("trashed_with = ?", folder.ID).
+			Count(&item.NoteCount).Error; err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// GetNote retrieves a trashed note by ID
+func (r *trashRepository) GetNote(ctx context.Context, id string) (*models.Note, error) {
+	var note models.Note
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&note).Error
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// GetFolder retrieves a trashed folder by ID
+func (r *trashRepository) GetFolder(ctx context.Context, id string) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&folder).Error
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// RestoreNote brings a trashed note back into the given folder (nil for root)
+func (r *trashRepository) RestoreNote(ctx context.Context, id string, folderID *string) error {
+	return r.db.WithContext(ctx).Unscoped().
+		Model(&models.Note{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deleted_at":   nil,
+			"trashed_with": nil,
+			"folder_id":    folderID,
+		}).Error
+}
+
+// RestoreFolder brings a trashed folder back under parentID at the given
+// order, together with every subfolder and note that was deleted with it
+func (r *trashRepository) RestoreFolder(ctx context.Context, id string, parentID *string, order int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Folder{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"deleted_at":  nil,
+				"parent_id":   parentID,
+				"order_index": order,
+			}).Error; err != nil {
+			return err
+		}
+
+		restore := map[string]interface{}{"deleted_at": nil, "trashed_with": nil}
+		if err := tx.Unscoped().Model(&models.Folder{}).
+			Where("trashed_with = ?", id).
+			Updates(restore).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Note{}).
+			Where("trashed_with = ?", id).
+			Updates(restore).Error
+	})
+}
+
+// PurgeNote permanently deletes a note and its derived data
+func (r *trashRepository) PurgeNote(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return purgeNotes(tx, []string{id})
+	})
+}
+
+// PurgeFolder permanently deletes a trashed folder and everything deleted with it
+func (r *trashRepository) PurgeFolder(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var noteIDs []string
+		if err := tx.Unscoped().Model(&models.Note{}).
+			Where("trashed_with = ?", id).
+			Pluck("id", &noteIDs).Error; err != nil {
+			return err
+		}
+		if err := purgeNotes(tx, noteIDs); err != nil {
+			return err
+		}
+
+		var folderIDs []string
+		if err := tx.Unscoped().Model(&models.Folder{}).
+			Where("id = ? OR trashed_with = ?", id, id).
+			Pluck("id", &folderIDs).Error; err != nil {
+			return err
+		}
+		if len(folderIDs) == 0 {
+			return nil
+		}
+
+		// Anything still live under these folders moves to the root instead of
+		// being cascaded away with them
+		if err := tx.Model(&models.Folder{}).
+			Where("parent_id IN ? AND id NOT IN ?", folderIDs, folderIDs).
+			Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Note{}).
+			Where("folder_id IN ?", folderIDs).
+			Update("folder_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("folder_id IN ?", folderIDs).Delete(&models.NoteShare{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().
+			Where("id IN ?", folderIDs).
+			Delete(&models.Folder{}).Error
+	})
+}
+
+// ListExpired returns the trash entries deleted before cutoff
+func (r *trashRepository) ListExpired(ctx context.Context, cutoff time.Time) ([]string, []string, error) {
+	var noteIDs []string
+	if err := r.db.WithContext(ctx).Unscoped().Model(&models.Note{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND trashed_with IS NULL", cutoff).
+		Pluck("id", &noteIDs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var folderIDs []string
+	if err := r.db.WithContext(ctx).Unscoped().Model(&models.Folder{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND trashed_with IS NULL", cutoff).
+		Pluck("id", &folderIDs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return noteIDs, folderIDs, nil
+}
+
+// trashScope matches a workspace's entries when one is given, otherwise the
+// user's own, like NoteRepository.GetByUserID
+func trashScope(query *gorm.DB, userID string, workspaceID *string) *gorm.DB {
+	if workspaceID != nil {
+		return query.Where("workspace_id = ?", *workspaceID)
+	}
+	return query.Where("user_id = ?", userID)
+}
+
+// purgeNotes hard deletes notes along with rows that only reference them by ID
+func purgeNotes(tx *gorm.DB, noteIDs []string) error {
+	if len(noteIDs) == 0 {
+		return nil
+	}
+
+	if err := tx.Unscoped().Where("note_id IN ?", noteIDs).Delete(&models.NoteChunk{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("note_id IN ?", noteIDs).Delete(&models.NoteRevision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("note_id IN ?", noteIDs).Delete(&models.ChunkJob{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("note_id IN ?", noteIDs).Delete(&models.NoteShare{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("note_id IN ?", noteIDs).Delete(&models.AIEditProposal{}).Error; err != nil {
+		return err
+	}
+	var linkIDs []string
+	if err := tx.Unscoped().Model(&models.NoteShareLink{}).
+		Where("note_id IN ?", noteIDs).
+		Pluck("id", &linkIDs).Error; err != nil {
+		return err
+	}
+	if len(linkIDs) > 0 {
+		if err := tx.Unscoped().Where("link_id IN ?", linkIDs).Delete(&models.NoteShareLinkView{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("id IN ?", linkIDs).Delete(&models.NoteShareLink{}).Error; err != nil {
+			return err
+		}
+	}
+	if tx.Migrator().HasTable("yjs_updates") {
+		if err := tx.Exec("DELETE FROM yjs_updates WHERE docname IN ?", noteIDs).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Unscoped().Where("id IN ?", noteIDs).Delete(&models.Note{}).Error
+}
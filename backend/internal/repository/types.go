@@ -19,6 +19,14 @@ type ListParams struct {
 	Email  *string
 }
 
+// TagMatchMode controls how multiple tag filters combine
+type TagMatchMode string
+
+const (
+	TagMatchAny TagMatchMode = "any" // note has at least one of the tags (OR)
+	TagMatchAll TagMatchMode = "all" // note has every tag (AND)
+)
+
 // NoteListParams represents note-specific list parameters
 type NoteListParams struct {
 	Page     int
@@ -27,6 +35,12 @@ type NoteListParams struct {
 	FolderID *string
 	IsPublic *bool
 	Query    *string
+	TagIDs   []string
+	TagMatch TagMatchMode
+
+	// WorkspaceID lists every member's notes in the workspace instead of
+	// only the user's own
+	WorkspaceID *string
 }
 
 // FolderListParams represents folder-specific list parameters
@@ -35,4 +49,8 @@ type FolderListParams struct {
 	Limit    int
 	ParentID *string
 	IsPublic *bool
+
+	// WorkspaceID lists every member's folders in the workspace instead of
+	// only the user's own
+	WorkspaceID *string
 }
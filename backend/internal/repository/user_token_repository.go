@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// UserTokenRepository defines the interface for single-use account token data operations
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	Consume(ctx context.Context, userID, tokenID string, purpose models.UserTokenPurpose, now time.Time) error
+	InvalidateUnused(ctx context.Context, userID string, purpose models.UserTokenPurpose, now time.Time) error
+}
+
+// userTokenRepository implements UserTokenRepository
+type userTokenRepository struct {
+	db *database.DB
+}
+
+// NewUserTokenRepository creates a new account token repository
+func NewUserTokenRepository(db *database.DB) UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+// Create stores a newly issued token
+func (r *userTokenRepository) Create(ctx context.Context, token *models.UserToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// Consume marks an unused, unexpired token as used. It returns
+// gorm.ErrRecordNotFound when there is no such token, so a token can only be
+// consumed once even by concurrent requests.
+func (r *userTokenRepository) Consume(ctx context.Context, userID, tokenID string, purpose models.UserTokenPurpose, now time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.UserToken{}).
+		Where("token_id = ? AND user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenID, userID, purpose, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// InvalidateUnused marks a user's outstanding tokens for a purpose as used,
+// so only the most recently emailed link works
+func (r *userTokenRepository) InvalidateUnused(ctx context.Context, userID string, purpose models.UserTokenPurpose, now time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", now).Error
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+// WorkspaceRepository defines the interface for workspace data operations
+type WorkspaceRepository interface {
+	Create(ctx context.Context, workspace *models.Workspace) error
+	GetByID(ctx context.Context, id string) (*models.Workspace, error)
+	GetPersonal(ctx context.Context, userID string) (*models.Workspace, error)
+	ListByUserID(ctx context.Context, userID string) ([]*models.Workspace, error)
+	Rename(ctx context.Context, id, name string) error
+	Delete(ctx context.Context, id string) error
+	CountNotesAndFolders(ctx context.Context, id string) (int64, error)
+	GetMember(ctx context.Context, workspaceID, userID string) (*models.WorkspaceMember, error)
+	ListMembers(ctx context.Context, workspaceID string) ([]*models.WorkspaceMember, error)
+	AddMember(ctx context.Context, member *models.WorkspaceMember) error
+	UpdateMemberRole(ctx context.Context, workspaceID, userID string, role models.WorkspaceRole) error
+	RemoveMember(ctx context.Context, workspaceID, userID string) error
+}
+
+// workspaceRepository implements WorkspaceRepository
+type workspaceRepository struct {
+	db *database.DB
+}
+
+// NewWorkspaceRepository creates a new workspace repository
+func NewWorkspaceRepository(db *database.DB) WorkspaceRepository {
+	return &workspaceRepository{db: db}
+}
+
+// Create stores a new workspace and makes its owner a member
+func (r *workspaceRepository) Create(ctx context.Context, workspace *models.Workspace) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      workspace.OwnerID,
+			Role:        models.WorkspaceRoleOwner,
+		}).Error
+	})
+}
+
+// GetByID retrieves a workspace by ID
+func (r *workspaceRepository) GetByID(ctx context.Context, id string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&workspace).Error; err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// GetPersonal retrieves a user's personal workspace
+func (r *workspaceRepository) GetPersonal(ctx context.Context, userID string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := r.db.WithContext(ctx).Where("owner_id = ? AND personal", userID).First(&workspace).Error; err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// ListByUserID lists the workspaces a user is a member of with their role,
+// personal workspace first
+func (r *workspaceRepository) ListByUserID(ctx context.Context, userID string) ([]*models.Workspace, error) {
+	var rows []struct {
+		models.Workspace
+		MemberRole models.WorkspaceRole
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.Workspace{}).
+		Select("workspaces.*, workspace_members.role AS member_role").
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id").
+		Where("workspace_members.user_id = ?", userID).
+		Order("workspaces.personal DESC, workspaces.name ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces := make([]*models.Workspace, 0, len(rows))
+	for i := range rows {
+		workspace := rows[i].Workspace
+		workspace.Role = rows[i].MemberRole
+		workspaces = append(workspaces, &workspace)
+	}
+	return workspaces, nil
+}
+
+// Rename changes a workspace's name
+func (r *workspaceRepository) Rename(ctx context.Context, id, name string) error {
+	return r.db.WithContext(ctx).Model(&models.Workspace{}).Where("id = ?", id).Update("name", name).Error
+}
+
+// Delete removes a workspace and its memberships, purging whatever is left in
+// its trash
+func (r *workspaceRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var noteIDs []string
+		if err := tx.Unscoped().Model(&models.Note{}).
+			Where("workspace_id = ?", id).
+			Pluck("id", &noteIDs).Error; err != nil {
+			return err
+		}
+		if err := purgeNotes(tx, noteIDs); err != nil {
+			return err
+		}
+		var folderIDs []string
+		if err := tx.Unscoped().Model(&models.Folder{}).
+			Where("workspace_id = ?", id).
+			Pluck("id", &folderIDs).Error; err != nil {
+			return err
+		}
+		if len(folderIDs) > 0 {
+			if err := tx.Unscoped().Where("folder_id IN ?", folderIDs).Delete(&models.NoteShare{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("id IN ?", folderIDs).Delete(&models.Folder{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("workspace_id = ?", id).Delete(&models.WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&models.Workspace{}).Error
+	})
+}
+
+// CountNotesAndFolders counts the live notes and folders in a workspace
+func (r *workspaceRepository) CountNotesAndFolders(ctx context.Context, id string) (int64, error) {
+	var notes, folders int64
+	db := r.db.WithContext(ctx)
+	if err := db.Model(&models.Note{}).Where("workspace_id = ?", id).Count(&notes).Error; err != nil {
+		return 0, err
+	}
+	if err := db.Model(&models.Folder{}).Where("workspace_id = ?", id).Count(&folders).Error; err != nil {
+		return 0, err
+	}
+	return notes + folders, nil
+}
+
+// GetMember retrieves a user's membership of a workspace
+func (r *workspaceRepository) GetMember(ctx context.Context, workspaceID, userID string) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListMembers lists a workspace's members with their user, oldest first
+func (r *workspaceRepository) ListMembers(ctx context.Context, workspaceID string) ([]*models.WorkspaceMember, error) {
+	var members []*models.WorkspaceMember
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("workspace_id = ?", workspaceID).
+		Order("created_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// AddMember adds a user to a workspace
+func (r *workspaceRepository) AddMember(ctx context.Context, member *models.WorkspaceMember) error {
+	return r.db.WithContext(ctx).Create(member).Error
+}
+
+// UpdateMemberRole changes a member's role
+func (r *workspaceRepository) UpdateMemberRole(ctx context.Context, workspaceID, userID string, role models.WorkspaceRole) error {
+	return r.db.WithContext(ctx).
+		Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Update("role", role).Error
+}
+
+// RemoveMember permanently removes a user from a workspace so they can be
+// added again later
+func (r *workspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	return r.db.WithContext(ctx).Unscoped().
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Delete(&models.WorkspaceMember{}).Error
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+// AIAuditService defines the interface for auditing AI runs and their tool calls
+type AIAuditService interface {
+	ListRuns(ctx context.Context, filter repository.AIRunAuditFilter) ([]AIRunAudit, int64, error)
+	GetRun(ctx context.Context, runID string) (*AIRunAudit, error)
+}
+
+// AIRunAudit is a run with everything it did
+type AIRunAudit struct {
+	models.AIRun
+	ToolCalls []AIToolCallAudit `json:"tool_calls"`
+	Revisions []AIRevisionAudit `json:"revisions"`
+}
+
+// AIToolCallAudit is one tool call of a run. Consent fields are only set for
+// tools that required the user's consent; OK is nil until the tool returned.
+type AIToolCallAudit struct {
+	ToolCallID    string                  `json:"tool_call_id"`
+	Tool          string                  `json:"tool"`
+	Args          datatypes.JSON          `json:"args,omitempty"`
+	CalledAt      *time.Time              `json:"called_at,omitempty"`
+	OK            *bool                   `json:"ok,omitempty"`
+	ConsentStatus models.AIToolCallStatus `json:"consent_status,omitempty"`
+	ApprovedBy    string                  `json:"approved_by,omitempty"`
+	ApprovedAt    *time.Time              `json:"approved_at,omitempty"`
+	NoteID        string                  `json:"note_id,omitempty"`
+	NoteVersion   *int                    `json:"note_version,omitempty"`
+}
+
+// AIRevisionAudit is a note version written by a run
+type AIRevisionAudit struct {
+	RevisionID string    `json:"revision_id"`
+	NoteID     string    `json:"note_id"`
+	Version    int       `json:"version"`
+	ToolCallID string    `json:"tool_call_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// aiAuditService implements AIAuditService
+type aiAuditService struct {
+	repo repository.AIAuditRepository
+	runs repository.AIRunRepository
+}
+
+// NewAIAuditService creates a new AI audit service
+func NewAIAuditService(repo repository.AIAuditRepository, runs repository.AIRunRepository) AIAuditService {
+	return &aiAuditService{
+		repo: repo,
+		runs: runs,
+	}
+}
+
+// ListRuns lists the matching runs with their tool calls and note versions
+func (s *aiAuditService) ListRuns(ctx context.Context, filter repository.AIRunAuditFilter) ([]AIRunAudit, int64, error) {
+	runs, total, err := s.repo.ListRuns(ctx, filter)
+	if err != nil {
+		return nil, 0, ErrInternalServerError
+	}
+	audits, err := s.auditRuns(ctx, runs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return audits, total, nil
+}
+
+// GetRun audits a single run
+func (s *aiAuditService) GetRun(ctx context.Context, runID string) (*AIRunAudit, error) {
+	run, err := s.runs.GetRun(ctx, runID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAIRunNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	audits, err := s.auditRuns(ctx, []models.AIRun{*run})
+	if err != nil {
+		return nil, err
+	}
+	return &audits[0], nil
+}
+
+func (s *aiAuditService) auditRuns(ctx context.Context, runs []models.AIRun) ([]AIRunAudit, error) {
+	runIDs := make([]string, 0, len(runs))
+	for _, run := range runs {
+		runIDs = append(runIDs, run.RunID)
+	}
+
+	calls, err := s.repo.ListToolCalls(ctx, runIDs)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	events, err := s.repo.ListToolEvents(ctx, runIDs)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	revisions, err := s.repo.ListRevisions(ctx, runIDs)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return buildRunAudits(runs, calls, events, revisions), nil
+}
+
+// buildRunAudits joins runs with their tool events, consent records and the
+// note revisions their tool calls produced. Tool calls are listed in the
+// order the ai-service made them.
+func buildRunAudits(runs []models.AIRun, calls []models.AIToolCall, events []models.AIRunEvent, revisions []models.NoteRevision) []AIRunAudit {
+	audits := make([]AIRunAudit, len(runs))
+	byRun := make(map[string]*AIRunAudit, len(runs))
+	for i, run := range runs {
+		run.ResumeToken = ""
+		audits[i] = AIRunAudit{AIRun: run, ToolCalls: []AIToolCallAudit{}, Revisions: []AIRevisionAudit{}}
+		byRun[run.RunID] = &audits[i]
+	}
+
+	for _, event := range events {
+		audit, ok := byRun[event.RunID]
+		if !ok {
+			continue
+		}
+		var payload struct {
+			ToolCallID string          `json:"tool_call_id"`
+			Tool       string          `json:"tool"`
+			Args       json.RawMessage `json:"args"`
+			OK         *bool           `json:"ok"`
+		}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			continue
+		}
+
+		switch event.EventType {
+		case "tool.call":
+			calledAt := event.CreatedAt
+			call := AIToolCallAudit{ToolCallID: payload.ToolCallID, Tool: payload.Tool, CalledAt: &calledAt}
+			if len(payload.Args) > 0 && string(payload.Args) != "null" {
+				call.Args = datatypes.JSON(payload.Args)
+			}
+			audit.ToolCalls = append(audit.ToolCalls, call)
+		case "tool.result":
+			// Results without an ID belong to the latest open call of that tool
+			for i := len(audit.ToolCalls) - 1; i >= 0; i-- {
+				call := &audit.ToolCalls[i]
+				if call.OK == nil && call.Tool == payload.Tool && call.ToolCallID == payload.ToolCallID {
+					call.OK = payload.OK
+					break
+				}
+			}
+		}
+	}
+
+	for _, consent := range calls {
+		audit, ok := byRun[consent.RunID]
+		if !ok {
+			continue
+		}
+		call := findToolCallAudit(audit, consent.ToolCallID)
+		if call == nil {
+			audit.ToolCalls = append(audit.ToolCalls, AIToolCallAudit{ToolCallID: consent.ToolCallID, Tool: consent.Tool})
+			call = &audit.ToolCalls[len(audit.ToolCalls)-1]
+		}
+		if call.Args == nil {
+			call.Args = consent.Args
+		}
+		call.ConsentStatus = consent.Status
+		call.ApprovedBy = consent.ApprovedBy
+		call.ApprovedAt = consent.ApprovedAt
+	}
+
+	for _, revision := range revisions {
+		audit, ok := byRun[revision.RunID]
+		if !ok {
+			continue
+		}
+		audit.Revisions = append(audit.Revisions, AIRevisionAudit{
+			RevisionID: revision.ID,
+			NoteID:     revision.NoteID,
+			Version:    revision.Version,
+			ToolCallID: revision.ToolCallID,
+			CreatedAt:  revision.CreatedAt,
+		})
+		if call := findToolCallAudit(audit, revision.ToolCallID); call != nil {
+			version := revision.Version
+			call.NoteID = revision.NoteID
+			call.NoteVersion = &version
+		}
+	}
+	return audits
+}
+
+func findToolCallAudit(audit *AIRunAudit, toolCallID string) *AIToolCallAudit {
+	if toolCallID == "" {
+		return nil
+	}
+	for i := range audit.ToolCalls {
+		if audit.ToolCalls[i].ToolCallID == toolCallID {
+			return &audit.ToolCalls[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+func TestBuildRunAuditsJoinsToolActivity(t *testing.T) {
+	approvedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	runs := []models.AIRun{{RunID: "run-1", ResumeToken: "secret"}, {RunID: "run-2"}}
+	events := []models.AIRunEvent{
+		{RunID: "run-1", EventType: "tool.call", Payload: []byte(`{"tool_call_id":"call-read","tool":"notes.read","args":{"note_id":"n1"}}`)},
+		{RunID: "run-1", EventType: "tool.result", Payload: []byte(`{"tool_call_id":"call-read","tool":"notes.read","ok":true}`)},
+		{RunID: "run-1", EventType: "tool.call", Payload: []byte(`{"tool_call_id":"call-write","tool":"notes.write"}`)},
+		{RunID: "run-1", EventType: "tool.result", Payload: []byte(`{"tool_call_id":"call-write","tool":"notes.write","ok":true}`)},
+	}
+	calls := []models.AIToolCall{{
+		RunID:      "run-1",
+		ToolCallID: "call-write",
+		Tool:       "notes.write",
+		Status:     models.AIToolCallStatusApproved,
+		Args:       []byte(`{"note_id":"n1"}`),
+		ApprovedBy: "user-1",
+		ApprovedAt: &approvedAt,
+	}}
+	revisions := []models.NoteRevision{{NoteID: "n1", Version: 7, RunID: "run-1", ToolCallID: "call-write"}}
+
+	audits := buildRunAudits(runs, calls, events, revisions)
+
+	if len(audits) != 2 || audits[0].ResumeToken != "" {
+		t.Fatalf("expected two audits without resume tokens, got %+v", audits)
+	}
+	if len(audits[1].ToolCalls) != 0 {
+		t.Fatalf("expected run-2 to have no tool calls, got %+v", audits[1].ToolCalls)
+	}
+
+	toolCalls := audits[0].ToolCalls
+	if len(toolCalls) != 2 || toolCalls[0].Tool != "notes.read" || toolCalls[1].Tool != "notes.write" {
+		t.Fatalf("expected read then write, got %+v", toolCalls)
+	}
+	if string(toolCalls[0].Args) != `{"note_id":"n1"}` || toolCalls[0].OK == nil || !*toolCalls[0].OK {
+		t.Fatalf("expected read args and result, got %+v", toolCalls[0])
+	}
+	write := toolCalls[1]
+	if write.ConsentStatus != models.AIToolCallStatusApproved || write.ApprovedBy != "user-1" || write.ApprovedAt == nil {
+		t.Fatalf("expected write approval, got %+v", write)
+	}
+	if write.NoteVersion == nil || *write.NoteVersion != 7 || string(write.Args) != `{"note_id":"n1"}` {
+		t.Fatalf("expected write to produce version 7, got %+v", write)
+	}
+	if len(audits[0].Revisions) != 1 {
+		t.Fatalf("expected one revision, got %+v", audits[0].Revisions)
+	}
+}
+
+func TestBuildRunAuditsKeepsConsentWithoutEvents(t *testing.T) {
+	audits := buildRunAudits(
+		[]models.AIRun{{RunID: "run-1"}},
+		[]models.AIToolCall{{RunID: "run-1", ToolCallID: "call-1", Tool: "notes.write", Status: models.AIToolCallStatusExpired}},
+		nil,
+		nil,
+	)
+
+	if len(audits[0].ToolCalls) != 1 || audits[0].ToolCalls[0].ConsentStatus != models.AIToolCallStatusExpired {
+		t.Fatalf("expected the expired consent to be listed, got %+v", audits[0].ToolCalls)
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/utils"
+)
+
+// AIEditProposalService defines the interface for reviewing AI edit proposals
+type AIEditProposalService interface {
+	CreateProposal(ctx context.Context, req CreateEditProposalRequest) (*models.AIEditProposal, error)
+	GetProposal(ctx context.Context, id string) (*models.AIEditProposal, error)
+	ListProposals(ctx context.Context, noteID string, status models.AIEditProposalStatus, limit int) ([]*models.AIEditProposal, error)
+	DiffProposal(ctx context.Context, id string) (*EditProposalDiff, error)
+	AcceptProposal(ctx context.Context, id string, hunks []int) (*EditProposalResult, error)
+	RejectProposal(ctx context.Context, id string) (*models.AIEditProposal, error)
+	ReleaseStaleClaims(ctx context.Context) (int64, error)
+}
+
+// editProposalClaimTimeout is how long a proposal may stay applying before it
+// is assumed abandoned and goes back to pending
+const editProposalClaimTimeout = 5 * time.Minute
+
+// CreateEditProposalRequest describes a proposed edit. Original is the note
+// content the AI worked from, at ExpectedVersion.
+type CreateEditProposalRequest struct {
+	NoteID          string
+	UserID          string
+	RunID           string
+	ToolCallID      string
+	ExpectedVersion int
+	Original        string
+	Proposed        string
+	Summary         string
+}
+
+// EditProposalDiff is the rendered diff of a proposal. Hunks are what can be
+// accepted one by one.
+type EditProposalDiff struct {
+	ProposalID      string                      `json:"proposal_id"`
+	NoteID          string                      `json:"note_id"`
+	Status          models.AIEditProposalStatus `json:"status"`
+	Summary         string                      `json:"summary"`
+	ExpectedVersion int                         `json:"expected_version"`
+	CurrentVersion  int                         `json:"current_version"`
+	Stale           bool                        `json:"stale"`
+	Lines           []utils.DiffLine            `json:"lines"`
+	Hunks           []utils.DiffHunk            `json:"hunks"`
+	Added           int                         `json:"added"`
+	Removed         int                         `json:"removed"`
+}
+
+// EditProposalResult is the outcome of accepting a proposal
+type EditProposalResult struct {
+	Proposal *models.AIEditProposal `json:"proposal"`
+	Note     *models.Note           `json:"note"`
+	Rebased  bool                   `json:"rebased"`
+}
+
+// aiEditProposalService implements AIEditProposalService
+type aiEditProposalService struct {
+	repo        repository.AIEditProposalRepository
+	noteService NoteService
+}
+
+// NewAIEditProposalService creates a new AI edit proposal service
+func NewAIEditProposalService(repo repository.AIEditProposalRepository, noteService NoteService) AIEditProposalService {
+	return &aiEditProposalService{
+		repo:        repo,
+		noteService: noteService,
+	}
+}
+
+// CreateProposal stores a pending proposal
+func (s *aiEditProposalService) CreateProposal(ctx context.Context, req CreateEditProposalRequest) (*models.AIEditProposal, error) {
+	if req.NoteID == "" || req.UserID == "" || strings.TrimSpace(req.Proposed) == "" {
+		return nil, ErrValidationFailed
+	}
+
+	proposal := &models.AIEditProposal{
+		NoteID:          req.NoteID,
+		UserID:          req.UserID,
+		RunID:           req.RunID,
+		ToolCallID:      req.ToolCallID,
+		ExpectedVersion: req.ExpectedVersion,
+		Original:        req.Original,
+		Proposed:        req.Proposed,
+		Summary:         req.Summary,
+		Status:          models.AIEditProposalStatusPending,
+	}
+	if err := s.repo.Create(ctx, proposal); err != nil {
+		return nil, ErrInternalServerError
+	}
+	return proposal, nil
+}
+
+// GetProposal retrieves a proposal by ID
+func (s *aiEditProposalService) GetProposal(ctx context.Context, id string) (*models.AIEditProposal, error) {
+	proposal, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEditProposalNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	return proposal, nil
+}
+
+// ListProposals lists the proposals of a note, newest first
+func (s *aiEditProposalService) ListProposals(ctx context.Context, noteID string, status models.AIEditProposalStatus, limit int) ([]*models.AIEditProposal, error) {
+	proposals, err := s.repo.ListByNoteID(ctx, noteID, status, limit)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return proposals, nil
+}
+
+// DiffProposal renders a proposal as a line diff against the content it was made from
+func (s *aiEditProposalService) DiffProposal(ctx context.Context, id string) (*EditProposalDiff, error) {
+	proposal, err := s.GetProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.noteService.GetNoteByID(ctx, proposal.NoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	original := utils.SplitContentLines(proposal.Original)
+	proposed := utils.SplitContentLines(proposal.Proposed)
+	diff := &EditProposalDiff{
+		ProposalID:      proposal.ID,
+		NoteID:          proposal.NoteID,
+		Status:          proposal.Status,
+		Summary:         proposal.Summary,
+		ExpectedVersion: proposal.ExpectedVersion,
+		CurrentVersion:  note.Version,
+		Stale:           note.Version != proposal.ExpectedVersion,
+		Lines:           utils.DiffLines(original, proposed),
+		Hunks:           utils.DiffHunks(original, proposed),
+	}
+	for _, line := range diff.Lines {
+		switch line.Op {
+		case utils.DiffOpInsert:
+			diff.Added++
+		case utils.DiffOpDelete:
+			diff.Removed++
+		}
+	}
+	return diff, nil
+}
+
+// AcceptProposal writes the chosen hunks (all of them when hunks is nil) to the
+// note. If the note moved on since the proposal was made, the accepted change
+// is rebased onto the current content with a three-way merge. The proposal is
+// claimed before the note is written, so concurrent accepts and rejects cannot
+// both go through; it goes back to pending when the write fails, or after
+// editProposalClaimTimeout when the accept never finished.
+func (s *aiEditProposalService) AcceptProposal(ctx context.Context, id string, hunks []int) (*EditProposalResult, error) {
+	proposal, err := s.GetProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status != models.AIEditProposalStatusPending {
+		return nil, ErrEditProposalResolved
+	}
+
+	if err := s.repo.Claim(ctx, proposal.ID); err != nil {
+		if errors.Is(err, repository.ErrAIEditProposalNotPending) {
+			return nil, ErrEditProposalResolved
+		}
+		return nil, ErrInternalServerError
+	}
+
+	updated, rebased, err := s.applyProposal(ctx, proposal, hunks)
+	if err != nil {
+		if releaseErr := s.repo.Release(ctx, proposal.ID); releaseErr != nil {
+			log.Printf("Warning: failed to release edit proposal %s: %v", proposal.ID, releaseErr)
+		}
+		return nil, err
+	}
+
+	if err := s.repo.Resolve(ctx, proposal.ID, models.AIEditProposalStatusApplying, models.AIEditProposalStatusAccepted, &updated.Version, rebased); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	proposal.Status = models.AIEditProposalStatusAccepted
+	proposal.AppliedVersion = &updated.Version
+	proposal.Rebased = rebased
+	return &EditProposalResult{Proposal: proposal, Note: updated, Rebased: rebased}, nil
+}
+
+// RejectProposal closes a proposal without touching the note
+func (s *aiEditProposalService) RejectProposal(ctx context.Context, id string) (*models.AIEditProposal, error) {
+	proposal, err := s.GetProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Resolve(ctx, proposal.ID, models.AIEditProposalStatusPending, models.AIEditProposalStatusRejected, nil, false); err != nil {
+		if errors.Is(err, repository.ErrAIEditProposalNotPending) {
+			return nil, ErrEditProposalResolved
+		}
+		return nil, ErrInternalServerError
+	}
+	proposal.Status = models.AIEditProposalStatusRejected
+	return proposal, nil
+}
+
+// ReleaseStaleClaims returns proposals stuck in applying for longer than
+// editProposalClaimTimeout to pending, so they can be accepted again
+func (s *aiEditProposalService) ReleaseStaleClaims(ctx context.Context) (int64, error) {
+	released, err := s.repo.ReleaseStale(ctx, time.Now().UTC().Add(-editProposalClaimTimeout))
+	if err != nil {
+		return 0, ErrInternalServerError
+	}
+	return released, nil
+}
+
+// applyProposal writes the merged content of a claimed proposal to its note
+func (s *aiEditProposalService) applyProposal(ctx context.Context, proposal *models.AIEditProposal, hunks []int) (*models.Note, bool, error) {
+	note, err := s.noteService.GetNoteByID(ctx, proposal.NoteID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	content, rebased, err := mergeEditProposal(proposal, note, hunks)
+	if err != nil {
+		return nil, false, err
+	}
+
+	updated, err := s.noteService.UpdateNoteContentWithVersion(ctx, note.ID, content, note.Version)
+	if err != nil {
+		return nil, false, err
+	}
+	return updated, rebased, nil
+}
+
+// mergeEditProposal builds the note content for accepting the given hunks of a
+// proposal and reports whether it had to be rebased onto newer content
+func mergeEditProposal(proposal *models.AIEditProposal, note *models.Note, hunks []int) (string, bool, error) {
+	original := utils.SplitContentLines(proposal.Original)
+	all := utils.DiffHunks(original, utils.SplitContentLines(proposal.Proposed))
+
+	accepted := all
+	if hunks != nil {
+		chosen := make(map[int]bool, len(hunks))
+		for _, index := range hunks {
+			if index < 0 || index >= len(all) {
+				return "", false, fmt.Errorf("%w: no hunk %d", ErrValidationFailed, index)
+			}
+			chosen[index] = true
+		}
+		accepted = make([]utils.DiffHunk, 0, len(chosen))
+		for _, hunk := range all {
+			if chosen[hunk.Index] {
+				accepted = append(accepted, hunk)
+			}
+		}
+		if len(accepted) == 0 {
+			return "", false, fmt.Errorf("%w: no hunks selected", ErrValidationFailed)
+		}
+	}
+
+	if note.Version == proposal.ExpectedVersion {
+		if len(accepted) == len(all) {
+			return proposal.Proposed, false, nil
+		}
+		return strings.Join(utils.ApplyHunks(original, accepted), "\n"), false, nil
+	}
+
+	merged, err := utils.Merge3(original, utils.SplitContentLines(note.Content), utils.ApplyHunks(original, accepted))
+	if err != nil {
+		return "", false, ErrEditProposalConflict
+	}
+	return strings.Join(merged, "\n"), true, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+func editProposalFixture() (*models.AIEditProposal, *models.Note) {
+	proposal := &models.AIEditProposal{
+		ExpectedVersion: 3,
+		Original:        "<p>One</p><p>Two</p><p>Three</p>",
+		Proposed:        "<p>1</p><p>Two</p><p>3</p>",
+	}
+	note := &models.Note{Content: proposal.Original, Version: 3}
+	return proposal, note
+}
+
+func TestMergeEditProposalAcceptsAllOrSomeHunks(t *testing.T) {
+	proposal, note := editProposalFixture()
+
+	content, rebased, err := mergeEditProposal(proposal, note, nil)
+	if err != nil || rebased || content != proposal.Proposed {
+		t.Fatalf("expected proposed content as is, got %q rebased=%v err=%v", content, rebased, err)
+	}
+
+	content, _, err = mergeEditProposal(proposal, note, []int{1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content != "<p>One</p>\n<p>Two</p>\n<p>3</p>" {
+		t.Fatalf("expected only the second hunk applied, got %q", content)
+	}
+
+	if _, _, err := mergeEditProposal(proposal, note, []int{5}); !errors.Is(err, ErrValidationFailed) {
+		t.Fatalf("expected validation error for unknown hunk, got %v", err)
+	}
+}
+
+func TestMergeEditProposalRebasesOntoNewerContent(t *testing.T) {
+	proposal, note := editProposalFixture()
+	note.Version = 4
+	note.Content = "<p>One</p><p>Two!</p><p>Three</p>"
+
+	content, rebased, err := mergeEditProposal(proposal, note, nil)
+	if err != nil || !rebased {
+		t.Fatalf("expected clean rebase, got rebased=%v err=%v", rebased, err)
+	}
+	if content != "<p>1</p>\n<p>Two!</p>\n<p>3</p>" {
+		t.Fatalf("unexpected rebased content %q", content)
+	}
+
+	note.Content = "<p>Uno</p><p>Two</p><p>Three</p>"
+	if _, _, err := mergeEditProposal(proposal, note, nil); !errors.Is(err, ErrEditProposalConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+}
+
+// fakeEditProposalRepo holds one proposal. With staleReads, reads report it
+// as pending whatever its status, as if another request changed it since.
+type fakeEditProposalRepo struct {
+	repository.AIEditProposalRepository
+	proposal   *models.AIEditProposal
+	staleReads bool
+	claimedAt  time.Time
+}
+
+func (f *fakeEditProposalRepo) GetByID(ctx context.Context, id string) (*models.AIEditProposal, error) {
+	copied := *f.proposal
+	if f.staleReads {
+		copied.Status = models.AIEditProposalStatusPending
+	}
+	return &copied, nil
+}
+
+func (f *fakeEditProposalRepo) move(from, to models.AIEditProposalStatus) error {
+	if f.proposal.Status != from {
+		return repository.ErrAIEditProposalNotPending
+	}
+	f.proposal.Status = to
+	return nil
+}
+
+func (f *fakeEditProposalRepo) Claim(ctx context.Context, id string) error {
+	if err := f.move(models.AIEditProposalStatusPending, models.AIEditProposalStatusApplying); err != nil {
+		return err
+	}
+	f.claimedAt = time.Now().UTC()
+	return nil
+}
+
+func (f *fakeEditProposalRepo) Release(ctx context.Context, id string) error {
+	return f.move(models.AIEditProposalStatusApplying, models.AIEditProposalStatusPending)
+}
+
+func (f *fakeEditProposalRepo) ReleaseStale(ctx context.Context, claimedBefore time.Time) (int64, error) {
+	if f.proposal.Status != models.AIEditProposalStatusApplying || !f.claimedAt.Before(claimedBefore) {
+		return 0, nil
+	}
+	f.proposal.Status = models.AIEditProposalStatusPending
+	return 1, nil
+}
+
+func (f *fakeEditProposalRepo) Resolve(ctx context.Context, id string, from, status models.AIEditProposalStatus, appliedVersion *int, rebased bool) error {
+	return f.move(from, status)
+}
+
+// fakeProposalNoteService counts note writes; the write fails while err is set
+type fakeProposalNoteService struct {
+	NoteService
+	note   *models.Note
+	writes int
+	err    error
+}
+
+func (f *fakeProposalNoteService) GetNoteByID(ctx context.Context, id string) (*models.Note, error) {
+	copied := *f.note
+	return &copied, nil
+}
+
+func (f *fakeProposalNoteService) UpdateNoteContentWithVersion(ctx context.Context, id string, content string, expectedVersion int) (*models.Note, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.writes++
+	f.note.Content = content
+	f.note.Version++
+	copied := *f.note
+	return &copied, nil
+}
+
+func TestAcceptProposalClaimsBeforeWritingNote(t *testing.T) {
+	proposal, note := editProposalFixture()
+	proposal.Status = models.AIEditProposalStatusPending
+	repo := &fakeEditProposalRepo{proposal: proposal}
+	notes := &fakeProposalNoteService{note: note, err: ErrVersionConflict}
+	s := &aiEditProposalService{repo: repo, noteService: notes}
+	ctx := context.Background()
+
+	if _, err := s.AcceptProposal(ctx, "p1", nil); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected the failed write to surface, got %v", err)
+	}
+	if proposal.Status != models.AIEditProposalStatusPending {
+		t.Fatalf("expected a failed accept to leave the proposal pending, got %q", proposal.Status)
+	}
+
+	notes.err = nil
+	result, err := s.AcceptProposal(ctx, "p1", nil)
+	if err != nil {
+		t.Fatalf("accept proposal: %v", err)
+	}
+	if result.Proposal.Status != models.AIEditProposalStatusAccepted || proposal.Status != models.AIEditProposalStatusAccepted {
+		t.Fatalf("expected the proposal to be accepted, got %q", proposal.Status)
+	}
+
+	// Neither a second accept nor one that loses the race to claim writes again
+	if _, err := s.AcceptProposal(ctx, "p1", nil); !errors.Is(err, ErrEditProposalResolved) {
+		t.Fatalf("expected an accepted proposal to be refused, got %v", err)
+	}
+	proposal.Status = models.AIEditProposalStatusApplying
+	repo.staleReads = true
+	if _, err := s.AcceptProposal(ctx, "p1", nil); !errors.Is(err, ErrEditProposalResolved) {
+		t.Fatalf("expected a proposal claimed by another request to be refused, got %v", err)
+	}
+	if notes.writes != 1 {
+		t.Fatalf("expected exactly one note write, got %d", notes.writes)
+	}
+}
+
+func TestReleaseStaleClaimsReturnsAbandonedAcceptsToPending(t *testing.T) {
+	proposal, note := editProposalFixture()
+	proposal.Status = models.AIEditProposalStatusPending
+	repo := &fakeEditProposalRepo{proposal: proposal}
+	s := &aiEditProposalService{repo: repo, noteService: &fakeProposalNoteService{note: note}}
+	ctx := context.Background()
+
+	// An accept that crashed between claiming and resolving
+	if err := repo.Claim(ctx, "p1"); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if released, err := s.ReleaseStaleClaims(ctx); err != nil || released != 0 {
+		t.Fatalf("expected a fresh claim to be kept, got released=%d err=%v", released, err)
+	}
+
+	repo.claimedAt = repo.claimedAt.Add(-editProposalClaimTimeout - time.Second)
+	if released, err := s.ReleaseStaleClaims(ctx); err != nil || released != 1 {
+		t.Fatalf("expected the stale claim to be released, got released=%d err=%v", released, err)
+	}
+	if proposal.Status != models.AIEditProposalStatusPending {
+		t.Fatalf("expected the proposal back in pending, got %q", proposal.Status)
+	}
+	if _, err := s.AcceptProposal(ctx, "p1", nil); err != nil {
+		t.Fatalf("expected the released proposal to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+// AIUsageService defines the interface for AI usage metering and quotas
+type AIUsageService interface {
+	CheckQuota(ctx context.Context, userID string) (*AIQuotaStatus, error)
+	RecordRunUsage(ctx context.Context, runID string, usage AIRunUsage) error
+	GetUsage(ctx context.Context, userID string, period string) (*AIUsageReport, error)
+}
+
+// AIRunUsage is the token usage the ai-service reports for a completed run
+type AIRunUsage struct {
+	Model            string
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+}
+
+// AIQuota holds the limits that apply to a user, 0 means unlimited
+type AIQuota struct {
+	Tier             string  `json:"tier"`
+	DailyTokens      int64   `json:"daily_tokens"`
+	MonthlyTokens    int64   `json:"monthly_tokens"`
+	DailyRuns        int64   `json:"daily_runs"`
+	MonthlyBudgetUSD float64 `json:"monthly_budget_usd"`
+}
+
+// AIQuotaStatus is a user's usage measured against their quota.
+// TokensRemaining is -1 when no token quota applies.
+type AIQuotaStatus struct {
+	Quota           AIQuota                  `json:"quota"`
+	Today           repository.AIUsageTotals `json:"today"`
+	Month           repository.AIUsageTotals `json:"month"`
+	TokensRemaining int64                    `json:"tokens_remaining"`
+	Exceeded        string                   `json:"exceeded,omitempty"`
+}
+
+// AIUsageReport breaks a user's usage over a period down by mode and tool
+type AIUsageReport struct {
+	Period string                   `json:"period"`
+	Since  time.Time                `json:"since"`
+	Totals repository.AIUsageTotals `json:"totals"`
+	ByMode []repository.AIModeUsage `json:"by_mode"`
+	ByTool []repository.AIToolUsage `json:"by_tool"`
+	Quota  *AIQuotaStatus           `json:"quota"`
+}
+
+// aiUsageService implements AIUsageService
+type aiUsageService struct {
+	repo   repository.AIUsageRepository
+	config *config.AIConfig
+}
+
+// NewAIUsageService creates a new AI usage service
+func NewAIUsageService(repo repository.AIUsageRepository, cfg *config.AIConfig) AIUsageService {
+	return &aiUsageService{
+		repo:   repo,
+		config: cfg,
+	}
+}
+
+// CheckQuota measures a user's usage against their quota and returns
+// ErrAIQuotaExceeded, along with the status, once any limit is reached
+func (s *aiUsageService) CheckQuota(ctx context.Context, userID string) (*AIQuotaStatus, error) {
+	quota, err := s.quotaFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	today, err := s.repo.SumUsage(ctx, userID, startOfDay(now))
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	month, err := s.repo.SumUsage(ctx, userID, startOfMonth(now))
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	status := evaluateQuota(quota, *today, *month)
+	if status.Exceeded != "" {
+		return status, ErrAIQuotaExceeded
+	}
+	return status, nil
+}
+
+// RecordRunUsage prices and stores the usage of a completed run
+func (s *aiUsageService) RecordRunUsage(ctx context.Context, runID string, usage AIRunUsage) error {
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+	cost := usageCost(usage.PromptTokens, usage.CompletionTokens, s.config.PromptTokenPriceUSD, s.config.CompletionTokenPriceUSD)
+	if err := s.repo.RecordRunUsage(ctx, runID, usage.Model, usage.PromptTokens, usage.CompletionTokens, total, cost); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// GetUsage reports a user's usage for the current UTC "day" or "month"
+func (s *aiUsageService) GetUsage(ctx context.Context, userID string, period string) (*AIUsageReport, error) {
+	now := time.Now().UTC()
+	var since time.Time
+	switch period {
+	case "day":
+		since = startOfDay(now)
+	case "", "month":
+		period = "month"
+		since = startOfMonth(now)
+	default:
+		return nil, ErrValidationFailed
+	}
+
+	totals, err := s.repo.SumUsage(ctx, userID, since)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	byMode, err := s.repo.UsageByMode(ctx, userID, since)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	byTool, err := s.repo.ToolCallsByTool(ctx, userID, since)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	status, err := s.CheckQuota(ctx, userID)
+	if err != nil && !errors.Is(err, ErrAIQuotaExceeded) {
+		return nil, err
+	}
+
+	if byMode == nil {
+		byMode = []repository.AIModeUsage{}
+	}
+	if byTool == nil {
+		byTool = []repository.AIToolUsage{}
+	}
+	return &AIUsageReport{
+		Period: period,
+		Since:  since,
+		Totals: *totals,
+		ByMode: byMode,
+		ByTool: byTool,
+		Quota:  status,
+	}, nil
+}
+
+// quotaFor resolves a user's quota from the config and their overrides
+func (s *aiUsageService) quotaFor(ctx context.Context, userID string) (AIQuota, error) {
+	quota := AIQuota{
+		Tier:             "free",
+		DailyTokens:      s.config.DailyTokenQuota,
+		MonthlyTokens:    s.config.MonthlyTokenQuota,
+		DailyRuns:        s.config.DailyRunQuota,
+		MonthlyBudgetUSD: s.config.MonthlyBudgetUSD,
+	}
+
+	limit, err := s.repo.GetLimit(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return quota, nil
+		}
+		return quota, ErrInternalServerError
+	}
+	return applyUsageLimit(quota, limit), nil
+}
+
+func applyUsageLimit(quota AIQuota, limit *models.AIUsageLimit) AIQuota {
+	if limit.Tier != "" {
+		quota.Tier = limit.Tier
+	}
+	if limit.DailyTokens != nil {
+		quota.DailyTokens = *limit.DailyTokens
+	}
+	if limit.MonthlyTokens != nil {
+		quota.MonthlyTokens = *limit.MonthlyTokens
+	}
+	if limit.DailyRuns != nil {
+		quota.DailyRuns = *limit.DailyRuns
+	}
+	if limit.MonthlyBudgetUSD != nil {
+		quota.MonthlyBudgetUSD = *limit.MonthlyBudgetUSD
+	}
+	return quota
+}
+
+// evaluateQuota checks usage against each limit in turn and works out how
+// many tokens the next run may use
+func evaluateQuota(quota AIQuota, today, month repository.AIUsageTotals) *AIQuotaStatus {
+	status := &AIQuotaStatus{
+		Quota:           quota,
+		Today:           today,
+		Month:           month,
+		TokensRemaining: -1,
+	}
+
+	switch {
+	case quota.DailyRuns > 0 && today.Runs >= quota.DailyRuns:
+		status.Exceeded = "daily_runs"
+	case quota.DailyTokens > 0 && today.TotalTokens >= quota.DailyTokens:
+		status.Exceeded = "daily_tokens"
+	case quota.MonthlyTokens > 0 && month.TotalTokens >= quota.MonthlyTokens:
+		status.Exceeded = "monthly_tokens"
+	case quota.MonthlyBudgetUSD > 0 && month.CostUSD >= quota.MonthlyBudgetUSD:
+		status.Exceeded = "monthly_budget"
+	}
+
+	if quota.DailyTokens > 0 {
+		status.TokensRemaining = max(quota.DailyTokens-today.TotalTokens, 0)
+	}
+	if quota.MonthlyTokens > 0 {
+		remaining := max(quota.MonthlyTokens-month.TotalTokens, 0)
+		if status.TokensRemaining < 0 || remaining < status.TokensRemaining {
+			status.TokensRemaining = remaining
+		}
+	}
+	return status
+}
+
+// usageCost prices a run from per-million token prices
+func usageCost(promptTokens, completionTokens int64, promptPrice, completionPrice float64) float64 {
+	return (float64(promptTokens)*promptPrice + float64(completionTokens)*completionPrice) / 1_000_000
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+func TestEvaluateQuotaReportsFirstExceededLimit(t *testing.T) {
+	quota := AIQuota{DailyTokens: 1000, MonthlyTokens: 5000, DailyRuns: 10, MonthlyBudgetUSD: 1}
+
+	status := evaluateQuota(quota, repository.AIUsageTotals{Runs: 3, TotalTokens: 400}, repository.AIUsageTotals{TotalTokens: 4800})
+	if status.Exceeded != "" {
+		t.Fatalf("expected quota not exceeded, got %q", status.Exceeded)
+	}
+	if status.TokensRemaining != 200 {
+		t.Fatalf("expected the monthly quota to bound remaining tokens to 200, got %d", status.TokensRemaining)
+	}
+
+	status = evaluateQuota(quota, repository.AIUsageTotals{Runs: 10}, repository.AIUsageTotals{})
+	if status.Exceeded != "daily_runs" {
+		t.Fatalf("expected daily_runs exceeded, got %q", status.Exceeded)
+	}
+
+	status = evaluateQuota(quota, repository.AIUsageTotals{}, repository.AIUsageTotals{CostUSD: 1.2})
+	if status.Exceeded != "monthly_budget" {
+		t.Fatalf("expected monthly_budget exceeded, got %q", status.Exceeded)
+	}
+}
+
+func TestEvaluateQuotaUnlimited(t *testing.T) {
+	status := evaluateQuota(AIQuota{}, repository.AIUsageTotals{Runs: 1000, TotalTokens: 1 << 40}, repository.AIUsageTotals{CostUSD: 1e6})
+	if status.Exceeded != "" || status.TokensRemaining != -1 {
+		t.Fatalf("expected no limits, got exceeded=%q remaining=%d", status.Exceeded, status.TokensRemaining)
+	}
+}
+
+func TestApplyUsageLimitOverridesOnlySetFields(t *testing.T) {
+	daily := int64(0)
+	budget := 20.0
+	quota := applyUsageLimit(
+		AIQuota{Tier: "free", DailyTokens: 1000, MonthlyTokens: 5000, MonthlyBudgetUSD: 1},
+		&models.AIUsageLimit{Tier: "pro", DailyTokens: &daily, MonthlyBudgetUSD: &budget},
+	)
+
+	if quota.Tier != "pro" || quota.DailyTokens != 0 || quota.MonthlyTokens != 5000 || quota.MonthlyBudgetUSD != 20 {
+		t.Fatalf("unexpected quota %+v", quota)
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	cost := usageCost(2_000_000, 500_000, 0.15, 0.6)
+	if math.Abs(cost-0.6) > 1e-9 {
+		t.Fatalf("expected cost 0.6, got %f", cost)
+	}
+}
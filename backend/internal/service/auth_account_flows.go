@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/mailer"
+)
+
+const (
+	emailVerificationTTL = 48 * time.Hour
+	passwordResetTTL     = time.Hour
+
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores anything longer
+)
+
+// SendVerificationEmail emails the user a link that verifies their address
+func (s *authService) SendVerificationEmail(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return ErrInternalServerError
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// VerifyEmail marks the address a verification link was sent to as verified
+func (s *authService) VerifyEmail(ctx context.Context, token string) error {
+	user, err := s.consumeAccountToken(ctx, token, models.UserTokenEmailVerification)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+	user.EmailVerified = true
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// RequestPasswordReset emails a reset link when the address belongs to an
+// active user. Unknown addresses succeed silently so they cannot be probed,
+// and so does a link that failed to send, which is only logged.
+func (s *authService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return ErrInternalServerError
+	}
+	if user.Status != models.UserStatusActive {
+		return nil
+	}
+
+	token, err := s.issueAccountToken(ctx, user, models.UserTokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	err = s.sendMail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your account. Open this link within an hour to choose a new one:\n\n%s\n\nIf it wasn't you, you can ignore this email; your password stays the same.\n",
+			user.Name, s.accountLink("/auth/reset-password", token)),
+	})
+	if err != nil {
+		// Failing here would tell the caller the address has an account
+		log.Printf("Warning: failed to send password reset email to user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password from a reset link and signs the user out
+// everywhere. Receiving the link also proves the address is theirs.
+func (s *authService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if !validPassword(newPassword) {
+		return ErrValidationFailed
+	}
+	user, err := s.consumeAccountToken(ctx, token, models.UserTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user.EmailVerified = true
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	_, err = s.revokeAllSessions(ctx, user.ID, "", models.SessionRevokedPasswordReset)
+	return err
+}
+
+// ChangePassword replaces the password of a signed-in user, keeping the
+// current session and signing every other one out
+func (s *authService) ChangePassword(ctx context.Context, userID, currentSessionID, currentPassword, newPassword string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return ErrInternalServerError
+	}
+	if user.Password == nil || *user.Password == "" {
+		return ErrPasswordNotSet
+	}
+	if bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(currentPassword)) != nil {
+		return ErrCurrentPasswordInvalid
+	}
+	if !validPassword(newPassword) {
+		return ErrValidationFailed
+	}
+
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	_, err = s.revokeAllSessions(ctx, user.ID, currentSessionID, models.SessionRevokedPasswordChanged)
+	return err
+}
+
+func (s *authService) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := s.issueAccountToken(ctx, user, models.UserTokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.sendMail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm that %s is your email address by opening this link within two days:\n\n%s\n",
+			user.Name, user.Email, s.accountLink("/auth/verify-email", token)),
+	})
+}
+
+// setPassword hashes and saves a new password. Outstanding reset links stop
+// working once the password has changed.
+func (s *authService) setPassword(ctx context.Context, user *models.User, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	hashedStr := string(hashed)
+	user.Password = &hashedStr
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return ErrInternalServerError
+	}
+	if err := s.tokenRepo.InvalidateUnused(ctx, user.ID, models.UserTokenPasswordReset, time.Now()); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// issueAccountToken signs a single-use token for purpose. It replaces any
+// earlier unused token for the same purpose, and is bound to the user's
+// current address.
+func (s *authService) issueAccountToken(ctx context.Context, user *models.User, purpose models.UserTokenPurpose, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if err := s.tokenRepo.InvalidateUnused(ctx, user.ID, purpose, now); err != nil {
+		return "", ErrInternalServerError
+	}
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", ErrInternalServerError
+	}
+	record := &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenID:   tokenID,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.tokenRepo.Create(ctx, record); err != nil {
+		return "", ErrInternalServerError
+	}
+
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"jti":     tokenID,
+		"typ":     string(purpose),
+		"exp":     record.ExpiresAt.Unix(),
+		"iat":     now.Unix(),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.JWT.SecretKey))
+	if err != nil {
+		return "", ErrInternalServerError
+	}
+	return signed, nil
+}
+
+// consumeAccountToken checks a token's signature, expiry, purpose and address
+// and uses it up, returning its user
+func (s *authService) consumeAccountToken(ctx context.Context, token string, purpose models.UserTokenPurpose) (*models.User, error) {
+	claims, err := s.parseToken(strings.TrimSpace(token))
+	if err != nil {
+		return nil, ErrAccountTokenInvalid
+	}
+	tokenType, _ := (*claims)["typ"].(string)
+	tokenID, _ := (*claims)["jti"].(string)
+	email, _ := (*claims)["email"].(string)
+	userID, _ := (*claims)["user_id"].(string)
+	if tokenType != string(purpose) || tokenID == "" {
+		return nil, ErrAccountTokenInvalid
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountTokenInvalid
+		}
+		return nil, ErrInternalServerError
+	}
+	if !strings.EqualFold(user.Email, email) || user.Status != models.UserStatusActive {
+		return nil, ErrAccountTokenInvalid
+	}
+
+	if err := s.tokenRepo.Consume(ctx, user.ID, tokenID, purpose, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountTokenInvalid
+		}
+		return nil, ErrInternalServerError
+	}
+	return user, nil
+}
+
+func (s *authService) sendMail(ctx context.Context, msg mailer.Message) error {
+	if s.mailer == nil {
+		return ErrNotImplemented
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		fmt.Printf("Failed to send mail: %v\n", err)
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// accountLink builds a frontend link carrying an account token
+func (s *authService) accountLink(path, token string) string {
+	return strings.TrimRight(s.config.Mail.FrontendURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func validPassword(password string) bool {
+	return len(password) >= minPasswordLength && len(password) <= maxPasswordLength
+}
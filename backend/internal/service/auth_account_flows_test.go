@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/mailer"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[string]*models.User
+}
+
+func (f *fakeUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
+	if user, ok := f.users[id]; ok {
+		copied := *user
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeUserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeUserRepo) Update(ctx context.Context, user *models.User) error {
+	copied := *user
+	f.users[user.ID] = &copied
+	return nil
+}
+
+type fakeUserTokenRepo struct {
+	tokens map[string]*models.UserToken
+}
+
+func (f *fakeUserTokenRepo) Create(ctx context.Context, token *models.UserToken) error {
+	f.tokens[token.TokenID] = token
+	return nil
+}
+
+func (f *fakeUserTokenRepo) Consume(ctx context.Context, userID, tokenID string, purpose models.UserTokenPurpose, now time.Time) error {
+	token, ok := f.tokens[tokenID]
+	if !ok || token.UserID != userID || token.Purpose != purpose || token.UsedAt != nil || !token.ExpiresAt.After(now) {
+		return gorm.ErrRecordNotFound
+	}
+	token.UsedAt = &now
+	return nil
+}
+
+func (f *fakeUserTokenRepo) InvalidateUnused(ctx context.Context, userID string, purpose models.UserTokenPurpose, now time.Time) error {
+	for _, token := range f.tokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	return errors.New("connection refused")
+}
+
+var mailedTokenPattern = regexp.MustCompile(`\?token=(\S+)`)
+
+// newAccountFlowService sets up one active user, Ana, whose mail is written
+// to the returned directory
+func newAccountFlowService(t *testing.T) (*authService, *fakeUserRepo, *fakeSessionRepo, string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	password := string(hash)
+	users := &fakeUserRepo{users: map[string]*models.User{
+		"user-1": {BaseModel: models.BaseModel{ID: "user-1"}, Email: "ana@example.com", Name: "Ana", Password: &password, Status: models.UserStatusActive},
+	}}
+	sessions := &fakeSessionRepo{sessions: map[string]*models.AuthSession{}}
+
+	dir := t.TempDir()
+	fileMailer, err := mailer.NewFileMailer("no-reply@example.com", dir)
+	if err != nil {
+		t.Fatalf("file mailer: %v", err)
+	}
+
+	s := newTestAuthService(sessions, nil)
+	s.userRepo = users
+	s.tokenRepo = &fakeUserTokenRepo{tokens: map[string]*models.UserToken{}}
+	s.mailer = fileMailer
+	s.config.Mail.FrontendURL = "http://app.test/"
+	return s, users, sessions, dir
+}
+
+// lastMailedToken reads the token from the newest email in dir
+func lastMailedToken(t *testing.T, dir string) string {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) == 0 {
+		t.Fatalf("expected an email to be sent")
+	}
+	data, err := os.ReadFile(files[len(files)-1])
+	if err != nil {
+		t.Fatalf("read mail: %v", err)
+	}
+	match := mailedTokenPattern.FindSubmatch(data)
+	if match == nil {
+		t.Fatalf("expected a link in the email, got:\n%s", data)
+	}
+	token, err := url.QueryUnescape(string(match[1]))
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	return token
+}
+
+func TestResetPasswordIsSingleUseAndRevokesSessions(t *testing.T) {
+	s, users, sessions, dir := newAccountFlowService(t)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := s.startSession(ctx, "user-1"); err != nil {
+			t.Fatalf("start session: %v", err)
+		}
+	}
+
+	if err := s.RequestPasswordReset(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("expected unknown addresses to succeed silently, got %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.eml")); len(files) != 0 {
+		t.Fatalf("expected no email for an unknown address, got %v", files)
+	}
+
+	if err := s.RequestPasswordReset(ctx, "ana@example.com"); err != nil {
+		t.Fatalf("request reset: %v", err)
+	}
+	token := lastMailedToken(t, dir)
+
+	if err := s.ResetPassword(ctx, token, "short"); !errors.Is(err, ErrValidationFailed) {
+		t.Fatalf("expected a short password to be rejected, got %v", err)
+	}
+	if err := s.ResetPassword(ctx, token, "new-password"); err != nil {
+		t.Fatalf("reset password: %v", err)
+	}
+	if err := s.ResetPassword(ctx, token, "another-password"); !errors.Is(err, ErrAccountTokenInvalid) {
+		t.Fatalf("expected the reset link to work only once, got %v", err)
+	}
+
+	user := users.users["user-1"]
+	if bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte("new-password")) != nil {
+		t.Fatalf("expected the new password to be saved")
+	}
+	if !user.EmailVerified {
+		t.Fatalf("expected a reset to verify the address it was sent to")
+	}
+	for id, session := range sessions.sessions {
+		if session.RevokedAt == nil || session.RevokedReason != models.SessionRevokedPasswordReset {
+			t.Fatalf("expected session %s to be revoked by the reset, got %+v", id, session)
+		}
+	}
+}
+
+func TestRequestPasswordResetHidesMailFailures(t *testing.T) {
+	s, _, _, _ := newAccountFlowService(t)
+	ctx := context.Background()
+
+	s.mailer = failingMailer{}
+	if err := s.RequestPasswordReset(ctx, "ana@example.com"); err != nil {
+		t.Fatalf("expected a failed email to look like any other request, got %v", err)
+	}
+	s.mailer = nil
+	if err := s.RequestPasswordReset(ctx, "ana@example.com"); err != nil {
+		t.Fatalf("expected a missing mailer to look like any other request, got %v", err)
+	}
+}
+
+func TestAccountTokensAreBoundToPurposeAndAddress(t *testing.T) {
+	s, users, _, dir := newAccountFlowService(t)
+	ctx := context.Background()
+
+	if err := s.SendVerificationEmail(ctx, "user-1"); err != nil {
+		t.Fatalf("send verification: %v", err)
+	}
+	token := lastMailedToken(t, dir)
+
+	if err := s.ResetPassword(ctx, token, "new-password"); !errors.Is(err, ErrAccountTokenInvalid) {
+		t.Fatalf("expected a verification token to be refused for reset, got %v", err)
+	}
+
+	users.users["user-1"].Email = "ana@elsewhere.example"
+	if err := s.VerifyEmail(ctx, token); !errors.Is(err, ErrAccountTokenInvalid) {
+		t.Fatalf("expected a token for the old address to be refused, got %v", err)
+	}
+
+	users.users["user-1"].Email = "ana@example.com"
+	if err := s.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("verify email: %v", err)
+	}
+	if !users.users["user-1"].EmailVerified {
+		t.Fatalf("expected the address to be verified")
+	}
+	if err := s.SendVerificationEmail(ctx, "user-1"); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Fatalf("expected no new link for a verified address, got %v", err)
+	}
+}
+
+func TestChangePasswordKeepsCurrentSession(t *testing.T) {
+	s, _, sessions, _ := newAccountFlowService(t)
+	ctx := context.Background()
+
+	current, err := s.startSession(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	other, err := s.startSession(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	currentID := s.SessionIDFromToken(current.AccessToken)
+
+	if err := s.ChangePassword(ctx, "user-1", currentID, "wrong-password", "new-password"); !errors.Is(err, ErrCurrentPasswordInvalid) {
+		t.Fatalf("expected a wrong current password to be rejected, got %v", err)
+	}
+	if err := s.ChangePassword(ctx, "user-1", currentID, "old-password", "new-password"); err != nil {
+		t.Fatalf("change password: %v", err)
+	}
+
+	if sessions.sessions[currentID].RevokedAt != nil {
+		t.Fatalf("expected the current session to stay signed in")
+	}
+	if otherSession := sessions.sessions[s.SessionIDFromToken(other.AccessToken)]; otherSession.RevokedReason != models.SessionRevokedPasswordChanged {
+		t.Fatalf("expected the other session to be revoked, got %+v", otherSession)
+	}
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,6 +16,7 @@ import (
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/dto"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/mailer"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
 	"gorm.io/gorm"
 )
@@ -24,20 +29,70 @@ type AuthService interface {
 	ValidateToken(ctx context.Context, token string) (*models.User, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*dto.ResAuthTokens, error)
 	GoogleLogin(ctx context.Context, providerAccountID, email string, emailVerified bool, name, avatar string) (*dto.ResAuthTokens, error)
+	SessionIDFromToken(token string) string
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]*models.AuthSession, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userID, exceptSessionID string) (int, error)
+	SendVerificationEmail(ctx context.Context, userID string) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, userID, currentSessionID, currentPassword, newPassword string) error
+}
+
+const (
+	accessTokenTTL  = time.Hour
+	refreshTokenTTL = 7 * 24 * time.Hour
+
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+// ClientInfo describes the device a sign-in comes from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+type clientInfoContextKey struct{}
+
+// WithClientInfo returns a context carrying the client for sessions created
+// or refreshed with it
+func WithClientInfo(ctx context.Context, client ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoContextKey{}, client)
+}
+
+func clientInfoFromContext(ctx context.Context) ClientInfo {
+	client, _ := ctx.Value(clientInfoContextKey{}).(ClientInfo)
+	return client
 }
 
 // authService implements AuthService
 type authService struct {
 	userRepo    repository.UserRepository
 	accountRepo repository.AccountRepository
+	sessionRepo repository.AuthSessionRepository
+	tokenRepo   repository.UserTokenRepository
+	revocations TokenRevocationList
+	mailer      mailer.Mailer
 	config      *config.Config
+
+	// publishFailedUntil is when the last failed revocation publish stops
+	// mattering, as Unix nanoseconds: the access tokens it should have cut
+	// off have expired by then
+	publishFailedUntil atomic.Int64
 }
 
-// NewAuthService creates a new auth service
-func NewAuthService(userRepo repository.UserRepository, accountRepo repository.AccountRepository, config *config.Config) AuthService {
+// NewAuthService creates a new auth service. Without a revocation list,
+// revoked sessions are looked up in the database on every request.
+func NewAuthService(userRepo repository.UserRepository, accountRepo repository.AccountRepository, sessionRepo repository.AuthSessionRepository, tokenRepo repository.UserTokenRepository, revocations TokenRevocationList, accountMailer mailer.Mailer, config *config.Config) AuthService {
 	return &authService{
 		userRepo:    userRepo,
 		accountRepo: accountRepo,
+		sessionRepo: sessionRepo,
+		tokenRepo:   tokenRepo,
+		revocations: revocations,
+		mailer:      accountMailer,
 		config:      config,
 	}
 }
@@ -98,8 +153,13 @@ func (s *authService) Register(ctx context.Context, req dto.ReqUserRegistration)
 		return nil, fmt.Errorf("failed to create auth account: %w", err)
 	}
 
+	// Registration works without a mail server; the link can be resent later
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		fmt.Printf("Failed to send verification email: %v\n", err)
+	}
+
 	// Generate tokens
-	tokens, err := s.generateTokens(user.ID)
+	tokens, err := s.startSession(ctx, user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -145,7 +205,7 @@ func (s *authService) Login(ctx context.Context, req dto.ReqLoginCredentials) (*
 	}
 
 	// Generate tokens
-	tokens, err := s.generateTokens(user.ID)
+	tokens, err := s.startSession(ctx, user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -153,16 +213,18 @@ func (s *authService) Login(ctx context.Context, req dto.ReqLoginCredentials) (*
 	return tokens, nil
 }
 
-// Logout invalidates a token (in a real app, you'd add token to blacklist)
+// Logout revokes the session the token belongs to. Tokens that no longer
+// parse have nothing left to revoke.
 func (s *authService) Logout(ctx context.Context, token string) error {
-	// In a real application, you would:
-	// 1. Parse the token to get the user ID
-	// 2. Add the token to a blacklist/revocation list
-	// 3. Store in Redis or database
-
-	// For now, we'll just return success
-	// TODO: Implement token blacklisting
-	return nil
+	claims, err := s.parseToken(token)
+	if err != nil {
+		return nil
+	}
+	sessionID, _ := (*claims)["sid"].(string)
+	if sessionID == "" {
+		return nil
+	}
+	return s.revokeSession(ctx, sessionID, models.SessionRevokedLogout)
 }
 
 // ValidateToken validates a JWT token and returns the user
@@ -173,6 +235,21 @@ func (s *authService) ValidateToken(ctx context.Context, token string) (*models.
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
+	if tokenType, _ := (*claims)["typ"].(string); tokenType != tokenTypeAccess {
+		return nil, errors.New("invalid token: not an access token")
+	}
+	sessionID, _ := (*claims)["sid"].(string)
+	if sessionID == "" {
+		return nil, errors.New("invalid token: missing session")
+	}
+	revoked, err := s.sessionRevoked(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check session: %w", err)
+	}
+	if revoked {
+		return nil, ErrSessionRevoked
+	}
+
 	// Get user from database
 	userID, _ := (*claims)["user_id"].(string)
 	if userID == "" {
@@ -191,16 +268,43 @@ func (s *authService) ValidateToken(ctx context.Context, token string) (*models.
 	return user, nil
 }
 
-// RefreshToken generates new tokens using refresh token
+// RefreshToken rotates a session's refresh token. Each refresh token can be
+// exchanged once; presenting one that was already exchanged means it leaked,
+// so the whole session is revoked.
 func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*dto.ResAuthTokens, error) {
 	// Parse refresh token
 	claims, err := s.parseToken(refreshToken)
 	if err != nil {
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
+	if tokenType, _ := (*claims)["typ"].(string); tokenType != tokenTypeRefresh {
+		return nil, errors.New("invalid refresh token: not a refresh token")
+	}
+	sessionID, _ := (*claims)["sid"].(string)
+	tokenID, _ := (*claims)["jti"].(string)
+	if sessionID == "" || tokenID == "" {
+		return nil, errors.New("invalid refresh token: missing session")
+	}
 
-	// Get user from database
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
 	userID, _ := (*claims)["user_id"].(string)
+	if session.UserID != userID {
+		return nil, ErrSessionNotFound
+	}
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+	if session.RefreshTokenID != tokenID {
+		return nil, s.revokeReusedSession(ctx, session.ID)
+	}
+
+	// Get user from database
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
@@ -211,8 +315,30 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*d
 		return nil, errors.New("user account is not active")
 	}
 
+	// Rotate, unless a concurrent refresh already exchanged this token
+	nextTokenID, err := newTokenID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token id: %w", err)
+	}
+	now := time.Now()
+	session.RefreshTokenID = nextTokenID
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(refreshTokenTTL)
+	if client := clientInfoFromContext(ctx); client.UserAgent != "" || client.IPAddress != "" {
+		session.Device = describeDevice(client.UserAgent)
+		session.UserAgent = truncateUserAgent(client.UserAgent)
+		session.IPAddress = client.IPAddress
+	}
+	rotated, err := s.sessionRepo.Rotate(ctx, session, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	if !rotated {
+		return nil, s.revokeReusedSession(ctx, session.ID)
+	}
+
 	// Generate new tokens
-	tokens, err := s.generateTokens(user.ID)
+	tokens, err := s.generateTokens(session)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -220,6 +346,60 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (*d
 	return tokens, nil
 }
 
+// SessionIDFromToken returns the session a valid token belongs to, or ""
+func (s *authService) SessionIDFromToken(token string) string {
+	claims, err := s.parseToken(token)
+	if err != nil {
+		return ""
+	}
+	sessionID, _ := (*claims)["sid"].(string)
+	return sessionID
+}
+
+// ListSessions lists the user's active sessions, marking the current one
+func (s *authService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]*models.AuthSession, error) {
+	sessions, err := s.sessionRepo.ListActiveByUserID(ctx, userID, time.Now())
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs one of the user's sessions out
+func (s *authService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return ErrInternalServerError
+	}
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	return s.revokeSession(ctx, session.ID, models.SessionRevokedByUser)
+}
+
+// RevokeAllSessions signs the user out everywhere except exceptSessionID, if
+// set, and returns how many sessions were revoked
+func (s *authService) RevokeAllSessions(ctx context.Context, userID, exceptSessionID string) (int, error) {
+	return s.revokeAllSessions(ctx, userID, exceptSessionID, models.SessionRevokedByUser)
+}
+
+func (s *authService) revokeAllSessions(ctx context.Context, userID, exceptSessionID, reason string) (int, error) {
+	ids, err := s.sessionRepo.RevokeAllByUserID(ctx, userID, exceptSessionID, reason, time.Now())
+	if err != nil {
+		return 0, ErrInternalServerError
+	}
+	for _, id := range ids {
+		s.publishRevocation(ctx, id)
+	}
+	return len(ids), nil
+}
+
 // GoogleLogin authenticates a user via Google OAuth profile, creating or linking an account if needed.
 func (s *authService) GoogleLogin(ctx context.Context, providerAccountID, email string, emailVerified bool, name, avatar string) (*dto.ResAuthTokens, error) {
 	if providerAccountID == "" {
@@ -315,7 +495,7 @@ func (s *authService) GoogleLogin(ctx context.Context, providerAccountID, email
 	}
 
 	// Generate tokens
-	tokens, err := s.generateTokens(user.ID)
+	tokens, err := s.startSession(ctx, user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -323,16 +503,96 @@ func (s *authService) GoogleLogin(ctx context.Context, providerAccountID, email
 	return tokens, nil
 }
 
-// generateTokens generates access and refresh tokens
-func (s *authService) generateTokens(userID string) (*dto.ResAuthTokens, error) {
+// startSession records a session for the client in ctx and issues its first
+// pair of tokens
+func (s *authService) startSession(ctx context.Context, userID string) (*dto.ResAuthTokens, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	client := clientInfoFromContext(ctx)
+	session := &models.AuthSession{
+		UserID:         userID,
+		RefreshTokenID: tokenID,
+		Device:         describeDevice(client.UserAgent),
+		UserAgent:      truncateUserAgent(client.UserAgent),
+		IPAddress:      client.IPAddress,
+		LastUsedAt:     now,
+		ExpiresAt:      now.Add(refreshTokenTTL),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return s.generateTokens(session)
+}
+
+func (s *authService) revokeSession(ctx context.Context, sessionID, reason string) error {
+	if err := s.sessionRepo.Revoke(ctx, sessionID, reason, time.Now()); err != nil {
+		return ErrInternalServerError
+	}
+	s.publishRevocation(ctx, sessionID)
+	return nil
+}
+
+func (s *authService) revokeReusedSession(ctx context.Context, sessionID string) error {
+	if err := s.revokeSession(ctx, sessionID, models.SessionRevokedTokenReuse); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// publishRevocation adds a revoked session to the revocation list. The
+// database stays the source of truth, so failures are only logged.
+func (s *authService) publishRevocation(ctx context.Context, sessionID string) {
+	if s.revocations == nil {
+		return
+	}
+	if err := s.revocations.Revoke(ctx, sessionID, accessTokenTTL); err != nil {
+		fmt.Printf("Failed to publish session revocation: %v\n", err)
+		s.publishFailedUntil.Store(time.Now().Add(accessTokenTTL).UnixNano())
+	}
+}
+
+// sessionRevoked checks the revocation list, falling back to the database
+// when there is none or it cannot be reached. After a failed publish the list
+// may be missing a revocation, so sessions it clears are also checked in the
+// database until the access tokens that were issued by then have expired.
+func (s *authService) sessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	if s.revocations != nil {
+		revoked, err := s.revocations.IsRevoked(ctx, sessionID)
+		switch {
+		case err != nil:
+			fmt.Printf("Revocation list unavailable, checking database: %v\n", err)
+		case revoked || time.Now().UnixNano() >= s.publishFailedUntil.Load():
+			return revoked, nil
+		}
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	return session.RevokedAt != nil, nil
+}
+
+// generateTokens generates access and refresh tokens for a session
+func (s *authService) generateTokens(session *models.AuthSession) (*dto.ResAuthTokens, error) {
 	// Access token (short-lived)
-	accessToken, err := s.createToken(userID, time.Hour*1) // 1 hour
+	accessTokenID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.createToken(session.UserID, session.ID, accessTokenID, tokenTypeAccess, accessTokenTTL)
 	if err != nil {
 		return nil, err
 	}
 
-	// Refresh token (long-lived)
-	refreshToken, err := s.createToken(userID, time.Hour*24*7) // 7 days
+	// Refresh token (long-lived), the only one the session accepts next
+	refreshToken, err := s.createToken(session.UserID, session.ID, session.RefreshTokenID, tokenTypeRefresh, refreshTokenTTL)
 	if err != nil {
 		return nil, err
 	}
@@ -344,9 +604,12 @@ func (s *authService) generateTokens(userID string) (*dto.ResAuthTokens, error)
 }
 
 // createToken creates a JWT token
-func (s *authService) createToken(userID string, duration time.Duration) (string, error) {
+func (s *authService) createToken(userID, sessionID, tokenID, tokenType string, duration time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
+		"sid":     sessionID,
+		"jti":     tokenID,
+		"typ":     tokenType,
 		"exp":     time.Now().Add(duration).Unix(),
 		"iat":     time.Now().Unix(),
 	}
@@ -387,3 +650,61 @@ type JWTClaims struct {
 	UserID string `json:"user_id"`
 	jwt.RegisteredClaims
 }
+
+// newTokenID returns a random token identifier
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// describeDevice names the browser and operating system in a user agent,
+// e.g. "Chrome on macOS"
+func describeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	platform := ""
+	for _, p := range []struct{ token, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, p.token) {
+			platform = p.name
+			break
+		}
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
+
+// truncateUserAgent fits a user agent into its column
+func truncateUserAgent(userAgent string) string {
+	return truncateRunes(userAgent, 255)
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+type fakeSessionRepo struct {
+	sessions map[string]*models.AuthSession
+}
+
+func (f *fakeSessionRepo) Create(ctx context.Context, session *models.AuthSession) error {
+	session.ID = "session-" + session.RefreshTokenID[:6]
+	f.sessions[session.ID] = session
+	return nil
+}
+
+func (f *fakeSessionRepo) GetByID(ctx context.Context, id string) (*models.AuthSession, error) {
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (f *fakeSessionRepo) ListActiveByUserID(ctx context.Context, userID string, now time.Time) ([]*models.AuthSession, error) {
+	return nil, nil
+}
+
+func (f *fakeSessionRepo) Rotate(ctx context.Context, session *models.AuthSession, previousTokenID string) (bool, error) {
+	return false, errors.New("not used")
+}
+
+func (f *fakeSessionRepo) Revoke(ctx context.Context, id, reason string, at time.Time) error {
+	if session, ok := f.sessions[id]; ok && session.RevokedAt == nil {
+		session.RevokedAt = &at
+		session.RevokedReason = reason
+	}
+	return nil
+}
+
+func (f *fakeSessionRepo) RevokeAllByUserID(ctx context.Context, userID, exceptID, reason string, at time.Time) ([]string, error) {
+	var ids []string
+	for id, session := range f.sessions {
+		if session.UserID == userID && id != exceptID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+			session.RevokedReason = reason
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type fakeRevocationList struct {
+	revoked    map[string]bool
+	err        error
+	publishErr error
+}
+
+func (f *fakeRevocationList) Revoke(ctx context.Context, sessionID string, ttl time.Duration) error {
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.revoked[sessionID] = true
+	return nil
+}
+
+func (f *fakeRevocationList) IsRevoked(ctx context.Context, sessionID string) (bool, error) {
+	return f.revoked[sessionID], f.err
+}
+
+func newTestAuthService(sessions *fakeSessionRepo, revocations TokenRevocationList) *authService {
+	cfg := &config.Config{}
+	cfg.JWT.SecretKey = "test-secret-key"
+	return &authService{sessionRepo: sessions, revocations: revocations, config: cfg}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	sessions := &fakeSessionRepo{sessions: map[string]*models.AuthSession{}}
+	revocations := &fakeRevocationList{revoked: map[string]bool{}}
+	s := newTestAuthService(sessions, revocations)
+
+	tokens, err := s.startSession(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	sessionID := s.SessionIDFromToken(tokens.RefreshToken)
+
+	// The session has moved on to a newer refresh token, as after a refresh
+	sessions.sessions[sessionID].RefreshTokenID = "rotated"
+
+	if _, err := s.RefreshToken(context.Background(), tokens.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected reuse to be detected, got %v", err)
+	}
+	session := sessions.sessions[sessionID]
+	if session.RevokedAt == nil || session.RevokedReason != models.SessionRevokedTokenReuse {
+		t.Fatalf("expected the session to be revoked for token reuse, got %+v", session)
+	}
+	if !revocations.revoked[sessionID] {
+		t.Fatalf("expected the revocation to be published")
+	}
+	if _, err := s.RefreshToken(context.Background(), tokens.RefreshToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("expected revoked session to refuse refresh, got %v", err)
+	}
+}
+
+func TestAccessTokenIsNotARefreshToken(t *testing.T) {
+	sessions := &fakeSessionRepo{sessions: map[string]*models.AuthSession{}}
+	s := newTestAuthService(sessions, nil)
+
+	tokens, err := s.startSession(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	if _, err := s.RefreshToken(context.Background(), tokens.AccessToken); err == nil {
+		t.Fatalf("expected an access token to be refused for refresh")
+	}
+	if _, err := s.ValidateToken(context.Background(), tokens.RefreshToken); err == nil {
+		t.Fatalf("expected a refresh token to be refused as an access token")
+	}
+}
+
+func TestSessionRevokedFallsBackToDatabase(t *testing.T) {
+	revokedAt := time.Now()
+	sessions := &fakeSessionRepo{sessions: map[string]*models.AuthSession{
+		"active":  {},
+		"revoked": {RevokedAt: &revokedAt},
+	}}
+	unreachable := &fakeRevocationList{revoked: map[string]bool{}, err: errors.New("connection refused")}
+
+	for _, revocations := range []TokenRevocationList{nil, unreachable} {
+		s := newTestAuthService(sessions, revocations)
+		for id, want := range map[string]bool{"active": false, "revoked": true, "missing": true} {
+			got, err := s.sessionRevoked(context.Background(), id)
+			if err != nil {
+				t.Fatalf("session %s: %v", id, err)
+			}
+			if got != want {
+				t.Fatalf("session %s: expected revoked=%v, got %v", id, want, got)
+			}
+		}
+	}
+}
+
+func TestSessionRevokedChecksDatabaseAfterFailedPublish(t *testing.T) {
+	sessions := &fakeSessionRepo{sessions: map[string]*models.AuthSession{}}
+	revocations := &fakeRevocationList{revoked: map[string]bool{}}
+	s := newTestAuthService(sessions, revocations)
+	ctx := context.Background()
+
+	tokens, err := s.startSession(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	sessionID := s.SessionIDFromToken(tokens.AccessToken)
+
+	revocations.publishErr = errors.New("connection reset")
+	if err := s.revokeSession(ctx, sessionID, models.SessionRevokedLogout); err != nil {
+		t.Fatalf("revoke session: %v", err)
+	}
+	if revocations.revoked[sessionID] {
+		t.Fatalf("expected the publish to have failed")
+	}
+	if revoked, err := s.sessionRevoked(ctx, sessionID); err != nil || !revoked {
+		t.Fatalf("expected the database to catch the unpublished revocation, got %v (%v)", revoked, err)
+	}
+
+	s.publishFailedUntil.Store(time.Now().Add(-time.Second).UnixNano())
+	if revoked, _ := s.sessionRevoked(ctx, sessionID); revoked {
+		t.Fatalf("expected the revocation list to be trusted again once the window passed")
+	}
+}
+
+func TestDescribeDevice(t *testing.T) {
+	cases := map[string]string{
+		"":           "Unknown device",
+		"curl/8.4.0": "curl",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36":      "Chrome on macOS",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36 Edg/120": "Edge on Windows",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Safari/604.1":        "Safari on iOS",
+	}
+	for userAgent, want := range cases {
+		if got := describeDevice(userAgent); got != want {
+			t.Fatalf("describeDevice(%q) = %q, want %q", userAgent, got, want)
+		}
+	}
+}
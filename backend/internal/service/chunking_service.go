@@ -4,20 +4,59 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"gorm.io/gorm"
+
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/embeddings"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/utils"
 )
 
-// ChunkingService dispatches note save events to AI service for chunk generation.
+// ChunkingService queues note save events for chunk generation. Queued jobs
+// are processed by the ai-service, or in-process when an embedding provider
+// is configured.
 type ChunkingService interface {
 	DispatchNoteSaved(ctx context.Context, note *models.Note, event string)
+	// ProcessDueJobs runs one batch of due chunking jobs and returns how many were claimed
+	ProcessDueJobs(ctx context.Context) (int, error)
+	GetNoteChunkStatus(ctx context.Context, noteID string) (*NoteChunkStatus, error)
+	ListUnhealthyNotes(ctx context.Context, userID string, limit int) ([]*NoteChunkStatus, error)
+	ListJobs(ctx context.Context, userID string, status *models.ChunkJobStatus, limit int) ([]*models.ChunkJob, error)
+	// Requeue schedules notes for chunking right away, clearing dead jobs
+	Requeue(ctx context.Context, userID string, noteIDs []string) (int, error)
+}
+
+// Chunk health reported by the status endpoints
+const (
+	ChunkStateFresh   = "fresh"
+	ChunkStateStale   = "stale"
+	ChunkStateMissing = "missing"
+	ChunkStateQueued  = "queued"
+	ChunkStateDead    = "dead"
+)
+
+// NoteChunkStatus describes whether a note's stored chunks match its content
+type NoteChunkStatus struct {
+	NoteID        string     `json:"note_id"`
+	Title         string     `json:"title"`
+	State         string     `json:"state"`
+	ChunkCount    int64      `json:"chunk_count"`
+	NoteUpdatedAt time.Time  `json:"note_updated_at"`
+	LastChunkedAt *time.Time `json:"last_chunked_at,omitempty"`
+	JobStatus     string     `json:"job_status,omitempty"`
+	JobAttempts   int        `json:"job_attempts"`
+	JobLastError  string     `json:"job_last_error,omitempty"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+
+	userID string
 }
 
 type chunkingService struct {
@@ -26,6 +65,12 @@ type chunkingService struct {
 	serviceToken string
 	client       *http.Client
 	chunkRepo    repository.NoteChunkRepository
+	jobRepo      repository.ChunkJobRepository
+	noteRepo     repository.NoteRepository
+	embedder     embeddings.EmbeddingProvider
+	chunkSize    int
+	chunkOverlap int
+	queue        config.ChunkQueueConfig
 }
 
 type embedChunk struct {
@@ -40,7 +85,9 @@ type embedChunksResponse struct {
 	Chunks []embedChunk `json:"chunks"`
 }
 
-func NewChunkingService(cfg config.AIConfig, chunkRepo repository.NoteChunkRepository) ChunkingService {
+// NewChunkingService creates a chunking service. With a nil embedder notes are
+// sent to the ai-service's /notes/embed-chunks endpoint.
+func NewChunkingService(cfg config.AIConfig, chunkRepo repository.NoteChunkRepository, jobRepo repository.ChunkJobRepository, noteRepo repository.NoteRepository, embedder embeddings.EmbeddingProvider, embedCfg config.EmbeddingsConfig, queueCfg config.ChunkQueueConfig) ChunkingService {
 	timeout := time.Duration(cfg.RequestTimeoutMs) * time.Millisecond
 	if cfg.RequestTimeoutMs <= 0 {
 		timeout = 30 * time.Second
@@ -52,52 +99,291 @@ func NewChunkingService(cfg config.AIConfig, chunkRepo repository.NoteChunkRepos
 		serviceToken: cfg.ServiceToken,
 		client:       &http.Client{Timeout: timeout},
 		chunkRepo:    chunkRepo,
+		jobRepo:      jobRepo,
+		noteRepo:     noteRepo,
+		embedder:     embedder,
+		chunkSize:    embedCfg.ChunkSize,
+		chunkOverlap: embedCfg.ChunkOverlap,
+		queue:        queueCfg,
 	}
 }
 
+// DispatchNoteSaved queues the note for chunking after the debounce window.
+// Saving again within the window pushes the job back, so bursts of saves are
+// chunked once.
 func (s *chunkingService) DispatchNoteSaved(ctx context.Context, note *models.Note, event string) {
 	if note == nil {
 		log.Printf("[CHUNK][DEBUG] skip dispatch: note is nil")
 		return
 	}
-	if !s.enabled {
-		log.Printf("[CHUNK][DEBUG] skip dispatch: ai.enabled=false")
+	if err := s.canProcess(); err != nil {
+		log.Printf("[CHUNK][DEBUG] skip dispatch: %v", err)
 		return
 	}
-	if s.baseURL == "" {
-		log.Printf("[CHUNK][DEBUG] skip dispatch: ai.service_url is empty")
+
+	runAfter := time.Now().UTC().Add(time.Duration(s.queue.DebounceSeconds) * time.Second)
+	if err := s.jobRepo.Enqueue(ctx, note.ID, note.UserID, event, runAfter); err != nil {
+		log.Printf("[CHUNK][ERROR] enqueue failed note_id=%s: %v", note.ID, err)
+	}
+}
+
+// ProcessDueJobs runs one batch of due chunking jobs and returns how many were claimed
+func (s *chunkingService) ProcessDueJobs(ctx context.Context) (int, error) {
+	if err := s.canProcess(); err != nil {
+		return 0, nil
+	}
+
+	lease := time.Duration(s.queue.LeaseSeconds) * time.Second
+	jobs, err := s.jobRepo.ClaimDue(ctx, time.Now().UTC(), lease, s.queue.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("claim chunk jobs: %w", err)
+	}
+
+	for _, job := range jobs {
+		s.runJob(ctx, job)
+	}
+	return len(jobs), nil
+}
+
+func (s *chunkingService) runJob(ctx context.Context, job *models.ChunkJob) {
+	count, err := s.processNote(ctx, job.NoteID, job.Event)
+	if err == nil {
+		if err := s.jobRepo.Complete(ctx, job.ID, job.Generation, count); err != nil {
+			log.Printf("[CHUNK][ERROR] failed to complete job note_id=%s: %v", job.NoteID, err)
+		}
 		return
 	}
 
+	retryAt := s.nextRetry(job.Attempts)
+	if retryAt == nil {
+		log.Printf("[CHUNK][ERROR] dead-lettering job note_id=%s after %d attempts: %v", job.NoteID, job.Attempts, err)
+	} else {
+		log.Printf("[CHUNK][WARN] job failed note_id=%s attempt=%d retry_at=%s: %v", job.NoteID, job.Attempts, retryAt.Format(time.RFC3339), err)
+	}
+	if err := s.jobRepo.Fail(ctx, job.ID, job.Generation, err.Error(), retryAt); err != nil {
+		log.Printf("[CHUNK][ERROR] failed to record job failure note_id=%s: %v", job.NoteID, err)
+	}
+}
+
+// nextRetry returns when a job that failed its nth attempt should run again,
+// or nil once it has used up its attempts
+func (s *chunkingService) nextRetry(attempts int) *time.Time {
+	if attempts >= s.queue.MaxAttempts {
+		return nil
+	}
+	retryAt := time.Now().UTC().Add(chunkJobBackoff(attempts, s.queue.BackoffBaseSeconds, s.queue.BackoffMaxSeconds))
+	return &retryAt
+}
+
+// chunkJobBackoff doubles the base delay per failed attempt, up to max
+func chunkJobBackoff(attempts, baseSeconds, maxSeconds int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := time.Duration(baseSeconds) * time.Second
+	limit := time.Duration(maxSeconds) * time.Second
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// processNote chunks the note's current content and returns the chunk count.
+// A note that no longer exists has nothing to chunk.
+func (s *chunkingService) processNote(ctx context.Context, noteID, event string) (int, error) {
+	note, err := s.noteRepo.GetByID(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("load note: %w", err)
+	}
+
+	if s.embedder != nil {
+		return s.embedInProcess(ctx, note.ID, note.UserID, note.Title, note.Content)
+	}
+	if s.chunkRepo == nil {
+		return 0, fmt.Errorf("chunk repository is nil")
+	}
+
+	// The ai-service skips embedding chunks whose text is already stored
+	known, err := s.chunkRepo.ListChunkHashes(ctx, note.ID)
+	if err != nil {
+		return 0, fmt.Errorf("load chunk hashes: %w", err)
+	}
+	knownHashes := make([]string, 0, len(known))
+	for hash := range known {
+		knownHashes = append(knownHashes, hash)
+	}
+
 	payload := map[string]any{
-		"note_id":      note.ID,
-		"user_id":      note.UserID,
-		"title":        note.Title,
-		"content":      note.Content,
-		"status":       string(note.Status),
-		"content_type": note.ContentType,
-		"updated_at":   note.UpdatedAt.Format(time.RFC3339Nano),
-		"event":        event,
+		"note_id":            note.ID,
+		"user_id":            note.UserID,
+		"title":              note.Title,
+		"content":            note.Content,
+		"status":             string(note.Status),
+		"content_type":       note.ContentType,
+		"updated_at":         note.UpdatedAt.Format(time.RFC3339Nano),
+		"event":              event,
+		"known_chunk_hashes": knownHashes,
 	}
+	return s.send(ctx, payload)
+}
 
-	go s.send(payload)
+func (s *chunkingService) canProcess() error {
+	if s.jobRepo == nil || s.noteRepo == nil {
+		return fmt.Errorf("chunk job queue is not configured")
+	}
+	if s.embedder != nil {
+		return nil
+	}
+	if !s.enabled {
+		return fmt.Errorf("ai.enabled=false")
+	}
+	if s.baseURL == "" {
+		return fmt.Errorf("ai.service_url is empty")
+	}
+	return nil
+}
+
+// GetNoteChunkStatus reports whether a note's chunks are up to date
+func (s *chunkingService) GetNoteChunkStatus(ctx context.Context, noteID string) (*NoteChunkStatus, error) {
+	if s.jobRepo == nil {
+		return nil, ErrNotImplemented
+	}
+	state, err := s.jobRepo.GetNoteChunkState(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, err
+	}
+	return newNoteChunkStatus(state), nil
+}
+
+// ListUnhealthyNotes lists the user's notes with stale, missing, queued or dead chunks
+func (s *chunkingService) ListUnhealthyNotes(ctx context.Context, userID string, limit int) ([]*NoteChunkStatus, error) {
+	if s.jobRepo == nil {
+		return nil, ErrNotImplemented
+	}
+	states, err := s.jobRepo.ListUnhealthyNoteChunkStates(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*NoteChunkStatus, 0, len(states))
+	for _, state := range states {
+		statuses = append(statuses, newNoteChunkStatus(state))
+	}
+	return statuses, nil
+}
+
+// ListJobs lists the user's chunk jobs, optionally by status
+func (s *chunkingService) ListJobs(ctx context.Context, userID string, status *models.ChunkJobStatus, limit int) ([]*models.ChunkJob, error) {
+	if s.jobRepo == nil {
+		return nil, ErrNotImplemented
+	}
+	return s.jobRepo.ListByUserID(ctx, userID, status, limit)
+}
+
+// Requeue schedules the user's notes for chunking right away, clearing dead
+// jobs. With no note IDs every stale, missing or dead note is re-driven.
+func (s *chunkingService) Requeue(ctx context.Context, userID string, noteIDs []string) (int, error) {
+	if err := s.canProcess(); err != nil {
+		return 0, ErrNotImplemented
+	}
+
+	var targets []*NoteChunkStatus
+	if len(noteIDs) == 0 {
+		unhealthy, err := s.ListUnhealthyNotes(ctx, userID, 500)
+		if err != nil {
+			return 0, err
+		}
+		for _, status := range unhealthy {
+			// Queued jobs are already on their way
+			if status.State != ChunkStateQueued {
+				targets = append(targets, status)
+			}
+		}
+	} else {
+		for _, noteID := range noteIDs {
+			status, err := s.GetNoteChunkStatus(ctx, noteID)
+			if err != nil {
+				return 0, err
+			}
+			if status.userID != userID {
+				return 0, ErrNoteNotFound
+			}
+			targets = append(targets, status)
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, status := range targets {
+		if err := s.jobRepo.Enqueue(ctx, status.NoteID, userID, "requeue", now); err != nil {
+			return 0, err
+		}
+	}
+	return len(targets), nil
+}
+
+func newNoteChunkStatus(state *repository.NoteChunkState) *NoteChunkStatus {
+	status := &NoteChunkStatus{
+		NoteID:        state.NoteID,
+		Title:         state.Title,
+		State:         classifyChunkState(state),
+		ChunkCount:    state.ChunkCount,
+		NoteUpdatedAt: state.NoteUpdatedAt,
+		LastChunkedAt: state.LastChunkedAt,
+		JobAttempts:   state.JobAttempts,
+		JobLastError:  state.JobLastError,
+		userID:        state.UserID,
+	}
+	if state.JobStatus != nil {
+		status.JobStatus = string(*state.JobStatus)
+		if *state.JobStatus == models.ChunkJobStatusPending {
+			status.NextRunAt = state.JobRunAfter
+		}
+	}
+	return status
+}
+
+// classifyChunkState prefers the job's view (queued or dead) over comparing
+// chunk and note timestamps
+func classifyChunkState(state *repository.NoteChunkState) string {
+	if state.JobStatus != nil {
+		switch *state.JobStatus {
+		case models.ChunkJobStatusPending, models.ChunkJobStatusRunning:
+			return ChunkStateQueued
+		case models.ChunkJobStatusDead:
+			return ChunkStateDead
+		}
+	}
+	if state.ChunkCount == 0 || state.LastChunkedAt == nil {
+		return ChunkStateMissing
+	}
+	if state.LastChunkedAt.Before(state.NoteUpdatedAt) {
+		return ChunkStateStale
+	}
+	return ChunkStateFresh
 }
 
 // Hàm bắn note qua python api để chunk và embedding
-func (s *chunkingService) send(payload map[string]any) {
+func (s *chunkingService) send(ctx context.Context, payload map[string]any) (int, error) {
 	body, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("[CHUNK][ERROR] marshal payload failed: %v", err)
-		return
+		return 0, fmt.Errorf("marshal payload: %w", err)
 	}
 
 	url := s.baseURL + "/notes/embed-chunks"
 	log.Printf("[CHUNK][DEBUG] dispatch event to ai-service url=%s note_id=%v", url, payload["note_id"])
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		log.Printf("[CHUNK][ERROR] create request failed: %v", err)
-		return
+		return 0, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	if s.serviceToken != "" {
@@ -106,29 +392,21 @@ func (s *chunkingService) send(payload map[string]any) {
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		log.Printf("[CHUNK][ERROR] request failed: %v", err)
-		return
+		return 0, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		log.Printf("[CHUNK][ERROR] ai-service returned status=%d note_id=%v", resp.StatusCode, payload["note_id"])
-		return
+		return 0, fmt.Errorf("ai-service returned status=%d", resp.StatusCode)
 	}
 
 	var res embedChunksResponse
 	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		log.Printf("[CHUNK][ERROR] decode response failed: %v", err)
-		return
+		return 0, fmt.Errorf("decode response: %w", err)
 	}
 
 	log.Printf("[CHUNK][DEBUG] dispatch success status=%d note_id=%s chunks=%d", resp.StatusCode, res.NoteID, len(res.Chunks))
 
-	if s.chunkRepo == nil {
-		log.Printf("[CHUNK][WARN] chunk repository is nil, skip persisting embeddings")
-		return
-	}
-
 	// Khởi tạo mảng với KDL là repository.NoteChunkInput với súc chứa res.Chunks
 	inputs := make([]repository.NoteChunkInput, 0, len(res.Chunks))
 	// Đưa data từ output python qua input
@@ -140,13 +418,70 @@ func (s *chunkingService) send(payload map[string]any) {
 		})
 	}
 
-	ctx := context.Background()
 	if err := s.chunkRepo.ReplaceNoteChunks(ctx, res.NoteID, res.UserID, inputs); err != nil {
-		log.Printf("[CHUNK][ERROR] failed to persist note chunks: %v", err)
-		return
+		return 0, fmt.Errorf("persist note chunks: %w", err)
 	}
 
 	log.Printf("[CHUNK][DEBUG] persisted %d chunks for note_id=%s", len(inputs), res.NoteID)
+	return len(inputs), nil
+}
+
+// embedInProcess chunks a note and embeds the chunks with the local provider
+func (s *chunkingService) embedInProcess(ctx context.Context, noteID, userID, title, content string) (int, error) {
+	if s.chunkRepo == nil {
+		return 0, fmt.Errorf("chunk repository is nil")
+	}
+
+	chunks := utils.ChunkText(utils.PrepareNoteText(title, content), s.chunkSize, s.chunkOverlap)
+
+	// Only chunks whose text is not already stored need embedding
+	known, err := s.chunkRepo.ListChunkHashes(ctx, noteID)
+	if err != nil {
+		return 0, fmt.Errorf("load chunk hashes: %w", err)
+	}
+	toEmbed := make([]string, 0, len(chunks))
+	embedAt := make(map[string]int, len(chunks))
+	for _, text := range chunks {
+		hash := utils.ChunkContentHash(text)
+		if _, ok := known[hash]; ok {
+			continue
+		}
+		if _, ok := embedAt[hash]; ok {
+			continue
+		}
+		embedAt[hash] = len(toEmbed)
+		toEmbed = append(toEmbed, text)
+	}
+
+	var vectors [][]float32
+	if len(toEmbed) > 0 {
+		vectors, err = s.embedder.Embed(ctx, toEmbed)
+		if err != nil {
+			return 0, fmt.Errorf("embed chunks: %w", err)
+		}
+		if len(vectors) != len(toEmbed) {
+			return 0, fmt.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(toEmbed))
+		}
+	}
+
+	inputs := make([]repository.NoteChunkInput, 0, len(chunks))
+	for i, text := range chunks {
+		input := repository.NoteChunkInput{ChunkIndex: i, Text: text}
+		if at, ok := embedAt[utils.ChunkContentHash(text)]; ok {
+			input.TextEmbeddings = make([]float64, 0, len(vectors[at]))
+			for _, value := range vectors[at] {
+				input.TextEmbeddings = append(input.TextEmbeddings, float64(value))
+			}
+		}
+		inputs = append(inputs, input)
+	}
+
+	if err := s.chunkRepo.ReplaceNoteChunks(ctx, noteID, userID, inputs); err != nil {
+		return 0, fmt.Errorf("persist note chunks: %w", err)
+	}
+
+	log.Printf("[CHUNK][DEBUG] embedded %d of %d chunks in-process for note_id=%s", len(toEmbed), len(inputs), noteID)
+	return len(inputs), nil
 }
 
 func (s *chunkingService) String() string {
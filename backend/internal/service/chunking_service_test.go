@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+func TestChunkJobBackoffDoublesUpToMax(t *testing.T) {
+	cases := map[int]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		4: 80 * time.Second,
+		9: 120 * time.Second,
+	}
+	for attempts, want := range cases {
+		if got := chunkJobBackoff(attempts, 10, 120); got != want {
+			t.Fatalf("attempt %d: expected %s, got %s", attempts, want, got)
+		}
+	}
+}
+
+func TestClassifyChunkState(t *testing.T) {
+	updated := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	before := updated.Add(-time.Minute)
+	after := updated.Add(time.Minute)
+	dead := models.ChunkJobStatusDead
+	succeeded := models.ChunkJobStatusSucceeded
+
+	cases := []struct {
+		name  string
+		state repository.NoteChunkState
+		want  string
+	}{
+		{"no chunks", repository.NoteChunkState{NoteUpdatedAt: updated}, ChunkStateMissing},
+		{"chunks older than note", repository.NoteChunkState{NoteUpdatedAt: updated, ChunkCount: 2, LastChunkedAt: &before, JobStatus: &succeeded}, ChunkStateStale},
+		{"chunks newer than note", repository.NoteChunkState{NoteUpdatedAt: updated, ChunkCount: 2, LastChunkedAt: &after}, ChunkStateFresh},
+		{"dead job", repository.NoteChunkState{NoteUpdatedAt: updated, ChunkCount: 2, LastChunkedAt: &after, JobStatus: &dead}, ChunkStateDead},
+	}
+	for _, tc := range cases {
+		if got := classifyChunkState(&tc.state); got != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
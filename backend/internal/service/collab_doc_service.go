@@ -0,0 +1,205 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/utils"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/yjs"
+)
+
+// CollabDocService maintains the Yjs documents the collab server persists
+// for notes
+type CollabDocService interface {
+	// LoadDoc builds a note's collab document from its stored updates. The
+	// document is empty when the note has never been opened collaboratively.
+	LoadDoc(ctx context.Context, noteID string) (*yjs.Doc, error)
+	// CompactDoc merges a note's stored updates into one and refreshes the
+	// note's content snapshot from the result
+	CompactDoc(ctx context.Context, noteID string) (*CollabCompaction, error)
+	// CompactDue compacts one batch of documents with enough stored updates
+	// and returns how many were compacted
+	CompactDue(ctx context.Context) (int, error)
+}
+
+// CollabCompaction describes one compacted document
+type CollabCompaction struct {
+	NoteID          string `json:"note_id"`
+	Updates         int    `json:"updates"`
+	BytesBefore     int    `json:"bytes_before"`
+	BytesAfter      int    `json:"bytes_after"`
+	SnapshotChanged bool   `json:"snapshot_changed"`
+}
+
+// collabDocService implements CollabDocService
+type collabDocService struct {
+	docRepo         repository.CollabDocRepository
+	noteRepo        repository.NoteRepository
+	chunkingService ChunkingService
+	revisions       noteRevisionRecorder
+	config          config.CollabConfig
+}
+
+// NewCollabDocService creates a new collab document service
+func NewCollabDocService(docRepo repository.CollabDocRepository, noteRepo repository.NoteRepository, revisionRepo repository.NoteRevisionRepository, chunkingService ChunkingService, cfg config.CollabConfig) CollabDocService {
+	return &collabDocService{
+		docRepo:         docRepo,
+		noteRepo:        noteRepo,
+		chunkingService: chunkingService,
+		revisions:       noteRevisionRecorder{repo: revisionRepo},
+		config:          cfg,
+	}
+}
+
+func (s *collabDocService) LoadDoc(ctx context.Context, noteID string) (*yjs.Doc, error) {
+	updates, err := s.docRepo.ListUpdates(ctx, noteID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	values := make([][]byte, 0, len(updates))
+	for _, update := range updates {
+		values = append(values, update.Value)
+	}
+	doc, err := yjs.LoadDoc(values...)
+	if err != nil {
+		return nil, fmt.Errorf("decode collab document %s: %w", noteID, err)
+	}
+	return doc, nil
+}
+
+// CompactDoc refuses documents with structs waiting on missing updates:
+// re-encoding them would drop those structs for good.
+func (s *collabDocService) CompactDoc(ctx context.Context, noteID string) (*CollabCompaction, error) {
+	updates, err := s.docRepo.ListUpdates(ctx, noteID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	result := &CollabCompaction{NoteID: noteID, Updates: len(updates)}
+	ids := make([]int64, 0, len(updates))
+	values := make([][]byte, 0, len(updates))
+	for _, update := range updates {
+		ids = append(ids, update.ID)
+		values = append(values, update.Value)
+		result.BytesBefore += len(update.Value)
+	}
+
+	doc, err := yjs.LoadDoc(values...)
+	if err != nil {
+		return nil, fmt.Errorf("decode collab document %s: %w", noteID, err)
+	}
+	if doc.HasPending() {
+		return nil, ErrCollabDocIncomplete
+	}
+
+	if len(updates) > 1 {
+		doc.Compact()
+		merged := doc.EncodeStateAsUpdate()
+		if err := s.docRepo.ReplaceUpdates(ctx, noteID, ids, merged); err != nil {
+			return nil, ErrInternalServerError
+		}
+		result.BytesAfter = len(merged)
+	} else {
+		result.BytesAfter = result.BytesBefore
+	}
+
+	if result.SnapshotChanged, err = s.refreshSnapshot(ctx, noteID, doc); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// refreshSnapshot stores the document's content on the note when it differs
+// from what the note holds, as a new version with its own revision.
+// Documents with an empty fragment are left alone so a session that never
+// loaded cannot blank the note. A note saved meanwhile keeps its content;
+// the next compaction retries.
+func (s *collabDocService) refreshSnapshot(ctx context.Context, noteID string, doc *yjs.Doc) (bool, error) {
+	pmDoc := doc.ProseMirrorJSON(yjs.DefaultFragment)
+	if content, _ := pmDoc["content"].([]interface{}); len(content) == 0 {
+		return false, nil
+	}
+
+	note, err := s.noteRepo.GetByID(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Not a note document, or the note was deleted meanwhile
+			return false, nil
+		}
+		return false, ErrInternalServerError
+	}
+
+	tiptapContent, err := json.Marshal(pmDoc)
+	if err != nil {
+		return false, fmt.Errorf("encode snapshot of %s: %w", noteID, err)
+	}
+	if sameTiptapJSON(note.TiptapContent, tiptapContent) {
+		return false, nil
+	}
+
+	before := snapshotNoteContent(note)
+	content := utils.TiptapJSONToHTML(pmDoc)
+	updated, err := s.noteRepo.UpdateCollabSnapshotWithVersion(ctx, noteID, content, string(tiptapContent), note.Version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return false, ErrVersionConflict
+		}
+		return false, ErrInternalServerError
+	}
+	note = updated
+
+	s.revisions.record(ctx, &before, note, nil)
+
+	if s.chunkingService != nil {
+		s.chunkingService.DispatchNoteSaved(ctx, note, "note.collab.snapshot")
+	}
+	return true, nil
+}
+
+// sameTiptapJSON compares stored Tiptap JSON with a freshly encoded document,
+// ignoring key order and whitespace
+func sameTiptapJSON(stored string, encoded []byte) bool {
+	var v interface{}
+	if err := json.Unmarshal([]byte(stored), &v); err != nil {
+		return false
+	}
+	normalized, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(normalized, encoded)
+}
+
+func (s *collabDocService) CompactDue(ctx context.Context) (int, error) {
+	docnames, err := s.docRepo.ListDocsNeedingCompaction(ctx, s.config.CompactionMinUpdates, s.config.CompactionBatchSize)
+	if err != nil {
+		return 0, ErrInternalServerError
+	}
+
+	compacted := 0
+	for _, docname := range docnames {
+		if ctx.Err() != nil {
+			break
+		}
+		result, err := s.CompactDoc(ctx, docname)
+		if err != nil {
+			log.Printf("[COLLAB][WARN] compaction skipped doc=%s: %v", docname, err)
+			continue
+		}
+		compacted++
+		log.Printf("[COLLAB][DEBUG] compacted doc=%s updates=%d bytes=%d->%d snapshot_changed=%t",
+			docname, result.Updates, result.BytesBefore, result.BytesAfter, result.SnapshotChanged)
+	}
+	return compacted, nil
+}
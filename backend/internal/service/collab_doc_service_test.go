@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/yjs"
+)
+
+type fakeCollabDocRepo struct {
+	repository.CollabDocRepository
+	updates []*models.YjsUpdate
+}
+
+func (f *fakeCollabDocRepo) ListUpdates(ctx context.Context, docname string) ([]*models.YjsUpdate, error) {
+	return f.updates, nil
+}
+
+type fakeCollabNoteRepo struct {
+	repository.NoteRepository
+	note *models.Note
+}
+
+func (f *fakeCollabNoteRepo) GetByID(ctx context.Context, id string) (*models.Note, error) {
+	if f.note == nil || f.note.ID != id {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *f.note
+	return &copied, nil
+}
+
+func (f *fakeCollabNoteRepo) UpdateCollabSnapshotWithVersion(ctx context.Context, id string, content, tiptapContent string, expectedVersion int) (*models.Note, error) {
+	if f.note.Version != expectedVersion {
+		return nil, repository.ErrVersionConflict
+	}
+	f.note.Content = content
+	f.note.TiptapContent = tiptapContent
+	f.note.Version++
+	copied := *f.note
+	return &copied, nil
+}
+
+type fakeCollabRevisionRepo struct {
+	repository.NoteRevisionRepository
+	created []*models.NoteRevision
+}
+
+func (f *fakeCollabRevisionRepo) GetLatest(ctx context.Context, noteID string) (*models.NoteRevision, error) {
+	if len(f.created) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.created[len(f.created)-1], nil
+}
+
+func (f *fakeCollabRevisionRepo) Create(ctx context.Context, revision *models.NoteRevision) error {
+	f.created = append(f.created, revision)
+	return nil
+}
+
+func collabTestNote() *models.Note {
+	note := &models.Note{Content: "<p>old</p>", TiptapContent: `{"type":"doc"}`, Version: 3}
+	note.ID = "note-1"
+	return note
+}
+
+func collabParagraphUpdate(t *testing.T, text string) []byte {
+	t.Helper()
+	update, err := yjs.NewDoc().ReplaceFragment(yjs.DefaultFragment, map[string]interface{}{
+		"type": "doc",
+		"content": []interface{}{
+			map[string]interface{}{
+				"type":    "paragraph",
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": text}},
+			},
+		},
+	}, 7)
+	if err != nil {
+		t.Fatalf("build update: %v", err)
+	}
+	return update
+}
+
+func TestCompactDocSnapshotsAsNewRevision(t *testing.T) {
+	docs := &fakeCollabDocRepo{updates: []*models.YjsUpdate{{ID: 1, Docname: "note-1", Value: collabParagraphUpdate(t, "from collab")}}}
+	notes := &fakeCollabNoteRepo{note: collabTestNote()}
+	revisions := &fakeCollabRevisionRepo{}
+	svc := NewCollabDocService(docs, notes, revisions, nil, config.CollabConfig{})
+
+	result, err := svc.CompactDoc(context.Background(), "note-1")
+	if err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if !result.SnapshotChanged {
+		t.Fatal("expected the snapshot to change")
+	}
+	if notes.note.Version != 4 || notes.note.Content != "<p>from collab</p>" {
+		t.Fatalf("expected version 4 with the collab content, got %d %q", notes.note.Version, notes.note.Content)
+	}
+	if len(revisions.created) != 2 {
+		t.Fatalf("expected a baseline and a snapshot revision, got %d", len(revisions.created))
+	}
+	if got := revisions.created[1]; got.Version != 4 || got.Source != models.NoteRevisionSourceSystem {
+		t.Fatalf("expected a system revision at version 4, got %d %s", got.Version, got.Source)
+	}
+}
+
+func TestCompactDocKeepsNoteSavedMeanwhile(t *testing.T) {
+	docs := &fakeCollabDocRepo{updates: []*models.YjsUpdate{{ID: 1, Docname: "note-1", Value: collabParagraphUpdate(t, "from collab")}}}
+	notes := &concurrentSaveNoteRepo{fakeCollabNoteRepo: fakeCollabNoteRepo{note: collabTestNote()}}
+	revisions := &fakeCollabRevisionRepo{}
+	svc := NewCollabDocService(docs, notes, revisions, nil, config.CollabConfig{})
+
+	if _, err := svc.CompactDoc(context.Background(), "note-1"); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected a version conflict, got %v", err)
+	}
+	if notes.note.Content != "<p>saved</p>" || len(revisions.created) != 0 {
+		t.Fatalf("expected the concurrent save to win, got %q with %d revisions", notes.note.Content, len(revisions.created))
+	}
+}
+
+// concurrentSaveNoteRepo saves the note between the read and the snapshot write
+type concurrentSaveNoteRepo struct {
+	fakeCollabNoteRepo
+}
+
+func (f *concurrentSaveNoteRepo) UpdateCollabSnapshotWithVersion(ctx context.Context, id string, content, tiptapContent string, expectedVersion int) (*models.Note, error) {
+	f.note.Content = "<p>saved</p>"
+	f.note.Version++
+	return f.fakeCollabNoteRepo.UpdateCollabSnapshotWithVersion(ctx, id, content, tiptapContent, expectedVersion)
+}
@@ -5,20 +5,47 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/domain"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/pubsub"
 	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
 	"github.com/gorilla/websocket"
 )
 
-// CollaborationServiceImpl implements CollaborationService
+// CollaborationServiceImpl implements CollaborationService. Each note is a
+// room: messages are published to the note's topic on the broker and every
+// instance delivers them to its own clients in that room.
 type CollaborationServiceImpl struct {
 	userRepo    repository.UserRepository
 	noteRepo    repository.NoteRepository
 	clientRepo  *domain.InMemoryClientRepository
 	userUseCase UserService
 	noteUseCase NoteService
+
+	broker      pubsub.Broker
+	instanceID  string
+	presenceTTL time.Duration
+
+	roomsMu sync.Mutex
+	rooms   map[string]pubsub.Subscription
+}
+
+// roomEnvelope carries a room message between instances. Except names the
+// sending connection on the origin instance, which should not get it back.
+type roomEnvelope struct {
+	Origin  string          `json:"origin"`
+	Except  string          `json:"except,omitempty"`
+	Message json.RawMessage `json:"message"`
+}
+
+func noteRoomTopic(noteID string) string {
+	return "note:" + noteID
 }
 
 // NewCollaborationService creates a new collaboration service
@@ -28,20 +55,42 @@ func NewCollaborationService(
 	clientRepo *domain.InMemoryClientRepository,
 	userService UserService,
 	noteService NoteService,
+	broker pubsub.Broker,
+	cfg config.CollabConfig,
 ) *CollaborationServiceImpl {
+	presenceTTL := time.Duration(cfg.PresenceTTLSeconds) * time.Second
+	if presenceTTL <= 0 {
+		presenceTTL = time.Minute
+	}
+
 	return &CollaborationServiceImpl{
 		userRepo:    userRepo,
 		noteRepo:    noteRepo,
 		clientRepo:  clientRepo,
 		userUseCase: userService,
 		noteUseCase: noteService,
+		broker:      broker,
+		instanceID:  uuid.NewString(),
+		presenceTTL: presenceTTL,
+		rooms:       make(map[string]pubsub.Subscription),
 	}
 }
 
+// PresenceTTL is how long a member stays present without a refresh
+func (s *CollaborationServiceImpl) PresenceTTL() time.Duration {
+	return s.presenceTTL
+}
+
 // HandleConnection handles a new WebSocket connection
 func (s *CollaborationServiceImpl) HandleConnection(ctx context.Context, client *domain.Client) error {
-	// Add client to repository
+	// Add client to repository and its note's room
 	s.clientRepo.Add(client)
+	if err := s.joinRoom(ctx, client.NoteID); err != nil {
+		return err
+	}
+	if err := s.setPresence(ctx, client); err != nil {
+		return err
+	}
 
 	// Get current document
 	doc, err := s.noteUseCase.GetNoteByID(ctx, client.NoteID)
@@ -49,22 +98,16 @@ func (s *CollaborationServiceImpl) HandleConnection(ctx context.Context, client
 		return fmt.Errorf("failed to get document: %w", err)
 	}
 
-	// Get all users
-	users, _, err := s.userUseCase.ListUsers(ctx, repository.ListParams{})
+	// Users present in this note, on any instance
+	users, err := s.roomUsers(ctx, client.NoteID)
 	if err != nil {
 		return fmt.Errorf("failed to get users: %w", err)
 	}
 
-	// Convert to domain.User slice for init payload
-	domainUsers := make([]models.User, len(users))
-	for i, user := range users {
-		domainUsers[i] = *user
-	}
-
 	// Send init message
 	initPayload := domain.InitPayload{
 		Self:    *client.User,
-		Users:   domainUsers,
+		Users:   users,
 		Content: doc.Content,
 		Version: doc.Version,
 	}
@@ -74,7 +117,7 @@ func (s *CollaborationServiceImpl) HandleConnection(ctx context.Context, client
 		Payload: s.mustMarshal(initPayload),
 	}
 
-	if err := s.writeJSON(client.Conn, initMsg); err != nil {
+	if err := s.writeJSON(client, initMsg); err != nil {
 		return fmt.Errorf("failed to send init message: %w", err)
 	}
 
@@ -84,32 +127,160 @@ func (s *CollaborationServiceImpl) HandleConnection(ctx context.Context, client
 		Payload: s.mustMarshal(client.User),
 	}
 
-	return s.BroadcastMessage(&userJoinedMsg, client.Conn)
+	return s.BroadcastMessage(ctx, client.NoteID, &userJoinedMsg, client)
 }
 
-// BroadcastMessage broadcasts a message to all clients except the specified one
-func (s *CollaborationServiceImpl) BroadcastMessage(message *domain.Message, except *websocket.Conn) error {
+// HandleDisconnect removes a client from its room and tells the room it left,
+// unless the same user is still present through another connection
+func (s *CollaborationServiceImpl) HandleDisconnect(ctx context.Context, client *domain.Client) {
+	s.clientRepo.Remove(client.Conn)
+	defer s.leaveRoom(client.NoteID)
+
+	topic := noteRoomTopic(client.NoteID)
+	if err := s.broker.RemovePresence(ctx, topic, client.ID); err != nil {
+		log.Printf("failed to remove presence: %v", err)
+	}
+	if client.User == nil {
+		return
+	}
+
+	users, err := s.roomUsers(ctx, client.NoteID)
+	if err != nil {
+		log.Printf("failed to list room users: %v", err)
+	}
+	for _, user := range users {
+		if user.ID == client.User.ID {
+			return
+		}
+	}
+
+	userLeftMsg := domain.Message{
+		Type:    domain.MessageTypeUserLeft,
+		Payload: s.mustMarshal(domain.UserLeftPayload{ID: client.User.ID}),
+	}
+	if err := s.BroadcastMessage(ctx, client.NoteID, &userLeftMsg, client); err != nil {
+		log.Printf("Failed to broadcast user left message: %v", err)
+	}
+}
+
+// BroadcastMessage sends a message to every client in a note's room, on any
+// instance, except the given client (nil to include everyone)
+func (s *CollaborationServiceImpl) BroadcastMessage(ctx context.Context, noteID string, message *domain.Message, except *domain.Client) error {
 	data, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	clients := s.clientRepo.GetAll()
-	for _, client := range clients {
-		if client.Conn == except {
+	envelope := roomEnvelope{Origin: s.instanceID, Message: data}
+	if except != nil {
+		envelope.Except = except.ID
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal room message: %w", err)
+	}
+
+	return s.broker.Publish(ctx, noteRoomTopic(noteID), payload)
+}
+
+// RefreshPresence keeps this instance's clients present in their rooms. It
+// must run more often than the presence TTL.
+func (s *CollaborationServiceImpl) RefreshPresence(ctx context.Context) {
+	for _, client := range s.clientRepo.GetAll() {
+		if err := s.setPresence(ctx, client); err != nil {
+			log.Printf("failed to refresh presence: %v", err)
+		}
+	}
+}
+
+// joinRoom subscribes this instance to a note's room if it is not already
+func (s *CollaborationServiceImpl) joinRoom(ctx context.Context, noteID string) error {
+	s.roomsMu.Lock()
+	defer s.roomsMu.Unlock()
+	if _, ok := s.rooms[noteID]; ok {
+		return nil
+	}
+
+	sub, err := s.broker.Subscribe(ctx, noteRoomTopic(noteID))
+	if err != nil {
+		return fmt.Errorf("failed to join room: %w", err)
+	}
+	s.rooms[noteID] = sub
+	go s.deliverRoom(noteID, sub)
+	return nil
+}
+
+// leaveRoom unsubscribes from a note's room once no local client is in it
+func (s *CollaborationServiceImpl) leaveRoom(noteID string) {
+	s.roomsMu.Lock()
+	defer s.roomsMu.Unlock()
+	sub, ok := s.rooms[noteID]
+	if !ok || len(s.clientRepo.GetByNoteID(noteID)) > 0 {
+		return
+	}
+	delete(s.rooms, noteID)
+	sub.Close()
+}
+
+// deliverRoom writes a room's messages to the local clients in it
+func (s *CollaborationServiceImpl) deliverRoom(noteID string, sub pubsub.Subscription) {
+	for payload := range sub.Messages() {
+		var envelope roomEnvelope
+		if err := json.Unmarshal(payload, &envelope); err != nil {
+			log.Printf("invalid room message: %v", err)
 			continue
 		}
 
-		if err := client.Conn.WriteMessage(websocket.TextMessage, data); err != nil {
-			log.Printf("broadcast error: %v", err)
-			client.Conn.Close()
-			s.clientRepo.Remove(client.Conn)
+		for _, client := range s.clientRepo.GetByNoteID(noteID) {
+			if envelope.Origin == s.instanceID && client.ID == envelope.Except {
+				continue
+			}
+			if err := client.Send(envelope.Message); err != nil {
+				// Closing ends the client's read loop, which disconnects it
+				log.Printf("broadcast error: %v", err)
+				client.Conn.Close()
+			}
 		}
 	}
+}
 
+func (s *CollaborationServiceImpl) setPresence(ctx context.Context, client *domain.Client) error {
+	if client.User == nil {
+		return nil
+	}
+	data, err := json.Marshal(client.User)
+	if err != nil {
+		return fmt.Errorf("failed to marshal presence: %w", err)
+	}
+	if err := s.broker.SetPresence(ctx, noteRoomTopic(client.NoteID), client.ID, data, s.presenceTTL); err != nil {
+		return fmt.Errorf("failed to set presence: %w", err)
+	}
 	return nil
 }
 
+// roomUsers lists the users present in a note, once per user
+func (s *CollaborationServiceImpl) roomUsers(ctx context.Context, noteID string) ([]models.User, error) {
+	entries, err := s.broker.ListPresence(ctx, noteRoomTopic(noteID))
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]models.User, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		var user models.User
+		if err := json.Unmarshal(entry, &user); err != nil {
+			continue
+		}
+		if _, ok := seen[user.ID]; ok {
+			continue
+		}
+		seen[user.ID] = struct{}{}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
 // HandleMessage handles incoming WebSocket messages
 func (s *CollaborationServiceImpl) HandleMessage(client *domain.Client, message *domain.Message) error {
 	switch message.Type {
@@ -137,6 +308,9 @@ func (s *CollaborationServiceImpl) handleJoin(client *domain.Client, payload jso
 
 	if joinPayload.Name != "" {
 		client.User.Name = joinPayload.Name
+	}
+	// Public editors are anonymous guests with no user row to rename
+	if joinPayload.Name != "" && client.Role != domain.CollabRolePublicEdit {
 		_, err := s.userUseCase.UpdateUser(ctx, client.User.ID, UpdateUserRequest{
 			Name: joinPayload.Name,
 		})
@@ -145,13 +319,17 @@ func (s *CollaborationServiceImpl) handleJoin(client *domain.Client, payload jso
 		}
 	}
 
+	if err := s.setPresence(ctx, client); err != nil {
+		return err
+	}
+
 	// Broadcast user updated message
 	userUpdatedMsg := domain.Message{
 		Type:    domain.MessageTypeUserUpdated,
 		Payload: s.mustMarshal(client.User),
 	}
 
-	return s.BroadcastMessage(&userUpdatedMsg, client.Conn)
+	return s.BroadcastMessage(ctx, client.NoteID, &userUpdatedMsg, client)
 }
 
 // handleCursor handles cursor update messages
@@ -179,18 +357,35 @@ func (s *CollaborationServiceImpl) handleCursor(client *domain.Client, payload j
 		}),
 	}
 
-	return s.BroadcastMessage(&cursorUpdateMsg, client.Conn)
+	return s.BroadcastMessage(context.Background(), client.NoteID, &cursorUpdateMsg, client)
 }
 
 // handleDocUpdate handles document update messages
 func (s *CollaborationServiceImpl) handleDocUpdate(client *domain.Client, payload json.RawMessage) error {
+	if !client.Role.CanEdit() {
+		return s.writeJSON(client, domain.Message{
+			Type: domain.MessageTypeError,
+			Payload: s.mustMarshal(domain.ErrorPayload{
+				Code:    "read_only",
+				Message: "you do not have permission to edit this note",
+			}),
+		})
+	}
+
 	var docUpdatePayload domain.DocUpdatePayload
 	if err := json.Unmarshal(payload, &docUpdatePayload); err != nil {
 		return fmt.Errorf("failed to unmarshal doc update payload: %w", err)
 	}
 
+	ctx := context.Background()
+	if client.Role == domain.CollabRolePublicEdit {
+		ctx = WithRevisionActor(ctx, RevisionActor{Source: models.NoteRevisionSourcePublicEdit})
+	} else if client.User != nil {
+		ctx = WithRevisionActor(ctx, RevisionActor{Source: models.NoteRevisionSourceUser, AuthorID: client.User.ID})
+	}
+
 	// Try to update the document
-	updatedDoc, err := s.noteUseCase.UpdateNote(context.Background(), client.NoteID, UpdateNoteRequest{
+	updatedDoc, err := s.noteUseCase.UpdateNote(ctx, client.NoteID, UpdateNoteRequest{
 		Content: docUpdatePayload.Content,
 	})
 	if err != nil {
@@ -208,10 +403,10 @@ func (s *CollaborationServiceImpl) handleDocUpdate(client *domain.Client, payloa
 			}),
 		}
 
-		return s.writeJSON(client.Conn, docStateMsg)
+		return s.writeJSON(client, docStateMsg)
 	}
 
-	// Broadcast document state to all clients
+	// Broadcast document state to everyone editing the note
 	docStateMsg := domain.Message{
 		Type: domain.MessageTypeDocState,
 		Payload: s.mustMarshal(domain.DocUpdatePayload{
@@ -220,7 +415,7 @@ func (s *CollaborationServiceImpl) handleDocUpdate(client *domain.Client, payloa
 		}),
 	}
 
-	return s.BroadcastMessage(&docStateMsg, nil)
+	return s.BroadcastMessage(ctx, client.NoteID, &docStateMsg, nil)
 }
 
 // handlePing handles ping messages
@@ -229,16 +424,16 @@ func (s *CollaborationServiceImpl) handlePing(client *domain.Client) error {
 		Type: domain.MessageTypePong,
 	}
 
-	return s.writeJSON(client.Conn, pongMsg)
+	return s.writeJSON(client, pongMsg)
 }
 
-// writeJSON writes a JSON message to a WebSocket connection
-func (s *CollaborationServiceImpl) writeJSON(conn *websocket.Conn, v interface{}) error {
+// writeJSON writes a JSON message to a single client
+func (s *CollaborationServiceImpl) writeJSON(client *domain.Client, v interface{}) error {
 	data, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
-	return conn.WriteMessage(websocket.TextMessage, data)
+	return client.Send(data)
 }
 
 // RemoveClient removes a client from the repository
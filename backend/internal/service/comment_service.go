@@ -16,7 +16,7 @@ type CommentService interface {
 	GetCommentByID(ctx context.Context, id string) (*CommentResponse, error)
 	UpdateComment(ctx context.Context, id string, userID string, req UpdateCommentRequest) (*CommentResponse, error)
 	DeleteComment(ctx context.Context, id string, userID string) error
-	ListCommentsByNoteID(ctx context.Context, noteID string) ([]*CommentResponse, error)
+	ListCommentsByNoteID(ctx context.Context, noteID string, userID string) ([]*CommentResponse, error)
 }
 
 // commentService implements CommentService
@@ -24,6 +24,7 @@ type commentService struct {
 	repo     repository.CommentRepository
 	noteRepo repository.NoteRepository
 	userRepo repository.UserRepository
+	shares   NoteShareService
 }
 
 // CreateCommentRequest represents the request to create a comment
@@ -54,11 +55,12 @@ type CommentResponse struct {
 }
 
 // NewCommentService creates a new comment service
-func NewCommentService(repo repository.CommentRepository, noteRepo repository.NoteRepository, userRepo repository.UserRepository) CommentService {
+func NewCommentService(repo repository.CommentRepository, noteRepo repository.NoteRepository, userRepo repository.UserRepository, shares NoteShareService) CommentService {
 	return &commentService{
 		repo:     repo,
 		noteRepo: noteRepo,
 		userRepo: userRepo,
+		shares:   shares,
 	}
 }
 
@@ -82,6 +84,15 @@ func (s *commentService) CreateComment(ctx context.Context, req CreateCommentReq
 		return nil, err
 	}
 
+	// Owners, commenters and editors may comment
+	role, err := s.noteRole(ctx, note, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanComment() {
+		return nil, ErrCommentForbidden
+	}
+
 	// Create comment
 	comment := &models.Comment{
 		NoteID:   note.ID,
@@ -168,9 +179,9 @@ func (s *commentService) DeleteComment(ctx context.Context, id string, userID st
 }
 
 // ListCommentsByNoteID retrieves all comments for a note
-func (s *commentService) ListCommentsByNoteID(ctx context.Context, noteID string) ([]*CommentResponse, error) {
+func (s *commentService) ListCommentsByNoteID(ctx context.Context, noteID string, userID string) ([]*CommentResponse, error) {
 	// Verify note exists
-	_, err := s.noteRepo.GetByID(ctx, noteID)
+	note, err := s.noteRepo.GetByID(ctx, noteID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("note not found")
@@ -178,6 +189,15 @@ func (s *commentService) ListCommentsByNoteID(ctx context.Context, noteID string
 		return nil, err
 	}
 
+	// Anyone who can read the note can read its comments
+	role, err := s.noteRole(ctx, note, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanView() {
+		return nil, ErrNoteForbidden
+	}
+
 	comments, err := s.repo.ListByNoteID(ctx, noteID)
 	if err != nil {
 		return nil, err
@@ -191,6 +211,26 @@ func (s *commentService) ListCommentsByNoteID(ctx context.Context, noteID string
 	return responses, nil
 }
 
+// noteRole resolves the user's role on the note, falling back to
+// owner-only access when sharing is not wired in. A share link opened for
+// this request can add to it.
+func (s *commentService) noteRole(ctx context.Context, note *models.Note, userID string) (models.NoteRole, error) {
+	var role models.NoteRole
+	if s.shares != nil {
+		var err error
+		if role, err = s.shares.NoteRole(ctx, note, userID); err != nil {
+			return "", err
+		}
+	} else if note.UserID == userID {
+		role = models.NoteRoleOwner
+	}
+
+	if grant := linkGrantFromContext(ctx, note.ID); !role.Includes(grant) {
+		role = grant
+	}
+	return role, nil
+}
+
 // toResponse converts a comment model to a response
 func (s *commentService) toResponse(comment *models.Comment) *CommentResponse {
 	replies := make([]CommentResponse, len(comment.Replies))
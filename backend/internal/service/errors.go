@@ -15,13 +15,66 @@ var (
 	ErrNoteNotFound    = errors.New("note not found")
 	ErrVersionConflict = errors.New("version conflict")
 
+	// Note share errors
+	ErrNoteShareNotFound = errors.New("note share not found")
+	ErrNoteForbidden     = errors.New("you don't have access to this note")
+
+	// Comment errors
+	ErrCommentForbidden = errors.New("you don't have permission to comment on this note")
+
+	// Note revision errors
+	ErrNoteRevisionNotFound = errors.New("note revision not found")
+
+	// AI edit proposal errors
+	ErrEditProposalNotFound = errors.New("edit proposal not found")
+	ErrEditProposalResolved = errors.New("edit proposal is already resolved")
+	ErrEditProposalConflict = errors.New("edit proposal conflicts with newer changes")
+
+	// AI run errors
+	ErrAIRunNotFound   = errors.New("ai run not found")
+	ErrAIQuotaExceeded = errors.New("ai usage quota exceeded")
+
+	// Collab document errors
+	ErrCollabDocIncomplete = errors.New("collab document is missing updates")
+
 	// Folder errors
 	ErrFolderNotFound       = errors.New("folder not found")
 	ErrInvalidFolderReorder = errors.New("invalid folder reorder payload")
 
+	// Tag errors
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrTagAlreadyExists = errors.New("tag already exists")
+
+	// Trash errors
+	ErrTrashItemNotFound = errors.New("trash item not found")
+
 	// Template errors
 	ErrTemplateNotFound = errors.New("template not found")
 
+	// Workspace errors
+	ErrWorkspaceNotFound       = errors.New("workspace not found")
+	ErrWorkspaceForbidden      = errors.New("you don't have permission to manage this workspace")
+	ErrWorkspaceNotEmpty       = errors.New("workspace still has notes or folders")
+	ErrWorkspaceMemberNotFound = errors.New("workspace member not found")
+
+	// Session errors
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrSessionRevoked     = errors.New("session has been revoked")
+	ErrRefreshTokenReused = errors.New("refresh token was already used, the session has been revoked")
+
+	// Account email and password errors
+	ErrAccountTokenInvalid    = errors.New("link is invalid, has expired or was already used")
+	ErrEmailAlreadyVerified   = errors.New("email is already verified")
+	ErrCurrentPasswordInvalid = errors.New("current password is incorrect")
+	ErrPasswordNotSet         = errors.New("account has no password yet, use password reset to set one")
+
+	// Share link errors
+	ErrShareLinkNotFound         = errors.New("share link not found")
+	ErrShareLinkUnavailable      = errors.New("share link has expired or was revoked")
+	ErrShareLinkPasswordRequired = errors.New("share link requires a password")
+	ErrShareLinkPasswordInvalid  = errors.New("share link password is incorrect")
+	ErrShareLinkCommentsDisabled = errors.New("comments are disabled for this share link")
+
 	// General errors
 	ErrInternalServerError = errors.New("internal server error")
 	ErrNotImplemented      = errors.New("not implemented")
@@ -33,11 +33,12 @@ type folderService struct {
 
 // CreateFolderRequest represents the request to create a folder
 type CreateFolderRequest struct {
-	Name     string  `json:"name" validate:"required,min=1,max=100"`
-	IsPublic bool    `json:"is_public"`
-	UserID   string  `json:"user_id" validate:"required"`
-	ParentID *string `json:"parent_id,omitempty"`
-	Order    *int    `json:"order,omitempty"`
+	Name        string  `json:"name" validate:"required,min=1,max=100"`
+	IsPublic    bool    `json:"is_public"`
+	UserID      string  `json:"user_id" validate:"required"`
+	ParentID    *string `json:"parent_id,omitempty"`
+	Order       *int    `json:"order,omitempty"`
+	WorkspaceID *string `json:"workspace_id,omitempty"`
 }
 
 // UpdateFolderRequest represents the request to update a folder
@@ -125,11 +126,12 @@ func (s *folderService) CreateFolder(ctx context.Context, req CreateFolderReques
 	}
 
 	folder := &models.Folder{
-		Name:      req.Name,
-		IsPublic:  req.IsPublic,
-		UserID:    req.UserID,
-		ParentID:  targetParent,
-		SortOrder: maxOrder + 1,
+		Name:        req.Name,
+		IsPublic:    req.IsPublic,
+		UserID:      req.UserID,
+		ParentID:    targetParent,
+		SortOrder:   maxOrder + 1,
+		WorkspaceID: req.WorkspaceID,
 	}
 
 	if err := s.repo.Create(ctx, folder); err != nil {
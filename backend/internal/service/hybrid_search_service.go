@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/embeddings"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+// HybridSearchService fuses full-text and note chunk vector search, plus the
+// note-level vector store when one is configured, with reciprocal rank fusion.
+// It backs both note search and the rag.search tool.
+type HybridSearchService interface {
+	SearchService
+
+	// HybridSearch ranks notes for a query. The query is embedded with the
+	// configured provider; without one, a precomputed query embedding is used.
+	HybridSearch(ctx context.Context, req HybridSearchRequest) ([]*NoteSearchResult, int64, error)
+}
+
+// HybridSearchRequest is a note search with an optional query embedding.
+// MinVectorScore, when set, drops fused notes whose only match is a note
+// chunk less similar than it.
+type HybridSearchRequest struct {
+	NoteSearchRequest
+	QueryEmbedding []float64
+	MinVectorScore float64
+}
+
+// hybridSearchService implements HybridSearchService
+type hybridSearchService struct {
+	lexical   SearchService
+	chunkRepo repository.NoteChunkRepository
+	noteRepo  repository.NoteRepository
+	embedder  embeddings.EmbeddingProvider
+	semantic  SearchService
+	config    config.SearchConfig
+}
+
+// NewHybridSearchService creates a hybrid search service. embedder must be the
+// provider that embedded note_chunks; when chunks come from the ai-service it
+// is nil and the vector side only runs for requests carrying an embedding.
+// semantic is the optional vector store backed search service; it is kept
+// indexed through this service and fused as a third ranking.
+func NewHybridSearchService(
+	searchRepo repository.NoteSearchRepository,
+	chunkRepo repository.NoteChunkRepository,
+	noteRepo repository.NoteRepository,
+	embedder embeddings.EmbeddingProvider,
+	semantic SearchService,
+	cfg config.SearchConfig,
+) HybridSearchService {
+	return &hybridSearchService{
+		lexical:   NewLexicalSearchService(searchRepo),
+		chunkRepo: chunkRepo,
+		noteRepo:  noteRepo,
+		embedder:  embedder,
+		semantic:  semantic,
+		config:    cfg,
+	}
+}
+
+// IndexNote indexes a note in the vector store, if any. The search vector is
+// generated and chunks are written by the chunking service.
+func (s *hybridSearchService) IndexNote(ctx context.Context, note *models.Note) error {
+	if s.semantic == nil {
+		return nil
+	}
+	return s.semantic.IndexNote(ctx, note)
+}
+
+// SearchNotes performs hybrid search on a user's notes
+func (s *hybridSearchService) SearchNotes(ctx context.Context, query string, userID string, limit int) ([]*models.Note, int64, error) {
+	results, total, err := s.Search(ctx, NoteSearchRequest{
+		UserID: userID,
+		Query:  query,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return SearchNoteList(results), total, nil
+}
+
+// DeleteNoteIndex removes a note from the vector store, if any
+func (s *hybridSearchService) DeleteNoteIndex(ctx context.Context, noteID string) error {
+	if s.semantic == nil {
+		return nil
+	}
+	return s.semantic.DeleteNoteIndex(ctx, noteID)
+}
+
+// ReindexAllNotes reindexes a user's notes in the vector store, if any
+func (s *hybridSearchService) ReindexAllNotes(ctx context.Context, userID string) error {
+	if s.semantic == nil {
+		return nil
+	}
+	return s.semantic.ReindexAllNotes(ctx, userID)
+}
+
+// Search performs hybrid search, embedding the query when a provider is configured
+func (s *hybridSearchService) Search(ctx context.Context, req NoteSearchRequest) ([]*NoteSearchResult, int64, error) {
+	return s.HybridSearch(ctx, HybridSearchRequest{NoteSearchRequest: req})
+}
+
+// hybridCandidate collects what each ranker knows about one note
+type hybridCandidate struct {
+	noteID      string
+	score       float64
+	note        *models.Note
+	lexical     *NoteSearchResult
+	chunk       *repository.NoteChunkSearchResult
+	lexicalRank int
+}
+
+// HybridSearch ranks notes from both rankers by sum(1 / (k + rank))
+func (s *hybridSearchService) HybridSearch(ctx context.Context, req HybridSearchRequest) ([]*NoteSearchResult, int64, error) {
+	if req.Query == "" {
+		return nil, 0, fmt.Errorf("query cannot be empty")
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	pool := s.config.CandidatePool
+	if pool < req.Offset+limit {
+		pool = req.Offset + limit
+	}
+
+	lexicalReq := req.NoteSearchRequest
+	lexicalReq.Limit = pool
+	lexicalReq.Offset = 0
+	lexicalResults, lexicalTotal, err := s.lexical.Search(ctx, lexicalReq)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	chunks, err := s.vectorCandidates(ctx, req, pool)
+	if err != nil {
+		return nil, 0, ErrInternalServerError
+	}
+
+	semanticResults := s.semanticCandidates(ctx, req.NoteSearchRequest, pool)
+
+	candidates := fuseRankings(s.rrfK(), lexicalResults, chunks, semanticResults)
+	if req.MinVectorScore > 0 {
+		candidates = dropWeakVectorMatches(candidates, req.MinVectorScore)
+	}
+
+	// Lexical matches past the candidate pool were never fused but still count
+	total := int64(len(candidates)) + lexicalTotal - int64(len(lexicalResults))
+
+	if req.Offset >= len(candidates) {
+		return []*NoteSearchResult{}, total, nil
+	}
+	page := candidates[req.Offset:]
+	if len(page) > limit {
+		page = page[:limit]
+	}
+
+	lexicalChunks := s.lexicalChunks(ctx, page, req.Query)
+
+	results := make([]*NoteSearchResult, 0, len(page))
+	for _, candidate := range page {
+		result := &NoteSearchResult{Score: candidate.score}
+		if candidate.lexical != nil {
+			lexicalScore := candidate.lexical.Score
+			result.Note = candidate.lexical.Note
+			result.TitleHighlight = candidate.lexical.TitleHighlight
+			result.Snippet = candidate.lexical.Snippet
+			result.LexicalScore = &lexicalScore
+		} else if candidate.note != nil {
+			result.Note = candidate.note
+		} else {
+			note, err := s.noteRepo.GetByID(ctx, candidate.noteID)
+			if err != nil {
+				log.Printf("Warning: failed to fetch note %s: %v", candidate.noteID, err)
+				continue
+			}
+			result.Note = note
+		}
+
+		chunk := candidate.chunk
+		if chunk != nil {
+			vectorScore := chunk.Score
+			result.VectorScore = &vectorScore
+		} else if lexicalChunk, ok := lexicalChunks[candidate.noteID]; ok {
+			chunk = &lexicalChunk
+		}
+		if chunk != nil {
+			chunkIndex := chunk.ChunkIndex
+			result.ChunkIndex = &chunkIndex
+			result.ChunkText = chunk.Text
+		}
+
+		results = append(results, result)
+	}
+
+	return results, total, nil
+}
+
+// vectorCandidates returns the best chunk per note, most similar first
+func (s *hybridSearchService) vectorCandidates(ctx context.Context, req HybridSearchRequest, pool int) ([]repository.NoteChunkSearchResult, error) {
+	if s.chunkRepo == nil {
+		return nil, nil
+	}
+
+	// Chunks embedded in-process must be searched with the same provider, so
+	// it wins over an embedding computed elsewhere
+	embedding := req.QueryEmbedding
+	if s.embedder != nil {
+		vector, err := s.embedder.EmbedQuery(ctx, req.Query)
+		if err != nil {
+			// Fall back to full-text ranking alone
+			log.Printf("Warning: failed to embed search query: %v", err)
+			return nil, nil
+		}
+		embedding = make([]float64, 0, len(vector))
+		for _, value := range vector {
+			embedding = append(embedding, float64(value))
+		}
+	}
+	if len(embedding) == 0 {
+		return nil, nil
+	}
+
+	// Notes usually have several matching chunks; over-fetch so the pool
+	// still holds enough distinct notes
+	chunks, err := s.chunkRepo.SearchSimilarFiltered(ctx, repository.NoteChunkSearchParams{
+		UserID:         req.UserID,
+		WorkspaceID:    req.WorkspaceID,
+		QueryEmbedding: embedding,
+		TopK:           pool * 4,
+		MinScore:       s.config.MinVectorScore,
+		FolderID:       req.FolderID,
+		Status:         req.Status,
+		TagIDs:         req.TagIDs,
+		TagMatch:       req.TagMatch,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(chunks))
+	best := make([]repository.NoteChunkSearchResult, 0, pool)
+	for _, chunk := range chunks {
+		if _, dup := seen[chunk.NoteID]; dup {
+			continue
+		}
+		seen[chunk.NoteID] = struct{}{}
+		best = append(best, chunk)
+		if len(best) == pool {
+			break
+		}
+	}
+	return best, nil
+}
+
+// semanticCandidates ranks notes with the vector store, if any. Failures only
+// drop this ranking.
+func (s *hybridSearchService) semanticCandidates(ctx context.Context, req NoteSearchRequest, pool int) []*NoteSearchResult {
+	if s.semantic == nil {
+		return nil
+	}
+
+	req.Limit = pool
+	req.Offset = 0
+	results, _, err := s.semantic.Search(ctx, req)
+	if err != nil {
+		log.Printf("Warning: vector store search failed: %v", err)
+		return nil
+	}
+	return results
+}
+
+// lexicalChunks finds a chunk to show for notes only the full-text ranker matched
+func (s *hybridSearchService) lexicalChunks(ctx context.Context, page []*hybridCandidate, query string) map[string]repository.NoteChunkSearchResult {
+	byNote := make(map[string]repository.NoteChunkSearchResult)
+	if s.chunkRepo == nil {
+		return byNote
+	}
+
+	noteIDs := make([]string, 0, len(page))
+	for _, candidate := range page {
+		if candidate.chunk == nil {
+			noteIDs = append(noteIDs, candidate.noteID)
+		}
+	}
+	if len(noteIDs) == 0 {
+		return byNote
+	}
+
+	chunks, err := s.chunkRepo.BestLexicalChunks(ctx, noteIDs, query)
+	if err != nil {
+		log.Printf("Warning: failed to load matching chunks: %v", err)
+		return byNote
+	}
+	for _, chunk := range chunks {
+		byNote[chunk.NoteID] = chunk
+	}
+	return byNote
+}
+
+func (s *hybridSearchService) rrfK() int {
+	if s.config.RRFK <= 0 {
+		return 60
+	}
+	return s.config.RRFK
+}
+
+// fuseRankings merges the rankings with reciprocal rank fusion. Ties go to the
+// note ranked higher by full-text search, then to the note ID for stable pages.
+func fuseRankings(k int, lexical []*NoteSearchResult, chunks []repository.NoteChunkSearchResult, semantic []*NoteSearchResult) []*hybridCandidate {
+	byNote := make(map[string]*hybridCandidate, len(lexical)+len(chunks)+len(semantic))
+	candidates := make([]*hybridCandidate, 0, len(lexical)+len(chunks)+len(semantic))
+	candidate := func(noteID string) *hybridCandidate {
+		if c, ok := byNote[noteID]; ok {
+			return c
+		}
+		c := &hybridCandidate{noteID: noteID}
+		byNote[noteID] = c
+		candidates = append(candidates, c)
+		return c
+	}
+
+	for i, result := range lexical {
+		if result.Note == nil {
+			continue
+		}
+		c := candidate(result.Note.ID)
+		c.lexical = result
+		c.lexicalRank = i + 1
+		c.score += 1 / float64(k+i+1)
+	}
+	for i := range chunks {
+		c := candidate(chunks[i].NoteID)
+		c.chunk = &chunks[i]
+		c.score += 1 / float64(k+i+1)
+	}
+	for i, result := range semantic {
+		if result.Note == nil {
+			continue
+		}
+		c := candidate(result.Note.ID)
+		c.note = result.Note
+		c.score += 1 / float64(k+i+1)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if (a.lexicalRank == 0) != (b.lexicalRank == 0) {
+			return a.lexicalRank != 0
+		}
+		if a.lexicalRank != b.lexicalRank {
+			return a.lexicalRank < b.lexicalRank
+		}
+		return a.noteID < b.noteID
+	})
+	return candidates
+}
+
+// dropWeakVectorMatches removes fused notes found only through a chunk whose
+// similarity is below minScore. Fused scores are rank based, so the threshold
+// applies to the chunk similarity; the vector store only reports ranks, and
+// full-text matches have no similarity, so notes they found are kept.
+func dropWeakVectorMatches(candidates []*hybridCandidate, minScore float64) []*hybridCandidate {
+	kept := candidates[:0]
+	for _, c := range candidates {
+		if c.lexical == nil && c.note == nil && c.chunk != nil && c.chunk.Score < minScore {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
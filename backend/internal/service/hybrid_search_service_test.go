@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+func lexicalHit(id string) *NoteSearchResult {
+	note := &models.Note{}
+	note.ID = id
+	return &NoteSearchResult{Note: note}
+}
+
+func TestFuseRankingsPrefersNotesFoundByBothRankers(t *testing.T) {
+	lexical := []*NoteSearchResult{lexicalHit("a"), lexicalHit("b")}
+	chunks := []repository.NoteChunkSearchResult{
+		{NoteID: "c", ChunkIndex: 0},
+		{NoteID: "b", ChunkIndex: 3},
+	}
+
+	fused := fuseRankings(60, lexical, chunks, nil)
+
+	if len(fused) != 3 {
+		t.Fatalf("expected 3 fused notes, got %d", len(fused))
+	}
+	if fused[0].noteID != "b" {
+		t.Fatalf("expected note matched by both rankers first, got %q", fused[0].noteID)
+	}
+	if fused[0].chunk == nil || fused[0].chunk.ChunkIndex != 3 {
+		t.Fatal("expected fused note to keep its best vector chunk")
+	}
+	// a and c tie at rank 1 of one ranker each; the full-text hit wins the tie
+	if fused[1].noteID != "a" || fused[2].noteID != "c" {
+		t.Fatalf("expected tie broken towards full-text match, got %q then %q", fused[1].noteID, fused[2].noteID)
+	}
+}
+
+func TestDropWeakVectorMatchesKeepsFullTextMatches(t *testing.T) {
+	lexical := []*NoteSearchResult{lexicalHit("a")}
+	chunks := []repository.NoteChunkSearchResult{
+		{NoteID: "a", Score: 0.1},
+		{NoteID: "b", Score: 0.9},
+		{NoteID: "c", Score: 0.1},
+	}
+
+	fused := dropWeakVectorMatches(fuseRankings(60, lexical, chunks, nil), 0.5)
+
+	ids := make([]string, 0, len(fused))
+	for _, c := range fused {
+		ids = append(ids, c.noteID)
+	}
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("expected a (full-text) and b (similar chunk) to remain, got %v", ids)
+	}
+}
+
+type fakeNoteSearchRepo struct {
+	repository.NoteSearchRepository
+	params []repository.NoteSearchParams
+}
+
+func (f *fakeNoteSearchRepo) SearchLexical(ctx context.Context, params repository.NoteSearchParams) ([]*repository.NoteSearchHit, int64, error) {
+	f.params = append(f.params, params)
+	return nil, 0, nil
+}
+
+type fakeChunkSearchRepo struct {
+	repository.NoteChunkRepository
+	params []repository.NoteChunkSearchParams
+}
+
+func (f *fakeChunkSearchRepo) SearchSimilarFiltered(ctx context.Context, params repository.NoteChunkSearchParams) ([]repository.NoteChunkSearchResult, error) {
+	f.params = append(f.params, params)
+	return nil, nil
+}
+
+func TestHybridSearchIsScopedToTheWorkspace(t *testing.T) {
+	searchRepo := &fakeNoteSearchRepo{}
+	chunkRepo := &fakeChunkSearchRepo{}
+	s := NewHybridSearchService(searchRepo, chunkRepo, nil, nil, nil, config.SearchConfig{})
+	team := "team"
+
+	_, _, err := s.HybridSearch(context.Background(), HybridSearchRequest{
+		NoteSearchRequest: NoteSearchRequest{UserID: "user-1", WorkspaceID: &team, Query: "plan"},
+		QueryEmbedding:    []float64{0.1, 0.2},
+	})
+	if err != nil {
+		t.Fatalf("hybrid search: %v", err)
+	}
+	if len(searchRepo.params) != 1 || searchRepo.params[0].WorkspaceID != &team {
+		t.Fatalf("expected full-text search in the workspace, got %+v", searchRepo.params)
+	}
+	if len(chunkRepo.params) != 1 || chunkRepo.params[0].WorkspaceID != &team {
+		t.Fatalf("expected chunk search in the workspace, got %+v", chunkRepo.params)
+	}
+}
+
+func TestSemanticFiltersKeepToTheWorkspace(t *testing.T) {
+	team, other := "team", "other"
+	mine := &models.Note{UserID: "user-1"}
+	teammates := &models.Note{UserID: "user-2", WorkspaceID: &team}
+	elsewhere := &models.Note{UserID: "user-1", WorkspaceID: &other}
+
+	inWorkspace := NoteSearchRequest{UserID: "user-1", WorkspaceID: &team}
+	if noteMatchesSearchFilters(mine, inWorkspace) || !noteMatchesSearchFilters(teammates, inWorkspace) || noteMatchesSearchFilters(elsewhere, inWorkspace) {
+		t.Fatalf("expected only the workspace's notes to match")
+	}
+	personal := NoteSearchRequest{UserID: "user-1"}
+	if !noteMatchesSearchFilters(mine, personal) || noteMatchesSearchFilters(teammates, personal) {
+		t.Fatalf("expected only the user's own notes to match without a workspace")
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+// lexicalSearchService implements SearchService with Postgres full-text search.
+// notes.search_vector is a generated column, so indexing is a no-op.
+type lexicalSearchService struct {
+	repo repository.NoteSearchRepository
+}
+
+// NewLexicalSearchService creates a search service backed by notes.search_vector
+func NewLexicalSearchService(repo repository.NoteSearchRepository) SearchService {
+	return &lexicalSearchService{repo: repo}
+}
+
+// IndexNote is a no-op; Postgres keeps the search vector up to date
+func (s *lexicalSearchService) IndexNote(ctx context.Context, note *models.Note) error {
+	return nil
+}
+
+// SearchNotes performs full-text search on a user's notes
+func (s *lexicalSearchService) SearchNotes(ctx context.Context, query string, userID string, limit int) ([]*models.Note, int64, error) {
+	results, total, err := s.Search(ctx, NoteSearchRequest{
+		UserID: userID,
+		Query:  query,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return SearchNoteList(results), total, nil
+}
+
+// DeleteNoteIndex is a no-op; deleted notes are excluded by the query
+func (s *lexicalSearchService) DeleteNoteIndex(ctx context.Context, noteID string) error {
+	return nil
+}
+
+// ReindexAllNotes is a no-op; Postgres keeps the search vector up to date
+func (s *lexicalSearchService) ReindexAllNotes(ctx context.Context, userID string) error {
+	return nil
+}
+
+// Search runs a filtered full-text search with highlighted snippets
+func (s *lexicalSearchService) Search(ctx context.Context, req NoteSearchRequest) ([]*NoteSearchResult, int64, error) {
+	if req.Query == "" {
+		return nil, 0, fmt.Errorf("query cannot be empty")
+	}
+
+	hits, total, err := s.repo.SearchLexical(ctx, repository.NoteSearchParams{
+		UserID:      req.UserID,
+		WorkspaceID: req.WorkspaceID,
+		Query:       req.Query,
+		FolderID:    req.FolderID,
+		Status:      req.Status,
+		TagIDs:      req.TagIDs,
+		TagMatch:    req.TagMatch,
+		Limit:       req.Limit,
+		Offset:      req.Offset,
+	})
+	if err != nil {
+		return nil, 0, ErrInternalServerError
+	}
+
+	results := make([]*NoteSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, &NoteSearchResult{
+			Note:           hit.Note,
+			Score:          hit.Rank,
+			TitleHighlight: hit.TitleHighlight,
+			Snippet:        hit.Snippet,
+		})
+	}
+	return results, total, nil
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/utils"
+)
+
+// RevisionActor identifies who is saving a note. Handlers attach it to the
+// request context so the note service can attribute the resulting revision.
+type RevisionActor struct {
+	Source     models.NoteRevisionSource
+	AuthorID   string
+	RunID      string
+	ToolCallID string
+	EditToken  string
+}
+
+type revisionActorContextKey struct{}
+
+// WithRevisionActor returns a context carrying the actor for revisions created with it
+func WithRevisionActor(ctx context.Context, actor RevisionActor) context.Context {
+	return context.WithValue(ctx, revisionActorContextKey{}, actor)
+}
+
+func revisionActorFromContext(ctx context.Context) RevisionActor {
+	if actor, ok := ctx.Value(revisionActorContextKey{}).(RevisionActor); ok && actor.Source != "" {
+		return actor
+	}
+	return RevisionActor{Source: models.NoteRevisionSourceSystem}
+}
+
+// NoteRevisionService defines the interface for note history business logic
+type NoteRevisionService interface {
+	ListRevisions(ctx context.Context, noteID string, limit int) ([]*models.NoteRevision, error)
+	GetRevision(ctx context.Context, noteID, revisionID string) (*models.NoteRevision, error)
+	DiffRevision(ctx context.Context, noteID, revisionID, againstID string) (*NoteRevisionDiff, error)
+	RestoreRevision(ctx context.Context, noteID, revisionID string, expectedVersion *int) (*models.Note, error)
+}
+
+// NoteRevisionRef identifies one side of a diff. An empty ID means the current note.
+type NoteRevisionRef struct {
+	ID        string    `json:"id,omitempty"`
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NoteRevisionDiff is a line diff between two states of a note
+type NoteRevisionDiff struct {
+	NoteID       string           `json:"note_id"`
+	From         NoteRevisionRef  `json:"from"`
+	To           NoteRevisionRef  `json:"to"`
+	TitleChanged bool             `json:"title_changed"`
+	Lines        []utils.DiffLine `json:"lines"`
+	Added        int              `json:"added"`
+	Removed      int              `json:"removed"`
+}
+
+// noteRevisionService implements NoteRevisionService
+type noteRevisionService struct {
+	noteRepo        repository.NoteRepository
+	revisions       noteRevisionRecorder
+	chunkingService ChunkingService
+}
+
+// NewNoteRevisionService creates a new note revision service
+func NewNoteRevisionService(revisionRepo repository.NoteRevisionRepository, noteRepo repository.NoteRepository, chunkingService ChunkingService) NoteRevisionService {
+	return &noteRevisionService{
+		noteRepo:        noteRepo,
+		revisions:       noteRevisionRecorder{repo: revisionRepo},
+		chunkingService: chunkingService,
+	}
+}
+
+// ListRevisions lists the revisions of a note, newest first
+func (s *noteRevisionService) ListRevisions(ctx context.Context, noteID string, limit int) ([]*models.NoteRevision, error) {
+	revisions, err := s.revisions.repo.ListByNoteID(ctx, noteID, limit)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return revisions, nil
+}
+
+// GetRevision retrieves a single revision of a note
+func (s *noteRevisionService) GetRevision(ctx context.Context, noteID, revisionID string) (*models.NoteRevision, error) {
+	revision, err := s.revisions.repo.GetByID(ctx, noteID, revisionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteRevisionNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	return revision, nil
+}
+
+// DiffRevision diffs a revision against another revision, or against the
+// current note when againstID is empty
+func (s *noteRevisionService) DiffRevision(ctx context.Context, noteID, revisionID, againstID string) (*NoteRevisionDiff, error) {
+	from, err := s.GetRevision(ctx, noteID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		to        NoteRevisionRef
+		toContent string
+	)
+	if againstID == "" {
+		note, err := s.noteRepo.GetByID(ctx, noteID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrNoteNotFound
+			}
+			return nil, ErrInternalServerError
+		}
+		to = NoteRevisionRef{Version: note.Version, Title: note.Title, CreatedAt: note.UpdatedAt}
+		toContent = note.Content
+	} else {
+		against, err := s.GetRevision(ctx, noteID, againstID)
+		if err != nil {
+			return nil, err
+		}
+		to = revisionRef(against)
+		toContent = against.Content
+	}
+
+	lines := utils.DiffLines(utils.SplitContentLines(from.Content), utils.SplitContentLines(toContent))
+	diff := &NoteRevisionDiff{
+		NoteID:       noteID,
+		From:         revisionRef(from),
+		To:           to,
+		TitleChanged: from.Title != to.Title,
+		Lines:        lines,
+	}
+	for _, line := range lines {
+		switch line.Op {
+		case utils.DiffOpInsert:
+			diff.Added++
+		case utils.DiffOpDelete:
+			diff.Removed++
+		}
+	}
+	return diff, nil
+}
+
+// RestoreRevision writes a revision back to the note as a new version
+func (s *noteRevisionService) RestoreRevision(ctx context.Context, noteID, revisionID string, expectedVersion *int) (*models.Note, error) {
+	revision, err := s.GetRevision(ctx, noteID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := s.noteRepo.GetByID(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	before := snapshotNoteContent(note)
+
+	version := note.Version
+	if expectedVersion != nil {
+		version = *expectedVersion
+	}
+
+	restored, err := s.noteRepo.RestoreContentWithVersion(ctx, noteID, revision.Title, revision.Content, revision.TiptapContent, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, ErrVersionConflict
+		}
+		return nil, ErrInternalServerError
+	}
+
+	s.revisions.record(ctx, &before, restored, &revision.ID)
+
+	if s.chunkingService != nil {
+		s.chunkingService.DispatchNoteSaved(ctx, restored, "note.restore")
+	}
+
+	return restored, nil
+}
+
+func revisionRef(revision *models.NoteRevision) NoteRevisionRef {
+	return NoteRevisionRef{
+		ID:        revision.ID,
+		Version:   revision.Version,
+		Title:     revision.Title,
+		CreatedAt: revision.CreatedAt,
+	}
+}
+
+// noteContentState is the part of a note that revisions keep track of
+type noteContentState struct {
+	Title         string
+	Content       string
+	TiptapContent string
+	Version       int
+}
+
+func snapshotNoteContent(note *models.Note) noteContentState {
+	return noteContentState{
+		Title:         note.Title,
+		Content:       note.Content,
+		TiptapContent: note.TiptapContent,
+		Version:       note.Version,
+	}
+}
+
+func (c noteContentState) sameContent(title, content, tiptapContent string) bool {
+	return c.Title == title && c.Content == content && c.TiptapContent == tiptapContent
+}
+
+// noteRevisionRecorder appends revisions after a note has been saved
+type noteRevisionRecorder struct {
+	repo repository.NoteRevisionRepository
+}
+
+// record stores the saved state of a note. Notes that predate revision history
+// get their pre-save state recorded first so the first overwrite can be undone.
+// Failures are logged and never fail the save itself.
+func (r noteRevisionRecorder) record(ctx context.Context, before *noteContentState, after *models.Note, restoredFromID *string) {
+	if r.repo == nil || after == nil {
+		return
+	}
+	if before != nil && before.sameContent(after.Title, after.Content, after.TiptapContent) {
+		return
+	}
+
+	latest, err := r.repo.GetLatest(ctx, after.ID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if before != nil {
+			baseline := &models.NoteRevision{
+				NoteID:        after.ID,
+				Version:       before.Version,
+				Title:         before.Title,
+				Content:       before.Content,
+				TiptapContent: before.TiptapContent,
+				Source:        models.NoteRevisionSourceSystem,
+			}
+			if err := r.repo.Create(ctx, baseline); err != nil {
+				log.Printf("Warning: failed to record baseline revision for note %s: %v", after.ID, err)
+			}
+		}
+	case err != nil:
+		log.Printf("Warning: failed to load latest revision for note %s: %v", after.ID, err)
+		return
+	case restoredFromID == nil && latest.Title == after.Title && latest.Content == after.Content && latest.TiptapContent == after.TiptapContent:
+		// Nothing changed since the last recorded revision
+		return
+	}
+
+	actor := revisionActorFromContext(ctx)
+	revision := &models.NoteRevision{
+		NoteID:         after.ID,
+		Version:        after.Version,
+		Title:          after.Title,
+		Content:        after.Content,
+		TiptapContent:  after.TiptapContent,
+		Source:         actor.Source,
+		RunID:          actor.RunID,
+		ToolCallID:     actor.ToolCallID,
+		RestoredFromID: restoredFromID,
+	}
+	if actor.AuthorID != "" {
+		authorID := actor.AuthorID
+		revision.AuthorID = &authorID
+	}
+	if actor.EditToken != "" {
+		revision.EditTokenFingerprint = editTokenFingerprint(actor.EditToken)
+	}
+
+	if err := r.repo.Create(ctx, revision); err != nil {
+		log.Printf("Warning: failed to record revision for note %s: %v", after.ID, err)
+	}
+}
+
+// editTokenFingerprint identifies a public edit token without storing it
+func editTokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])[:16]
+}
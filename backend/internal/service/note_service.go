@@ -40,6 +40,8 @@ type noteService struct {
 	config          *config.Config
 	searchService   SearchService
 	chunkingService ChunkingService
+	revisions       noteRevisionRecorder
+	tagRepo         repository.TagRepository
 }
 
 // CreateNoteRequest represents the request to create a note
@@ -53,6 +55,7 @@ type CreateNoteRequest struct {
 	IsPublic    bool    `json:"is_public"`
 	UserID      string  `json:"user_id" validate:"required"`
 	TagIDs      []uint  `json:"tag_ids,omitempty"`
+	WorkspaceID *string `json:"workspace_id,omitempty"`
 }
 
 // UpdateNoteRequest represents the request to update a note
@@ -69,12 +72,14 @@ type UpdateNoteRequest struct {
 }
 
 // NewNoteService creates a new note service
-func NewNoteService(repo repository.NoteRepository, config *config.Config, searchService SearchService, chunkingService ChunkingService) NoteService {
+func NewNoteService(repo repository.NoteRepository, config *config.Config, searchService SearchService, chunkingService ChunkingService, revisionRepo repository.NoteRevisionRepository, tagRepo repository.TagRepository) NoteService {
 	return &noteService{
 		repo:            repo,
 		config:          config,
 		searchService:   searchService,
 		chunkingService: chunkingService,
+		revisions:       noteRevisionRecorder{repo: revisionRepo},
+		tagRepo:         tagRepo,
 	}
 }
 
@@ -98,6 +103,7 @@ func (s *noteService) CreateNote(ctx context.Context, req CreateNoteRequest) (*m
 		FolderID:    req.FolderID,
 		IsPublic:    req.IsPublic,
 		UserID:      req.UserID,
+		WorkspaceID: req.WorkspaceID,
 	}
 
 	if err := s.repo.Create(ctx, note); err != nil {
@@ -139,6 +145,7 @@ func (s *noteService) UpdateNote(ctx context.Context, id string, req UpdateNoteR
 		}
 		return nil, ErrInternalServerError
 	}
+	before := snapshotNoteContent(note)
 
 	// Update fields
 	if req.Title != "" {
@@ -176,6 +183,8 @@ func (s *noteService) UpdateNote(ctx context.Context, id string, req UpdateNoteR
 		return nil, ErrInternalServerError
 	}
 
+	s.revisions.record(ctx, &before, note, nil)
+
 	if s.chunkingService != nil {
 		s.chunkingService.DispatchNoteSaved(ctx, note, "note.update")
 	}
@@ -184,6 +193,15 @@ func (s *noteService) UpdateNote(ctx context.Context, id string, req UpdateNoteR
 }
 
 func (s *noteService) UpdateNoteContentWithVersion(ctx context.Context, id string, content string, expectedVersion int) (*models.Note, error) {
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	before := snapshotNoteContent(current)
+
 	note, err := s.repo.UpdateContentWithVersion(ctx, id, content, expectedVersion)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -195,6 +213,8 @@ func (s *noteService) UpdateNoteContentWithVersion(ctx context.Context, id strin
 		return nil, ErrInternalServerError
 	}
 
+	s.revisions.record(ctx, &before, note, nil)
+
 	return note, nil
 }
 
@@ -218,13 +238,8 @@ func (s *noteService) DeleteNote(ctx context.Context, id string) error {
 
 // ListNotes retrieves notes with pagination
 func (s *noteService) ListNotes(ctx context.Context, params repository.NoteListParams, userID string) ([]*models.Note, int64, error) {
-	if params.Query != nil {
-		notes, total, err := s.searchService.SearchNotes(ctx, *params.Query, userID, params.Limit)
-		if err != nil {
-			return nil, 0, err
-		}
-		utils.FormatNotePreviews(notes, utils.DefaultNotePreviewLength)
-		return notes, total, nil
+	if params.Query != nil && *params.Query != "" && s.searchService != nil {
+		return s.searchNotes(ctx, userID, params)
 	}
 
 	notes, total, err := s.repo.List(ctx, params)
@@ -238,13 +253,8 @@ func (s *noteService) ListNotes(ctx context.Context, params repository.NoteListP
 
 // GetNotesByUserID retrieves notes by user ID
 func (s *noteService) GetNotesByUserID(ctx context.Context, userID string, params repository.NoteListParams) ([]*models.Note, int64, error) {
-	if params.Query != nil && *params.Query != "" {
-		notes, total, err := s.searchService.SearchNotes(ctx, *params.Query, userID, params.Limit)
-		if err != nil {
-			return nil, 0, err
-		}
-		utils.FormatNotePreviews(notes, utils.DefaultNotePreviewLength)
-		return notes, total, nil
+	if params.Query != nil && *params.Query != "" && s.searchService != nil {
+		return s.searchNotes(ctx, userID, params)
 	}
 
 	notes, total, err := s.repo.GetByUserID(ctx, userID, params)
@@ -256,18 +266,81 @@ func (s *noteService) GetNotesByUserID(ctx context.Context, userID string, param
 	return notes, total, nil
 }
 
-// AddTagToNote adds a tag to a note
+// searchNotes runs a list query through the search backend. Search spans all
+// folders unless one is given.
+func (s *noteService) searchNotes(ctx context.Context, userID string, params repository.NoteListParams) ([]*models.Note, int64, error) {
+	offset := 0
+	if params.Page > 1 && params.Limit > 0 {
+		offset = (params.Page - 1) * params.Limit
+	}
+
+	results, total, err := s.searchService.Search(ctx, NoteSearchRequest{
+		UserID:   userID,
+		Query:    *params.Query,
+		FolderID: params.FolderID,
+		Status:   params.Status,
+		TagIDs:   params.TagIDs,
+		TagMatch: params.TagMatch,
+		Limit:    params.Limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	notes := SearchNoteList(results)
+	utils.FormatNotePreviews(notes, utils.DefaultNotePreviewLength)
+	return notes, total, nil
+}
+
+// AddTagToNote adds a tag to a note. The tag must belong to the note's owner.
 func (s *noteService) AddTagToNote(ctx context.Context, noteID, tagID string) error {
-	// This would need to be implemented in the repository
-	// For now, return not implemented
-	return ErrNotImplemented
+	if err := s.checkNoteTag(ctx, noteID, tagID); err != nil {
+		return err
+	}
+	if err := s.tagRepo.AttachToNote(ctx, noteID, tagID); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
 }
 
 // RemoveTagFromNote removes a tag from a note
 func (s *noteService) RemoveTagFromNote(ctx context.Context, noteID, tagID string) error {
-	// This would need to be implemented in the repository
-	// For now, return not implemented
-	return ErrNotImplemented
+	if err := s.checkNoteTag(ctx, noteID, tagID); err != nil {
+		return err
+	}
+	if err := s.tagRepo.DetachFromNote(ctx, noteID, tagID); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// checkNoteTag verifies the note exists and tagID is one of its owner's tags
+func (s *noteService) checkNoteTag(ctx context.Context, noteID, tagID string) error {
+	if s.tagRepo == nil {
+		return ErrNotImplemented
+	}
+
+	note, err := s.repo.GetByID(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNoteNotFound
+		}
+		return ErrInternalServerError
+	}
+
+	tag, err := s.tagRepo.GetByID(ctx, tagID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTagNotFound
+		}
+		return ErrInternalServerError
+	}
+	if tag.UserID != note.UserID {
+		return ErrTagNotFound
+	}
+
+	return nil
 }
 
 // UpdateNoteTOM updates a note top of mind by ID
@@ -400,11 +473,14 @@ func (s *noteService) SaveNoteSnapshot(ctx context.Context, id string, content s
 		return nil, ErrInternalServerError
 	}
 
+	before := snapshotNoteContent(note)
 	note.Content = content
 	if err := s.repo.Update(ctx, note); err != nil {
 		return nil, ErrInternalServerError
 	}
 
+	s.revisions.record(ctx, &before, note, nil)
+
 	if s.chunkingService != nil {
 		s.chunkingService.DispatchNoteSaved(ctx, note, "note.snapshot")
 	}
@@ -422,11 +498,14 @@ func (s *noteService) SaveNoteTiptapSnapshot(ctx context.Context, id string, tip
 		return nil, ErrInternalServerError
 	}
 
+	before := snapshotNoteContent(note)
 	note.TiptapContent = tiptapContent
 	if err := s.repo.Update(ctx, note); err != nil {
 		return nil, ErrInternalServerError
 	}
 
+	s.revisions.record(ctx, &before, note, nil)
+
 	if s.chunkingService != nil {
 		s.chunkingService.DispatchNoteSaved(ctx, note, "note.snapshot.tiptap")
 	}
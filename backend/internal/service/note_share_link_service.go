@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+// NoteShareLinkService defines the interface for read-only public share links
+type NoteShareLinkService interface {
+	CreateLink(ctx context.Context, ownerID, noteID string, req CreateShareLinkRequest) (*models.NoteShareLink, error)
+	ListLinks(ctx context.Context, ownerID, noteID string) ([]*models.NoteShareLink, error)
+	UpdateLink(ctx context.Context, ownerID, linkID string, req UpdateShareLinkRequest) (*models.NoteShareLink, error)
+	RevokeLink(ctx context.Context, ownerID, linkID string) error
+	LinkAnalytics(ctx context.Context, ownerID, linkID string, days int) (*ShareLinkAnalytics, error)
+	OpenLink(ctx context.Context, slug, password string) (*models.NoteShareLink, *models.Note, error)
+	RecordView(ctx context.Context, link *models.NoteShareLink, visitor, referrer string) error
+	ListLinkComments(ctx context.Context, slug, password string) ([]*CommentResponse, error)
+	AddLinkComment(ctx context.Context, slug, password string, req CreateCommentRequest) (*CommentResponse, error)
+}
+
+// CreateShareLinkRequest configures a new share link. Without ExpiresAt the
+// link never expires; without Password anyone with the slug can open it.
+type CreateShareLinkRequest struct {
+	Label         string     `json:"label"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	Password      string     `json:"password"`
+	AllowComments bool       `json:"allow_comments"`
+}
+
+// UpdateShareLinkRequest changes the fields that are set. An empty Password
+// removes the password and NoExpiry removes the expiry.
+type UpdateShareLinkRequest struct {
+	Label         *string    `json:"label"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	NoExpiry      bool       `json:"no_expiry"`
+	Password      *string    `json:"password"`
+	AllowComments *bool      `json:"allow_comments"`
+}
+
+// ShareLinkAnalytics summarises who opened a share link
+type ShareLinkAnalytics struct {
+	LinkID         string                           `json:"link_id"`
+	ViewCount      int64                            `json:"view_count"`
+	UniqueVisitors int64                            `json:"unique_visitors"`
+	LastViewedAt   *time.Time                       `json:"last_viewed_at,omitempty"`
+	Days           []repository.ShareLinkDailyViews `json:"days"`
+}
+
+const (
+	maxShareLinkAnalyticsDays = 365
+	shareLinkSlugBytes        = 12
+)
+
+// noteShareLinkService implements NoteShareLinkService
+type noteShareLinkService struct {
+	repo     repository.NoteShareLinkRepository
+	noteRepo repository.NoteRepository
+	comments CommentService
+	now      func() time.Time
+}
+
+// NewNoteShareLinkService creates a new share link service
+func NewNoteShareLinkService(repo repository.NoteShareLinkRepository, noteRepo repository.NoteRepository, comments CommentService) NoteShareLinkService {
+	return &noteShareLinkService{
+		repo:     repo,
+		noteRepo: noteRepo,
+		comments: comments,
+		now:      time.Now,
+	}
+}
+
+// CreateLink creates a new link to one of the owner's notes
+func (s *noteShareLinkService) CreateLink(ctx context.Context, ownerID, noteID string, req CreateShareLinkRequest) (*models.NoteShareLink, error) {
+	note, err := s.ownedNote(ctx, ownerID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	label := strings.TrimSpace(req.Label)
+	if len(label) > 100 || (req.ExpiresAt != nil && !req.ExpiresAt.After(s.now())) {
+		return nil, ErrValidationFailed
+	}
+	passwordHash, err := hashShareLinkPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := newShareLinkSlug()
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	link := &models.NoteShareLink{
+		NoteID:        note.ID,
+		OwnerID:       ownerID,
+		Slug:          slug,
+		Label:         label,
+		ExpiresAt:     req.ExpiresAt,
+		PasswordHash:  passwordHash,
+		AllowComments: req.AllowComments,
+	}
+	if err := s.repo.Create(ctx, link); err != nil {
+		return nil, ErrInternalServerError
+	}
+	return s.present(link), nil
+}
+
+// ListLinks lists the links to one of the owner's notes
+func (s *noteShareLinkService) ListLinks(ctx context.Context, ownerID, noteID string) ([]*models.NoteShareLink, error) {
+	note, err := s.ownedNote(ctx, ownerID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.repo.ListByNoteID(ctx, note.ID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	for _, link := range links {
+		s.present(link)
+	}
+	return links, nil
+}
+
+// UpdateLink changes one of the owner's links. Revoked links stay revoked.
+func (s *noteShareLinkService) UpdateLink(ctx context.Context, ownerID, linkID string, req UpdateShareLinkRequest) (*models.NoteShareLink, error) {
+	link, err := s.ownedLink(ctx, ownerID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if link.RevokedAt != nil {
+		return nil, ErrShareLinkUnavailable
+	}
+
+	if req.Label != nil {
+		label := strings.TrimSpace(*req.Label)
+		if len(label) > 100 {
+			return nil, ErrValidationFailed
+		}
+		link.Label = label
+	}
+	switch {
+	case req.NoExpiry:
+		link.ExpiresAt = nil
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(s.now()) {
+			return nil, ErrValidationFailed
+		}
+		link.ExpiresAt = req.ExpiresAt
+	}
+	if req.Password != nil {
+		if link.PasswordHash, err = hashShareLinkPassword(*req.Password); err != nil {
+			return nil, err
+		}
+	}
+	if req.AllowComments != nil {
+		link.AllowComments = *req.AllowComments
+	}
+
+	if err := s.repo.Update(ctx, link); err != nil {
+		return nil, ErrInternalServerError
+	}
+	return s.present(link), nil
+}
+
+// RevokeLink stops one of the owner's links from opening the note
+func (s *noteShareLinkService) RevokeLink(ctx context.Context, ownerID, linkID string) error {
+	link, err := s.ownedLink(ctx, ownerID, linkID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Revoke(ctx, link.ID, s.now()); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// LinkAnalytics reports the views of one of the owner's links over the last
+// days
+func (s *noteShareLinkService) LinkAnalytics(ctx context.Context, ownerID, linkID string, days int) (*ShareLinkAnalytics, error) {
+	link, err := s.ownedLink(ctx, ownerID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if days <= 0 || days > maxShareLinkAnalyticsDays {
+		days = 30
+	}
+
+	since := s.now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	daily, err := s.repo.DailyViews(ctx, link.ID, since)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	unique, err := s.repo.CountUniqueVisitors(ctx, link.ID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	if daily == nil {
+		daily = []repository.ShareLinkDailyViews{}
+	}
+
+	return &ShareLinkAnalytics{
+		LinkID:         link.ID,
+		ViewCount:      link.ViewCount,
+		UniqueVisitors: unique,
+		LastViewedAt:   link.LastViewedAt,
+		Days:           daily,
+	}, nil
+}
+
+// OpenLink resolves a slug to its note, checking expiry, revocation and the
+// password
+func (s *noteShareLinkService) OpenLink(ctx context.Context, slug, password string) (*models.NoteShareLink, *models.Note, error) {
+	link, err := s.repo.GetBySlug(ctx, strings.TrimSpace(slug))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrShareLinkNotFound
+		}
+		return nil, nil, ErrInternalServerError
+	}
+	if link.StatusAt(s.now()) != models.ShareLinkStatusActive {
+		return nil, nil, ErrShareLinkUnavailable
+	}
+	if link.PasswordHash != "" {
+		if password == "" {
+			return nil, nil, ErrShareLinkPasswordRequired
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			return nil, nil, ErrShareLinkPasswordInvalid
+		}
+	}
+
+	note, err := s.noteRepo.GetByID(ctx, link.NoteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrShareLinkNotFound
+		}
+		return nil, nil, ErrInternalServerError
+	}
+	return s.present(link), note, nil
+}
+
+// RecordView counts a view of an opened link. The visitor key is hashed with
+// the link ID so visitors cannot be followed across links.
+func (s *noteShareLinkService) RecordView(ctx context.Context, link *models.NoteShareLink, visitor, referrer string) error {
+	sum := sha256.Sum256([]byte(link.ID + "|" + visitor))
+	view := &models.NoteShareLinkView{
+		LinkID:      link.ID,
+		VisitorHash: hex.EncodeToString(sum[:]),
+		Referrer:    truncateRunes(referrer, 255),
+	}
+	view.CreatedAt = s.now()
+	if err := s.repo.RecordView(ctx, view); err != nil {
+		return ErrInternalServerError
+	}
+	link.ViewCount++
+	link.LastViewedAt = &view.CreatedAt
+	return nil
+}
+
+// truncateRunes cuts s to at most limit characters without splitting a
+// multi-byte character, matching how varchar(n) columns count
+func truncateRunes(s string, limit int) string {
+	runes := 0
+	for i := range s {
+		if runes == limit {
+			return s[:i]
+		}
+		runes++
+	}
+	return s
+}
+
+// ListLinkComments lists the comments on a link's note when the link allows
+// comments
+func (s *noteShareLinkService) ListLinkComments(ctx context.Context, slug, password string) ([]*CommentResponse, error) {
+	link, note, err := s.OpenLink(ctx, slug, password)
+	if err != nil {
+		return nil, err
+	}
+	if !link.AllowComments {
+		return nil, ErrShareLinkCommentsDisabled
+	}
+	return s.comments.ListCommentsByNoteID(withLinkGrant(ctx, note.ID, models.NoteRoleViewer), note.ID, "")
+}
+
+// AddLinkComment lets a signed-in user holding the link comment on its note
+func (s *noteShareLinkService) AddLinkComment(ctx context.Context, slug, password string, req CreateCommentRequest) (*CommentResponse, error) {
+	link, note, err := s.OpenLink(ctx, slug, password)
+	if err != nil {
+		return nil, err
+	}
+	if !link.AllowComments {
+		return nil, ErrShareLinkCommentsDisabled
+	}
+	req.NoteID = note.ID
+	return s.comments.CreateComment(withLinkGrant(ctx, note.ID, models.NoteRoleCommenter), req)
+}
+
+func (s *noteShareLinkService) ownedNote(ctx context.Context, ownerID, noteID string) (*models.Note, error) {
+	note, err := s.noteRepo.GetByID(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	if note.UserID != ownerID {
+		return nil, ErrNoteNotFound
+	}
+	return note, nil
+}
+
+func (s *noteShareLinkService) ownedLink(ctx context.Context, ownerID, linkID string) (*models.NoteShareLink, error) {
+	link, err := s.repo.GetByID(ctx, linkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	if link.OwnerID != ownerID {
+		return nil, ErrShareLinkNotFound
+	}
+	return link, nil
+}
+
+// present fills in the computed fields of a link for responses
+func (s *noteShareLinkService) present(link *models.NoteShareLink) *models.NoteShareLink {
+	link.HasPassword = link.PasswordHash != ""
+	link.Status = link.StatusAt(s.now())
+	return link
+}
+
+func hashShareLinkPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) < 4 || len(password) > 72 {
+		return "", ErrValidationFailed
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", ErrInternalServerError
+	}
+	return string(hash), nil
+}
+
+func newShareLinkSlug() (string, error) {
+	b := make([]byte, shareLinkSlugBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type linkGrantContextKey struct{}
+
+type linkGrant struct {
+	noteID string
+	role   models.NoteRole
+}
+
+// withLinkGrant returns a context in which an opened share link grants a
+// role on its note, so comment checks let link holders through
+func withLinkGrant(ctx context.Context, noteID string, role models.NoteRole) context.Context {
+	return context.WithValue(ctx, linkGrantContextKey{}, linkGrant{noteID: noteID, role: role})
+}
+
+func linkGrantFromContext(ctx context.Context, noteID string) models.NoteRole {
+	if grant, ok := ctx.Value(linkGrantContextKey{}).(linkGrant); ok && grant.noteID == noteID {
+		return grant.role
+	}
+	return ""
+}
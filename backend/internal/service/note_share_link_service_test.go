@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+)
+
+func TestShareLinkStatusAt(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	cases := []struct {
+		name string
+		link models.NoteShareLink
+		want models.ShareLinkStatus
+	}{
+		{"no expiry", models.NoteShareLink{}, models.ShareLinkStatusActive},
+		{"expires later", models.NoteShareLink{ExpiresAt: &future}, models.ShareLinkStatusActive},
+		{"expired", models.NoteShareLink{ExpiresAt: &past}, models.ShareLinkStatusExpired},
+		{"expires now", models.NoteShareLink{ExpiresAt: &now}, models.ShareLinkStatusExpired},
+		{"revoked", models.NoteShareLink{ExpiresAt: &future, RevokedAt: &past}, models.ShareLinkStatusRevoked},
+	}
+	for _, tc := range cases {
+		if got := tc.link.StatusAt(now); got != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestHashShareLinkPassword(t *testing.T) {
+	if hash, err := hashShareLinkPassword(""); err != nil || hash != "" {
+		t.Fatalf("expected no hash for an empty password, got %q, %v", hash, err)
+	}
+	if _, err := hashShareLinkPassword("abc"); err != ErrValidationFailed {
+		t.Fatalf("expected short password to fail validation, got %v", err)
+	}
+
+	hash, err := hashShareLinkPassword("open sesame")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte("open sesame")) != nil {
+		t.Fatalf("expected hash to match the password")
+	}
+}
+
+func TestNewShareLinkSlugIsRandomAndURLSafe(t *testing.T) {
+	a, err := newShareLinkSlug()
+	if err != nil {
+		t.Fatalf("new slug: %v", err)
+	}
+	b, _ := newShareLinkSlug()
+	if a == b || len(a) != 16 {
+		t.Fatalf("expected distinct 16 character slugs, got %q and %q", a, b)
+	}
+	for _, r := range a {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			t.Fatalf("expected URL-safe slug, got %q", a)
+		}
+	}
+}
+
+func TestLinkGrantOnlyAppliesToItsNote(t *testing.T) {
+	ctx := withLinkGrant(context.Background(), "note-1", models.NoteRoleCommenter)
+	if got := linkGrantFromContext(ctx, "note-1"); got != models.NoteRoleCommenter {
+		t.Fatalf("expected commenter on the linked note, got %q", got)
+	}
+	if got := linkGrantFromContext(ctx, "note-2"); got != "" {
+		t.Fatalf("expected no grant on another note, got %q", got)
+	}
+	if got := linkGrantFromContext(context.Background(), "note-1"); got != "" {
+		t.Fatalf("expected no grant without a link, got %q", got)
+	}
+}
+
+func TestTruncateRunesKeepsCharactersWhole(t *testing.T) {
+	referrer := "https://example.com/" + strings.Repeat("é", 300)
+	got := truncateRunes(referrer, 255)
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != 255 {
+		t.Fatalf("expected 255 whole characters, got %d (valid=%v)", utf8.RuneCountInString(got), utf8.ValidString(got))
+	}
+	if short := "https://example.com/"; truncateRunes(short, 255) != short {
+		t.Fatalf("expected a short referrer to be kept as is")
+	}
+}
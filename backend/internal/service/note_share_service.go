@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+// NoteShareService defines the interface for sharing notes and folders with
+// other users, and for resolving what a user may do with a note
+type NoteShareService interface {
+	NoteRole(ctx context.Context, note *models.Note, userID string) (models.NoteRole, error)
+	FolderRole(ctx context.Context, folder *models.Folder, userID string) (models.NoteRole, error)
+	ShareNote(ctx context.Context, ownerID, noteID string, req ShareRequest) (*models.NoteShare, error)
+	ShareFolder(ctx context.Context, ownerID, folderID string, req ShareRequest) (*models.NoteShare, error)
+	ListNoteShares(ctx context.Context, ownerID, noteID string) ([]*models.NoteShare, error)
+	ListFolderShares(ctx context.Context, ownerID, folderID string) ([]*models.NoteShare, error)
+	UpdateShare(ctx context.Context, ownerID, shareID string, role models.NoteRole) (*models.NoteShare, error)
+	RevokeShare(ctx context.Context, ownerID, shareID string) error
+	ListSharedWithUser(ctx context.Context, userID string) ([]*models.NoteShare, error)
+}
+
+// ShareRequest names who to share with, by user ID or by email, and the
+// role to grant. Sharing again with the same person changes their role.
+type ShareRequest struct {
+	UserID string          `json:"user_id"`
+	Email  string          `json:"email"`
+	Role   models.NoteRole `json:"role"`
+}
+
+// noteShareService implements NoteShareService
+type noteShareService struct {
+	repo       repository.NoteShareRepository
+	noteRepo   repository.NoteRepository
+	folderRepo repository.FolderRepository
+	userRepo   repository.UserRepository
+
+	workspaceRepo repository.WorkspaceRepository
+}
+
+// NewNoteShareService creates a new note share service
+func NewNoteShareService(repo repository.NoteShareRepository, noteRepo repository.NoteRepository, folderRepo repository.FolderRepository, userRepo repository.UserRepository, workspaceRepo repository.WorkspaceRepository) NoteShareService {
+	return &noteShareService{
+		repo:          repo,
+		noteRepo:      noteRepo,
+		folderRepo:    folderRepo,
+		userRepo:      userRepo,
+		workspaceRepo: workspaceRepo,
+	}
+}
+
+// NoteRole resolves the strongest role a user has on a note: owner, or the
+// best of their role in the note's workspace, the note's own shares and those
+// of the folders it sits in. An empty role means no access.
+func (s *noteShareService) NoteRole(ctx context.Context, note *models.Note, userID string) (models.NoteRole, error) {
+	return s.resolveRole(ctx, note.UserID, note.WorkspaceID, note.ID, note.FolderID, userID)
+}
+
+// FolderRole resolves the strongest role a user has on a folder and so on
+// every note in it
+func (s *noteShareService) FolderRole(ctx context.Context, folder *models.Folder, userID string) (models.NoteRole, error) {
+	return s.resolveRole(ctx, folder.UserID, folder.WorkspaceID, "", &folder.ID, userID)
+}
+
+func (s *noteShareService) resolveRole(ctx context.Context, ownerID string, workspaceID *string, noteID string, folderID *string, userID string) (models.NoteRole, error) {
+	if userID == "" {
+		return "", nil
+	}
+	if ownerID == userID {
+		return models.NoteRoleOwner, nil
+	}
+
+	var workspaceRole models.NoteRole
+	if workspaceID != nil && s.workspaceRepo != nil {
+		member, err := s.workspaceRepo.GetMember(ctx, *workspaceID, userID)
+		switch {
+		case err == nil:
+			workspaceRole = member.Role.NoteRole()
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return "", ErrInternalServerError
+		}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", ErrInternalServerError
+	}
+
+	var folderIDs []string
+	if folderID != nil && *folderID != "" {
+		folderIDs, err = s.repo.FolderAncestorIDs(ctx, *folderID)
+		if err != nil {
+			return "", ErrInternalServerError
+		}
+	}
+
+	grants, err := s.repo.ListGrants(ctx, noteID, folderIDs, user.ID, verifiedEmail(user))
+	if err != nil {
+		return "", ErrInternalServerError
+	}
+	if role := strongestGrant(ownerID, grants); !workspaceRole.Includes(role) {
+		return role, nil
+	}
+	return workspaceRole, nil
+}
+
+// strongestGrant picks the best role among the grants made by the owner.
+// Shares of someone else's folder do not carry over to notes moved out of it.
+func strongestGrant(ownerID string, grants []*models.NoteShare) models.NoteRole {
+	var role models.NoteRole
+	for _, grant := range grants {
+		if grant.OwnerID != ownerID {
+			continue
+		}
+		if !role.Includes(grant.Role) {
+			role = grant.Role
+		}
+	}
+	return role
+}
+
+// ShareNote shares one of the owner's notes
+func (s *noteShareService) ShareNote(ctx context.Context, ownerID, noteID string, req ShareRequest) (*models.NoteShare, error) {
+	note, err := s.noteRepo.GetByID(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	if note.UserID != ownerID {
+		return nil, ErrNoteNotFound
+	}
+	return s.share(ctx, ownerID, &models.NoteShare{NoteID: &note.ID}, req)
+}
+
+// ShareFolder shares one of the owner's folders, and with it every note in
+// the folder and its subfolders
+func (s *noteShareService) ShareFolder(ctx context.Context, ownerID, folderID string, req ShareRequest) (*models.NoteShare, error) {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFolderNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	if folder.UserID != ownerID {
+		return nil, ErrFolderNotFound
+	}
+	return s.share(ctx, ownerID, &models.NoteShare{FolderID: &folder.ID}, req)
+}
+
+func (s *noteShareService) share(ctx context.Context, ownerID string, share *models.NoteShare, req ShareRequest) (*models.NoteShare, error) {
+	if !isShareableRole(req.Role) {
+		return nil, ErrValidationFailed
+	}
+
+	grantee, email, err := s.resolveGrantee(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if grantee != nil && grantee.ID == ownerID {
+		return nil, ErrValidationFailed
+	}
+
+	existing, err := s.repo.FindByTarget(ctx, share.NoteID, share.FolderID, email)
+	if err == nil {
+		if err := s.repo.UpdateRole(ctx, existing.ID, req.Role); err != nil {
+			return nil, ErrInternalServerError
+		}
+		existing.Role = req.Role
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInternalServerError
+	}
+
+	share.OwnerID = ownerID
+	share.GranteeEmail = email
+	share.Role = req.Role
+	if grantee != nil {
+		share.GranteeUserID = &grantee.ID
+	}
+	if err := s.repo.Create(ctx, share); err != nil {
+		return nil, ErrInternalServerError
+	}
+	return share, nil
+}
+
+// resolveGrantee finds the user being shared with. Emails without an account,
+// or whose account has not verified them, are kept so the share applies once
+// the address is verified.
+func (s *noteShareService) resolveGrantee(ctx context.Context, req ShareRequest) (*models.User, string, error) {
+	if userID := strings.TrimSpace(req.UserID); userID != "" {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, "", ErrUserNotFound
+			}
+			return nil, "", ErrInternalServerError
+		}
+		return user, strings.ToLower(user.Email), nil
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, "", ErrValidationFailed
+	}
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, email, nil
+		}
+		return nil, "", ErrInternalServerError
+	}
+	if !user.EmailVerified {
+		return nil, email, nil
+	}
+	return user, email, nil
+}
+
+// verifiedEmail is the address a user's email shares are matched on. An
+// unverified address matches nothing, or anyone could pick up the shares made
+// out to an email by signing up with it.
+func verifiedEmail(user *models.User) string {
+	if !user.EmailVerified {
+		return ""
+	}
+	return user.Email
+}
+
+// ListNoteShares lists who one of the owner's notes is shared with
+func (s *noteShareService) ListNoteShares(ctx context.Context, ownerID, noteID string) ([]*models.NoteShare, error) {
+	note, err := s.noteRepo.GetByID(ctx, noteID)
+	if err != nil || note.UserID != ownerID {
+		return nil, ErrNoteNotFound
+	}
+	shares, err := s.repo.ListByNoteID(ctx, note.ID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return shares, nil
+}
+
+// ListFolderShares lists who one of the owner's folders is shared with
+func (s *noteShareService) ListFolderShares(ctx context.Context, ownerID, folderID string) ([]*models.NoteShare, error) {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
+	if err != nil || folder.UserID != ownerID {
+		return nil, ErrFolderNotFound
+	}
+	shares, err := s.repo.ListByFolderID(ctx, folder.ID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return shares, nil
+}
+
+// UpdateShare changes the role of one of the owner's shares
+func (s *noteShareService) UpdateShare(ctx context.Context, ownerID, shareID string, role models.NoteRole) (*models.NoteShare, error) {
+	if !isShareableRole(role) {
+		return nil, ErrValidationFailed
+	}
+	share, err := s.ownedShare(ctx, ownerID, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateRole(ctx, share.ID, role); err != nil {
+		return nil, ErrInternalServerError
+	}
+	share.Role = role
+	return share, nil
+}
+
+// RevokeShare removes one of the owner's shares
+func (s *noteShareService) RevokeShare(ctx context.Context, ownerID, shareID string) error {
+	share, err := s.ownedShare(ctx, ownerID, shareID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, share.ID); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// ListSharedWithUser lists the notes and folders others shared with a user
+func (s *noteShareService) ListSharedWithUser(ctx context.Context, userID string) ([]*models.NoteShare, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	shares, err := s.repo.ListByGrantee(ctx, user.ID, verifiedEmail(user))
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return shares, nil
+}
+
+func (s *noteShareService) ownedShare(ctx context.Context, ownerID, shareID string) (*models.NoteShare, error) {
+	share, err := s.repo.GetByID(ctx, shareID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteShareNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	if share.OwnerID != ownerID {
+		return nil, ErrNoteShareNotFound
+	}
+	return share, nil
+}
+
+func isShareableRole(role models.NoteRole) bool {
+	return role == models.NoteRoleViewer || role == models.NoteRoleCommenter || role == models.NoteRoleEditor
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+type fakeNoteShareRepo struct {
+	repository.NoteShareRepository
+	shares []*models.NoteShare
+}
+
+func (f *fakeNoteShareRepo) ListGrants(ctx context.Context, noteID string, folderIDs []string, userID, email string) ([]*models.NoteShare, error) {
+	return f.ListByGrantee(ctx, userID, email)
+}
+
+func (f *fakeNoteShareRepo) ListByGrantee(ctx context.Context, userID, email string) ([]*models.NoteShare, error) {
+	var shares []*models.NoteShare
+	for _, share := range f.shares {
+		if share.GranteeUserID != nil && *share.GranteeUserID == userID ||
+			share.GranteeUserID == nil && email != "" && share.GranteeEmail == strings.ToLower(email) {
+			shares = append(shares, share)
+		}
+	}
+	return shares, nil
+}
+
+func TestStrongestGrantPicksBestOwnerGrant(t *testing.T) {
+	grants := []*models.NoteShare{
+		{OwnerID: "owner-1", Role: models.NoteRoleViewer},
+		{OwnerID: "owner-1", Role: models.NoteRoleCommenter},
+		{OwnerID: "someone-else", Role: models.NoteRoleEditor},
+	}
+
+	role := strongestGrant("owner-1", grants)
+	if role != models.NoteRoleCommenter {
+		t.Fatalf("expected commenter, got %q", role)
+	}
+	if !role.CanView() || !role.CanComment() || role.CanEdit() {
+		t.Fatalf("expected commenter to view and comment but not edit")
+	}
+
+	if role := strongestGrant("owner-1", nil); role != "" || role.CanView() {
+		t.Fatalf("expected no access without grants, got %q", role)
+	}
+}
+
+func TestNoteRoleIncludes(t *testing.T) {
+	if !models.NoteRoleOwner.CanEdit() || !models.NoteRoleEditor.CanComment() {
+		t.Fatalf("expected owner and editor to include the lower roles")
+	}
+	if models.NoteRoleViewer.CanComment() || models.NoteRole("admin").CanView() {
+		t.Fatalf("expected viewer and unknown roles to be limited")
+	}
+}
+
+func TestWorkspaceRoleGrantsNoteRole(t *testing.T) {
+	cases := map[models.WorkspaceRole]models.NoteRole{
+		models.WorkspaceRoleOwner:  models.NoteRoleEditor,
+		models.WorkspaceRoleAdmin:  models.NoteRoleEditor,
+		models.WorkspaceRoleMember: models.NoteRoleEditor,
+		models.WorkspaceRoleViewer: models.NoteRoleViewer,
+		models.WorkspaceRole(""):   "",
+	}
+	for workspaceRole, want := range cases {
+		if got := workspaceRole.NoteRole(); got != want {
+			t.Fatalf("expected %q to grant %q, got %q", workspaceRole, want, got)
+		}
+	}
+	if isAssignableWorkspaceRole(models.WorkspaceRoleOwner) || !isAssignableWorkspaceRole(models.WorkspaceRoleAdmin) {
+		t.Fatalf("expected admin but not owner to be assignable")
+	}
+}
+
+func TestEmailSharesNeedAVerifiedAddress(t *testing.T) {
+	users := &fakeUserRepo{users: map[string]*models.User{
+		"user-1": {BaseModel: models.BaseModel{ID: "user-1"}, Email: "bea@example.com"},
+	}}
+	shares := &fakeNoteShareRepo{shares: []*models.NoteShare{
+		{OwnerID: "owner-1", GranteeEmail: "bea@example.com", Role: models.NoteRoleEditor},
+	}}
+	s := &noteShareService{repo: shares, userRepo: users}
+	note := &models.Note{BaseModel: models.BaseModel{ID: "note-1"}, UserID: "owner-1"}
+	ctx := context.Background()
+
+	if role, err := s.NoteRole(ctx, note, "user-1"); err != nil || role != "" {
+		t.Fatalf("expected an unverified address to get no role, got %q (%v)", role, err)
+	}
+	if listed, err := s.ListSharedWithUser(ctx, "user-1"); err != nil || len(listed) != 0 {
+		t.Fatalf("expected nothing shared with an unverified address, got %v (%v)", listed, err)
+	}
+	if grantee, email, err := s.resolveGrantee(ctx, ShareRequest{Email: "Bea@example.com"}); err != nil || grantee != nil || email != "bea@example.com" {
+		t.Fatalf("expected a share with an unverified address to stay an email share, got %v %q (%v)", grantee, email, err)
+	}
+
+	users.users["user-1"].EmailVerified = true
+	if role, err := s.NoteRole(ctx, note, "user-1"); err != nil || role != models.NoteRoleEditor {
+		t.Fatalf("expected the verified address to pick up the share, got %q (%v)", role, err)
+	}
+	if grantee, _, err := s.resolveGrantee(ctx, ShareRequest{Email: "bea@example.com"}); err != nil || grantee == nil || grantee.ID != "user-1" {
+		t.Fatalf("expected a verified address to resolve to its user, got %v (%v)", grantee, err)
+	}
+}
@@ -25,13 +25,53 @@ type SearchService interface {
 
 	// ReindexAllNotes reindexes all notes for a user
 	ReindexAllNotes(ctx context.Context, userID string) error
+
+	// Search runs a filtered search and returns ranked results with snippets
+	Search(ctx context.Context, req NoteSearchRequest) ([]*NoteSearchResult, int64, error)
+}
+
+// NoteSearchRequest represents a filtered note search. With a WorkspaceID it
+// searches that workspace's notes instead of the user's own.
+type NoteSearchRequest struct {
+	UserID      string
+	WorkspaceID *string
+	Query       string
+	FolderID    *string
+	Status      *models.NoteStatus
+	TagIDs      []string
+	TagMatch    repository.TagMatchMode
+	Limit       int
+	Offset      int
+}
+
+// NoteSearchResult is a ranked search hit. Hybrid results also carry the best
+// matching chunk and the score each ranker gave the note.
+type NoteSearchResult struct {
+	Note           *models.Note `json:"note"`
+	Score          float64      `json:"score"`
+	TitleHighlight string       `json:"title_highlight,omitempty"`
+	Snippet        string       `json:"snippet,omitempty"`
+	ChunkIndex     *int         `json:"chunk_index,omitempty"`
+	ChunkText      string       `json:"chunk_text,omitempty"`
+	LexicalScore   *float64     `json:"lexical_score,omitempty"`
+	VectorScore    *float64     `json:"vector_score,omitempty"`
+}
+
+// SearchNoteList unwraps search results into their notes
+func SearchNoteList(results []*NoteSearchResult) []*models.Note {
+	notes := make([]*models.Note, 0, len(results))
+	for _, result := range results {
+		notes = append(notes, result.Note)
+	}
+	return notes
 }
 
 // searchService implements SearchService
 type searchService struct {
-	embeddings  embeddings.EmbeddingProvider
-	vectorStore vectorstore.VectorStore
-	noteRepo    repository.NoteRepository
+	embeddings    embeddings.EmbeddingProvider
+	vectorStore   vectorstore.VectorStore
+	noteRepo      repository.NoteRepository
+	workspaceRepo repository.WorkspaceRepository
 }
 
 // NewSearchService creates a new search service
@@ -39,11 +79,13 @@ func NewSearchService(
 	embeddingProvider embeddings.EmbeddingProvider,
 	vectorStore vectorstore.VectorStore,
 	noteRepo repository.NoteRepository,
+	workspaceRepo repository.WorkspaceRepository,
 ) SearchService {
 	return &searchService{
-		embeddings:  embeddingProvider,
-		vectorStore: vectorStore,
-		noteRepo:    noteRepo,
+		embeddings:    embeddingProvider,
+		vectorStore:   vectorStore,
+		noteRepo:      noteRepo,
+		workspaceRepo: workspaceRepo,
 	}
 }
 
@@ -118,6 +160,160 @@ func (s *searchService) SearchNotes(ctx context.Context, query string, userID st
 	return notes, total, nil
 }
 
+// maxSemanticCandidates caps how many matches Search asks each namespace for
+const maxSemanticCandidates = 512
+
+// Search performs semantic search and applies the filters to the matched
+// notes. The vector store knows nothing of folders, tags or status, so it is
+// asked for more matches until the page fills up. Pinecone only returns note
+// IDs, so results carry their rank as the score. The total is exact when the
+// store ran out of matches and -1 when there may be more.
+func (s *searchService) Search(ctx context.Context, req NoteSearchRequest) ([]*NoteSearchResult, int64, error) {
+	if req.Query == "" {
+		return nil, 0, fmt.Errorf("query cannot be empty")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	namespaces, err := s.searchNamespaces(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	want := req.Offset + limit
+	seen := make(map[string]struct{})
+	var matched []*models.Note
+	exhausted := false
+	for topK := min(want*2, maxSemanticCandidates); ; topK = min(topK*2, maxSemanticCandidates) {
+		ids, more, err := s.rankedNoteIDs(ctx, req.Query, topK, namespaces)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, id := range ids {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			note, err := s.noteRepo.GetByID(ctx, id)
+			if err != nil {
+				log.Printf("Warning: failed to fetch note %s: %v", id, err)
+				continue
+			}
+			if noteMatchesSearchFilters(note, req) {
+				matched = append(matched, note)
+			}
+		}
+		exhausted = !more
+		if exhausted || len(matched) > want || topK == maxSemanticCandidates {
+			break
+		}
+	}
+
+	total := int64(-1)
+	if exhausted {
+		total = int64(len(matched))
+	}
+	if req.Offset >= len(matched) {
+		return []*NoteSearchResult{}, total, nil
+	}
+	page := matched[req.Offset:min(want, len(matched))]
+	results := make([]*NoteSearchResult, 0, len(page))
+	for i, note := range page {
+		results = append(results, &NoteSearchResult{
+			Note:  note,
+			Score: 1 / float64(req.Offset+i+1),
+		})
+	}
+	return results, total, nil
+}
+
+// searchNamespaces lists the users whose vector store namespaces hold the
+// notes a search covers: every member of the workspace, or the user alone
+func (s *searchService) searchNamespaces(ctx context.Context, req NoteSearchRequest) ([]string, error) {
+	if req.WorkspaceID == nil || s.workspaceRepo == nil {
+		return []string{req.UserID}, nil
+	}
+	members, err := s.workspaceRepo.ListMembers(ctx, *req.WorkspaceID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	namespaces := make([]string, 0, len(members))
+	for _, member := range members {
+		namespaces = append(namespaces, member.UserID)
+	}
+	return namespaces, nil
+}
+
+// rankedNoteIDs asks each namespace for its topK matches and interleaves
+// them by rank. more reports whether some namespace may hold further matches.
+func (s *searchService) rankedNoteIDs(ctx context.Context, query string, topK int, namespaces []string) ([]string, bool, error) {
+	lists := make([][]string, 0, len(namespaces))
+	more := false
+	for _, namespace := range namespaces {
+		ids, err := s.vectorStore.SearchText(ctx, query, topK, namespace)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to search vector store: %w", err)
+		}
+		lists = append(lists, ids)
+		if len(ids) >= topK {
+			more = true
+		}
+	}
+
+	var ranked []string
+	seen := make(map[string]struct{})
+	for rank := 0; rank < topK; rank++ {
+		for _, ids := range lists {
+			if rank >= len(ids) {
+				continue
+			}
+			if _, ok := seen[ids[rank]]; ok {
+				continue
+			}
+			seen[ids[rank]] = struct{}{}
+			ranked = append(ranked, ids[rank])
+		}
+	}
+	return ranked, more, nil
+}
+
+// noteMatchesSearchFilters applies folder, status and tag filters in memory
+func noteMatchesSearchFilters(note *models.Note, req NoteSearchRequest) bool {
+	if req.WorkspaceID != nil {
+		if note.WorkspaceID == nil || *note.WorkspaceID != *req.WorkspaceID {
+			return false
+		}
+	} else if note.UserID != req.UserID {
+		return false
+	}
+	if req.FolderID != nil && (note.FolderID == nil || *note.FolderID != *req.FolderID) {
+		return false
+	}
+	if req.Status != nil && note.Status != *req.Status {
+		return false
+	}
+	if len(req.TagIDs) == 0 {
+		return true
+	}
+
+	noteTags := make(map[string]struct{}, len(note.Tags))
+	for _, tag := range note.Tags {
+		noteTags[tag.ID] = struct{}{}
+	}
+	matched := 0
+	for _, id := range req.TagIDs {
+		if _, ok := noteTags[id]; ok {
+			matched++
+		}
+	}
+	if req.TagMatch == repository.TagMatchAll {
+		return matched == len(req.TagIDs)
+	}
+	return matched > 0
+}
+
 // DeleteNoteIndex removes a note from the vector store
 func (s *searchService) DeleteNoteIndex(ctx context.Context, noteID string) error {
 	if noteID == "" {
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/vectorstore"
+)
+
+// fakeTextVectorStore ranks note IDs per user namespace
+type fakeTextVectorStore struct {
+	vectorstore.VectorStore
+	namespaces map[string][]string
+	topKs      []int
+}
+
+func (f *fakeTextVectorStore) SearchText(ctx context.Context, query string, topK int, userID string) ([]string, error) {
+	f.topKs = append(f.topKs, topK)
+	ids := f.namespaces[userID]
+	return ids[:min(topK, len(ids))], nil
+}
+
+type fakeSearchNoteRepo struct {
+	repository.NoteRepository
+	notes map[string]*models.Note
+}
+
+func (f *fakeSearchNoteRepo) GetByID(ctx context.Context, id string) (*models.Note, error) {
+	note, ok := f.notes[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return note, nil
+}
+
+type fakeSearchWorkspaceRepo struct {
+	repository.WorkspaceRepository
+	members map[string][]string
+}
+
+func (f *fakeSearchWorkspaceRepo) ListMembers(ctx context.Context, workspaceID string) ([]*models.WorkspaceMember, error) {
+	var members []*models.WorkspaceMember
+	for _, userID := range f.members[workspaceID] {
+		members = append(members, &models.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID})
+	}
+	return members, nil
+}
+
+// addSearchNotes indexes count notes of a user in their namespace, in rank
+// order, and returns their IDs
+func addSearchNotes(store *fakeTextVectorStore, repo *fakeSearchNoteRepo, userID string, count int, setup func(i int, note *models.Note)) []string {
+	ids := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		note := &models.Note{UserID: userID}
+		note.ID = fmt.Sprintf("%s-note-%d", userID, i)
+		if setup != nil {
+			setup(i, note)
+		}
+		repo.notes[note.ID] = note
+		ids = append(ids, note.ID)
+	}
+	store.namespaces[userID] = append(store.namespaces[userID], ids...)
+	return ids
+}
+
+func newSemanticTestService(members map[string][]string) (*searchService, *fakeTextVectorStore, *fakeSearchNoteRepo) {
+	store := &fakeTextVectorStore{namespaces: map[string][]string{}}
+	notes := &fakeSearchNoteRepo{notes: map[string]*models.Note{}}
+	return &searchService{vectorStore: store, noteRepo: notes, workspaceRepo: &fakeSearchWorkspaceRepo{members: members}}, store, notes
+}
+
+func TestSemanticSearchFetchesMoreUntilThePageIsFull(t *testing.T) {
+	s, store, notes := newSemanticTestService(nil)
+	folder := "folder-1"
+	ids := addSearchNotes(store, notes, "user-1", 10, func(i int, note *models.Note) {
+		if i%3 == 0 {
+			note.FolderID = &folder
+		}
+	})
+
+	results, total, err := s.Search(context.Background(), NoteSearchRequest{UserID: "user-1", Query: "plan", FolderID: &folder, Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 2 || results[0].Note.ID != ids[3] || results[1].Note.ID != ids[6] {
+		t.Fatalf("expected the second and third folder notes, got %+v", results)
+	}
+	if total != 4 {
+		t.Fatalf("expected an exact total of 4 once the store ran out, got %d", total)
+	}
+	if len(store.topKs) < 2 {
+		t.Fatalf("expected the store to be asked again for more matches, got %v", store.topKs)
+	}
+}
+
+func TestSemanticSearchLeavesTotalUnknownWhileMatchesRemain(t *testing.T) {
+	s, store, notes := newSemanticTestService(nil)
+	addSearchNotes(store, notes, "user-1", 100, nil)
+
+	results, total, err := s.Search(context.Background(), NoteSearchRequest{UserID: "user-1", Query: "plan", Limit: 10})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(results) != 10 || total != -1 {
+		t.Fatalf("expected a full page and an unknown total, got %d results and total %d", len(results), total)
+	}
+}
+
+func TestSemanticSearchCoversTeammatesInTheWorkspace(t *testing.T) {
+	team := "team"
+	s, store, notes := newSemanticTestService(map[string][]string{team: {"user-1", "user-2"}})
+	mine := addSearchNotes(store, notes, "user-1", 1, func(i int, note *models.Note) { note.WorkspaceID = &team })
+	// The teammate's second note is in their personal workspace
+	theirs := addSearchNotes(store, notes, "user-2", 2, func(i int, note *models.Note) {
+		if i == 0 {
+			note.WorkspaceID = &team
+		}
+	})
+
+	results, total, err := s.Search(context.Background(), NoteSearchRequest{UserID: "user-1", WorkspaceID: &team, Query: "plan"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if total != 2 || len(results) != 2 || results[0].Note.ID != mine[0] || results[1].Note.ID != theirs[0] {
+		t.Fatalf("expected both members' workspace notes, got %d results and total %d", len(results), total)
+	}
+}
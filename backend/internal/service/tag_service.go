@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+// TagService defines the interface for tag business logic
+type TagService interface {
+	CreateTag(ctx context.Context, req CreateTagRequest) (*models.Tag, error)
+	GetTagByID(ctx context.Context, id string) (*models.Tag, error)
+	UpdateTag(ctx context.Context, id string, req UpdateTagRequest) (*models.Tag, error)
+	DeleteTag(ctx context.Context, id string) error
+	ListTags(ctx context.Context, userID string) ([]*repository.TagWithCount, error)
+	ListWorkspaceTags(ctx context.Context, workspaceID string) ([]*repository.TagWithCount, error)
+}
+
+// CreateTagRequest represents the request to create a tag
+type CreateTagRequest struct {
+	Name        string  `json:"name" validate:"required,min=1,max=50"`
+	Color       string  `json:"color,omitempty"`
+	UserID      string  `json:"user_id" validate:"required"`
+	WorkspaceID *string `json:"workspace_id,omitempty"`
+}
+
+// UpdateTagRequest represents the request to update a tag
+type UpdateTagRequest struct {
+	Name  string `json:"name,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+var tagColorRegex = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// tagService implements TagService
+type tagService struct {
+	repo repository.TagRepository
+}
+
+// NewTagService creates a new tag service
+func NewTagService(repo repository.TagRepository) TagService {
+	return &tagService{repo: repo}
+}
+
+// CreateTag creates a new tag for a user
+func (s *tagService) CreateTag(ctx context.Context, req CreateTagRequest) (*models.Tag, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 50 {
+		return nil, ErrValidationFailed
+	}
+	if req.Color != "" && !tagColorRegex.MatchString(req.Color) {
+		return nil, ErrValidationFailed
+	}
+
+	if err := s.ensureNameAvailable(ctx, req.UserID, req.WorkspaceID, name, ""); err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{
+		Name:        name,
+		Color:       req.Color,
+		UserID:      req.UserID,
+		WorkspaceID: req.WorkspaceID,
+	}
+	if err := s.repo.Create(ctx, tag); err != nil {
+		return nil, ErrInternalServerError
+	}
+	return tag, nil
+}
+
+// GetTagByID retrieves a tag by ID
+func (s *tagService) GetTagByID(ctx context.Context, id string) (*models.Tag, error) {
+	tag, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	return tag, nil
+}
+
+// UpdateTag renames or recolors a tag
+func (s *tagService) UpdateTag(ctx context.Context, id string, req UpdateTagRequest) (*models.Tag, error) {
+	tag, err := s.GetTagByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		if len(name) > 50 {
+			return nil, ErrValidationFailed
+		}
+		if err := s.ensureNameAvailable(ctx, tag.UserID, tag.WorkspaceID, name, tag.ID); err != nil {
+			return nil, err
+		}
+		tag.Name = name
+	}
+	if req.Color != "" {
+		if !tagColorRegex.MatchString(req.Color) {
+			return nil, ErrValidationFailed
+		}
+		tag.Color = req.Color
+	}
+
+	if err := s.repo.Update(ctx, tag); err != nil {
+		return nil, ErrInternalServerError
+	}
+	return tag, nil
+}
+
+// DeleteTag deletes a tag and detaches it from its notes
+func (s *tagService) DeleteTag(ctx context.Context, id string) error {
+	if _, err := s.GetTagByID(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// ListTags lists a user's tags with note counts
+func (s *tagService) ListTags(ctx context.Context, userID string) ([]*repository.TagWithCount, error) {
+	tags, err := s.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return tags, nil
+}
+
+// ListWorkspaceTags lists every member's tags in a workspace with note counts
+func (s *tagService) ListWorkspaceTags(ctx context.Context, workspaceID string) ([]*repository.TagWithCount, error) {
+	tags, err := s.repo.ListByWorkspaceID(ctx, workspaceID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return tags, nil
+}
+
+// ensureNameAvailable rejects a name already used by another tag of the
+// workspace, or of the user when the tag belongs to no workspace
+func (s *tagService) ensureNameAvailable(ctx context.Context, userID string, workspaceID *string, name, exceptID string) error {
+	existing, err := s.repo.GetByName(ctx, userID, workspaceID, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return ErrInternalServerError
+	}
+	if existing.ID != exceptID {
+		return ErrTagAlreadyExists
+	}
+	return nil
+}
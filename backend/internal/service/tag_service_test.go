@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+type fakeTagRepo struct {
+	repository.TagRepository
+	tags map[string]*models.Tag
+}
+
+func (f *fakeTagRepo) Create(ctx context.Context, tag *models.Tag) error {
+	tag.ID = fmt.Sprintf("tag-%d", len(f.tags)+1)
+	f.tags[tag.ID] = tag
+	return nil
+}
+
+func (f *fakeTagRepo) GetByID(ctx context.Context, id string) (*models.Tag, error) {
+	tag, ok := f.tags[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *tag
+	return &copied, nil
+}
+
+func (f *fakeTagRepo) GetByName(ctx context.Context, userID string, workspaceID *string, name string) (*models.Tag, error) {
+	for _, tag := range f.tags {
+		inScope := tag.WorkspaceID == nil && tag.UserID == userID
+		if workspaceID != nil {
+			inScope = tag.WorkspaceID != nil && *tag.WorkspaceID == *workspaceID
+		}
+		if inScope && strings.EqualFold(tag.Name, name) {
+			copied := *tag
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeTagRepo) Update(ctx context.Context, tag *models.Tag) error {
+	copied := *tag
+	f.tags[tag.ID] = &copied
+	return nil
+}
+
+func TestTagNamesAreUniquePerUser(t *testing.T) {
+	s := &tagService{repo: &fakeTagRepo{tags: map[string]*models.Tag{}}}
+	ctx := context.Background()
+
+	if _, err := s.CreateTag(ctx, CreateTagRequest{Name: "Work", UserID: "user-1"}); err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+	if _, err := s.CreateTag(ctx, CreateTagRequest{Name: " work ", UserID: "user-1"}); !errors.Is(err, ErrTagAlreadyExists) {
+		t.Fatalf("expected a duplicate name to be rejected regardless of case, got %v", err)
+	}
+	if _, err := s.CreateTag(ctx, CreateTagRequest{Name: "Work", UserID: "user-2"}); err != nil {
+		t.Fatalf("expected another user to reuse the name, got %v", err)
+	}
+}
+
+func TestRenameTagKeepsNamesUniquePerUser(t *testing.T) {
+	s := &tagService{repo: &fakeTagRepo{tags: map[string]*models.Tag{}}}
+	ctx := context.Background()
+
+	work, err := s.CreateTag(ctx, CreateTagRequest{Name: "Work", UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+	home, err := s.CreateTag(ctx, CreateTagRequest{Name: "Home", UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+	if _, err := s.CreateTag(ctx, CreateTagRequest{Name: "Ideas", UserID: "user-2"}); err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+
+	if _, err := s.UpdateTag(ctx, home.ID, UpdateTagRequest{Name: "WORK"}); !errors.Is(err, ErrTagAlreadyExists) {
+		t.Fatalf("expected renaming onto another tag's name to be rejected, got %v", err)
+	}
+	if _, err := s.UpdateTag(ctx, work.ID, UpdateTagRequest{Name: "work"}); err != nil {
+		t.Fatalf("expected a tag to change the case of its own name, got %v", err)
+	}
+	if _, err := s.UpdateTag(ctx, home.ID, UpdateTagRequest{Name: "Ideas"}); err != nil {
+		t.Fatalf("expected a name used only by another user to be free, got %v", err)
+	}
+}
+
+func TestTagNamesAreUniquePerWorkspace(t *testing.T) {
+	s := &tagService{repo: &fakeTagRepo{tags: map[string]*models.Tag{}}}
+	ctx := context.Background()
+	personal, team := "ws-personal", "ws-team"
+
+	if _, err := s.CreateTag(ctx, CreateTagRequest{Name: "Work", UserID: "user-1", WorkspaceID: &personal}); err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+	if _, err := s.CreateTag(ctx, CreateTagRequest{Name: "Work", UserID: "user-1", WorkspaceID: &team}); err != nil {
+		t.Fatalf("expected the name to be free in another workspace, got %v", err)
+	}
+	if _, err := s.CreateTag(ctx, CreateTagRequest{Name: "work", UserID: "user-2", WorkspaceID: &team}); !errors.Is(err, ErrTagAlreadyExists) {
+		t.Fatalf("expected another member's duplicate to be rejected, got %v", err)
+	}
+
+	ideas, err := s.CreateTag(ctx, CreateTagRequest{Name: "Ideas", UserID: "user-2", WorkspaceID: &team})
+	if err != nil {
+		t.Fatalf("create tag: %v", err)
+	}
+	if _, err := s.UpdateTag(ctx, ideas.ID, UpdateTagRequest{Name: "WORK"}); !errors.Is(err, ErrTagAlreadyExists) {
+		t.Fatalf("expected renaming onto a workspace tag's name to be rejected, got %v", err)
+	}
+}
@@ -18,6 +18,7 @@ type TemplateService interface {
 	UpdateTemplate(ctx context.Context, id string, req UpdateTemplateRequest) (*models.Template, error)
 	DeleteTemplate(ctx context.Context, id string) error
 	ListTemplatesByUserID(ctx context.Context, userID string) ([]*models.Template, error)
+	ListTemplatesByWorkspaceID(ctx context.Context, workspaceID string) ([]*models.Template, error)
 }
 
 // templateService implements TemplateService
@@ -27,12 +28,13 @@ type templateService struct {
 
 // CreateTemplateRequest represents the request to create a template
 type CreateTemplateRequest struct {
-	Name    string   `json:"name" validate:"required,min=1,max=100"`
-	Icon    string   `json:"icon" validate:"required"`
-	Content string   `json:"content" validate:"required"`
-	Tags    []string `json:"tags"`
-	Color   string   `json:"color"`
-	UserID  string   `json:"user_id" validate:"required"`
+	Name        string   `json:"name" validate:"required,min=1,max=100"`
+	Icon        string   `json:"icon" validate:"required"`
+	Content     string   `json:"content" validate:"required"`
+	Tags        []string `json:"tags"`
+	Color       string   `json:"color"`
+	UserID      string   `json:"user_id" validate:"required"`
+	WorkspaceID *string  `json:"workspace_id,omitempty"`
 }
 
 // UpdateTemplateRequest represents the request to update a template
@@ -59,12 +61,13 @@ func (s *templateService) CreateTemplate(ctx context.Context, req CreateTemplate
 	}
 
 	template := &models.Template{
-		Name:    req.Name,
-		Icon:    req.Icon,
-		Content: req.Content,
-		Tags:    strings.Join(req.Tags, ","),
-		Color:   req.Color,
-		UserID:  req.UserID,
+		Name:        req.Name,
+		Icon:        req.Icon,
+		Content:     req.Content,
+		Tags:        strings.Join(req.Tags, ","),
+		Color:       req.Color,
+		UserID:      req.UserID,
+		WorkspaceID: req.WorkspaceID,
 	}
 
 	if err := s.repo.Create(ctx, template); err != nil {
@@ -145,3 +148,12 @@ func (s *templateService) ListTemplatesByUserID(ctx context.Context, userID stri
 	}
 	return templates, nil
 }
+
+// ListTemplatesByWorkspaceID retrieves every member's templates in a workspace
+func (s *templateService) ListTemplatesByWorkspaceID(ctx context.Context, workspaceID string) ([]*models.Template, error) {
+	templates, err := s.repo.ListByWorkspaceID(ctx, workspaceID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return templates, nil
+}
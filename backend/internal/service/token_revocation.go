@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+)
+
+// TokenRevocationList remembers revoked sessions until every access token
+// issued for them has expired, so ValidateToken can reject them without a
+// database round trip
+type TokenRevocationList interface {
+	Revoke(ctx context.Context, sessionID string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// revokedSessionKeyPrefix namespaces the revocation keys in Redis
+const revokedSessionKeyPrefix = "auth:revoked-session:"
+
+// redisRevocationList implements TokenRevocationList with expiring Redis keys
+type redisRevocationList struct {
+	client *redis.Client
+}
+
+// NewRedisRevocationList connects to Redis and verifies the connection
+func NewRedisRevocationList(ctx context.Context, cfg config.RedisConfig) (TokenRevocationList, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return &redisRevocationList{client: client}, nil
+}
+
+// Revoke marks a session as revoked for ttl
+func (l *redisRevocationList) Revoke(ctx context.Context, sessionID string, ttl time.Duration) error {
+	return l.client.Set(ctx, revokedSessionKeyPrefix+sessionID, 1, ttl).Err()
+}
+
+// IsRevoked reports whether a session was revoked within the last ttl
+func (l *redisRevocationList) IsRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := l.client.Exists(ctx, revokedSessionKeyPrefix+sessionID).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
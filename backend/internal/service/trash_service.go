@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/utils"
+)
+
+// TrashService defines the interface for trash business logic
+type TrashService interface {
+	ListTrash(ctx context.Context, userID string, workspaceID *string) (*TrashListing, error)
+	RestoreNote(ctx context.Context, userID string, workspaceID *string, noteID string) (*models.Note, error)
+	RestoreFolder(ctx context.Context, userID string, workspaceID *string, folderID string) (*models.Folder, error)
+	PurgeNote(ctx context.Context, userID string, workspaceID *string, noteID string) error
+	PurgeFolder(ctx context.Context, userID string, workspaceID *string, folderID string) error
+	PurgeExpired(ctx context.Context) (int, error)
+}
+
+// TrashListing is the content of a user's or a workspace's trash
+type TrashListing struct {
+	Notes         []*models.Note              `json:"notes"`
+	Folders       []*repository.TrashedFolder `json:"folders"`
+	RetentionDays int                         `json:"retention_days"`
+}
+
+// trashService implements TrashService
+type trashService struct {
+	trashRepo  repository.TrashRepository
+	folderRepo repository.FolderRepository
+	config     config.TrashConfig
+}
+
+// NewTrashService creates a new trash service
+func NewTrashService(trashRepo repository.TrashRepository, folderRepo repository.FolderRepository, cfg config.TrashConfig) TrashService {
+	return &trashService{
+		trashRepo:  trashRepo,
+		folderRepo: folderRepo,
+		config:     cfg,
+	}
+}
+
+// ListTrash lists the notes and folders a user has deleted, or with a
+// workspace, everything deleted in that workspace
+func (s *trashService) ListTrash(ctx context.Context, userID string, workspaceID *string) (*TrashListing, error) {
+	notes, err := s.trashRepo.ListNotes(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	folders, err := s.trashRepo.ListFolders(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	utils.FormatNotePreviews(notes, utils.DefaultNotePreviewLength)
+	return &TrashListing{
+		Notes:         notes,
+		Folders:       folders,
+		RetentionDays: s.config.RetentionDays,
+	}, nil
+}
+
+// RestoreNote restores a note into its original folder, or the root when
+// that folder is gone
+func (s *trashService) RestoreNote(ctx context.Context, userID string, workspaceID *string, noteID string) (*models.Note, error) {
+	note, err := s.trashRepo.GetNote(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTrashItemNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	// Notes deleted along with a folder are restored through that folder
+	if !inTrashScope(note.UserID, note.WorkspaceID, userID, workspaceID) || note.TrashedWith != nil {
+		return nil, ErrTrashItemNotFound
+	}
+
+	folderID, err := s.liveFolderOrRoot(ctx, note.UserID, note.WorkspaceID, note.FolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.trashRepo.RestoreNote(ctx, noteID, folderID); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	note.DeletedAt = gorm.DeletedAt{}
+	note.TrashedWith = nil
+	note.FolderID = folderID
+	return note, nil
+}
+
+// RestoreFolder restores a folder with its subtree and notes, back at its
+// original position among its siblings
+func (s *trashService) RestoreFolder(ctx context.Context, userID string, workspaceID *string, folderID string) (*models.Folder, error) {
+	folder, err := s.trashRepo.GetFolder(ctx, folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTrashItemNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	// Folders deleted along with an ancestor are restored through that ancestor
+	if !inTrashScope(folder.UserID, folder.WorkspaceID, userID, workspaceID) || folder.TrashedWith != nil {
+		return nil, ErrTrashItemNotFound
+	}
+
+	// Sibling order is kept per owner
+	ownerID := folder.UserID
+	parentID, err := s.liveFolderOrRoot(ctx, ownerID, folder.WorkspaceID, folder.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.folderRepo.NormalizeOrders(ctx, ownerID, parentID); err != nil {
+		return nil, ErrInternalServerError
+	}
+	maxOrder, err := s.folderRepo.GetMaxOrderByParent(ctx, ownerID, parentID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	order := clampOrder(folder.SortOrder, 1, maxOrder+1)
+	if order <= maxOrder {
+		if err := s.folderRepo.ShiftOrders(ctx, ownerID, parentID, order, maxOrder, 1, nil); err != nil {
+			return nil, ErrInternalServerError
+		}
+	}
+
+	if err := s.trashRepo.RestoreFolder(ctx, folderID, parentID, order); err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	restored, err := s.folderRepo.GetByID(ctx, folderID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return restored, nil
+}
+
+// PurgeNote permanently deletes a trashed note
+func (s *trashService) PurgeNote(ctx context.Context, userID string, workspaceID *string, noteID string) error {
+	note, err := s.trashRepo.GetNote(ctx, noteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTrashItemNotFound
+		}
+		return ErrInternalServerError
+	}
+	if !inTrashScope(note.UserID, note.WorkspaceID, userID, workspaceID) || note.TrashedWith != nil {
+		return ErrTrashItemNotFound
+	}
+
+	if err := s.trashRepo.PurgeNote(ctx, noteID); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// PurgeFolder permanently deletes a trashed folder and everything deleted with it
+func (s *trashService) PurgeFolder(ctx context.Context, userID string, workspaceID *string, folderID string) error {
+	folder, err := s.trashRepo.GetFolder(ctx, folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTrashItemNotFound
+		}
+		return ErrInternalServerError
+	}
+	if !inTrashScope(folder.UserID, folder.WorkspaceID, userID, workspaceID) || folder.TrashedWith != nil {
+		return ErrTrashItemNotFound
+	}
+
+	if err := s.trashRepo.PurgeFolder(ctx, folderID); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// PurgeExpired permanently deletes every trash entry older than the retention period
+func (s *trashService) PurgeExpired(ctx context.Context) (int, error) {
+	cutoff := time.Now().UTC().AddDate(0, 0, -s.config.RetentionDays)
+	noteIDs, folderIDs, err := s.trashRepo.ListExpired(ctx, cutoff)
+	if err != nil {
+		return 0, ErrInternalServerError
+	}
+
+	purged := 0
+	for _, id := range folderIDs {
+		if err := s.trashRepo.PurgeFolder(ctx, id); err != nil {
+			log.Printf("Warning: failed to purge trashed folder %s: %v", id, err)
+			continue
+		}
+		purged++
+	}
+	for _, id := range noteIDs {
+		if err := s.trashRepo.PurgeNote(ctx, id); err != nil {
+			log.Printf("Warning: failed to purge trashed note %s: %v", id, err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// inTrashScope reports whether a trashed item is the user's own or belongs to
+// the given workspace
+func inTrashScope(ownerID string, itemWorkspaceID *string, userID string, workspaceID *string) bool {
+	if ownerID == userID {
+		return true
+	}
+	return workspaceID != nil && itemWorkspaceID != nil && *itemWorkspaceID == *workspaceID
+}
+
+// liveFolderOrRoot returns folderID when it still exists in the item's
+// workspace, or for personal items among the owner's folders, or nil (root)
+func (s *trashService) liveFolderOrRoot(ctx context.Context, ownerID string, workspaceID *string, folderID *string) (*string, error) {
+	if folderID == nil || *folderID == "" {
+		return nil, nil
+	}
+
+	folder, err := s.folderRepo.GetByID(ctx, *folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, ErrInternalServerError
+	}
+	if workspaceID != nil {
+		if folder.WorkspaceID == nil || *folder.WorkspaceID != *workspaceID {
+			return nil, nil
+		}
+	} else if folder.UserID != ownerID {
+		return nil, nil
+	}
+
+	id := folder.ID
+	return &id, nil
+}
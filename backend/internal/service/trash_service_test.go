@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+type fakeTrashRepo struct {
+	repository.TrashRepository
+	notes    map[string]*models.Note
+	folders  map[string]*models.Folder
+	restored map[string]*string
+	purged   []string
+	expired  []string
+}
+
+func (f *fakeTrashRepo) GetNote(ctx context.Context, id string) (*models.Note, error) {
+	note, ok := f.notes[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *note
+	return &copied, nil
+}
+
+func (f *fakeTrashRepo) GetFolder(ctx context.Context, id string) (*models.Folder, error) {
+	folder, ok := f.folders[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *folder
+	return &copied, nil
+}
+
+func (f *fakeTrashRepo) RestoreNote(ctx context.Context, id string, folderID *string) error {
+	f.restored[id] = folderID
+	return nil
+}
+
+func (f *fakeTrashRepo) PurgeNote(ctx context.Context, id string) error {
+	f.purged = append(f.purged, id)
+	return nil
+}
+
+func (f *fakeTrashRepo) PurgeFolder(ctx context.Context, id string) error {
+	f.purged = append(f.purged, id)
+	return nil
+}
+
+func (f *fakeTrashRepo) ListExpired(ctx context.Context, cutoff time.Time) ([]string, []string, error) {
+	return f.expired, nil, nil
+}
+
+type fakeLiveFolderRepo struct {
+	repository.FolderRepository
+	folders map[string]*models.Folder
+}
+
+func (f *fakeLiveFolderRepo) GetByID(ctx context.Context, id string) (*models.Folder, error) {
+	folder, ok := f.folders[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return folder, nil
+}
+
+// newTrashTestService sets up a trash with a note deleted on its own from a
+// live folder, a note deleted from a folder that is gone, and a note that
+// went to the trash with the trashed folder "old"
+func newTrashTestService() (*trashService, *fakeTrashRepo) {
+	live, old := "live", "old"
+	trash := &fakeTrashRepo{
+		notes: map[string]*models.Note{
+			"alone":       {BaseModel: models.BaseModel{ID: "alone"}, UserID: "user-1", FolderID: &live},
+			"orphan":      {BaseModel: models.BaseModel{ID: "orphan"}, UserID: "user-1", FolderID: &old},
+			"with-folder": {BaseModel: models.BaseModel{ID: "with-folder"}, UserID: "user-1", FolderID: &old, TrashedWith: &old},
+		},
+		folders: map[string]*models.Folder{
+			"old": {BaseModel: models.BaseModel{ID: "old"}, UserID: "user-1"},
+		},
+		restored: map[string]*string{},
+	}
+	folders := &fakeLiveFolderRepo{folders: map[string]*models.Folder{
+		"live": {BaseModel: models.BaseModel{ID: "live"}, UserID: "user-1"},
+	}}
+	return &trashService{trashRepo: trash, folderRepo: folders, config: config.TrashConfig{RetentionDays: 30}}, trash
+}
+
+func TestRestoreNoteReturnsToLiveFolderOrRoot(t *testing.T) {
+	s, trash := newTrashTestService()
+	ctx := context.Background()
+
+	note, err := s.RestoreNote(ctx, "user-1", nil, "alone")
+	if err != nil {
+		t.Fatalf("restore note: %v", err)
+	}
+	if folderID := trash.restored["alone"]; folderID == nil || *folderID != "live" || note.FolderID == nil || *note.FolderID != "live" {
+		t.Fatalf("expected the note back in its live folder, got %v", folderID)
+	}
+
+	if _, err := s.RestoreNote(ctx, "user-1", nil, "orphan"); err != nil {
+		t.Fatalf("restore note: %v", err)
+	}
+	if folderID, ok := trash.restored["orphan"]; !ok || folderID != nil {
+		t.Fatalf("expected a note from a trashed folder to go to the root, got %v", folderID)
+	}
+
+	if _, err := s.RestoreNote(ctx, "user-2", nil, "alone"); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Fatalf("expected another user's note to be hidden, got %v", err)
+	}
+}
+
+func TestNoteTrashedWithFolderIsHandledThroughFolder(t *testing.T) {
+	s, trash := newTrashTestService()
+	ctx := context.Background()
+
+	if _, err := s.RestoreNote(ctx, "user-1", nil, "with-folder"); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Fatalf("expected restoring a note deleted with its folder to be refused, got %v", err)
+	}
+	if err := s.PurgeNote(ctx, "user-1", nil, "with-folder"); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Fatalf("expected purging a note deleted with its folder to be refused, got %v", err)
+	}
+	if len(trash.restored) != 0 || len(trash.purged) != 0 {
+		t.Fatalf("expected nothing restored or purged, got %v and %v", trash.restored, trash.purged)
+	}
+
+	if err := s.PurgeFolder(ctx, "user-1", nil, "old"); err != nil {
+		t.Fatalf("purge folder: %v", err)
+	}
+	if len(trash.purged) != 1 || trash.purged[0] != "old" {
+		t.Fatalf("expected the folder to be purged, got %v", trash.purged)
+	}
+}
+
+func TestWorkspaceTrashIsSharedWithMembers(t *testing.T) {
+	s, trash := newTrashTestService()
+	ctx := context.Background()
+	team, other := "team", "other"
+	trash.notes["alone"].WorkspaceID = &team
+	trash.notes["orphan"].WorkspaceID = &team
+
+	if _, err := s.RestoreNote(ctx, "user-2", nil, "alone"); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Fatalf("expected the note to be hidden outside its workspace, got %v", err)
+	}
+	if _, err := s.RestoreNote(ctx, "user-2", &other, "alone"); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Fatalf("expected the note to be hidden from another workspace, got %v", err)
+	}
+	if _, err := s.RestoreNote(ctx, "user-2", &team, "alone"); err != nil {
+		t.Fatalf("expected a workspace member to restore the note, got %v", err)
+	}
+	if folderID := trash.restored["alone"]; folderID != nil {
+		t.Fatalf("expected the owner's personal folder not to take the note back, got %v", *folderID)
+	}
+	if err := s.PurgeNote(ctx, "user-2", &team, "orphan"); err != nil {
+		t.Fatalf("expected a workspace member to purge the note, got %v", err)
+	}
+}
+
+func TestPurgeExpiredPurgesEveryExpiredEntry(t *testing.T) {
+	s, trash := newTrashTestService()
+	trash.expired = []string{"alone", "orphan"}
+
+	purged, err := s.PurgeExpired(context.Background())
+	if err != nil {
+		t.Fatalf("purge expired: %v", err)
+	}
+	if purged != 2 || len(trash.purged) != 2 {
+		t.Fatalf("expected two notes purged, got %d (%v)", purged, trash.purged)
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+// WorkspaceService defines the interface for workspaces, the tenants notes,
+// folders, templates and tags belong to, and their members
+type WorkspaceService interface {
+	ResolveActive(ctx context.Context, userID, workspaceID string) (*models.Workspace, models.WorkspaceRole, error)
+	MemberRole(ctx context.Context, workspaceID, userID string) (models.WorkspaceRole, error)
+	ListWorkspaces(ctx context.Context, userID string) ([]*models.Workspace, error)
+	CreateWorkspace(ctx context.Context, userID, name string) (*models.Workspace, error)
+	RenameWorkspace(ctx context.Context, userID, workspaceID, name string) (*models.Workspace, error)
+	DeleteWorkspace(ctx context.Context, userID, workspaceID string) error
+	ListMembers(ctx context.Context, userID, workspaceID string) ([]*models.WorkspaceMember, error)
+	AddMember(ctx context.Context, userID, workspaceID string, req AddWorkspaceMemberRequest) (*models.WorkspaceMember, error)
+	UpdateMemberRole(ctx context.Context, userID, workspaceID, memberUserID string, role models.WorkspaceRole) (*models.WorkspaceMember, error)
+	RemoveMember(ctx context.Context, userID, workspaceID, memberUserID string) error
+}
+
+// AddWorkspaceMemberRequest names the user to add, by user ID or by email,
+// and their role. Adding someone who is already a member changes their role.
+type AddWorkspaceMemberRequest struct {
+	UserID string               `json:"user_id"`
+	Email  string               `json:"email"`
+	Role   models.WorkspaceRole `json:"role"`
+}
+
+// workspaceService implements WorkspaceService
+type workspaceService struct {
+	repo     repository.WorkspaceRepository
+	userRepo repository.UserRepository
+}
+
+// NewWorkspaceService creates a new workspace service
+func NewWorkspaceService(repo repository.WorkspaceRepository, userRepo repository.UserRepository) WorkspaceService {
+	return &workspaceService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// ResolveActive returns the workspace a request acts in. Without an explicit
+// workspace that is the user's personal one, created on first use. Users who
+// are not members get ErrWorkspaceNotFound.
+func (s *workspaceService) ResolveActive(ctx context.Context, userID, workspaceID string) (*models.Workspace, models.WorkspaceRole, error) {
+	if workspaceID == "" {
+		workspace, err := s.personalWorkspace(ctx, userID)
+		if err != nil {
+			return nil, "", err
+		}
+		return workspace, models.WorkspaceRoleOwner, nil
+	}
+
+	workspace, err := s.repo.GetByID(ctx, workspaceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrWorkspaceNotFound
+		}
+		return nil, "", ErrInternalServerError
+	}
+	role, err := s.MemberRole(ctx, workspace.ID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if role == "" {
+		return nil, "", ErrWorkspaceNotFound
+	}
+	workspace.Role = role
+	return workspace, role, nil
+}
+
+func (s *workspaceService) personalWorkspace(ctx context.Context, userID string) (*models.Workspace, error) {
+	workspace, err := s.repo.GetPersonal(ctx, userID)
+	if err == nil {
+		workspace.Role = models.WorkspaceRoleOwner
+		return workspace, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInternalServerError
+	}
+
+	workspace = &models.Workspace{Name: "Personal", OwnerID: userID, Personal: true}
+	if err := s.repo.Create(ctx, workspace); err != nil {
+		// A concurrent request may have created it first
+		existing, getErr := s.repo.GetPersonal(ctx, userID)
+		if getErr != nil {
+			return nil, ErrInternalServerError
+		}
+		workspace = existing
+	}
+	workspace.Role = models.WorkspaceRoleOwner
+	return workspace, nil
+}
+
+// MemberRole returns a user's role in a workspace, or an empty role when
+// they are not a member
+func (s *workspaceService) MemberRole(ctx context.Context, workspaceID, userID string) (models.WorkspaceRole, error) {
+	if workspaceID == "" || userID == "" {
+		return "", nil
+	}
+	member, err := s.repo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", ErrInternalServerError
+	}
+	return member.Role, nil
+}
+
+// ListWorkspaces lists the workspaces a user belongs to, making sure their
+// personal workspace exists
+func (s *workspaceService) ListWorkspaces(ctx context.Context, userID string) ([]*models.Workspace, error) {
+	if _, err := s.personalWorkspace(ctx, userID); err != nil {
+		return nil, err
+	}
+	workspaces, err := s.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return workspaces, nil
+}
+
+// CreateWorkspace creates a team workspace owned by the user
+func (s *workspaceService) CreateWorkspace(ctx context.Context, userID, name string) (*models.Workspace, error) {
+	name, err := validWorkspaceName(name)
+	if err != nil {
+		return nil, err
+	}
+	workspace := &models.Workspace{Name: name, OwnerID: userID}
+	if err := s.repo.Create(ctx, workspace); err != nil {
+		return nil, ErrInternalServerError
+	}
+	workspace.Role = models.WorkspaceRoleOwner
+	return workspace, nil
+}
+
+// RenameWorkspace renames a workspace (admins and the owner)
+func (s *workspaceService) RenameWorkspace(ctx context.Context, userID, workspaceID, name string) (*models.Workspace, error) {
+	name, err := validWorkspaceName(name)
+	if err != nil {
+		return nil, err
+	}
+	workspace, role, err := s.managed(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Rename(ctx, workspace.ID, name); err != nil {
+		return nil, ErrInternalServerError
+	}
+	workspace.Name = name
+	workspace.Role = role
+	return workspace, nil
+}
+
+// DeleteWorkspace deletes an empty team workspace (owner only). Notes and
+// folders still in its trash are purged with it.
+func (s *workspaceService) DeleteWorkspace(ctx context.Context, userID, workspaceID string) error {
+	workspace, _, err := s.managed(ctx, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if workspace.OwnerID != userID || workspace.Personal {
+		return ErrWorkspaceForbidden
+	}
+
+	count, err := s.repo.CountNotesAndFolders(ctx, workspace.ID)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	if count > 0 {
+		return ErrWorkspaceNotEmpty
+	}
+	if err := s.repo.Delete(ctx, workspace.ID); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// ListMembers lists a workspace's members (any member)
+func (s *workspaceService) ListMembers(ctx context.Context, userID, workspaceID string) ([]*models.WorkspaceMember, error) {
+	workspace, _, err := s.ResolveActive(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.repo.ListMembers(ctx, workspace.ID)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return members, nil
+}
+
+// AddMember adds an existing user to a team workspace (admins and the owner)
+func (s *workspaceService) AddMember(ctx context.Context, userID, workspaceID string, req AddWorkspaceMemberRequest) (*models.WorkspaceMember, error) {
+	if !isAssignableWorkspaceRole(req.Role) {
+		return nil, ErrValidationFailed
+	}
+	workspace, _, err := s.managed(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.Personal {
+		return nil, ErrValidationFailed
+	}
+
+	user, err := s.resolveUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == workspace.OwnerID {
+		return nil, ErrValidationFailed
+	}
+
+	existing, err := s.repo.GetMember(ctx, workspace.ID, user.ID)
+	if err == nil {
+		if err := s.repo.UpdateMemberRole(ctx, workspace.ID, user.ID, req.Role); err != nil {
+			return nil, ErrInternalServerError
+		}
+		existing.Role = req.Role
+		existing.User = *user
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInternalServerError
+	}
+
+	member := &models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: user.ID, Role: req.Role}
+	if err := s.repo.AddMember(ctx, member); err != nil {
+		return nil, ErrInternalServerError
+	}
+	member.User = *user
+	return member, nil
+}
+
+// UpdateMemberRole changes a member's role (admins and the owner). The
+// owner's role cannot change.
+func (s *workspaceService) UpdateMemberRole(ctx context.Context, userID, workspaceID, memberUserID string, role models.WorkspaceRole) (*models.WorkspaceMember, error) {
+	if !isAssignableWorkspaceRole(role) {
+		return nil, ErrValidationFailed
+	}
+	workspace, _, err := s.managed(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if memberUserID == workspace.OwnerID {
+		return nil, ErrValidationFailed
+	}
+
+	member, err := s.repo.GetMember(ctx, workspace.ID, memberUserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkspaceMemberNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	if err := s.repo.UpdateMemberRole(ctx, workspace.ID, member.UserID, role); err != nil {
+		return nil, ErrInternalServerError
+	}
+	member.Role = role
+	return member, nil
+}
+
+// RemoveMember removes a member (admins and the owner), or lets a member
+// leave. The owner cannot be removed.
+func (s *workspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberUserID string) error {
+	var workspace *models.Workspace
+	var err error
+	if memberUserID == userID {
+		workspace, _, err = s.ResolveActive(ctx, userID, workspaceID)
+	} else {
+		workspace, _, err = s.managed(ctx, userID, workspaceID)
+	}
+	if err != nil {
+		return err
+	}
+	if memberUserID == workspace.OwnerID {
+		return ErrValidationFailed
+	}
+
+	if _, err := s.repo.GetMember(ctx, workspace.ID, memberUserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWorkspaceMemberNotFound
+		}
+		return ErrInternalServerError
+	}
+	if err := s.repo.RemoveMember(ctx, workspace.ID, memberUserID); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// managed resolves a workspace the user may manage
+func (s *workspaceService) managed(ctx context.Context, userID, workspaceID string) (*models.Workspace, models.WorkspaceRole, error) {
+	if workspaceID == "" {
+		return nil, "", ErrWorkspaceNotFound
+	}
+	workspace, role, err := s.ResolveActive(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !role.CanManage() {
+		return nil, "", ErrWorkspaceForbidden
+	}
+	return workspace, role, nil
+}
+
+func (s *workspaceService) resolveUser(ctx context.Context, req AddWorkspaceMemberRequest) (*models.User, error) {
+	var user *models.User
+	var err error
+	if userID := strings.TrimSpace(req.UserID); userID != "" {
+		user, err = s.userRepo.GetByID(ctx, userID)
+	} else if email := strings.ToLower(strings.TrimSpace(req.Email)); email != "" {
+		user, err = s.userRepo.GetByEmail(ctx, email)
+	} else {
+		return nil, ErrValidationFailed
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, ErrInternalServerError
+	}
+	return user, nil
+}
+
+func validWorkspaceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", ErrValidationFailed
+	}
+	return name, nil
+}
+
+// isAssignableWorkspaceRole reports whether a role can be given to a member.
+// There is exactly one owner, the workspace's creator.
+func isAssignableWorkspaceRole(role models.WorkspaceRole) bool {
+	return role.Valid() && role != models.WorkspaceRoleOwner
+}
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/repository"
+)
+
+type fakeWorkspaceRepo struct {
+	repository.WorkspaceRepository
+	workspaces map[string]*models.Workspace
+	members    map[string]map[string]models.WorkspaceRole
+	contents   map[string]int64
+	created    int
+	deleted    []string
+	// createErr fails Create after storing the workspace, as when a
+	// concurrent request created it first
+	createErr error
+}
+
+func newFakeWorkspaceRepo() *fakeWorkspaceRepo {
+	return &fakeWorkspaceRepo{
+		workspaces: map[string]*models.Workspace{},
+		members:    map[string]map[string]models.WorkspaceRole{},
+		contents:   map[string]int64{},
+	}
+}
+
+func (f *fakeWorkspaceRepo) add(id, ownerID string, personal bool, members map[string]models.WorkspaceRole) {
+	workspace := &models.Workspace{Name: id, OwnerID: ownerID, Personal: personal}
+	workspace.ID = id
+	f.workspaces[id] = workspace
+	f.members[id] = map[string]models.WorkspaceRole{ownerID: models.WorkspaceRoleOwner}
+	for userID, role := range members {
+		f.members[id][userID] = role
+	}
+}
+
+func (f *fakeWorkspaceRepo) Create(ctx context.Context, workspace *models.Workspace) error {
+	f.created++
+	workspace.ID = fmt.Sprintf("ws-%d", len(f.workspaces)+1)
+	copied := *workspace
+	f.workspaces[workspace.ID] = &copied
+	f.members[workspace.ID] = map[string]models.WorkspaceRole{workspace.OwnerID: models.WorkspaceRoleOwner}
+	return f.createErr
+}
+
+func (f *fakeWorkspaceRepo) GetByID(ctx context.Context, id string) (*models.Workspace, error) {
+	workspace, ok := f.workspaces[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *workspace
+	return &copied, nil
+}
+
+func (f *fakeWorkspaceRepo) GetPersonal(ctx context.Context, userID string) (*models.Workspace, error) {
+	for _, workspace := range f.workspaces {
+		if workspace.Personal && workspace.OwnerID == userID {
+			copied := *workspace
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeWorkspaceRepo) Delete(ctx context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	delete(f.workspaces, id)
+	return nil
+}
+
+func (f *fakeWorkspaceRepo) CountNotesAndFolders(ctx context.Context, id string) (int64, error) {
+	return f.contents[id], nil
+}
+
+func (f *fakeWorkspaceRepo) GetMember(ctx context.Context, workspaceID, userID string) (*models.WorkspaceMember, error) {
+	role, ok := f.members[workspaceID][userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID, Role: role}, nil
+}
+
+func (f *fakeWorkspaceRepo) AddMember(ctx context.Context, member *models.WorkspaceMember) error {
+	f.members[member.WorkspaceID][member.UserID] = member.Role
+	return nil
+}
+
+func (f *fakeWorkspaceRepo) UpdateMemberRole(ctx context.Context, workspaceID, userID string, role models.WorkspaceRole) error {
+	f.members[workspaceID][userID] = role
+	return nil
+}
+
+func (f *fakeWorkspaceRepo) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	delete(f.members[workspaceID], userID)
+	return nil
+}
+
+// newTeamWorkspaceService has a personal workspace for the owner and a team
+// workspace with one member of each role
+func newTeamWorkspaceService() (*workspaceService, *fakeWorkspaceRepo) {
+	repo := newFakeWorkspaceRepo()
+	repo.add("ws-personal", "owner", true, nil)
+	repo.add("ws-team", "owner", false, map[string]models.WorkspaceRole{
+		"admin":  models.WorkspaceRoleAdmin,
+		"member": models.WorkspaceRoleMember,
+		"viewer": models.WorkspaceRoleViewer,
+	})
+
+	users := &fakeUserRepo{users: map[string]*models.User{}}
+	for _, id := range []string{"owner", "admin", "member", "viewer", "newcomer"} {
+		user := &models.User{Email: id + "@example.com"}
+		user.ID = id
+		users.users[id] = user
+	}
+	return &workspaceService{repo: repo, userRepo: users}, repo
+}
+
+func TestResolveActiveWorkspace(t *testing.T) {
+	cases := []struct {
+		name        string
+		setup       func(repo *fakeWorkspaceRepo)
+		userID      string
+		workspaceID string
+		wantID      string
+		wantRole    models.WorkspaceRole
+		wantErr     error
+		wantCreated int
+	}{
+		{
+			name:     "personal by default",
+			setup:    func(repo *fakeWorkspaceRepo) { repo.add("ws-personal", "user-1", true, nil) },
+			userID:   "user-1",
+			wantID:   "ws-personal",
+			wantRole: models.WorkspaceRoleOwner,
+		},
+		{
+			name:        "personal created on first use",
+			setup:       func(repo *fakeWorkspaceRepo) {},
+			userID:      "user-1",
+			wantID:      "ws-1",
+			wantRole:    models.WorkspaceRoleOwner,
+			wantCreated: 1,
+		},
+		{
+			name:        "personal created by a concurrent request",
+			setup:       func(repo *fakeWorkspaceRepo) { repo.createErr = errors.New("duplicate key") },
+			userID:      "user-1",
+			wantID:      "ws-1",
+			wantRole:    models.WorkspaceRoleOwner,
+			wantCreated: 1,
+		},
+		{
+			name: "team workspace of a member",
+			setup: func(repo *fakeWorkspaceRepo) {
+				repo.add("ws-team", "owner", false, map[string]models.WorkspaceRole{"user-1": models.WorkspaceRoleViewer})
+			},
+			userID:      "user-1",
+			workspaceID: "ws-team",
+			wantID:      "ws-team",
+			wantRole:    models.WorkspaceRoleViewer,
+		},
+		{
+			name:        "foreign workspace",
+			setup:       func(repo *fakeWorkspaceRepo) { repo.add("ws-team", "owner", false, nil) },
+			userID:      "user-1",
+			workspaceID: "ws-team",
+			wantErr:     ErrWorkspaceNotFound,
+		},
+		{
+			name:        "missing workspace",
+			setup:       func(repo *fakeWorkspaceRepo) {},
+			userID:      "user-1",
+			workspaceID: "ws-missing",
+			wantErr:     ErrWorkspaceNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		repo := newFakeWorkspaceRepo()
+		tc.setup(repo)
+		s := &workspaceService{repo: repo}
+
+		workspace, role, err := s.ResolveActive(context.Background(), tc.userID, tc.workspaceID)
+		if !errors.Is(err, tc.wantErr) {
+			t.Fatalf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+		}
+		if tc.wantErr != nil {
+			continue
+		}
+		if workspace.ID != tc.wantID || role != tc.wantRole || workspace.Role != tc.wantRole {
+			t.Fatalf("%s: expected %s as %s, got %s as %s", tc.name, tc.wantID, tc.wantRole, workspace.ID, role)
+		}
+		if repo.created != tc.wantCreated {
+			t.Fatalf("%s: expected %d workspaces created, got %d", tc.name, tc.wantCreated, repo.created)
+		}
+	}
+}
+
+func TestWorkspaceMembership(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name    string
+		run     func(s *workspaceService) error
+		wantErr error
+		// wantRoles are the expected roles afterwards, "" for not a member
+		wantRoles map[string]models.WorkspaceRole
+	}{
+		{
+			name: "admin adds a user by email",
+			run: func(s *workspaceService) error {
+				_, err := s.AddMember(ctx, "admin", "ws-team", AddWorkspaceMemberRequest{Email: " Newcomer@example.com ", Role: models.WorkspaceRoleMember})
+				return err
+			},
+			wantRoles: map[string]models.WorkspaceRole{"newcomer": models.WorkspaceRoleMember},
+		},
+		{
+			name: "adding a member again changes their role",
+			run: func(s *workspaceService) error {
+				_, err := s.AddMember(ctx, "owner", "ws-team", AddWorkspaceMemberRequest{UserID: "viewer", Role: models.WorkspaceRoleAdmin})
+				return err
+			},
+			wantRoles: map[string]models.WorkspaceRole{"viewer": models.WorkspaceRoleAdmin},
+		},
+		{
+			name: "member cannot add",
+			run: func(s *workspaceService) error {
+				_, err := s.AddMember(ctx, "member", "ws-team", AddWorkspaceMemberRequest{UserID: "newcomer", Role: models.WorkspaceRoleViewer})
+				return err
+			},
+			wantErr:   ErrWorkspaceForbidden,
+			wantRoles: map[string]models.WorkspaceRole{"newcomer": ""},
+		},
+		{
+			name: "owner role cannot be given",
+			run: func(s *workspaceService) error {
+				_, err := s.AddMember(ctx, "owner", "ws-team", AddWorkspaceMemberRequest{UserID: "newcomer", Role: models.WorkspaceRoleOwner})
+				return err
+			},
+			wantErr: ErrValidationFailed,
+		},
+		{
+			name: "owner cannot be re-added",
+			run: func(s *workspaceService) error {
+				_, err := s.AddMember(ctx, "admin", "ws-team", AddWorkspaceMemberRequest{UserID: "owner", Role: models.WorkspaceRoleViewer})
+				return err
+			},
+			wantErr:   ErrValidationFailed,
+			wantRoles: map[string]models.WorkspaceRole{"owner": models.WorkspaceRoleOwner},
+		},
+		{
+			name: "personal workspace has no members",
+			run: func(s *workspaceService) error {
+				_, err := s.AddMember(ctx, "owner", "ws-personal", AddWorkspaceMemberRequest{UserID: "newcomer", Role: models.WorkspaceRoleMember})
+				return err
+			},
+			wantErr: ErrValidationFailed,
+		},
+		{
+			name: "unknown user",
+			run: func(s *workspaceService) error {
+				_, err := s.AddMember(ctx, "owner", "ws-team", AddWorkspaceMemberRequest{Email: "nobody@example.com", Role: models.WorkspaceRoleMember})
+				return err
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "admin changes a role",
+			run: func(s *workspaceService) error {
+				_, err := s.UpdateMemberRole(ctx, "admin", "ws-team", "member", models.WorkspaceRoleViewer)
+				return err
+			},
+			wantRoles: map[string]models.WorkspaceRole{"member": models.WorkspaceRoleViewer},
+		},
+		{
+			name: "owner role cannot change",
+			run: func(s *workspaceService) error {
+				_, err := s.UpdateMemberRole(ctx, "admin", "ws-team", "owner", models.WorkspaceRoleViewer)
+				return err
+			},
+			wantErr:   ErrValidationFailed,
+			wantRoles: map[string]models.WorkspaceRole{"owner": models.WorkspaceRoleOwner},
+		},
+		{
+			name: "changing the role of a non-member",
+			run: func(s *workspaceService) error {
+				_, err := s.UpdateMemberRole(ctx, "owner", "ws-team", "newcomer", models.WorkspaceRoleViewer)
+				return err
+			},
+			wantErr: ErrWorkspaceMemberNotFound,
+		},
+		{
+			name: "viewer cannot change roles",
+			run: func(s *workspaceService) error {
+				_, err := s.UpdateMemberRole(ctx, "viewer", "ws-team", "viewer", models.WorkspaceRoleAdmin)
+				return err
+			},
+			wantErr:   ErrWorkspaceForbidden,
+			wantRoles: map[string]models.WorkspaceRole{"viewer": models.WorkspaceRoleViewer},
+		},
+		{
+			name:      "member leaves",
+			run:       func(s *workspaceService) error { return s.RemoveMember(ctx, "viewer", "ws-team", "viewer") },
+			wantRoles: map[string]models.WorkspaceRole{"viewer": ""},
+		},
+		{
+			name:      "admin removes a member",
+			run:       func(s *workspaceService) error { return s.RemoveMember(ctx, "admin", "ws-team", "member") },
+			wantRoles: map[string]models.WorkspaceRole{"member": ""},
+		},
+		{
+			name:      "member cannot remove others",
+			run:       func(s *workspaceService) error { return s.RemoveMember(ctx, "member", "ws-team", "viewer") },
+			wantErr:   ErrWorkspaceForbidden,
+			wantRoles: map[string]models.WorkspaceRole{"viewer": models.WorkspaceRoleViewer},
+		},
+		{
+			name:      "owner cannot leave",
+			run:       func(s *workspaceService) error { return s.RemoveMember(ctx, "owner", "ws-team", "owner") },
+			wantErr:   ErrValidationFailed,
+			wantRoles: map[string]models.WorkspaceRole{"owner": models.WorkspaceRoleOwner},
+		},
+		{
+			name:      "admin cannot remove the owner",
+			run:       func(s *workspaceService) error { return s.RemoveMember(ctx, "admin", "ws-team", "owner") },
+			wantErr:   ErrValidationFailed,
+			wantRoles: map[string]models.WorkspaceRole{"owner": models.WorkspaceRoleOwner},
+		},
+		{
+			name:    "non-member cannot leave",
+			run:     func(s *workspaceService) error { return s.RemoveMember(ctx, "newcomer", "ws-team", "newcomer") },
+			wantErr: ErrWorkspaceNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		s, repo := newTeamWorkspaceService()
+		if err := tc.run(s); !errors.Is(err, tc.wantErr) {
+			t.Fatalf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+		}
+		for userID, want := range tc.wantRoles {
+			if got := repo.members["ws-team"][userID]; got != want {
+				t.Fatalf("%s: expected %s to be %q, got %q", tc.name, userID, want, got)
+			}
+		}
+	}
+}
+
+func TestDeleteWorkspace(t *testing.T) {
+	cases := []struct {
+		name        string
+		userID      string
+		workspaceID string
+		contents    int64
+		wantErr     error
+	}{
+		{name: "owner deletes an empty workspace", userID: "owner", workspaceID: "ws-team"},
+		{name: "workspace with notes", userID: "owner", workspaceID: "ws-team", contents: 2, wantErr: ErrWorkspaceNotEmpty},
+		{name: "personal workspace", userID: "owner", workspaceID: "ws-personal", wantErr: ErrWorkspaceForbidden},
+		{name: "admin is not the owner", userID: "admin", workspaceID: "ws-team", wantErr: ErrWorkspaceForbidden},
+		{name: "member", userID: "member", workspaceID: "ws-team", wantErr: ErrWorkspaceForbidden},
+		{name: "non-member", userID: "newcomer", workspaceID: "ws-team", wantErr: ErrWorkspaceNotFound},
+	}
+
+	for _, tc := range cases {
+		s, repo := newTeamWorkspaceService()
+		repo.contents[tc.workspaceID] = tc.contents
+
+		err := s.DeleteWorkspace(context.Background(), tc.userID, tc.workspaceID)
+		if !errors.Is(err, tc.wantErr) {
+			t.Fatalf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+		}
+		if deleted := len(repo.deleted) == 1; deleted != (tc.wantErr == nil) {
+			t.Fatalf("%s: expected deleted=%t, got %v", tc.name, tc.wantErr == nil, repo.deleted)
+		}
+	}
+}
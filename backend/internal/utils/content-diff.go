@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DiffOp is the kind of change a DiffLine represents.
+type DiffOp string
+
+const (
+	DiffOpEqual  DiffOp = "equal"
+	DiffOpInsert DiffOp = "insert"
+	DiffOpDelete DiffOp = "delete"
+)
+
+// DiffLine is one line of a line-based diff.
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffCells bounds the LCS table so huge notes cannot exhaust memory.
+const maxDiffCells = 4_000_000
+
+var blockBoundaryRegex = regexp.MustCompile(`(?i)(</(p|h[1-6]|li|ul|ol|blockquote|pre|div|table|tr)>|<br\s*/?>|<hr\s*/?>)`)
+
+// SplitContentLines splits note content into comparable lines. HTML content is
+// broken after every block-level element so single-line HTML still diffs per block.
+func SplitContentLines(content string) []string {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	normalized = blockBoundaryRegex.ReplaceAllString(normalized, "$1\n")
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(normalized, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		lines = append(lines, trimmed)
+	}
+	return lines
+}
+
+// DiffLines computes a line diff from a to b using a longest common subsequence.
+func DiffLines(a, b []string) []DiffLine {
+	// Strip common prefix and suffix first; most edits touch a small region.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	out := make([]DiffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		out = append(out, DiffLine{Op: DiffOpEqual, Text: line})
+	}
+	out = append(out, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		out = append(out, DiffLine{Op: DiffOpEqual, Text: line})
+	}
+	return out
+}
+
+func diffMiddle(a, b []string) []DiffLine {
+	out := make([]DiffLine, 0, len(a)+len(b))
+	if len(a) == 0 || len(b) == 0 || (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			out = append(out, DiffLine{Op: DiffOpDelete, Text: line})
+		}
+		for _, line := range b {
+			out = append(out, DiffLine{Op: DiffOpInsert, Text: line})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, DiffLine{Op: DiffOpEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{Op: DiffOpDelete, Text: a[i]})
+			i++
+		default:
+			out = append(out, DiffLine{Op: DiffOpInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, DiffLine{Op: DiffOpDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, DiffLine{Op: DiffOpInsert, Text: b[j]})
+	}
+	return out
+}
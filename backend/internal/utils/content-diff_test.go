@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitContentLinesBreaksHTMLBlocks(t *testing.T) {
+	lines := SplitContentLines("<h1>Title</h1><p>First</p><p>Second<br>line</p>")
+
+	require.Equal(t, []string{"<h1>Title</h1>", "<p>First</p>", "<p>Second<br>", "line</p>"}, lines)
+}
+
+func TestDiffLines(t *testing.T) {
+	a := []string{"a", "b", "c", "d"}
+	b := []string{"a", "c", "x", "d"}
+
+	require.Equal(t, []DiffLine{
+		{Op: DiffOpEqual, Text: "a"},
+		{Op: DiffOpDelete, Text: "b"},
+		{Op: DiffOpEqual, Text: "c"},
+		{Op: DiffOpInsert, Text: "x"},
+		{Op: DiffOpEqual, Text: "d"},
+	}, DiffLines(a, b))
+}
+
+func TestDiffLinesEmptySides(t *testing.T) {
+	require.Equal(t, []DiffLine{{Op: DiffOpInsert, Text: "new"}}, DiffLines(nil, []string{"new"}))
+	require.Equal(t, []DiffLine{{Op: DiffOpDelete, Text: "old"}}, DiffLines([]string{"old"}, nil))
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"errors"
+	"slices"
+)
+
+// ErrMergeConflict is returned when both sides changed the same lines differently
+var ErrMergeConflict = errors.New("merge conflict")
+
+// DiffHunk is a run of changed lines. Lines [Start, End) of the old side are
+// replaced by Lines; Start == End is a pure insertion.
+type DiffHunk struct {
+	Index   int      `json:"index"`
+	Start   int      `json:"start"`
+	End     int      `json:"end"`
+	Deleted []string `json:"deleted"`
+	Lines   []string `json:"lines"`
+}
+
+// DiffHunks groups the changes from a to b into hunks, in order
+func DiffHunks(a, b []string) []DiffHunk {
+	hunks := make([]DiffHunk, 0)
+	var current *DiffHunk
+	pos := 0
+	for _, line := range DiffLines(a, b) {
+		if line.Op == DiffOpEqual {
+			if current != nil {
+				hunks = append(hunks, *current)
+				current = nil
+			}
+			pos++
+			continue
+		}
+		if current == nil {
+			current = &DiffHunk{Index: len(hunks), Start: pos, End: pos, Deleted: []string{}, Lines: []string{}}
+		}
+		if line.Op == DiffOpDelete {
+			current.Deleted = append(current.Deleted, line.Text)
+			current.End++
+			pos++
+		} else {
+			current.Lines = append(current.Lines, line.Text)
+		}
+	}
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+	return hunks
+}
+
+// ApplyHunks applies hunks, which must be in order and come from DiffHunks
+// against base, to base
+func ApplyHunks(base []string, hunks []DiffHunk) []string {
+	return applyHunkRange(base, 0, len(base), hunks)
+}
+
+func applyHunkRange(base []string, start, end int, hunks []DiffHunk) []string {
+	out := make([]string, 0, end-start)
+	pos := start
+	for _, hunk := range hunks {
+		out = append(out, base[pos:hunk.Start]...)
+		out = append(out, hunk.Lines...)
+		pos = hunk.End
+	}
+	return append(out, base[pos:end]...)
+}
+
+// Merge3 merges the changes base->ours and base->theirs. Changes that overlap,
+// or insert where the other side edits, must be identical, otherwise
+// ErrMergeConflict is returned.
+func Merge3(base, ours, theirs []string) ([]string, error) {
+	a := DiffHunks(base, ours)
+	b := DiffHunks(base, theirs)
+
+	out := make([]string, 0, len(base))
+	pos := 0
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		// Start a cluster at the earliest hunk and absorb everything touching it
+		var start int
+		switch {
+		case j >= len(b) || (i < len(a) && a[i].Start <= b[j].Start):
+			start = a[i].Start
+		default:
+			start = b[j].Start
+		}
+		end := start
+		fromA, fromB := i, j
+		for {
+			if i < len(a) && touchesCluster(a[i], start, end) {
+				end = max(end, a[i].End)
+				i++
+				continue
+			}
+			if j < len(b) && touchesCluster(b[j], start, end) {
+				end = max(end, b[j].End)
+				j++
+				continue
+			}
+			break
+		}
+
+		out = append(out, base[pos:start]...)
+		switch {
+		case fromB == j:
+			out = append(out, applyHunkRange(base, start, end, a[fromA:i])...)
+		case fromA == i:
+			out = append(out, applyHunkRange(base, start, end, b[fromB:j])...)
+		default:
+			left := applyHunkRange(base, start, end, a[fromA:i])
+			right := applyHunkRange(base, start, end, b[fromB:j])
+			if !slices.Equal(left, right) {
+				return nil, ErrMergeConflict
+			}
+			out = append(out, left...)
+		}
+		pos = end
+	}
+	return append(out, base[pos:]...), nil
+}
+
+// touchesCluster reports whether hunk overlaps the base range [start, end).
+// Edits that merely border each other don't, unless one is an insertion.
+func touchesCluster(hunk DiffHunk, start, end int) bool {
+	if hunk.Start < end {
+		return true
+	}
+	return hunk.Start == end && (hunk.Start == hunk.End || start == end)
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffHunksApplySubset(t *testing.T) {
+	base := []string{"a", "b", "c", "d"}
+	proposed := []string{"a", "B", "c", "d", "e"}
+
+	hunks := DiffHunks(base, proposed)
+	require.Len(t, hunks, 2)
+	require.Equal(t, DiffHunk{Index: 0, Start: 1, End: 2, Deleted: []string{"b"}, Lines: []string{"B"}}, hunks[0])
+	require.Equal(t, DiffHunk{Index: 1, Start: 4, End: 4, Deleted: []string{}, Lines: []string{"e"}}, hunks[1])
+
+	require.Equal(t, proposed, ApplyHunks(base, hunks))
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, ApplyHunks(base, hunks[1:]))
+}
+
+func TestMerge3(t *testing.T) {
+	base := []string{"a", "b", "c", "d"}
+	ours := []string{"x", "a", "b", "c", "d"}
+	theirs := []string{"a", "b", "C", "d"}
+
+	merged, err := Merge3(base, ours, theirs)
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "a", "b", "C", "d"}, merged)
+
+	// Same change on both sides merges cleanly
+	merged, err = Merge3(base, theirs, theirs)
+	require.NoError(t, err)
+	require.Equal(t, theirs, merged)
+}
+
+func TestMerge3Conflict(t *testing.T) {
+	base := []string{"a", "b", "c"}
+
+	_, err := Merge3(base, []string{"a", "B1", "c"}, []string{"a", "B2", "c"})
+	require.ErrorIs(t, err, ErrMergeConflict)
+}
+
+func TestMerge3AdjacentEdits(t *testing.T) {
+	base := []string{"a", "b", "c"}
+
+	merged, err := Merge3(base, []string{"A", "b", "C"}, []string{"a", "B", "c"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "B", "C"}, merged)
+
+	_, err = Merge3(base, []string{"A", "b", "c"}, []string{"a", "x", "b", "c"})
+	require.ErrorIs(t, err, ErrMergeConflict)
+}
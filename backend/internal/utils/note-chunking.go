@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Note chunking mirrors ai-services/src/ai_services/rag/chunking.py so chunks
+// embedded in-process line up with the ones the ai-service produces.
+
+var (
+	chunkScriptRegex       = regexp.MustCompile(`(?is)<script\b[^>]*>.*?</script>`)
+	chunkStyleRegex        = regexp.MustCompile(`(?is)<style\b[^>]*>.*?</style>`)
+	chunkPreCodeOpenRegex  = regexp.MustCompile(`(?i)<pre\b[^>]*><code\b[^>]*>`)
+	chunkPreCodeCloseRegex = regexp.MustCompile(`(?i)</code>\s*</pre>`)
+	chunkBrRegex           = regexp.MustCompile(`(?i)<br\s*/?>`)
+	chunkBlockTagRegex     = regexp.MustCompile(`(?i)</?(p|div|section|article|header|footer|blockquote|pre|ul|ol|li|h[1-6]|table|tr|td|th|hr)\b[^>]*>`)
+	chunkCodeTagRegex      = regexp.MustCompile(`(?i)</?code\b[^>]*>`)
+	chunkTagRegex          = regexp.MustCompile(`<[^>]+>`)
+	chunkSpaceNewlineRegex = regexp.MustCompile(`[ \t]+\n`)
+	chunkMultiNewlineRegex = regexp.MustCompile(`\n{3,}`)
+
+	chunkHeadingRegex    = regexp.MustCompile(`^#{1,6}\s+.+`)
+	chunkListItemRegex   = regexp.MustCompile(`^(?:[-*+]\s+|\d+[.)]\s+).+`)
+	chunkSentenceRegex   = regexp.MustCompile(`[.!?]\s+`)
+	chunkTokenRegex      = regexp.MustCompile(`[\p{L}\p{N}_]+|[^\p{L}\p{N}_\s]`)
+	chunkCodeFencePrefix = "```"
+
+	// chunkFastReplacer matches the ai-service's legacy replacements, applied first
+	chunkFastReplacer = strings.NewReplacer(
+		"<br>", "\n", "<br/>", "\n", "<br />", "\n",
+		"<p>", "", "</p>", "\n", "<div>", "", "</div>", "\n",
+		"&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">",
+	)
+)
+
+// CleanNoteContent turns note HTML into plain text, keeping paragraph breaks
+// and code fences.
+func CleanNoteContent(content string) string {
+	result := chunkFastReplacer.Replace(content)
+	result = chunkScriptRegex.ReplaceAllString(result, " ")
+	result = chunkStyleRegex.ReplaceAllString(result, " ")
+
+	result = chunkPreCodeOpenRegex.ReplaceAllString(result, "\n```\n")
+	result = chunkPreCodeCloseRegex.ReplaceAllString(result, "\n```\n")
+
+	result = chunkBrRegex.ReplaceAllString(result, "\n")
+	result = chunkBlockTagRegex.ReplaceAllString(result, "\n")
+
+	result = chunkCodeTagRegex.ReplaceAllString(result, "")
+	result = chunkTagRegex.ReplaceAllString(result, " ")
+	result = html.UnescapeString(result)
+
+	result = strings.ReplaceAll(result, "\r\n", "\n")
+	result = strings.ReplaceAll(result, "\r", "\n")
+	result = chunkSpaceNewlineRegex.ReplaceAllString(result, "\n")
+	result = chunkMultiNewlineRegex.ReplaceAllString(result, "\n\n")
+
+	lines := strings.Split(result, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// PrepareNoteText joins the title and cleaned content for chunking
+func PrepareNoteText(title, content string) string {
+	parts := make([]string, 0, 2)
+	if t := strings.TrimSpace(title); t != "" {
+		parts = append(parts, t)
+	}
+	if cleaned := CleanNoteContent(content); cleaned != "" {
+		parts = append(parts, cleaned)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// ChunkText splits prepared note text into chunks of at most chunkSize tokens.
+// Paragraphs, headings, list items and code blocks are kept whole when they
+// fit, and each chunk starts with the last chunkOverlap tokens of the previous one.
+func ChunkText(text string, chunkSize, chunkOverlap int) []string {
+	if text == "" || chunkSize <= 0 {
+		return []string{}
+	}
+	if chunkOverlap < 0 || chunkOverlap >= chunkSize {
+		chunkOverlap = 0
+	}
+
+	chunks := make([]string, 0)
+	current := make([]string, 0)
+	currentTokens := 0
+
+	push := func(unit string, tokens int) {
+		if len(current) > 0 && currentTokens+tokens > chunkSize {
+			chunks = append(chunks, strings.TrimSpace(strings.Join(current, "\n")))
+			current, currentTokens = chunkOverlapUnits(chunks[len(chunks)-1], chunkOverlap)
+		}
+		current = append(current, unit)
+		currentTokens += tokens
+	}
+
+	for _, unit := range splitChunkUnits(text) {
+		unitTokens := estimateChunkTokens(unit)
+		if unitTokens > chunkSize {
+			for _, part := range splitLargeChunkUnit(unit, chunkSize) {
+				push(part, estimateChunkTokens(part))
+			}
+			continue
+		}
+		push(unit, unitTokens)
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.TrimSpace(strings.Join(current, "\n")))
+	}
+
+	out := chunks[:0]
+	for _, chunk := range chunks {
+		if chunk != "" {
+			out = append(out, chunk)
+		}
+	}
+	return out
+}
+
+// ChunkContentHash identifies a chunk's text, so unchanged chunks can keep
+// their stored row and embedding
+func ChunkContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func estimateChunkTokens(text string) int {
+	return len(chunkTokenRegex.FindAllStringIndex(text, -1))
+}
+
+// splitChunkUnits breaks text into paragraphs, headings, list items and code blocks
+func splitChunkUnits(text string) []string {
+	units := make([]string, 0)
+	buffer := make([]string, 0)
+	inCode := false
+
+	flush := func() {
+		if len(buffer) > 0 {
+			if unit := strings.TrimSpace(strings.Join(buffer, "\n")); unit != "" {
+				units = append(units, unit)
+			}
+			buffer = buffer[:0]
+		}
+	}
+
+	for _, raw := range strings.Split(text, "\n") {
+		line := strings.TrimRight(raw, " \t")
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, chunkCodeFencePrefix) {
+			if inCode {
+				buffer = append(buffer, line)
+				flush()
+				inCode = false
+				continue
+			}
+			flush()
+			buffer = append(buffer, line)
+			inCode = true
+			continue
+		}
+		if inCode {
+			buffer = append(buffer, line)
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flush()
+		case chunkHeadingRegex.MatchString(trimmed), chunkListItemRegex.MatchString(trimmed):
+			flush()
+			units = append(units, trimmed)
+		default:
+			buffer = append(buffer, line)
+		}
+	}
+	flush()
+
+	return units
+}
+
+// splitLargeChunkUnit splits an oversized unit by sentence, or by token window
+// when it has no sentence breaks
+func splitLargeChunkUnit(unit string, maxTokens int) []string {
+	sentences := make([]string, 0)
+	start := 0
+	for _, loc := range chunkSentenceRegex.FindAllStringIndex(unit, -1) {
+		sentences = append(sentences, unit[start:loc[0]+1])
+		start = loc[1]
+	}
+	sentences = append(sentences, unit[start:])
+	if len(sentences) <= 1 {
+		return splitChunkTokenWindow(unit, maxTokens)
+	}
+
+	chunks := make([]string, 0)
+	current := make([]string, 0)
+	currentTokens := 0
+	for _, sentence := range sentences {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" {
+			continue
+		}
+		tokens := estimateChunkTokens(sentence)
+		if len(current) > 0 && currentTokens+tokens > maxTokens {
+			chunks = append(chunks, strings.Join(current, " "))
+			current = current[:0]
+			currentTokens = 0
+		}
+		current = append(current, sentence)
+		currentTokens += tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, " "))
+	}
+
+	if len(chunks) == 0 {
+		return splitChunkTokenWindow(unit, maxTokens)
+	}
+	return chunks
+}
+
+func splitChunkTokenWindow(text string, maxTokens int) []string {
+	tokens := chunkTokenRegex.FindAllString(text, -1)
+	chunks := make([]string, 0, len(tokens)/maxTokens+1)
+	for start := 0; start < len(tokens); start += maxTokens {
+		end := start + maxTokens
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		chunks = append(chunks, strings.Join(tokens[start:end], " "))
+	}
+	return chunks
+}
+
+// chunkOverlapUnits seeds the next chunk with the tail of the previous one
+func chunkOverlapUnits(chunk string, overlapTokens int) ([]string, int) {
+	if overlapTokens <= 0 {
+		return []string{}, 0
+	}
+	tokens := chunkTokenRegex.FindAllString(chunk, -1)
+	if len(tokens) == 0 {
+		return []string{}, 0
+	}
+	if overlapTokens > len(tokens) {
+		overlapTokens = len(tokens)
+	}
+	overlap := strings.Join(tokens[len(tokens)-overlapTokens:], " ")
+	return []string{overlap}, estimateChunkTokens(overlap)
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareNoteTextStripsHTML(t *testing.T) {
+	text := PrepareNoteText(" Plan ", "<h2>Goals</h2><p>Ship&nbsp;search &amp; sync</p><script>x()</script>")
+
+	require.Equal(t, "Plan\n\nGoals\nShip search & sync", text)
+}
+
+func TestChunkTextKeepsUnitsAndOverlaps(t *testing.T) {
+	text := "# Intro\n\none two three four\n\nfive six seven eight"
+
+	chunks := ChunkText(text, 6, 2)
+
+	require.Equal(t, []string{
+		"# Intro\none two three four",
+		"three four\nfive six seven eight",
+	}, chunks)
+}
+
+func TestChunkTextSplitsOversizedParagraph(t *testing.T) {
+	chunks := ChunkText(strings.Repeat("word ", 25), 10, 0)
+
+	require.Len(t, chunks, 3)
+	require.Equal(t, 10, estimateChunkTokens(chunks[0]))
+	require.Equal(t, 5, estimateChunkTokens(chunks[2]))
+}
@@ -0,0 +1,323 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Note patch operations
+const (
+	NotePatchInsert  = "insert"
+	NotePatchReplace = "replace"
+	NotePatchDelete  = "delete"
+)
+
+// Insert positions. Start and end need no anchor.
+const (
+	NotePatchBefore = "before"
+	NotePatchAfter  = "after"
+	NotePatchStart  = "start"
+	NotePatchEnd    = "end"
+)
+
+var (
+	ErrNotePatchInvalid         = errors.New("invalid patch")
+	ErrNotePatchAnchorNotFound  = errors.New("patch anchor not found")
+	ErrNotePatchAnchorAmbiguous = errors.New("patch anchor matches more than one block")
+)
+
+// NoteBlock is a top-level block of note HTML
+type NoteBlock struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Text string `json:"text"`
+	HTML string `json:"-"`
+}
+
+// NotePatchAnchor locates a block either by ID or by text it contains. Before
+// and After, when set, must directly surround Text (whitespace is ignored),
+// which disambiguates repeated text.
+type NotePatchAnchor struct {
+	BlockID string `json:"block_id,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+}
+
+// NotePatchOp is one block-level edit. Content is HTML.
+type NotePatchOp struct {
+	Op       string           `json:"op"`
+	Position string           `json:"position,omitempty"`
+	Anchor   *NotePatchAnchor `json:"anchor,omitempty"`
+	Content  string           `json:"content,omitempty"`
+}
+
+// SplitNoteBlocks splits note HTML into its top-level blocks. A block's ID is
+// its id or data-id attribute, or "b<index>" for blocks without one. When a
+// block already uses that ID, a suffix keeps the generated one unique.
+func SplitNoteBlocks(content string) []NoteBlock {
+	blocks := make([]NoteBlock, 0)
+	z := xhtml.NewTokenizer(strings.NewReader(content))
+
+	var current strings.Builder
+	var text strings.Builder
+	var block NoteBlock
+	depth := 0
+	flush := func() {
+		block.HTML = current.String()
+		block.Text = strings.Join(strings.Fields(text.String()), " ")
+		blocks = append(blocks, block)
+		current.Reset()
+		text.Reset()
+		block = NoteBlock{}
+	}
+
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		raw := string(z.Raw())
+		tok := z.Token()
+
+		switch tt {
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if depth == 0 {
+				block.Type = tok.Data
+				for _, attr := range tok.Attr {
+					if (attr.Key == "id" || attr.Key == "data-id") && attr.Val != "" && block.ID == "" {
+						block.ID = attr.Val
+					}
+				}
+			}
+			current.WriteString(raw)
+			if tt == xhtml.StartTagToken && !isVoidElement(tok.Data) {
+				depth++
+			} else if depth == 0 {
+				flush()
+			}
+			if tok.Data == "br" {
+				text.WriteString(" ")
+			}
+		case xhtml.EndTagToken:
+			if depth == 0 {
+				// Stray closing tag
+				continue
+			}
+			current.WriteString(raw)
+			depth--
+			if depth == 0 {
+				flush()
+			} else {
+				text.WriteString(" ")
+			}
+		case xhtml.TextToken:
+			if depth == 0 {
+				if strings.TrimSpace(tok.Data) == "" {
+					continue
+				}
+				block.Type = "text"
+				current.WriteString(raw)
+				text.WriteString(tok.Data)
+				flush()
+				continue
+			}
+			current.WriteString(raw)
+			text.WriteString(tok.Data)
+		default:
+			if depth > 0 {
+				current.WriteString(raw)
+			}
+		}
+	}
+	if depth > 0 {
+		// Unclosed block at the end
+		flush()
+	}
+
+	used := make(map[string]struct{}, len(blocks))
+	for _, b := range blocks {
+		if b.ID != "" {
+			used[b.ID] = struct{}{}
+		}
+	}
+	for i := range blocks {
+		if blocks[i].ID != "" {
+			continue
+		}
+		id := fmt.Sprintf("b%d", i)
+		for n := 2; ; n++ {
+			if _, taken := used[id]; !taken {
+				break
+			}
+			id = fmt.Sprintf("b%d-%d", i, n)
+		}
+		blocks[i].ID = id
+		used[id] = struct{}{}
+	}
+	return blocks
+}
+
+func isVoidElement(tag string) bool {
+	switch tag {
+	case "area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr":
+		return true
+	}
+	return false
+}
+
+// ApplyNotePatch applies ops to note HTML. Anchors are resolved against the
+// content as it was before the patch, so every op can refer to the blocks
+// the caller read. Two ops may not replace or delete the same block.
+func ApplyNotePatch(content string, ops []NotePatchOp) (string, error) {
+	if len(ops) == 0 {
+		return "", fmt.Errorf("%w: no operations", ErrNotePatchInvalid)
+	}
+
+	blocks := SplitNoteBlocks(content)
+	before := make([][]string, len(blocks))
+	after := make([][]string, len(blocks))
+	replaced := make([]*string, len(blocks))
+	removed := make([]bool, len(blocks))
+	var atStart, atEnd []string
+
+	for i, op := range ops {
+		if op.Op == NotePatchInsert && (op.Position == NotePatchStart || op.Position == NotePatchEnd) {
+			if strings.TrimSpace(op.Content) == "" {
+				return "", fmt.Errorf("op %d: %w: insert needs content", i, ErrNotePatchInvalid)
+			}
+			if op.Position == NotePatchStart {
+				atStart = append(atStart, op.Content)
+			} else {
+				atEnd = append(atEnd, op.Content)
+			}
+			continue
+		}
+
+		idx, err := resolveNotePatchAnchor(blocks, op.Anchor)
+		if err != nil {
+			return "", fmt.Errorf("op %d: %w", i, err)
+		}
+
+		switch op.Op {
+		case NotePatchInsert:
+			if strings.TrimSpace(op.Content) == "" {
+				return "", fmt.Errorf("op %d: %w: insert needs content", i, ErrNotePatchInvalid)
+			}
+			switch op.Position {
+			case NotePatchBefore:
+				before[idx] = append(before[idx], op.Content)
+			case NotePatchAfter, "":
+				after[idx] = append(after[idx], op.Content)
+			default:
+				return "", fmt.Errorf("op %d: %w: unknown position %q", i, ErrNotePatchInvalid, op.Position)
+			}
+		case NotePatchReplace, NotePatchDelete:
+			if replaced[idx] != nil || removed[idx] {
+				return "", fmt.Errorf("op %d: %w: block %s is already changed by an earlier op", i, ErrNotePatchInvalid, blocks[idx].ID)
+			}
+			if op.Op == NotePatchDelete {
+				removed[idx] = true
+				continue
+			}
+			if strings.TrimSpace(op.Content) == "" {
+				return "", fmt.Errorf("op %d: %w: replace needs content, use delete to remove a block", i, ErrNotePatchInvalid)
+			}
+			replacement := op.Content
+			replaced[idx] = &replacement
+		default:
+			return "", fmt.Errorf("op %d: %w: unknown op %q", i, ErrNotePatchInvalid, op.Op)
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range atStart {
+		b.WriteString(s)
+	}
+	for i, block := range blocks {
+		for _, s := range before[i] {
+			b.WriteString(s)
+		}
+		switch {
+		case removed[i]:
+		case replaced[i] != nil:
+			b.WriteString(*replaced[i])
+		default:
+			b.WriteString(block.HTML)
+		}
+		for _, s := range after[i] {
+			b.WriteString(s)
+		}
+	}
+	for _, s := range atEnd {
+		b.WriteString(s)
+	}
+	return b.String(), nil
+}
+
+func resolveNotePatchAnchor(blocks []NoteBlock, anchor *NotePatchAnchor) (int, error) {
+	if anchor == nil || (anchor.BlockID == "" && strings.TrimSpace(anchor.Text) == "") {
+		return 0, fmt.Errorf("%w: anchor needs block_id or text", ErrNotePatchInvalid)
+	}
+
+	if anchor.BlockID != "" {
+		for i, block := range blocks {
+			if block.ID == anchor.BlockID {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: no block %s", ErrNotePatchAnchorNotFound, anchor.BlockID)
+	}
+
+	// Match on whitespace-collapsed text; context may run into neighbouring blocks
+	target := collapseSpace(anchor.Text)
+	wantBefore := collapseSpace(anchor.Before)
+	wantAfter := collapseSpace(anchor.After)
+	match := -1
+	for i, block := range blocks {
+		text := collapseSpace(block.Text)
+		for from := 0; ; {
+			pos := strings.Index(text[from:], target)
+			if pos < 0 {
+				break
+			}
+			start := from + pos
+			from = start + 1
+
+			preceding := joinBlockText(blocks[:i], text[:start])
+			following := joinBlockText(nil, text[start+len(target):], blocks[i+1:]...)
+			if !strings.HasSuffix(preceding, wantBefore) || !strings.HasPrefix(following, wantAfter) {
+				continue
+			}
+			if match >= 0 && match != i {
+				return 0, fmt.Errorf("%w: %q", ErrNotePatchAnchorAmbiguous, anchor.Text)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrNotePatchAnchorNotFound, anchor.Text)
+	}
+	return match, nil
+}
+
+// joinBlockText joins the text of leading blocks, s and trailing blocks with
+// single spaces
+func joinBlockText(leading []NoteBlock, s string, trailing ...NoteBlock) string {
+	parts := make([]string, 0, len(leading)+len(trailing)+1)
+	for _, block := range leading {
+		parts = append(parts, block.Text)
+	}
+	parts = append(parts, s)
+	for _, block := range trailing {
+		parts = append(parts, block.Text)
+	}
+	return collapseSpace(strings.Join(parts, " "))
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
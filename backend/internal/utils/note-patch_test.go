@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitNoteBlocks(t *testing.T) {
+	blocks := SplitNoteBlocks(`<h1 id="title">Plan</h1><p>First &amp; second</p><hr><ul><li>a</li><li>b</li></ul>`)
+
+	require.Len(t, blocks, 4)
+	require.Equal(t, "title", blocks[0].ID)
+	require.Equal(t, "h1", blocks[0].Type)
+	require.Equal(t, "b1", blocks[1].ID)
+	require.Equal(t, "First & second", blocks[1].Text)
+	require.Equal(t, "hr", blocks[2].Type)
+	require.Equal(t, "a b", blocks[3].Text)
+	require.Equal(t, "<ul><li>a</li><li>b</li></ul>", blocks[3].HTML)
+}
+
+func TestApplyNotePatch(t *testing.T) {
+	content := `<h1 id="title">Plan</h1><p>Buy milk</p><p>Call Bob</p>`
+
+	patched, err := ApplyNotePatch(content, []NotePatchOp{
+		{Op: NotePatchReplace, Anchor: &NotePatchAnchor{Text: "milk"}, Content: "<p>Buy oat milk</p>"},
+		{Op: NotePatchDelete, Anchor: &NotePatchAnchor{Text: "Call Bob"}},
+		{Op: NotePatchInsert, Position: NotePatchAfter, Anchor: &NotePatchAnchor{BlockID: "title"}, Content: "<p>Today</p>"},
+		{Op: NotePatchInsert, Position: NotePatchEnd, Content: "<p>Done</p>"},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, `<h1 id="title">Plan</h1><p>Today</p><p>Buy oat milk</p><p>Done</p>`, patched)
+}
+
+func TestApplyNotePatchAnchorContext(t *testing.T) {
+	content := `<p>Intro</p><p>TODO</p><p>Middle</p><p>TODO</p>`
+
+	_, err := ApplyNotePatch(content, []NotePatchOp{{Op: NotePatchDelete, Anchor: &NotePatchAnchor{Text: "TODO"}}})
+	require.ErrorIs(t, err, ErrNotePatchAnchorAmbiguous)
+
+	patched, err := ApplyNotePatch(content, []NotePatchOp{{Op: NotePatchDelete, Anchor: &NotePatchAnchor{Text: "TODO", Before: "Middle"}}})
+	require.NoError(t, err)
+	require.Equal(t, `<p>Intro</p><p>TODO</p><p>Middle</p>`, patched)
+}
+
+func TestApplyNotePatchRejectsInvalidOps(t *testing.T) {
+	content := `<p>One</p>`
+
+	_, err := ApplyNotePatch(content, []NotePatchOp{{Op: NotePatchDelete, Anchor: &NotePatchAnchor{Text: "Two"}}})
+	require.ErrorIs(t, err, ErrNotePatchAnchorNotFound)
+
+	_, err = ApplyNotePatch(content, []NotePatchOp{
+		{Op: NotePatchDelete, Anchor: &NotePatchAnchor{Text: "One"}},
+		{Op: NotePatchReplace, Anchor: &NotePatchAnchor{Text: "One"}, Content: "<p>1</p>"},
+	})
+	require.ErrorIs(t, err, ErrNotePatchInvalid)
+
+	_, err = ApplyNotePatch(content, nil)
+	require.ErrorIs(t, err, ErrNotePatchInvalid)
+}
+
+func TestSplitNoteBlocksSkipsGeneratedIDsInUse(t *testing.T) {
+	blocks := SplitNoteBlocks(`<p>Intro</p><p id="b0">Named b0</p><p data-id="b2">Named b2</p><p>Last</p>`)
+
+	require.Len(t, blocks, 4)
+	require.Equal(t, "b0-2", blocks[0].ID)
+	require.Equal(t, "b0", blocks[1].ID)
+	require.Equal(t, "b2", blocks[2].ID)
+	require.Equal(t, "b3", blocks[3].ID)
+
+	patched, err := ApplyNotePatch(`<p>Intro</p><p id="b0">Named b0</p>`, []NotePatchOp{
+		{Op: NotePatchDelete, Anchor: &NotePatchAnchor{BlockID: "b0"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, `<p>Intro</p>`, patched)
+}
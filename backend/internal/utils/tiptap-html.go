@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// tiptapBlockTags maps Tiptap node types to the element the editor renders them as
+var tiptapBlockTags = map[string]string{
+	"paragraph":   "p",
+	"blockquote":  "blockquote",
+	"bulletList":  "ul",
+	"orderedList": "ol",
+	"listItem":    "li",
+	"taskList":    "ul",
+	"taskItem":    "li",
+	"table":       "table",
+	"tableRow":    "tr",
+	"tableHeader": "th",
+	"tableCell":   "td",
+}
+
+// tiptapMarkTags maps Tiptap mark types to inline elements
+var tiptapMarkTags = map[string]string{
+	"bold":        "strong",
+	"italic":      "em",
+	"underline":   "u",
+	"strike":      "s",
+	"code":        "code",
+	"highlight":   "mark",
+	"subscript":   "sub",
+	"superscript": "sup",
+}
+
+// TiptapJSONToHTML renders a Tiptap (ProseMirror) JSON document as HTML close
+// to what the editor's getHTML produces, for notes whose content only exists
+// in a collab document. Unknown nodes keep their children inside a div
+// carrying the node type.
+func TiptapJSONToHTML(doc map[string]interface{}) string {
+	var b strings.Builder
+	writeTiptapChildren(&b, doc)
+	return b.String()
+}
+
+func writeTiptapChildren(b *strings.Builder, node map[string]interface{}) {
+	children, _ := node["content"].([]interface{})
+	for _, child := range children {
+		if m, ok := child.(map[string]interface{}); ok {
+			writeTiptapNode(b, m)
+		}
+	}
+}
+
+func writeTiptapNode(b *strings.Builder, node map[string]interface{}) {
+	nodeType, _ := node["type"].(string)
+	attrs, _ := node["attrs"].(map[string]interface{})
+
+	switch nodeType {
+	case "text":
+		text, _ := node["text"].(string)
+		writeTiptapText(b, text, node["marks"])
+		return
+	case "hardBreak":
+		b.WriteString("<br>")
+		return
+	case "horizontalRule":
+		b.WriteString("<hr>")
+		return
+	case "image":
+		b.WriteString("<img" + htmlAttrs(attrs, "src", "alt", "title") + ">")
+		return
+	case "heading":
+		level := 1
+		if l, ok := attrs["level"].(float64); ok && l >= 1 && l <= 6 {
+			level = int(l)
+		}
+		fmt.Fprintf(b, "<h%d>", level)
+		writeTiptapChildren(b, node)
+		fmt.Fprintf(b, "</h%d>", level)
+		return
+	case "codeBlock":
+		b.WriteString("<pre")
+		if lang, ok := attrs["language"].(string); ok && lang != "" {
+			b.WriteString(` data-language="` + html.EscapeString(lang) + `"`)
+		}
+		b.WriteString("><code>")
+		writeTiptapChildren(b, node)
+		b.WriteString("</code></pre>")
+		return
+	case "taskItem":
+		checked, _ := attrs["checked"].(bool)
+		fmt.Fprintf(b, `<li data-type="taskItem" data-checked="%t">`, checked)
+		writeTiptapChildren(b, node)
+		b.WriteString("</li>")
+		return
+	}
+
+	tag, ok := tiptapBlockTags[nodeType]
+	if !ok {
+		b.WriteString(`<div data-type="` + html.EscapeString(nodeType) + `">`)
+		writeTiptapChildren(b, node)
+		b.WriteString("</div>")
+		return
+	}
+	b.WriteString("<" + tag)
+	if nodeType == "taskList" {
+		b.WriteString(` data-type="taskList"`)
+	}
+	if nodeType == "orderedList" {
+		if start, ok := attrs["start"].(float64); ok && start != 1 {
+			fmt.Fprintf(b, ` start="%d"`, int(start))
+		}
+	}
+	b.WriteString(">")
+	writeTiptapChildren(b, node)
+	b.WriteString("</" + tag + ">")
+}
+
+func writeTiptapText(b *strings.Builder, text string, rawMarks interface{}) {
+	marks, _ := rawMarks.([]interface{})
+	closing := make([]string, 0, len(marks))
+	for _, raw := range marks {
+		mark, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		markType, _ := mark["type"].(string)
+		attrs, _ := mark["attrs"].(map[string]interface{})
+		if markType == "link" {
+			b.WriteString("<a" + htmlAttrs(attrs, "href", "target", "rel") + ">")
+			closing = append(closing, "</a>")
+			continue
+		}
+		if tag, ok := tiptapMarkTags[markType]; ok {
+			b.WriteString("<" + tag + ">")
+			closing = append(closing, "</"+tag+">")
+		}
+	}
+	b.WriteString(html.EscapeString(text))
+	for i := len(closing) - 1; i >= 0; i-- {
+		b.WriteString(closing[i])
+	}
+}
+
+// htmlAttrs renders the named string attributes that are set, in name order
+func htmlAttrs(attrs map[string]interface{}, names ...string) string {
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		if v, ok := attrs[name].(string); ok && v != "" {
+			b.WriteString(" " + name + `="` + html.EscapeString(v) + `"`)
+		}
+	}
+	return b.String()
+}
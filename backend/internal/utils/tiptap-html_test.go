@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTiptapJSONToHTML(t *testing.T) {
+	var doc map[string]interface{}
+	raw := `{"type":"doc","content":[
+		{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Plan"}]},
+		{"type":"paragraph","content":[
+			{"type":"text","text":"a < b "},
+			{"type":"text","text":"docs","marks":[{"type":"bold"},{"type":"link","attrs":{"href":"https://example.com?a=1&b=2"}}]}
+		]},
+		{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"one"}]}]}]}
+	]}`
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+
+	want := `<h2>Plan</h2><p>a &lt; b <strong><a href="https://example.com?a=1&amp;b=2">docs</a></strong></p><ul><li><p>one</p></li></ul>`
+	if got := TiptapJSONToHTML(doc); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
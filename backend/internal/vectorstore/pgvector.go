@@ -0,0 +1,238 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/datatypes"
+	"gorm.io/gorm/clause"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/embeddings"
+)
+
+// pgVectorStore implements VectorStore on the vector_documents table, using
+// the same pgvector extension and HNSW indexing as note_chunks. Like the
+// Pinecone store, user-scoped calls live in a "user_<id>" namespace.
+type pgVectorStore struct {
+	db        *database.DB
+	embedder  embeddings.EmbeddingProvider
+	defaultNS string
+}
+
+// NewPgVectorStore creates a Postgres vector store. The embedder turns text
+// into vectors for UpsertText and SearchText.
+func NewPgVectorStore(db *database.DB, embedder embeddings.EmbeddingProvider, cfg config.VectorStoreConfig) (VectorStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database is required")
+	}
+	if embedder == nil {
+		return nil, fmt.Errorf("pgvector store requires an in-process embeddings provider")
+	}
+	if embedder.GetDimension() != models.NoteChunkEmbeddingDimension {
+		return nil, fmt.Errorf("embedding dimension %d does not match vector_documents (%d)",
+			embedder.GetDimension(), models.NoteChunkEmbeddingDimension)
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	return &pgVectorStore{
+		db:        db,
+		embedder:  embedder,
+		defaultNS: namespace,
+	}, nil
+}
+
+// Upsert inserts or updates documents in the default namespace
+func (p *pgVectorStore) Upsert(ctx context.Context, docs []InsertVectorDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	records := make([]models.VectorDocument, 0, len(docs))
+	for _, doc := range docs {
+		record, err := newVectorDocument(p.defaultNS, doc.ID, "", doc.Vector, doc.Metadata)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+	}
+	return p.upsert(ctx, records)
+}
+
+// UpsertText embeds and stores documents in the user's namespace
+func (p *pgVectorStore) UpsertText(ctx context.Context, docs []InsertTextDocument, userID string) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	if userID == "" {
+		return fmt.Errorf("userID is required for namespace-based upsert")
+	}
+
+	texts := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		texts = append(texts, doc.Text)
+	}
+	vectors, err := p.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed documents: %w", err)
+	}
+	if len(vectors) != len(docs) {
+		return fmt.Errorf("embedder returned %d vectors for %d documents", len(vectors), len(docs))
+	}
+
+	namespace := getUserNamespace(userID)
+	records := make([]models.VectorDocument, 0, len(docs))
+	for i, doc := range docs {
+		record, err := newVectorDocument(namespace, doc.ID, doc.Text, vectors[i], doc.Metadata)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+	}
+	return p.upsert(ctx, records)
+}
+
+// Search performs a cosine similarity search in the default namespace.
+// Every filter entry must match the document metadata exactly.
+func (p *pgVectorStore) Search(ctx context.Context, vector []float32, topK int, filter map[string]string) ([]SearchResult, error) {
+	return p.search(ctx, p.defaultNS, vector, topK, filter)
+}
+
+// SearchText embeds the query and searches the user's namespace
+func (p *pgVectorStore) SearchText(ctx context.Context, query string, topK int, userID string) ([]string, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("userID is required for namespace-based search")
+	}
+
+	vector, err := p.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	results, err := p.search(ctx, getUserNamespace(userID), vector, topK, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	return ids, nil
+}
+
+// Delete removes documents from the user's namespace
+func (p *pgVectorStore) Delete(ctx context.Context, ids []string, userID string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if userID == "" {
+		return fmt.Errorf("userID is required for namespace-based delete")
+	}
+
+	return p.db.WithContext(ctx).
+		Where("namespace = ? AND id IN ?", getUserNamespace(userID), ids).
+		Delete(&models.VectorDocument{}).Error
+}
+
+// Close is a no-op; the database connection is owned by the app
+func (p *pgVectorStore) Close() error {
+	return nil
+}
+
+func (p *pgVectorStore) upsert(ctx context.Context, records []models.VectorDocument) error {
+	return p.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "namespace"}, {Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"text", "embedding", "metadata", "updated_at"}),
+		}).
+		Create(&records).Error
+}
+
+func (p *pgVectorStore) search(ctx context.Context, namespace string, vector []float32, topK int, filter map[string]string) ([]SearchResult, error) {
+	if len(vector) == 0 {
+		return []SearchResult{}, nil
+	}
+	if topK <= 0 {
+		topK = 10
+	}
+
+	vectorParam := pgvector.NewVector(vector)
+	query := p.db.WithContext(ctx).
+		Model(&models.VectorDocument{}).
+		Where("namespace = ?", namespace)
+	if len(filter) > 0 {
+		filterJSON, err := json.Marshal(filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build metadata filter: %w", err)
+		}
+		query = query.Where("metadata @> ?::jsonb", string(filterJSON))
+	}
+
+	type searchRow struct {
+		ID       string
+		Metadata datatypes.JSON
+		Score    float64
+	}
+	var rows []searchRow
+	if err := query.
+		Select("id, metadata, 1 - (embedding <=> ?::vector) AS score", vectorParam).
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "embedding <=> ?::vector", Vars: []interface{}{vectorParam}}}).
+		Limit(topK).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query vectors: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(rows))
+	for _, row := range rows {
+		metadata := map[string]string{}
+		if len(row.Metadata) > 0 {
+			if err := json.Unmarshal(row.Metadata, &metadata); err != nil {
+				return nil, fmt.Errorf("failed to decode metadata for %s: %w", row.ID, err)
+			}
+		}
+		results = append(results, SearchResult{
+			ID:       row.ID,
+			Score:    float32(row.Score),
+			Metadata: metadata,
+		})
+	}
+	return results, nil
+}
+
+func newVectorDocument(namespace, id, text string, vector []float32, metadata map[string]string) (models.VectorDocument, error) {
+	if id == "" {
+		return models.VectorDocument{}, fmt.Errorf("document ID is required")
+	}
+	if len(vector) != models.NoteChunkEmbeddingDimension {
+		return models.VectorDocument{}, fmt.Errorf("document %s has dimension %d, expected %d",
+			id, len(vector), models.NoteChunkEmbeddingDimension)
+	}
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return models.VectorDocument{}, fmt.Errorf("failed to encode metadata for %s: %w", id, err)
+	}
+
+	now := time.Now().UTC()
+	return models.VectorDocument{
+		Namespace: namespace,
+		ID:        id,
+		Text:      text,
+		Embedding: pgvector.NewVector(vector),
+		Metadata:  datatypes.JSON(metadataJSON),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
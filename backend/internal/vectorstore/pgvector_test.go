@@ -0,0 +1,144 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/config"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/database/models"
+	"github.com/duckviet/gin-collaborative-editor/backend/internal/embeddings"
+)
+
+// sqlRecorder is a GORM logger that keeps the SQL of every statement
+type sqlRecorder struct {
+	logger.Interface
+	statements []string
+}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
+
+// newDryRunStore builds a pgvector store whose statements are rendered but
+// never sent to a database
+func newDryRunStore(t *testing.T) (*pgVectorStore, *sqlRecorder) {
+	t.Helper()
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               recorder,
+	})
+	if err != nil {
+		t.Fatalf("open dry-run database: %v", err)
+	}
+
+	store, err := NewPgVectorStore(&database.DB{DB: db}, embeddings.NewHashingProvider(models.NoteChunkEmbeddingDimension), config.VectorStoreConfig{})
+	if err != nil {
+		t.Fatalf("new pgvector store: %v", err)
+	}
+	return store.(*pgVectorStore), recorder
+}
+
+func TestNewPgVectorStoreValidatesDependencies(t *testing.T) {
+	db := &database.DB{}
+	cases := map[string]struct {
+		db       *database.DB
+		embedder embeddings.EmbeddingProvider
+	}{
+		"missing database": {nil, embeddings.NewHashingProvider(models.NoteChunkEmbeddingDimension)},
+		"missing embedder": {db, nil},
+		"wrong dimension":  {db, embeddings.NewHashingProvider(384)},
+	}
+	for name, tc := range cases {
+		if _, err := NewPgVectorStore(tc.db, tc.embedder, config.VectorStoreConfig{}); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	store, err := NewPgVectorStore(db, embeddings.NewHashingProvider(models.NoteChunkEmbeddingDimension), config.VectorStoreConfig{})
+	if err != nil {
+		t.Fatalf("new pgvector store: %v", err)
+	}
+	if ns := store.(*pgVectorStore).defaultNS; ns != "default" {
+		t.Fatalf("expected the default namespace, got %q", ns)
+	}
+}
+
+func TestNewVectorDocumentValidatesInput(t *testing.T) {
+	vector := make([]float32, models.NoteChunkEmbeddingDimension)
+
+	if _, err := newVectorDocument("ns", "", "", vector, nil); err == nil {
+		t.Fatalf("expected a document without ID to be rejected")
+	}
+	if _, err := newVectorDocument("ns", "doc", "", vector[:10], nil); err == nil {
+		t.Fatalf("expected a vector of the wrong dimension to be rejected")
+	}
+
+	doc, err := newVectorDocument("ns", "doc", "hello", vector, nil)
+	if err != nil {
+		t.Fatalf("new vector document: %v", err)
+	}
+	if doc.Namespace != "ns" || doc.ID != "doc" || doc.Text != "hello" || string(doc.Metadata) != "{}" {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+}
+
+func TestPgVectorSearchScopesByNamespaceAndMetadata(t *testing.T) {
+	store, recorder := newDryRunStore(t)
+	ctx := context.Background()
+
+	// Dry runs cannot return rows, so both calls fail after building their query
+	if _, err := store.Search(ctx, []float32{0.1, 0.2}, 0, map[string]string{"note_id": "n1"}); !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatalf("search: %v", err)
+	}
+	if _, err := store.SearchText(ctx, "meeting notes", 3, "user-1"); !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatalf("search text: %v", err)
+	}
+	if len(recorder.statements) != 2 {
+		t.Fatalf("expected two queries, got %v", recorder.statements)
+	}
+
+	search := recorder.statements[0]
+	for _, want := range []string{`namespace = 'default'`, `metadata @> '{"note_id":"n1"}'::jsonb`, "ORDER BY embedding <=>", "LIMIT 10"} {
+		if !strings.Contains(search, want) {
+			t.Fatalf("expected search SQL to contain %q, got %s", want, search)
+		}
+	}
+	searchText := recorder.statements[1]
+	for _, want := range []string{`namespace = 'user_user-1'`, "LIMIT 3"} {
+		if !strings.Contains(searchText, want) {
+			t.Fatalf("expected search text SQL to contain %q, got %s", want, searchText)
+		}
+	}
+	if strings.Contains(searchText, "metadata @>") {
+		t.Fatalf("expected no metadata filter without one, got %s", searchText)
+	}
+}
+
+func TestPgVectorUserScopedCallsRequireUser(t *testing.T) {
+	store, recorder := newDryRunStore(t)
+	ctx := context.Background()
+
+	if err := store.UpsertText(ctx, []InsertTextDocument{{ID: "doc", Text: "hello"}}, ""); err == nil {
+		t.Fatalf("expected upsert without a user to be rejected")
+	}
+	if _, err := store.SearchText(ctx, "hello", 3, ""); err == nil {
+		t.Fatalf("expected search without a user to be rejected")
+	}
+	if err := store.Delete(ctx, []string{"doc"}, ""); err == nil {
+		t.Fatalf("expected delete without a user to be rejected")
+	}
+	if len(recorder.statements) != 0 {
+		t.Fatalf("expected no queries, got %v", recorder.statements)
+	}
+}
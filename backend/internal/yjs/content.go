@@ -0,0 +1,297 @@
+package yjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Content reference numbers, as written in the low five bits of a struct's info byte
+const (
+	refGC             = 0
+	refContentDeleted = 1
+	refContentJSON    = 2
+	refContentBinary  = 3
+	refContentString  = 4
+	refContentEmbed   = 5
+	refContentFormat  = 6
+	refContentType    = 7
+	refContentAny     = 8
+	refContentDoc     = 9
+	refSkip           = 10
+)
+
+// Shared type references carried by ContentType
+const (
+	TypeArray       = 0
+	TypeMap         = 1
+	TypeText        = 2
+	TypeXMLElement  = 3
+	TypeXMLFragment = 4
+	TypeXMLHook     = 5
+	TypeXMLText     = 6
+)
+
+// content is the payload of an item. Lengths are in Yjs clock units; for
+// strings that is UTF-16 code units, as in JavaScript.
+type content interface {
+	ref() byte
+	length() int
+	countable() bool
+	// splice cuts the content at offset, keeping the left part and returning the right
+	splice(offset int) content
+	// mergeWith appends right when both are of the same kind; it reports success
+	mergeWith(right content) bool
+	write(e *encoder, offset int)
+}
+
+type contentDeleted struct{ n int }
+
+func (c *contentDeleted) ref() byte       { return refContentDeleted }
+func (c *contentDeleted) length() int     { return c.n }
+func (c *contentDeleted) countable() bool { return false }
+func (c *contentDeleted) splice(offset int) content {
+	right := &contentDeleted{n: c.n - offset}
+	c.n = offset
+	return right
+}
+func (c *contentDeleted) mergeWith(right content) bool {
+	r, ok := right.(*contentDeleted)
+	if ok {
+		c.n += r.n
+	}
+	return ok
+}
+func (c *contentDeleted) write(e *encoder, offset int) { e.writeVarUint(uint64(c.n - offset)) }
+
+// contentJSON keeps each value as its JSON text, "undefined" included
+type contentJSON struct{ values []string }
+
+func (c *contentJSON) ref() byte       { return refContentJSON }
+func (c *contentJSON) length() int     { return len(c.values) }
+func (c *contentJSON) countable() bool { return true }
+func (c *contentJSON) splice(offset int) content {
+	right := &contentJSON{values: append([]string(nil), c.values[offset:]...)}
+	c.values = c.values[:offset]
+	return right
+}
+func (c *contentJSON) mergeWith(right content) bool {
+	r, ok := right.(*contentJSON)
+	if ok {
+		c.values = append(c.values, r.values...)
+	}
+	return ok
+}
+func (c *contentJSON) write(e *encoder, offset int) {
+	e.writeVarUint(uint64(len(c.values) - offset))
+	for _, v := range c.values[offset:] {
+		e.writeVarString(v)
+	}
+}
+
+type contentBinary struct{ data []byte }
+
+func (c *contentBinary) ref() byte               { return refContentBinary }
+func (c *contentBinary) length() int             { return 1 }
+func (c *contentBinary) countable() bool         { return true }
+func (c *contentBinary) splice(int) content      { panic("yjs: binary content cannot be split") }
+func (c *contentBinary) mergeWith(content) bool  { return false }
+func (c *contentBinary) write(e *encoder, _ int) { e.writeVarBytes(c.data) }
+
+// contentString stores UTF-16 code units so splits land where Yjs puts them
+type contentString struct{ units []uint16 }
+
+func newContentString(s string) *contentString {
+	return &contentString{units: utf16.Encode([]rune(s))}
+}
+
+func (c *contentString) String() string  { return string(utf16.Decode(c.units)) }
+func (c *contentString) ref() byte       { return refContentString }
+func (c *contentString) length() int     { return len(c.units) }
+func (c *contentString) countable() bool { return true }
+func (c *contentString) splice(offset int) content {
+	right := &contentString{units: append([]uint16(nil), c.units[offset:]...)}
+	c.units = c.units[:offset]
+	return right
+}
+func (c *contentString) mergeWith(right content) bool {
+	r, ok := right.(*contentString)
+	if ok {
+		c.units = append(c.units, r.units...)
+	}
+	return ok
+}
+func (c *contentString) write(e *encoder, offset int) {
+	e.writeVarString(string(utf16.Decode(c.units[offset:])))
+}
+
+type contentEmbed struct{ value json.RawMessage }
+
+func (c *contentEmbed) ref() byte               { return refContentEmbed }
+func (c *contentEmbed) length() int             { return 1 }
+func (c *contentEmbed) countable() bool         { return true }
+func (c *contentEmbed) splice(int) content      { panic("yjs: embed content cannot be split") }
+func (c *contentEmbed) mergeWith(content) bool  { return false }
+func (c *contentEmbed) write(e *encoder, _ int) { e.writeVarString(string(c.value)) }
+
+// contentFormat starts (or, with a null value, ends) a text attribute
+type contentFormat struct {
+	key   string
+	value json.RawMessage
+}
+
+func (c *contentFormat) ref() byte              { return refContentFormat }
+func (c *contentFormat) length() int            { return 1 }
+func (c *contentFormat) countable() bool        { return false }
+func (c *contentFormat) splice(int) content     { panic("yjs: format content cannot be split") }
+func (c *contentFormat) mergeWith(content) bool { return false }
+func (c *contentFormat) write(e *encoder, _ int) {
+	e.writeVarString(c.key)
+	e.writeVarString(string(c.value))
+}
+
+// contentType holds a nested shared type such as an XML element
+type contentType struct{ t *sharedType }
+
+func (c *contentType) ref() byte              { return refContentType }
+func (c *contentType) length() int            { return 1 }
+func (c *contentType) countable() bool        { return true }
+func (c *contentType) splice(int) content     { panic("yjs: type content cannot be split") }
+func (c *contentType) mergeWith(content) bool { return false }
+func (c *contentType) write(e *encoder, _ int) {
+	e.writeVarUint(uint64(c.t.typeRef))
+	if c.t.typeRef == TypeXMLElement || c.t.typeRef == TypeXMLHook {
+		e.writeVarString(c.t.name)
+	}
+}
+
+type contentAny struct{ values []interface{} }
+
+func (c *contentAny) ref() byte       { return refContentAny }
+func (c *contentAny) length() int     { return len(c.values) }
+func (c *contentAny) countable() bool { return true }
+func (c *contentAny) splice(offset int) content {
+	right := &contentAny{values: append([]interface{}(nil), c.values[offset:]...)}
+	c.values = c.values[:offset]
+	return right
+}
+func (c *contentAny) mergeWith(right content) bool {
+	r, ok := right.(*contentAny)
+	if ok {
+		c.values = append(c.values, r.values...)
+	}
+	return ok
+}
+func (c *contentAny) write(e *encoder, offset int) {
+	e.writeVarUint(uint64(len(c.values) - offset))
+	for _, v := range c.values[offset:] {
+		e.writeAny(v)
+	}
+}
+
+// contentDoc references a subdocument by guid
+type contentDoc struct {
+	guid string
+	opts interface{}
+}
+
+func (c *contentDoc) ref() byte              { return refContentDoc }
+func (c *contentDoc) length() int            { return 1 }
+func (c *contentDoc) countable() bool        { return true }
+func (c *contentDoc) splice(int) content     { panic("yjs: doc content cannot be split") }
+func (c *contentDoc) mergeWith(content) bool { return false }
+func (c *contentDoc) write(e *encoder, _ int) {
+	e.writeVarString(c.guid)
+	e.writeAny(c.opts)
+}
+
+func readContent(d *decoder, ref byte) (content, error) {
+	switch ref {
+	case refContentDeleted:
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		return &contentDeleted{n: int(n)}, nil
+	case refContentJSON:
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := d.readVarString()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return &contentJSON{values: values}, nil
+	case refContentBinary:
+		b, err := d.readVarBytes()
+		if err != nil {
+			return nil, err
+		}
+		return &contentBinary{data: append([]byte(nil), b...)}, nil
+	case refContentString:
+		s, err := d.readVarString()
+		if err != nil {
+			return nil, err
+		}
+		return newContentString(s), nil
+	case refContentEmbed:
+		s, err := d.readVarString()
+		if err != nil {
+			return nil, err
+		}
+		return &contentEmbed{value: json.RawMessage(s)}, nil
+	case refContentFormat:
+		key, err := d.readVarString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readVarString()
+		if err != nil {
+			return nil, err
+		}
+		return &contentFormat{key: key, value: json.RawMessage(value)}, nil
+	case refContentType:
+		typeRef, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		t := newSharedType(int(typeRef))
+		if typeRef == TypeXMLElement || typeRef == TypeXMLHook {
+			if t.name, err = d.readVarString(); err != nil {
+				return nil, err
+			}
+		}
+		return &contentType{t: t}, nil
+	case refContentAny:
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := d.readAny()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return &contentAny{values: values}, nil
+	case refContentDoc:
+		guid, err := d.readVarString()
+		if err != nil {
+			return nil, err
+		}
+		opts, err := d.readAny()
+		if err != nil {
+			return nil, err
+		}
+		return &contentDoc{guid: guid, opts: opts}, nil
+	default:
+		return nil, fmt.Errorf("yjs: unknown content ref %d", ref)
+	}
+}
@@ -0,0 +1,815 @@
+package yjs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrMalformedUpdate is returned when an update decodes but its structs
+// cannot be integrated, such as a reference to a clock no struct holds
+var ErrMalformedUpdate = errors.New("yjs: malformed update")
+
+// ID identifies the first clock unit of a struct
+type ID struct {
+	Client uint64
+	Clock  uint64
+}
+
+// item is a Yjs struct: an Item, or a GC placeholder when gc is set. Only
+// the fields Yjs needs to integrate and re-encode a struct are kept.
+type item struct {
+	id          ID
+	length      int
+	gc          bool
+	skip        bool
+	origin      *ID
+	rightOrigin *ID
+	left, right *item
+	parent      *sharedType
+	parentSub   *string
+	deleted     bool
+	content     content
+
+	// Decoded parent reference, resolved on integration
+	parentKey *string
+	parentID  *ID
+}
+
+func (it *item) lastID() ID {
+	return ID{Client: it.id.Client, Clock: it.id.Clock + uint64(it.length) - 1}
+}
+
+// sharedType is a Yjs shared type: a root type when item is nil, otherwise
+// the type held by item's content
+type sharedType struct {
+	typeRef int
+	name    string // node name for XML elements and hooks
+	rootKey string // key in the document for root types
+	item    *item
+	start   *item
+	entries map[string]*item
+	length  int
+}
+
+func newSharedType(typeRef int) *sharedType {
+	return &sharedType{typeRef: typeRef, entries: make(map[string]*item)}
+}
+
+type deleteRange struct {
+	clock  uint64
+	length uint64
+}
+
+// Doc is an in-memory Yjs document built from binary v1 updates. It does
+// not track transactions or observers; it exists to merge, compact and read
+// documents persisted by the collab server.
+type Doc struct {
+	clients        map[uint64][]*item
+	roots          map[string]*sharedType
+	pending        []*item
+	pendingDeletes map[uint64][]deleteRange
+}
+
+// NewDoc creates an empty document
+func NewDoc() *Doc {
+	return &Doc{
+		clients:        make(map[uint64][]*item),
+		roots:          make(map[string]*sharedType),
+		pendingDeletes: make(map[uint64][]deleteRange),
+	}
+}
+
+// LoadDoc builds a document from updates applied in order
+func LoadDoc(updates ...[]byte) (*Doc, error) {
+	doc := NewDoc()
+	for i, update := range updates {
+		if err := doc.ApplyUpdate(update); err != nil {
+			return nil, fmt.Errorf("update %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
+
+// HasPending reports whether some structs or deletions are still waiting for
+// updates that were never applied
+func (d *Doc) HasPending() bool {
+	if len(d.pending) > 0 {
+		return true
+	}
+	for _, ranges := range d.pendingDeletes {
+		if len(ranges) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// StateVector returns the next expected clock of every client
+func (d *Doc) StateVector() map[uint64]uint64 {
+	sv := make(map[uint64]uint64, len(d.clients))
+	for client := range d.clients {
+		sv[client] = d.state(client)
+	}
+	return sv
+}
+
+func (d *Doc) root(key string) *sharedType {
+	t, ok := d.roots[key]
+	if !ok {
+		t = newSharedType(TypeXMLFragment)
+		t.rootKey = key
+		d.roots[key] = t
+	}
+	return t
+}
+
+func (d *Doc) state(client uint64) uint64 {
+	structs := d.clients[client]
+	if len(structs) == 0 {
+		return 0
+	}
+	last := structs[len(structs)-1]
+	return last.id.Clock + uint64(last.length)
+}
+
+// findIndex returns the index of the struct holding clock
+func (d *Doc) findIndex(client, clock uint64) int {
+	structs := d.clients[client]
+	i := sort.Search(len(structs), func(i int) bool {
+		s := structs[i]
+		return s.id.Clock+uint64(s.length) > clock
+	})
+	if i == len(structs) || structs[i].id.Clock > clock {
+		panic(fmt.Sprintf("yjs: no struct holds %d:%d", client, clock))
+	}
+	return i
+}
+
+func (d *Doc) getItem(id ID) *item {
+	return d.clients[id.Client][d.findIndex(id.Client, id.Clock)]
+}
+
+// getItemCleanStart returns the item starting at id, splitting the item holding it
+func (d *Doc) getItemCleanStart(id ID) *item {
+	s := d.getItem(id)
+	if s.id.Clock < id.Clock && !s.gc {
+		return d.splitItem(s, int(id.Clock-s.id.Clock))
+	}
+	return s
+}
+
+// getItemCleanEnd returns the item ending at id, splitting the item holding it
+func (d *Doc) getItemCleanEnd(id ID) *item {
+	s := d.getItem(id)
+	if id.Clock != s.id.Clock+uint64(s.length)-1 && !s.gc {
+		d.splitItem(s, int(id.Clock-s.id.Clock)+1)
+	}
+	return s
+}
+
+// splitItem cuts left at diff and returns the new right part
+func (d *Doc) splitItem(left *item, diff int) *item {
+	originID := ID{Client: left.id.Client, Clock: left.id.Clock + uint64(diff) - 1}
+	right := &item{
+		id:          ID{Client: left.id.Client, Clock: left.id.Clock + uint64(diff)},
+		length:      left.length - diff,
+		origin:      &originID,
+		rightOrigin: left.rightOrigin,
+		left:        left,
+		right:       left.right,
+		parent:      left.parent,
+		parentSub:   left.parentSub,
+		deleted:     left.deleted,
+		content:     left.content.splice(diff),
+	}
+	left.length = diff
+	left.right = right
+	if right.right != nil {
+		right.right.left = right
+	}
+
+	structs := d.clients[left.id.Client]
+	i := d.findIndex(left.id.Client, left.id.Clock)
+	structs = append(structs, nil)
+	copy(structs[i+2:], structs[i+1:])
+	structs[i+1] = right
+	d.clients[left.id.Client] = structs
+
+	if right.parentSub != nil && right.right == nil && right.parent != nil {
+		right.parent.entries[*right.parentSub] = right
+	}
+	return right
+}
+
+func (d *Doc) addStruct(s *item) {
+	structs := d.clients[s.id.Client]
+	if len(structs) > 0 {
+		last := structs[len(structs)-1]
+		if last.id.Clock+uint64(last.length) != s.id.Clock {
+			panic(fmt.Sprintf("yjs: struct %d:%d is not contiguous", s.id.Client, s.id.Clock))
+		}
+	}
+	d.clients[s.id.Client] = append(structs, s)
+}
+
+// ApplyUpdate decodes a v1 update and integrates it. Structs whose
+// dependencies are missing wait until a later update provides them. When
+// integration fails the document is left half-updated and must be discarded.
+func (d *Doc) ApplyUpdate(update []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrMalformedUpdate, r)
+		}
+	}()
+
+	structs, deletes, err := decodeUpdate(update)
+	if err != nil {
+		return err
+	}
+	d.pending = append(d.pending, structs...)
+	for client, ranges := range deletes {
+		d.pendingDeletes[client] = append(d.pendingDeletes[client], ranges...)
+	}
+	d.integratePending()
+	d.applyPendingDeletes()
+	return nil
+}
+
+func (d *Doc) integratePending() {
+	queues := make(map[uint64][]*item)
+	for _, s := range d.pending {
+		queues[s.id.Client] = append(queues[s.id.Client], s)
+	}
+	clients := make([]uint64, 0, len(queues))
+	for client, queue := range queues {
+		sort.SliceStable(queue, func(i, j int) bool { return queue[i].id.Clock < queue[j].id.Clock })
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i] < clients[j] })
+
+	for progress := true; progress; {
+		progress = false
+		for _, client := range clients {
+			for len(queues[client]) > 0 {
+				s := queues[client][0]
+				state := d.state(client)
+				end := s.id.Clock + uint64(s.length)
+				if end <= state {
+					// Already integrated
+					queues[client] = queues[client][1:]
+					progress = true
+					continue
+				}
+				if s.skip || s.id.Clock > state || d.missing(s) {
+					break
+				}
+				d.integrate(s, int(state-s.id.Clock))
+				queues[client] = queues[client][1:]
+				progress = true
+			}
+		}
+	}
+
+	d.pending = d.pending[:0]
+	for _, client := range clients {
+		d.pending = append(d.pending, queues[client]...)
+	}
+}
+
+// missing reports whether s depends on a struct of another client that has
+// not been integrated yet
+func (d *Doc) missing(s *item) bool {
+	for _, dep := range []*ID{s.origin, s.rightOrigin, s.parentID} {
+		if dep != nil && dep.Client != s.id.Client && dep.Clock >= d.state(dep.Client) {
+			return true
+		}
+	}
+	return false
+}
+
+// integrate places s into the document, following Item.integrate in Yjs
+func (d *Doc) integrate(s *item, offset int) {
+	if s.gc {
+		if offset > 0 {
+			s.id.Clock += uint64(offset)
+			s.length -= offset
+		}
+		d.addStruct(s)
+		return
+	}
+
+	// Resolve neighbours and parent (Item.getMissing)
+	if s.origin != nil {
+		s.left = d.getItemCleanEnd(*s.origin)
+		last := s.left.lastID()
+		s.origin = &last
+	}
+	if s.rightOrigin != nil {
+		s.right = d.getItemCleanStart(*s.rightOrigin)
+		first := s.right.id
+		s.rightOrigin = &first
+	}
+	switch {
+	case (s.left != nil && s.left.gc) || (s.right != nil && s.right.gc):
+		s.parent = nil
+	case s.parentID != nil:
+		if p := d.getItem(*s.parentID); !p.gc {
+			if ct, ok := p.content.(*contentType); ok {
+				s.parent = ct.t
+			}
+		}
+	case s.parentKey != nil:
+		s.parent = d.root(*s.parentKey)
+	default:
+		if s.left != nil {
+			s.parent, s.parentSub = s.left.parent, s.left.parentSub
+		}
+		if s.right != nil {
+			s.parent, s.parentSub = s.right.parent, s.right.parentSub
+		}
+	}
+	s.parentID, s.parentKey = nil, nil
+
+	if offset > 0 {
+		s.id.Clock += uint64(offset)
+		s.left = d.getItemCleanEnd(ID{Client: s.id.Client, Clock: s.id.Clock - 1})
+		last := s.left.lastID()
+		s.origin = &last
+		s.content = s.content.splice(offset)
+		s.length -= offset
+	}
+
+	if s.parent == nil {
+		s.gc, s.deleted, s.content = true, true, nil
+		s.left, s.right, s.origin, s.rightOrigin, s.parentSub = nil, nil, nil, nil, nil
+		d.addStruct(s)
+		return
+	}
+
+	parent := s.parent
+	if (s.left == nil && (s.right == nil || s.right.left != nil)) || (s.left != nil && s.left.right != s.right) {
+		left := s.left
+		var o *item
+		switch {
+		case left != nil:
+			o = left.right
+		case s.parentSub != nil:
+			o = parent.entries[*s.parentSub]
+			for o != nil && o.left != nil {
+				o = o.left
+			}
+		default:
+			o = parent.start
+		}
+
+		conflicting := make(map[*item]struct{})
+		beforeOrigin := make(map[*item]struct{})
+		for o != nil && o != s.right {
+			beforeOrigin[o] = struct{}{}
+			conflicting[o] = struct{}{}
+			if sameID(s.origin, o.origin) {
+				if o.id.Client < s.id.Client {
+					left = o
+					conflicting = make(map[*item]struct{})
+				} else if sameID(s.rightOrigin, o.rightOrigin) {
+					break
+				}
+			} else if o.origin != nil {
+				originItem := d.getItem(*o.origin)
+				if _, ok := beforeOrigin[originItem]; !ok {
+					break
+				}
+				if _, ok := conflicting[originItem]; !ok {
+					left = o
+					conflicting = make(map[*item]struct{})
+				}
+			} else {
+				break
+			}
+			o = o.right
+		}
+		s.left = left
+	}
+
+	if s.left != nil {
+		s.right = s.left.right
+		s.left.right = s
+	} else {
+		var r *item
+		if s.parentSub != nil {
+			r = parent.entries[*s.parentSub]
+			for r != nil && r.left != nil {
+				r = r.left
+			}
+		} else {
+			r = parent.start
+			parent.start = s
+		}
+		s.right = r
+	}
+	if s.right != nil {
+		s.right.left = s
+	} else if s.parentSub != nil {
+		parent.entries[*s.parentSub] = s
+		if s.left != nil {
+			d.deleteItem(s.left)
+		}
+	}
+
+	if s.parentSub == nil && s.content.countable() && !s.deleted {
+		parent.length += s.length
+	}
+	d.addStruct(s)
+
+	switch c := s.content.(type) {
+	case *contentType:
+		c.t.item = s
+	case *contentDeleted:
+		s.deleted = true
+	}
+
+	if (parent.item != nil && parent.item.deleted) || (s.parentSub != nil && s.right != nil) {
+		d.deleteItem(s)
+	}
+}
+
+func sameID(a, b *ID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// deleteItem marks an item deleted, along with everything inside a deleted type
+func (d *Doc) deleteItem(it *item) {
+	if it.deleted {
+		return
+	}
+	if it.parentSub == nil && it.content.countable() && it.parent != nil {
+		it.parent.length -= it.length
+	}
+	it.deleted = true
+	if ct, ok := it.content.(*contentType); ok {
+		for child := ct.t.start; child != nil; child = child.right {
+			d.deleteItem(child)
+		}
+		for _, child := range ct.t.entries {
+			d.deleteItem(child)
+		}
+	}
+}
+
+func (d *Doc) applyPendingDeletes() {
+	remaining := make(map[uint64][]deleteRange)
+	for client, ranges := range d.pendingDeletes {
+		for _, r := range ranges {
+			state := d.state(client)
+			end := r.clock + r.length
+			if r.clock >= state {
+				remaining[client] = append(remaining[client], r)
+				continue
+			}
+			if end > state {
+				remaining[client] = append(remaining[client], deleteRange{clock: state, length: end - state})
+				end = state
+			}
+
+			i := d.findIndex(client, r.clock)
+			if s := d.clients[client][i]; !s.deleted && s.id.Clock < r.clock {
+				d.splitItem(s, int(r.clock-s.id.Clock))
+				i++
+			}
+			for ; i < len(d.clients[client]); i++ {
+				s := d.clients[client][i]
+				if s.id.Clock >= end {
+					break
+				}
+				if !s.deleted {
+					if end < s.id.Clock+uint64(s.length) {
+						d.splitItem(s, int(end-s.id.Clock))
+					}
+					d.deleteItem(s)
+				}
+			}
+		}
+	}
+	d.pendingDeletes = remaining
+}
+
+// Compact replaces the content of deleted items with tombstones, turns
+// everything inside deleted types into GC structs and merges neighbouring
+// structs, as Yjs garbage collection does. The document reads the same
+// afterwards, but encodes much smaller.
+func (d *Doc) Compact() {
+	for _, structs := range d.clients {
+		for _, s := range structs {
+			if s.deleted && !s.gc {
+				d.gcItem(s, false)
+			}
+		}
+	}
+	for client, structs := range d.clients {
+		merged := structs[:1]
+		for _, right := range structs[1:] {
+			left := merged[len(merged)-1]
+			if d.tryMerge(left, right) {
+				continue
+			}
+			merged = append(merged, right)
+		}
+		d.clients[client] = merged
+	}
+}
+
+func (d *Doc) gcItem(it *item, parentGCd bool) {
+	if it.gc {
+		return
+	}
+	if ct, ok := it.content.(*contentType); ok {
+		for child := ct.t.start; child != nil; child = child.right {
+			d.gcItem(child, true)
+		}
+		for _, child := range ct.t.entries {
+			for ; child != nil; child = child.left {
+				d.gcItem(child, true)
+			}
+		}
+		ct.t.start = nil
+		ct.t.entries = make(map[string]*item)
+	}
+	if parentGCd {
+		it.gc, it.content, it.parent, it.parentSub = true, nil, nil, nil
+		it.origin, it.rightOrigin = nil, nil
+		return
+	}
+	it.content = &contentDeleted{n: it.length}
+}
+
+func (d *Doc) tryMerge(left, right *item) bool {
+	if left.gc || right.gc {
+		if left.gc && right.gc {
+			left.length += right.length
+			return true
+		}
+		return false
+	}
+	if left.right != right || !sameID(right.origin, ptrID(left.lastID())) ||
+		!sameID(left.rightOrigin, right.rightOrigin) || left.deleted != right.deleted ||
+		!left.content.mergeWith(right.content) {
+		return false
+	}
+	if right.parentSub != nil && left.parent != nil && left.parent.entries[*right.parentSub] == right {
+		left.parent.entries[*right.parentSub] = left
+	}
+	left.right = right.right
+	if left.right != nil {
+		left.right.left = left
+	}
+	left.length += right.length
+	return true
+}
+
+func ptrID(id ID) *ID {
+	return &id
+}
+
+// EncodeStateAsUpdate encodes the whole integrated document as one v1
+// update. Structs still waiting on missing dependencies are not included.
+func (d *Doc) EncodeStateAsUpdate() []byte {
+	e := &encoder{}
+	writeStructs(e, d.clients)
+	writeDeleteSet(e, d.deleteSet())
+	return e.bytes()
+}
+
+func (d *Doc) deleteSet() map[uint64][]deleteRange {
+	ds := make(map[uint64][]deleteRange)
+	for client, structs := range d.clients {
+		var ranges []deleteRange
+		for _, s := range structs {
+			if !s.deleted {
+				continue
+			}
+			if n := len(ranges); n > 0 && ranges[n-1].clock+ranges[n-1].length == s.id.Clock {
+				ranges[n-1].length += uint64(s.length)
+				continue
+			}
+			ranges = append(ranges, deleteRange{clock: s.id.Clock, length: uint64(s.length)})
+		}
+		if len(ranges) > 0 {
+			ds[client] = ranges
+		}
+	}
+	return ds
+}
+
+func sortedClientsDesc[T any](m map[uint64]T) []uint64 {
+	clients := make([]uint64, 0, len(m))
+	for client := range m {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i] > clients[j] })
+	return clients
+}
+
+func writeStructs(e *encoder, clients map[uint64][]*item) {
+	ordered := make([]uint64, 0, len(clients))
+	for _, client := range sortedClientsDesc(clients) {
+		if len(clients[client]) > 0 {
+			ordered = append(ordered, client)
+		}
+	}
+	e.writeVarUint(uint64(len(ordered)))
+	for _, client := range ordered {
+		structs := clients[client]
+		e.writeVarUint(uint64(len(structs)))
+		e.writeVarUint(client)
+		e.writeVarUint(structs[0].id.Clock)
+		for _, s := range structs {
+			writeItem(e, s)
+		}
+	}
+}
+
+func writeItem(e *encoder, s *item) {
+	if s.gc {
+		e.writeUint8(refGC)
+		e.writeVarUint(uint64(s.length))
+		return
+	}
+
+	info := s.content.ref()
+	if s.origin != nil {
+		info |= 0x80
+	}
+	if s.rightOrigin != nil {
+		info |= 0x40
+	}
+	if s.parentSub != nil {
+		info |= 0x20
+	}
+	e.writeUint8(info)
+	if s.origin != nil {
+		e.writeVarUint(s.origin.Client)
+		e.writeVarUint(s.origin.Clock)
+	}
+	if s.rightOrigin != nil {
+		e.writeVarUint(s.rightOrigin.Client)
+		e.writeVarUint(s.rightOrigin.Clock)
+	}
+	if s.origin == nil && s.rightOrigin == nil {
+		if s.parent.item == nil {
+			e.writeVarUint(1)
+			e.writeVarString(s.parent.rootKey)
+		} else {
+			e.writeVarUint(0)
+			e.writeVarUint(s.parent.item.id.Client)
+			e.writeVarUint(s.parent.item.id.Clock)
+		}
+		if s.parentSub != nil {
+			e.writeVarString(*s.parentSub)
+		}
+	}
+	s.content.write(e, 0)
+}
+
+func writeDeleteSet(e *encoder, ds map[uint64][]deleteRange) {
+	clients := sortedClientsDesc(ds)
+	e.writeVarUint(uint64(len(clients)))
+	for _, client := range clients {
+		e.writeVarUint(client)
+		e.writeVarUint(uint64(len(ds[client])))
+		for _, r := range ds[client] {
+			e.writeVarUint(r.clock)
+			e.writeVarUint(r.length)
+		}
+	}
+}
+
+// decodeUpdate reads the structs and delete set of a v1 update
+func decodeUpdate(update []byte) ([]*item, map[uint64][]deleteRange, error) {
+	d := newDecoder(update)
+	numClients, err := d.readVarUint()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	structs := make([]*item, 0)
+	for c := uint64(0); c < numClients; c++ {
+		numStructs, err := d.readVarUint()
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := d.readVarUint()
+		if err != nil {
+			return nil, nil, err
+		}
+		clock, err := d.readVarUint()
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := uint64(0); i < numStructs; i++ {
+			s, err := readStruct(d, ID{Client: client, Clock: clock})
+			if err != nil {
+				return nil, nil, err
+			}
+			clock += uint64(s.length)
+			structs = append(structs, s)
+		}
+	}
+
+	deletes := make(map[uint64][]deleteRange)
+	numClients, err = d.readVarUint()
+	if err != nil {
+		return nil, nil, err
+	}
+	for c := uint64(0); c < numClients; c++ {
+		client, err := d.readVarUint()
+		if err != nil {
+			return nil, nil, err
+		}
+		numRanges, err := d.readVarUint()
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := uint64(0); i < numRanges; i++ {
+			clock, err := d.readVarUint()
+			if err != nil {
+				return nil, nil, err
+			}
+			length, err := d.readVarUint()
+			if err != nil {
+				return nil, nil, err
+			}
+			deletes[client] = append(deletes[client], deleteRange{clock: clock, length: length})
+		}
+	}
+	return structs, deletes, nil
+}
+
+func readStruct(d *decoder, id ID) (*item, error) {
+	info, err := d.readUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	switch ref := info & 0x1f; ref {
+	case refGC, refSkip:
+		length, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		return &item{id: id, length: int(length), gc: ref == refGC, skip: ref == refSkip, deleted: true}, nil
+	}
+
+	s := &item{id: id}
+	readID := func() (*ID, error) {
+		client, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		clock, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		return &ID{Client: client, Clock: clock}, nil
+	}
+	if info&0x80 != 0 {
+		if s.origin, err = readID(); err != nil {
+			return nil, err
+		}
+	}
+	if info&0x40 != 0 {
+		if s.rightOrigin, err = readID(); err != nil {
+			return nil, err
+		}
+	}
+	if info&0xc0 == 0 {
+		isRootKey, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		if isRootKey == 1 {
+			key, err := d.readVarString()
+			if err != nil {
+				return nil, err
+			}
+			s.parentKey = &key
+		} else if s.parentID, err = readID(); err != nil {
+			return nil, err
+		}
+		if info&0x20 != 0 {
+			sub, err := d.readVarString()
+			if err != nil {
+				return nil, err
+			}
+			s.parentSub = &sub
+		}
+	}
+	if s.content, err = readContent(d, info&0x1f); err != nil {
+		return nil, err
+	}
+	s.length = s.content.length()
+	return s, nil
+}
@@ -0,0 +1,115 @@
+package yjs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// ydoc.getText('t').insert(0, 'hi') with clientID 1, as encoded by Yjs
+var insertHiUpdate = []byte{0x01, 0x01, 0x01, 0x00, 0x04, 0x01, 0x01, 0x74, 0x02, 0x68, 0x69, 0x00}
+
+func TestDocRoundTripsYjsUpdate(t *testing.T) {
+	doc, err := LoadDoc(insertHiUpdate)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if doc.HasPending() {
+		t.Fatal("expected the update to integrate fully")
+	}
+	if got := doc.StateVector()[1]; got != 2 {
+		t.Fatalf("expected client 1 at clock 2, got %d", got)
+	}
+	if got := doc.EncodeStateAsUpdate(); !bytes.Equal(got, insertHiUpdate) {
+		t.Fatalf("expected re-encoded update %x, got %x", insertHiUpdate, got)
+	}
+}
+
+func TestLoadDocRejectsMalformedUpdates(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		updates [][]byte
+		want    error
+	}{
+		{"truncated", [][]byte{insertHiUpdate[:len(insertHiUpdate)-3]}, ErrUnexpectedEOF},
+		// Turning the string item into a GC struct makes the next update's
+		// struct refer to a clock nothing holds
+		{"corrupt", [][]byte{corrupt(insertHiUpdate, 5, 0x00), insertHiUpdate}, ErrMalformedUpdate},
+	} {
+		doc, err := LoadDoc(tc.updates...)
+		if !errors.Is(err, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+		if doc != nil {
+			t.Fatalf("%s: expected no document", tc.name)
+		}
+	}
+}
+
+func TestReplaceFragmentRoundTripsProseMirrorJSON(t *testing.T) {
+	first := pmDoc(t, `{"type":"doc","content":[
+		{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"Title"}]},
+		{"type":"paragraph","content":[
+			{"type":"text","text":"plain "},
+			{"type":"text","text":"bold","marks":[{"type":"bold"}]},
+			{"type":"text","text":" link","marks":[{"type":"link","attrs":{"href":"https://example.com"}}]}
+		]}
+	]}`)
+	second := pmDoc(t, `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"rewritten"}]}]}`)
+
+	doc := NewDoc()
+	firstUpdate, err := doc.ReplaceFragment(DefaultFragment, first, 10)
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	assertJSON(t, doc.ProseMirrorJSON(DefaultFragment), first)
+
+	secondUpdate, err := doc.ReplaceFragment(DefaultFragment, second, 11)
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	assertJSON(t, doc.ProseMirrorJSON(DefaultFragment), second)
+
+	// Another replica applying the updates in reverse order converges
+	replica, err := LoadDoc(secondUpdate, firstUpdate)
+	if err != nil {
+		t.Fatalf("load replica: %v", err)
+	}
+	assertJSON(t, replica.ProseMirrorJSON(DefaultFragment), second)
+
+	// Compaction drops the deleted content but not the document
+	doc.Compact()
+	compacted, err := LoadDoc(doc.EncodeStateAsUpdate())
+	if err != nil {
+		t.Fatalf("load compacted: %v", err)
+	}
+	assertJSON(t, compacted.ProseMirrorJSON(DefaultFragment), second)
+	if len(doc.EncodeStateAsUpdate()) >= len(firstUpdate)+len(secondUpdate) {
+		t.Fatal("expected the compacted state to be smaller than the update log")
+	}
+}
+
+func corrupt(update []byte, at int, value byte) []byte {
+	out := append([]byte(nil), update...)
+	out[at] = value
+	return out
+}
+
+func pmDoc(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+	return doc
+}
+
+func assertJSON(t *testing.T, got, want map[string]interface{}) {
+	t.Helper()
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Fatalf("expected %s, got %s", wantJSON, gotJSON)
+	}
+}
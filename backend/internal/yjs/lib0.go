@@ -0,0 +1,307 @@
+package yjs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The binary primitives below follow lib0's encoding module, which Yjs uses
+// for the v1 update format.
+
+// ErrUnexpectedEOF is returned when an update ends in the middle of a value
+var ErrUnexpectedEOF = errors.New("yjs: unexpected end of update")
+
+// Undefined is JavaScript's undefined, which lib0 encodes separately from null
+type Undefined struct{}
+
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+func newDecoder(buf []byte) *decoder {
+	return &decoder{buf: buf}
+}
+
+func (d *decoder) hasContent() bool {
+	return d.pos < len(d.buf)
+}
+
+func (d *decoder) readUint8() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, ErrUnexpectedEOF
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) readVarUint() (uint64, error) {
+	var num uint64
+	var shift uint
+	for {
+		b, err := d.readUint8()
+		if err != nil {
+			return 0, err
+		}
+		num |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return num, nil
+		}
+		shift += 7
+		if shift > 63 {
+			return 0, fmt.Errorf("yjs: varuint overflows 64 bits")
+		}
+	}
+}
+
+// readVarInt reads lib0's signed varint: the first byte carries a sign bit
+// and six value bits
+func (d *decoder) readVarInt() (int64, error) {
+	b, err := d.readUint8()
+	if err != nil {
+		return 0, err
+	}
+	num := int64(b & 0x3f)
+	negative := b&0x40 != 0
+	shift := uint(6)
+	for b&0x80 != 0 {
+		if b, err = d.readUint8(); err != nil {
+			return 0, err
+		}
+		num |= int64(b&0x7f) << shift
+		shift += 7
+		if shift > 63 {
+			return 0, fmt.Errorf("yjs: varint overflows 64 bits")
+		}
+	}
+	if negative {
+		num = -num
+	}
+	return num, nil
+}
+
+func (d *decoder) readVarBytes() ([]byte, error) {
+	n, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(d.buf)-d.pos) < n {
+		return nil, ErrUnexpectedEOF
+	}
+	out := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return out, nil
+}
+
+func (d *decoder) readVarString() (string, error) {
+	b, err := d.readVarBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readAny reads a value written by lib0's writeAny
+func (d *decoder) readAny() (interface{}, error) {
+	t, err := d.readUint8()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case 127:
+		return Undefined{}, nil
+	case 126:
+		return nil, nil
+	case 125:
+		return d.readVarInt()
+	case 124:
+		if len(d.buf)-d.pos < 4 {
+			return nil, ErrUnexpectedEOF
+		}
+		v := math.Float32frombits(binary.BigEndian.Uint32(d.buf[d.pos:]))
+		d.pos += 4
+		return float64(v), nil
+	case 123:
+		if len(d.buf)-d.pos < 8 {
+			return nil, ErrUnexpectedEOF
+		}
+		v := math.Float64frombits(binary.BigEndian.Uint64(d.buf[d.pos:]))
+		d.pos += 8
+		return v, nil
+	case 122:
+		if len(d.buf)-d.pos < 8 {
+			return nil, ErrUnexpectedEOF
+		}
+		v := int64(binary.BigEndian.Uint64(d.buf[d.pos:]))
+		d.pos += 8
+		return v, nil
+	case 121:
+		return false, nil
+	case 120:
+		return true, nil
+	case 119:
+		return d.readVarString()
+	case 118:
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		obj := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			key, err := d.readVarString()
+			if err != nil {
+				return nil, err
+			}
+			if obj[key], err = d.readAny(); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case 117:
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		arr := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := d.readAny()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case 116:
+		b, err := d.readVarBytes()
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	default:
+		return nil, fmt.Errorf("yjs: unknown any type %d", t)
+	}
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) bytes() []byte {
+	return e.buf
+}
+
+func (e *encoder) writeUint8(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) writeVarUint(num uint64) {
+	for num > 0x7f {
+		e.buf = append(e.buf, byte(num&0x7f)|0x80)
+		num >>= 7
+	}
+	e.buf = append(e.buf, byte(num))
+}
+
+func (e *encoder) writeVarInt(num int64) {
+	negative := num < 0
+	if negative {
+		num = -num
+	}
+	first := byte(num & 0x3f)
+	if negative {
+		first |= 0x40
+	}
+	num >>= 6
+	if num > 0 {
+		first |= 0x80
+	}
+	e.buf = append(e.buf, first)
+	for num > 0 {
+		b := byte(num & 0x7f)
+		num >>= 7
+		if num > 0 {
+			b |= 0x80
+		}
+		e.buf = append(e.buf, b)
+	}
+}
+
+func (e *encoder) writeVarBytes(b []byte) {
+	e.writeVarUint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeVarString(s string) {
+	e.writeVarBytes([]byte(s))
+}
+
+// writeAny writes a value the way lib0's writeAny does. Numbers decoded from
+// JSON arrive as float64 and are written as integers when they are whole.
+func (e *encoder) writeAny(v interface{}) {
+	switch val := v.(type) {
+	case Undefined:
+		e.writeUint8(127)
+	case nil:
+		e.writeUint8(126)
+	case bool:
+		if val {
+			e.writeUint8(120)
+		} else {
+			e.writeUint8(121)
+		}
+	case string:
+		e.writeUint8(119)
+		e.writeVarString(val)
+	case int:
+		e.writeAnyNumber(float64(val))
+	case int64:
+		e.writeAnyNumber(float64(val))
+	case float32:
+		e.writeAnyNumber(float64(val))
+	case float64:
+		e.writeAnyNumber(val)
+	case []byte:
+		e.writeUint8(116)
+		e.writeVarBytes(val)
+	case []interface{}:
+		e.writeUint8(117)
+		e.writeVarUint(uint64(len(val)))
+		for _, item := range val {
+			e.writeAny(item)
+		}
+	case map[string]interface{}:
+		e.writeUint8(118)
+		e.writeVarUint(uint64(len(val)))
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			e.writeVarString(key)
+			e.writeAny(val[key])
+		}
+	default:
+		// Anything else has no lib0 representation
+		e.writeUint8(127)
+	}
+}
+
+func (e *encoder) writeAnyNumber(num float64) {
+	const bits31 = 0x7fffffff
+	switch {
+	case num == math.Trunc(num) && math.Abs(num) <= bits31:
+		e.writeUint8(125)
+		e.writeVarInt(int64(num))
+	case float64(float32(num)) == num:
+		e.writeUint8(124)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(num)))
+	default:
+		e.writeUint8(123)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(num))
+	}
+}
@@ -0,0 +1,391 @@
+package yjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// DefaultFragment is the XML fragment the Tiptap collaboration extension binds to
+const DefaultFragment = "default"
+
+// hashedMarkName matches y-prosemirror's attribute names for overlapping
+// marks of the same type, e.g. "comment--aGVsbG8x"
+var hashedMarkName = regexp.MustCompile(`^(.*)(--[a-zA-Z0-9+/=]{8})$`)
+
+// ProseMirrorJSON returns the document held by an XML fragment in
+// ProseMirror's JSON form, as y-prosemirror's yXmlFragmentToProsemirrorJSON does
+func (d *Doc) ProseMirrorJSON(fragment string) map[string]interface{} {
+	content := make([]interface{}, 0)
+	if t, ok := d.roots[fragment]; ok {
+		content = xmlChildrenJSON(t)
+	}
+	return map[string]interface{}{"type": "doc", "content": content}
+}
+
+func xmlChildrenJSON(t *sharedType) []interface{} {
+	nodes := make([]interface{}, 0)
+	for it := t.start; it != nil; it = it.right {
+		if it.deleted || it.gc {
+			continue
+		}
+		ct, ok := it.content.(*contentType)
+		if !ok {
+			continue
+		}
+		switch ct.t.typeRef {
+		case TypeXMLElement:
+			nodes = append(nodes, xmlElementJSON(ct.t))
+		case TypeXMLText, TypeText:
+			nodes = append(nodes, xmlTextJSON(ct.t)...)
+		}
+	}
+	return nodes
+}
+
+func xmlElementJSON(t *sharedType) map[string]interface{} {
+	node := map[string]interface{}{"type": t.name}
+	if attrs := typeAttributes(t); len(attrs) > 0 {
+		node["attrs"] = attrs
+	}
+	if children := xmlChildrenJSON(t); len(children) > 0 {
+		node["content"] = children
+	}
+	return node
+}
+
+// typeAttributes reads the current value of every map entry of a type
+func typeAttributes(t *sharedType) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	for key, it := range t.entries {
+		if it.deleted || it.gc {
+			continue
+		}
+		switch c := it.content.(type) {
+		case *contentAny:
+			attrs[key] = jsonValue(c.values[len(c.values)-1])
+		case *contentJSON:
+			var v interface{}
+			if err := json.Unmarshal([]byte(c.values[len(c.values)-1]), &v); err == nil {
+				attrs[key] = v
+			}
+		case *contentString:
+			attrs[key] = c.String()
+		case *contentBinary:
+			attrs[key] = c.data
+		}
+	}
+	return attrs
+}
+
+// jsonValue turns a lib0 value into something encoding/json writes the way
+// JSON.stringify would
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case Undefined:
+		return nil
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = jsonValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if _, undefined := item.(Undefined); !undefined {
+				out[k] = jsonValue(item)
+			}
+		}
+		return out
+	default:
+		return val
+	}
+}
+
+type textRun struct {
+	text  []uint16
+	attrs map[string]json.RawMessage
+}
+
+// xmlTextJSON turns a text type's delta into ProseMirror text nodes
+func xmlTextJSON(t *sharedType) []interface{} {
+	runs := make([]*textRun, 0)
+	current := make(map[string]json.RawMessage)
+	for it := t.start; it != nil; it = it.right {
+		if it.deleted || it.gc {
+			continue
+		}
+		switch c := it.content.(type) {
+		case *contentString:
+			if n := len(runs); n > 0 && sameAttributes(runs[n-1].attrs, current) {
+				runs[n-1].text = append(runs[n-1].text, c.units...)
+				continue
+			}
+			attrs := make(map[string]json.RawMessage, len(current))
+			for k, v := range current {
+				attrs[k] = v
+			}
+			runs = append(runs, &textRun{text: append([]uint16(nil), c.units...), attrs: attrs})
+		case *contentFormat:
+			if string(c.value) == "null" {
+				delete(current, c.key)
+			} else {
+				current[c.key] = c.value
+			}
+		}
+	}
+
+	nodes := make([]interface{}, 0, len(runs))
+	for _, run := range runs {
+		text := (&contentString{units: run.text}).String()
+		if text == "" {
+			continue
+		}
+		node := map[string]interface{}{"type": "text", "text": text}
+		if marks := runMarks(run.attrs); len(marks) > 0 {
+			node["marks"] = marks
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func sameAttributes(a, b map[string]json.RawMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if string(b[k]) != string(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func runMarks(attrs map[string]json.RawMessage) []interface{} {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	marks := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		name := key
+		if m := hashedMarkName.FindStringSubmatch(key); m != nil {
+			name = m[1]
+		}
+		mark := map[string]interface{}{"type": name}
+		var markAttrs map[string]interface{}
+		if err := json.Unmarshal(attrs[key], &markAttrs); err == nil && len(markAttrs) > 0 {
+			mark["attrs"] = markAttrs
+		}
+		marks = append(marks, mark)
+	}
+	return marks
+}
+
+// ReplaceFragment builds an update that deletes the fragment's current
+// content and inserts doc, a ProseMirror JSON document, as client. The
+// update is applied to d and returned so it can be persisted or broadcast;
+// clients that merge it keep their own concurrent edits.
+func (d *Doc) ReplaceFragment(fragment string, doc map[string]interface{}, client uint64) ([]byte, error) {
+	if len(d.clients[client]) > 0 {
+		return nil, fmt.Errorf("yjs: client %d already has structs in this document", client)
+	}
+
+	root := d.root(fragment)
+	deletes := make(map[uint64][]deleteRange)
+	var last *item
+	for it := root.start; it != nil; it = it.right {
+		if !it.deleted {
+			deletes[it.id.Client] = append(deletes[it.id.Client], deleteRange{clock: it.id.Clock, length: uint64(it.length)})
+		}
+		last = it
+	}
+
+	b := &fragmentBuilder{client: client}
+	var origin *ID
+	if last != nil {
+		origin = ptrID(last.lastID())
+	}
+	if err := b.insertChildren(root, origin, nodeContent(doc)); err != nil {
+		return nil, err
+	}
+
+	e := &encoder{}
+	writeStructs(e, map[uint64][]*item{client: b.items})
+	writeDeleteSet(e, deletes)
+	update := e.bytes()
+
+	if err := d.ApplyUpdate(update); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// fragmentBuilder creates the structs y-prosemirror would for a new document
+type fragmentBuilder struct {
+	client uint64
+	clock  uint64
+	items  []*item
+}
+
+func (b *fragmentBuilder) add(parent *sharedType, origin *ID, parentSub *string, c content) *item {
+	it := &item{
+		id:        ID{Client: b.client, Clock: b.clock},
+		length:    c.length(),
+		origin:    origin,
+		parent:    parent,
+		parentSub: parentSub,
+		content:   c,
+	}
+	b.clock += uint64(it.length)
+	b.items = append(b.items, it)
+	return it
+}
+
+// insertChildren appends nodes to parent after origin. Consecutive text
+// nodes share one XmlText, as in y-prosemirror.
+func (b *fragmentBuilder) insertChildren(parent *sharedType, origin *ID, nodes []map[string]interface{}) error {
+	for i := 0; i < len(nodes); {
+		if nodeType(nodes[i]) == "text" {
+			j := i
+			for j < len(nodes) && nodeType(nodes[j]) == "text" {
+				j++
+			}
+			text := newSharedType(TypeXMLText)
+			it := b.add(parent, origin, nil, &contentType{t: text})
+			text.item = it
+			if err := b.insertText(text, nodes[i:j]); err != nil {
+				return err
+			}
+			origin = ptrID(it.lastID())
+			i = j
+			continue
+		}
+
+		name := nodeType(nodes[i])
+		if name == "" {
+			return fmt.Errorf("yjs: node without a type")
+		}
+		element := newSharedType(TypeXMLElement)
+		element.name = name
+		it := b.add(parent, origin, nil, &contentType{t: element})
+		element.item = it
+
+		if attrs, ok := nodes[i]["attrs"].(map[string]interface{}); ok {
+			keys := make([]string, 0, len(attrs))
+			for k := range attrs {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if attrs[key] == nil {
+					continue
+				}
+				key := key
+				b.add(element, nil, &key, &contentAny{values: []interface{}{attrs[key]}})
+			}
+		}
+		if err := b.insertChildren(element, nil, nodeContent(nodes[i])); err != nil {
+			return err
+		}
+		origin = ptrID(it.lastID())
+		i++
+	}
+	return nil
+}
+
+// insertText writes text runs with their marks as format attributes
+func (b *fragmentBuilder) insertText(text *sharedType, nodes []map[string]interface{}) error {
+	var origin *ID
+	current := make(map[string]string)
+	setFormat := func(key, value string) {
+		it := b.add(text, origin, nil, &contentFormat{key: key, value: json.RawMessage(value)})
+		origin = ptrID(it.lastID())
+	}
+
+	for _, node := range nodes {
+		s, _ := node["text"].(string)
+		if s == "" {
+			continue
+		}
+		want, err := textMarks(node)
+		if err != nil {
+			return err
+		}
+		for _, key := range sortedKeys(current) {
+			if _, ok := want[key]; !ok {
+				setFormat(key, "null")
+				delete(current, key)
+			}
+		}
+		for _, key := range sortedKeys(want) {
+			if current[key] != want[key] {
+				setFormat(key, want[key])
+				current[key] = want[key]
+			}
+		}
+		it := b.add(text, origin, nil, newContentString(s))
+		origin = ptrID(it.lastID())
+	}
+	for _, key := range sortedKeys(current) {
+		setFormat(key, "null")
+	}
+	return nil
+}
+
+// textMarks maps a text node's marks to format attributes: mark name to
+// the JSON of its attrs
+func textMarks(node map[string]interface{}) (map[string]string, error) {
+	out := make(map[string]string)
+	marks, _ := node["marks"].([]interface{})
+	for _, m := range marks {
+		mark, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := mark["type"].(string)
+		if name == "" {
+			continue
+		}
+		attrs, ok := mark["attrs"].(map[string]interface{})
+		if !ok {
+			attrs = map[string]interface{}{}
+		}
+		value, err := json.Marshal(attrs)
+		if err != nil {
+			return nil, fmt.Errorf("yjs: encode %s mark: %w", name, err)
+		}
+		out[name] = string(value)
+	}
+	return out, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func nodeType(node map[string]interface{}) string {
+	t, _ := node["type"].(string)
+	return t
+}
+
+func nodeContent(node map[string]interface{}) []map[string]interface{} {
+	raw, _ := node["content"].([]interface{})
+	out := make([]map[string]interface{}, 0, len(raw))
+	for _, child := range raw {
+		if m, ok := child.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}